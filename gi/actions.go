@@ -89,11 +89,11 @@ func (g *Action) ButtonRelease() {
 	wasPressed := (g.State == ButtonDown)
 	updt := g.UpdateStart()
 	g.SetButtonState(ButtonActive)
-	g.ButtonSig.Emit(g.This, int64(ButtonReleased), nil)
+	g.ButtonSignal().Emit(g.This, ButtonReleased, nil)
 	menOpen := false
 	if wasPressed {
 		g.ActionSig.Emit(g.This, 0, g.Data)
-		g.ButtonSig.Emit(g.This, int64(ButtonClicked), g.Data)
+		g.ButtonSignal().Emit(g.This, ButtonClicked, g.Data)
 		menOpen = g.OpenMenu()
 	}
 	if !menOpen && g.IsMenu() && g.Viewport != nil {
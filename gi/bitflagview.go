@@ -0,0 +1,134 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gi
+
+import (
+	"reflect"
+
+	"github.com/rcoreilly/goki/ki"
+	"github.com/rcoreilly/goki/ki/bitflag"
+	"github.com/rcoreilly/goki/ki/kit"
+)
+
+////////////////////////////////////////////////////////////////////////////////////////
+//  BitFlagView
+
+// BitFlagView represents a set of bit flags of a bit flag enum type (see kit.EnumRegistry) as a row of checkboxes, one for each flag, in Parts -- the descriptions of the flags are the tooltips of the checkboxes
+type BitFlagView struct {
+	WidgetBase
+	EnumType reflect.Type `desc:"the bit flag enum type that we are a view onto"`
+	Bits     int64        `desc:"the current value of the bit flags"`
+	BitsSig  ki.Signal    `json:"-" xml:"-" desc:"signal for when the user checks or unchecks a flag -- data is the new value of the bit flags, as an int64"`
+}
+
+var KiT_BitFlagView = kit.Types.AddType(&BitFlagView{}, BitFlagViewProps)
+
+func (n *BitFlagView) New() ki.Ki { return &BitFlagView{} }
+
+var BitFlagViewProps = ki.Props{}
+
+// SetBits sets the enum type and current value of the bit flags -- rebuilds the checkboxes as needed
+func (fv *BitFlagView) SetBits(typ reflect.Type, flags int64) {
+	fv.EnumType = typ
+	fv.Bits = flags
+	fv.ConfigParts()
+}
+
+// ConfigParts configures Parts for the current enum type and flags
+func (fv *BitFlagView) ConfigParts() {
+	if fv.EnumType == nil {
+		return
+	}
+	fv.Parts.Lay = LayoutRow
+	vals := kit.Enums.TypeValues(fv.EnumType, true)
+	config := kit.TypeAndNameList{} // note: slice is already a pointer
+	for _, ev := range vals {
+		config.Add(KiT_CheckBox, "flag-"+ev.Name)
+	}
+	mods, updt := fv.Parts.ConfigChildren(config, false)
+	if !mods {
+		updt = fv.Parts.UpdateStart()
+	}
+	for i, ev := range vals {
+		cb := fv.Parts.Child(i).(*CheckBox)
+		cb.SetProp("vertical-align", AlignMiddle)
+		cb.Text = ev.Name
+		cb.Tooltip = ev.Desc
+		cb.SetChecked(ev.Value >= 0 && ev.Value < 64 && bitflag.Has(fv.Bits, int(ev.Value)))
+		cb.SetInactiveState(fv.IsInactive())
+		bit := int(ev.Value)
+		cb.ButtonSig.ConnectOnly(fv.This, func(recv, send ki.Ki, sig int64, data interface{}) {
+			if sig != int64(ButtonToggled) {
+				return
+			}
+			fvv, _ := recv.EmbeddedStruct(KiT_BitFlagView).(*BitFlagView)
+			cbb := send.(*CheckBox)
+			bitflag.SetState(&fvv.Bits, cbb.IsChecked(), bit)
+			fvv.BitsSig.Emit(fvv.This, 0, fvv.Bits)
+		})
+	}
+	fv.Parts.UpdateEnd(updt)
+}
+
+func (fv *BitFlagView) Style2D() {
+	fv.ConfigParts()
+	fv.WidgetBase.Style2D()
+}
+
+func (fv *BitFlagView) Render2D() {
+	if fv.PushBounds() {
+		fv.Render2DParts()
+		fv.Render2DChildren()
+		fv.PopBounds()
+	}
+}
+
+// check for interface implementation
+var _ Node2D = &BitFlagView{}
+
+////////////////////////////////////////////////////////////////////////////////////////
+//  BitFlagValueView
+
+// BitFlagValueView presents a BitFlagView of checkboxes for a bit flag enum value
+type BitFlagValueView struct {
+	ValueViewBase
+}
+
+var KiT_BitFlagValueView = kit.Types.AddType(&BitFlagValueView{}, nil)
+
+func (n *BitFlagValueView) New() ki.Ki { return &BitFlagValueView{} }
+
+func (vv *BitFlagValueView) WidgetType() reflect.Type {
+	vv.WidgetTyp = KiT_BitFlagView
+	return vv.WidgetTyp
+}
+
+// EnumType returns the bit flag enum type of the value
+func (vv *BitFlagValueView) EnumType() reflect.Type {
+	// derive type indirectly from the interface instead of directly from the value
+	// because that works for interface{} types as in property maps
+	return kit.NonPtrType(reflect.TypeOf(vv.Value.Interface()))
+}
+
+func (vv *BitFlagValueView) UpdateWidget() {
+	fv := vv.Widget.(*BitFlagView)
+	npv := kit.NonPtrValue(vv.Value)
+	iv, _ := kit.ToInt(npv.Interface())
+	fv.SetBits(vv.EnumType(), iv)
+}
+
+func (vv *BitFlagValueView) ConfigWidget(widg Node2D) {
+	vv.Widget = widg
+	fv := vv.Widget.(*BitFlagView)
+	fv.SetInactiveState(vv.This.(ValueView).IsInactive())
+	vv.UpdateWidget()
+	fv.BitsSig.ConnectOnly(vv.This, func(recv, send ki.Ki, sig int64, data interface{}) {
+		vvv, _ := recv.EmbeddedStruct(KiT_BitFlagValueView).(*BitFlagValueView)
+		flags := data.(int64)
+		if vvv.SetValue(kit.EnumIfaceFromInt64(flags, vvv.EnumType())) {
+			vvv.UpdateWidget()
+		}
+	})
+}
@@ -61,6 +61,8 @@ const (
 
 //go:generate stringer -type=ButtonSignals
 
+var KiT_ButtonSignals = kit.Enums.AddEnum(ButtonSignalsN, false, nil)
+
 // https://ux.stackexchange.com/questions/84872/what-is-the-buttons-unpressed-and-unhovered-state-called
 
 // mutually-exclusive button states -- determines appearance
@@ -120,6 +122,12 @@ var KiT_ButtonBase = kit.Types.AddType(&ButtonBase{}, ButtonBaseProps)
 
 func (n *ButtonBase) New() ki.Ki { return &ButtonBase{} }
 
+// ButtonSignal returns the typed interface to the ButtonSig signal -- data is
+// nil for buttons, and the Data for actions
+func (g *ButtonBase) ButtonSignal() ki.TypedSignal[ButtonSignals, interface{}] {
+	return ki.Typed[ButtonSignals, interface{}](&g.ButtonSig)
+}
+
 var ButtonBaseProps = ki.Props{
 	"base-type": true, // excludes type from user selections
 }
@@ -203,7 +211,7 @@ func (g *ButtonBase) SetButtonState(state ButtonStates) {
 func (g *ButtonBase) ButtonPressed() {
 	updt := g.UpdateStart()
 	g.SetButtonState(ButtonDown)
-	g.ButtonSig.Emit(g.This, int64(ButtonPressed), nil)
+	g.ButtonSignal().Emit(g.This, ButtonPressed, nil)
 	g.UpdateEnd(updt)
 }
 
@@ -213,14 +221,14 @@ func (g *ButtonBase) ButtonReleased() {
 	wasPressed := (g.State == ButtonDown)
 	updt := g.UpdateStart()
 	g.SetButtonState(ButtonActive)
-	g.ButtonSig.Emit(g.This, int64(ButtonReleased), nil)
+	g.ButtonSignal().Emit(g.This, ButtonReleased, nil)
 	if wasPressed {
-		g.ButtonSig.Emit(g.This, int64(ButtonClicked), nil)
+		g.ButtonSignal().Emit(g.This, ButtonClicked, nil)
 		g.OpenMenu()
 
 		if g.IsCheckable() {
 			g.ToggleChecked()
-			g.ButtonSig.Emit(g.This, int64(ButtonToggled), nil)
+			g.ButtonSignal().Emit(g.This, ButtonToggled, nil)
 		}
 	}
 	g.UpdateEnd(updt)
@@ -404,8 +412,10 @@ func Init2DButtonEvents(bw ButtonWidget) {
 		bb := ab.ButtonAsBase()
 		if me.Action == mouse.Enter {
 			bb.ButtonEnterHover()
+			bb.PopupTooltip()
 		} else {
 			bb.ButtonExitHover()
+			bb.DeleteTooltip()
 		}
 	})
 	g.ReceiveEventType(oswin.KeyChordEvent, func(recv, send ki.Ki, sig int64, d interface{}) {
@@ -399,6 +399,14 @@ var _ Node2D = &Dialog{}
 // the interface type -- optionally connects to given signal receiving object
 // and function for dialog signals (nil to ignore)
 func NewKiDialog(avp *Viewport2D, iface reflect.Type, title, prompt string, recv ki.Ki, fun ki.RecvFunc) *Dialog {
+	return NewKiTypesDialog(avp, kit.Types.AllImplementersOf(iface, false), title, prompt, recv, fun)
+}
+
+// New Ki item(s) of type dialog, showing given list of types -- e.g., those
+// allowed as children of a given node (see ki.AllowedChildTypes) --
+// optionally connects to given signal receiving object and function for
+// dialog signals (nil to ignore)
+func NewKiTypesDialog(avp *Viewport2D, typs []reflect.Type, title, prompt string, recv ki.Ki, fun ki.RecvFunc) *Dialog {
 	dlg := NewStdDialog("new-ki", title, prompt, true, true)
 
 	frame := dlg.Frame()
@@ -428,8 +436,8 @@ func NewKiDialog(avp *Viewport2D, iface reflect.Type, title, prompt string, recv
 	tlbl := trow.AddNewChild(KiT_Label, "t-label").(*Label)
 	tlbl.Text = "Type:    "
 
-	typs := trow.AddNewChild(KiT_ComboBox, "types").(*ComboBox)
-	typs.ItemsFromTypes(kit.Types.AllImplementersOf(iface, false), true, true, 50)
+	tcb := trow.AddNewChild(KiT_ComboBox, "types").(*ComboBox)
+	tcb.ItemsFromTypes(typs, true, true, 50)
 
 	if recv != nil && fun != nil {
 		dlg.DialogSig.Connect(recv, fun)
@@ -7,6 +7,9 @@ package gi
 import (
 	"fmt"
 
+	"github.com/rcoreilly/goki/gi/oswin"
+	"github.com/rcoreilly/goki/gi/oswin/key"
+	"github.com/rcoreilly/goki/gi/oswin/lifecycle"
 	"github.com/rcoreilly/goki/gi/units"
 	"github.com/rcoreilly/goki/ki"
 )
@@ -18,6 +21,15 @@ func GoGiEditorOf(obj ki.Ki) {
 	win := NewWindow2D("GoGi Editor Window", width, height, true)
 
 	vp := win.WinViewport2D()
+	prvus := ki.UndoStackFor(obj)
+	us := ki.AttachUndoStack(obj, 0)
+	if us != prvus { // ours, so detach when the window closes
+		win.ReceiveEventType(vp.This, oswin.LifeCycleEvent, func(recv, send ki.Ki, sig int64, d interface{}) {
+			if e, ok := d.(*lifecycle.Event); ok && e.To == lifecycle.StageDead {
+				us.Detach()
+			}
+		})
+	}
 	updt := vp.UpdateStart()
 	vp.SetProp("background-color", "#FFF")
 	vp.Fill = true
@@ -100,11 +112,46 @@ func GoGiEditorOf(obj ki.Ki) {
 					dlg, _ := send.(*Dialog)
 					fnm := StringPromptDialogValue(dlg)
 					recv.LoadJSONFromFile(fnm)
+					us.Reset() // loading is not recorded, so prior history is invalid
 				}
 			})
 		}
 	})
 
+	undob := brow.AddNewChild(KiT_Button, "undo").(*Button)
+	undob.SetText("Undo")
+	undob.ButtonSig.Connect(win.This, func(recv, send ki.Ki, sig int64, data interface{}) {
+		if sig == int64(ButtonClicked) {
+			us.Undo()
+		}
+	})
+
+	redob := brow.AddNewChild(KiT_Button, "redo").(*Button)
+	redob.SetText("Redo")
+	redob.ButtonSig.Connect(win.This, func(recv, send ki.Ki, sig int64, data interface{}) {
+		if sig == int64(ButtonClicked) {
+			us.Redo()
+		}
+	})
+
+	// viewport gets key events last, so the tree view undo / redo keys are
+	// not also processed here
+	win.ReceiveEventType(vp.This, oswin.KeyChordEvent, func(recv, send ki.Ki, sig int64, d interface{}) {
+		kt := d.(*key.ChordEvent)
+		if kt.IsProcessed() {
+			return
+		}
+		kf := KeyFun(kt.ChordString())
+		switch kf {
+		case KeyFunUndo:
+			us.Undo()
+			kt.SetProcessed()
+		case KeyFunRedo:
+			us.Redo()
+			kt.SetProcessed()
+		}
+	})
+
 	vp.UpdateEndNoSig(updt)
 	go win.StartEventLoopNoWait()
 }
@@ -51,6 +51,8 @@ const (
 	KeyFunZoomIn
 	KeyFunPrefs
 	KeyFunRefresh
+	KeyFunUndo
+	KeyFunRedo
 	KeyFunctionsN
 )
 
@@ -112,6 +114,10 @@ var DefaultKeyMap = KeyMap{
 	"Shift+Meta+-":     KeyFunZoomOut,
 	"Control+Alt+P":    KeyFunPrefs,
 	"F5":               KeyFunRefresh,
+	"Control+Z":        KeyFunUndo,
+	"Meta+Z":           KeyFunUndo,
+	"Shift+Control+Z":  KeyFunRedo,
+	"Shift+Meta+Z":     KeyFunRedo,
 }
 
 // ActiveKeyMap points to the active map -- users can set this to an
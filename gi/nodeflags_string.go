@@ -27,3 +27,13 @@ func StringToNodeFlags(s string) (NodeFlags, error) {
 	}
 	return 0, fmt.Errorf("String %v is not a valid option for type NodeFlags", s)
 }
+
+func (i *NodeFlags) FromString(s string) error {
+	for j := 0; j < len(_NodeFlags_index)-1; j++ {
+		if s == _NodeFlags_name[_NodeFlags_index[j]:_NodeFlags_index[j+1]] {
+			*i = NodeFlags(j + 14)
+			return nil
+		}
+	}
+	return fmt.Errorf("String %v is not a valid option for type NodeFlags", s)
+}
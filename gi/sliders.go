@@ -34,6 +34,8 @@ const (
 
 //go:generate stringer -type=SliderSignals
 
+var KiT_SliderSignals = kit.Enums.AddEnum(SliderSignalsN, false, nil)
+
 // SliderBase has common slider functionality -- two major modes: ValThumb =
 // false is a slider with a fixed-size thumb knob, while = true has a thumb
 // that represents a value, as in a scrollbar, and the scrolling range is size
@@ -71,6 +73,12 @@ var KiT_SliderBase = kit.Types.AddType(&SliderBase{}, SliderBaseProps)
 
 func (n *SliderBase) New() ki.Ki { return &SliderBase{} }
 
+// SliderSignal returns the typed interface to the SliderSig signal -- data is
+// the slider Value
+func (g *SliderBase) SliderSignal() ki.TypedSignal[SliderSignals, float32] {
+	return ki.Typed[SliderSignals, float32](&g.SliderSig)
+}
+
 var SliderBaseProps = ki.Props{
 	"base-type": true,
 }
@@ -141,7 +149,7 @@ func (g *SliderBase) SliderPressed(pos float32) {
 	updt := g.UpdateStart()
 	g.SetSliderState(SliderDown)
 	g.SetSliderPos(pos)
-	g.SliderSig.Emit(g.This, int64(SliderPressed), g.Value)
+	g.SliderSignal().Emit(g.This, SliderPressed, g.Value)
 	// bitflag.Set(&g.Flag, int(SliderFlagDragging))
 	g.UpdateEnd(updt)
 }
@@ -152,9 +160,9 @@ func (g *SliderBase) SliderReleased() {
 	wasPressed := (g.State == SliderDown)
 	updt := g.UpdateStart()
 	g.SetSliderState(SliderActive)
-	g.SliderSig.Emit(g.This, int64(SliderReleased), g.Value)
+	g.SliderSignal().Emit(g.This, SliderReleased, g.Value)
 	if wasPressed && g.Value != g.EmitValue {
-		g.SliderSig.Emit(g.This, int64(SliderValueChanged), g.Value)
+		g.SliderSignal().Emit(g.This, SliderValueChanged, g.Value)
 		g.EmitValue = g.Value
 	}
 	g.UpdateEnd(updt)
@@ -211,7 +219,7 @@ func (g *SliderBase) SetSliderPos(pos float32) {
 	}
 	if g.Tracking && g.Value != g.EmitValue {
 		if math32.Abs(g.Value-g.EmitValue) > g.TrackThr {
-			g.SliderSig.Emit(g.This, int64(SliderValueChanged), g.Value)
+			g.SliderSignal().Emit(g.This, SliderValueChanged, g.Value)
 			g.EmitValue = g.Value
 		}
 	}
@@ -258,7 +266,7 @@ func (g *SliderBase) SetValueAction(val float32) {
 		return
 	}
 	g.SetValue(val)
-	g.SliderSig.Emit(g.This, int64(SliderValueChanged), g.Value)
+	g.SliderSignal().Emit(g.This, SliderValueChanged, g.Value)
 }
 
 func (g *SliderBase) SetThumbValue(val float32) {
@@ -54,7 +54,18 @@ func (sv *StructView) SetStruct(st interface{}, tmpSave ValueView) {
 		updt = sv.UpdateStart()
 		sv.Struct = st
 		if k, ok := st.(ki.Ki); ok {
+			if cn := ki.ChangeNotifierFor(k); cn != nil {
+				cn.ChangeSig.Connect(sv.This, func(recv, send ki.Ki, sig int64, data interface{}) {
+					svv, _ := recv.EmbeddedStruct(KiT_StructView).(*StructView)
+					if svv.UpdateChangedFields(data.(ki.ChangeBatch)) {
+						svv.ViewSig.Emit(svv.This, 0, nil)
+					}
+				})
+			}
 			k.NodeSignal().Connect(sv.This, func(recv, send ki.Ki, sig int64, data interface{}) {
+				if cn := ki.ChangeNotifierFor(send); cn != nil && cn.Covers(send) {
+					return // UpdateChangedFields handled it
+				}
 				svv, _ := recv.EmbeddedStruct(KiT_StructView).(*StructView)
 				svv.UpdateFields()
 				svv.ViewSig.Emit(svv.This, 0, nil)
@@ -147,7 +158,7 @@ func (sv *StructView) ConfigStructGrid() {
 	sv.FieldViews = make([]ValueView, 0)
 	kit.FlatFieldsValueFun(sv.Struct, func(fval interface{}, typ reflect.Type, field reflect.StructField, fieldVal reflect.Value) bool {
 		// todo: check tags, skip various etc
-		vwtag := field.Tag.Get("view")
+		vwtag := kit.FieldTag(typ, field.Name, "view")
 		if vwtag == "-" {
 			return true
 		}
@@ -181,7 +192,7 @@ func (sv *StructView) ConfigStructGrid() {
 			// note: updating here is redundant -- relevant field will have already updated
 			svv.ViewSig.Emit(svv.This, 0, nil)
 		})
-		lbltag := vvb.Field.Tag.Get("label")
+		lbltag := vvb.ViewFieldTag("label")
 		if lbltag != "" {
 			lbl.Text = lbltag
 		} else {
@@ -212,6 +223,22 @@ func (sv *StructView) UpdateFields() {
 	sv.UpdateEnd(updt)
 }
 
+// UpdateChangedFields updates only the field views for the fields of our
+// struct, if it is a Ki node, that were changed (SetField) in given
+// ki.ChangeBatch -- returns true if any were updated
+func (sv *StructView) UpdateChangedFields(cb ki.ChangeBatch) bool {
+	fvs := ChangedFieldViews(sv.Struct, sv.FieldViews, cb)
+	if len(fvs) == 0 {
+		return false
+	}
+	updt := sv.UpdateStart()
+	for _, vv := range fvs {
+		vv.UpdateWidget()
+	}
+	sv.UpdateEnd(updt)
+	return true
+}
+
 func (sv *StructView) Style2D() {
 	sv.Frame.Style2D()
 	sv.UpdateFromStruct()
@@ -263,7 +290,18 @@ func (sv *StructViewInline) SetStruct(st interface{}, tmpSave ValueView) {
 		updt = sv.UpdateStart()
 		sv.Struct = st
 		if k, ok := st.(ki.Ki); ok {
+			if cn := ki.ChangeNotifierFor(k); cn != nil {
+				cn.ChangeSig.Connect(sv.This, func(recv, send ki.Ki, sig int64, data interface{}) {
+					svv, _ := recv.EmbeddedStruct(KiT_StructViewInline).(*StructViewInline)
+					if svv.UpdateChangedFields(data.(ki.ChangeBatch)) {
+						svv.ViewSig.Emit(svv.This, 0, k)
+					}
+				})
+			}
 			k.NodeSignal().Connect(sv.This, func(recv, send ki.Ki, sig int64, data interface{}) {
+				if cn := ki.ChangeNotifierFor(send); cn != nil && cn.Covers(send) {
+					return // UpdateChangedFields handled it
+				}
 				svv, _ := recv.EmbeddedStruct(KiT_StructViewInline).(*StructViewInline)
 				svv.UpdateFields()
 				fmt.Printf("struct view inline ki update values\n")
@@ -291,7 +329,7 @@ func (sv *StructViewInline) ConfigParts() {
 	sv.FieldViews = make([]ValueView, 0)
 	kit.FlatFieldsValueFun(sv.Struct, func(fval interface{}, typ reflect.Type, field reflect.StructField, fieldVal reflect.Value) bool {
 		// todo: check tags, skip various etc
-		vwtag := field.Tag.Get("view")
+		vwtag := kit.FieldTag(typ, field.Name, "view")
 		if vwtag == "-" {
 			return true
 		}
@@ -326,7 +364,7 @@ func (sv *StructViewInline) ConfigParts() {
 			// note: updating here is redundant
 			svv.ViewSig.Emit(svv.This, 0, nil)
 		})
-		lbltag := vvb.Field.Tag.Get("label")
+		lbltag := vvb.ViewFieldTag("label")
 		if lbltag != "" {
 			lbl.Text = lbltag
 		} else {
@@ -351,6 +389,22 @@ func (sv *StructViewInline) UpdateFields() {
 	sv.UpdateEnd(updt)
 }
 
+// UpdateChangedFields updates only the field views for the fields of our
+// struct, if it is a Ki node, that were changed (SetField) in given
+// ki.ChangeBatch -- returns true if any were updated
+func (sv *StructViewInline) UpdateChangedFields(cb ki.ChangeBatch) bool {
+	fvs := ChangedFieldViews(sv.Struct, sv.FieldViews, cb)
+	if len(fvs) == 0 {
+		return false
+	}
+	updt := sv.UpdateStart()
+	for _, vv := range fvs {
+		vv.UpdateWidget()
+	}
+	sv.UpdateEnd(updt)
+	return true
+}
+
 func (sv *StructViewInline) Render2D() {
 	if sv.PushBounds() {
 		sv.Render2DParts()
@@ -367,3 +421,27 @@ func (sv *StructViewInline) ReRender2D() (node Node2D, layout bool) {
 
 // check for interface implementation
 var _ Node2D = &StructViewInline{}
+
+// ChangedFieldViews returns the field views, from among given ones for
+// struct st, for the fields that were changed (SetField) in given
+// ki.ChangeBatch -- st must be a Ki node to have any changes
+func ChangedFieldViews(st interface{}, fvs []ValueView, cb ki.ChangeBatch) []ValueView {
+	k, ok := st.(ki.Ki)
+	if !ok {
+		return nil
+	}
+	var chg []ValueView
+	for _, cr := range cb {
+		if cr.Op != ki.ChangeSetField || cr.Node != k {
+			continue
+		}
+		for _, vv := range fvs {
+			vvb := vv.AsValueViewBase()
+			if vvb.Field != nil && vvb.Field.Name == cr.Key {
+				chg = append(chg, vv)
+				break
+			}
+		}
+	}
+	return chg
+}
@@ -615,8 +615,8 @@ func (fld *StyledField) FromProps(fields map[string]*StyledField, obj, par, val
 					kit.SetEnumFromInt64(fi, ival, npt)
 				}
 			}
-		} else {
-			kit.SetRobust(fi, val)
+		} else if err := kit.Convert(fi, val); err != nil {
+			log.Printf("gi.StyledField.FromProps: for field: %v: %v\n", fld.Field.Name, err)
 		}
 	}
 }
@@ -34,6 +34,8 @@ const (
 
 //go:generate stringer -type=TabViewSignals
 
+var KiT_TabViewSignals = kit.Enums.AddEnum(TabViewSignalsN, false, nil)
+
 // todo: could have different positioning of the tabs?
 
 // TabView represents children of a source node as tabs with a stacked
@@ -114,6 +114,8 @@ const (
 
 //go:generate stringer -type=TextFieldSignals
 
+var KiT_TextFieldSignals = kit.Enums.AddEnum(TextFieldSignalsN, false, nil)
+
 // mutually-exclusive textfield states -- determines appearance
 type TextFieldStates int32
 
@@ -155,6 +157,12 @@ var KiT_TextField = kit.Types.AddType(&TextField{}, TextFieldProps)
 
 func (n *TextField) New() ki.Ki { return &TextField{} }
 
+// TextFieldSignal returns the typed interface to the TextFieldSig signal --
+// data is the Text
+func (g *TextField) TextFieldSignal() ki.TypedSignal[TextFieldSignals, string] {
+	return ki.Typed[TextFieldSignals, string](&g.TextFieldSig)
+}
+
 var TextFieldProps = ki.Props{
 	"border-width":                      units.NewValue(1, units.Px),
 	"border-color":                      &Prefs.BorderColor,
@@ -186,7 +194,7 @@ func (g *TextField) SetText(txt string) {
 func (g *TextField) EditDone() {
 	if g.Text != g.EditText {
 		g.Text = g.EditText
-		g.TextFieldSig.Emit(g.This, int64(TextFieldDone), g.Text)
+		g.TextFieldSignal().Emit(g.This, TextFieldDone, g.Text)
 	}
 }
 
@@ -938,9 +946,9 @@ func (g *ComboBox) ButtonRelease() {
 	updt := g.UpdateStart()
 	g.MakeItemsMenu()
 	g.SetButtonState(ButtonActive)
-	g.ButtonSig.Emit(g.This, int64(ButtonReleased), nil)
+	g.ButtonSignal().Emit(g.This, ButtonReleased, nil)
 	if wasPressed {
-		g.ButtonSig.Emit(g.This, int64(ButtonClicked), nil)
+		g.ButtonSignal().Emit(g.This, ButtonClicked, nil)
 	}
 	g.UpdateEnd(updt)
 	pos := g.WinBBox.Max
@@ -1002,7 +1010,7 @@ func (g *ComboBox) ItemsFromTypes(tl []reflect.Type, setFirst, sort bool, maxLen
 	}
 }
 
-// ItemsFromEnumList sets the Items list from a list of enum values (see kit.EnumRegistry) -- if setFirst then set current item to the first item in the list, and maxLen if > 0 auto-sets the width of the button to the contents, with the given upper limit
+// ItemsFromEnumList sets the Items list from a list of enum values (see kit.EnumRegistry) -- if setFirst then set current item to the first item in the list, and maxLen if > 0 auto-sets the width of the button to the contents, with the given upper limit -- the descriptions of the values are the tooltips of the items
 func (g *ComboBox) ItemsFromEnumList(el []kit.EnumValue, setFirst bool, maxLen int) {
 	sz := len(el)
 	g.Items = make([]interface{}, sz)
@@ -1017,7 +1025,7 @@ func (g *ComboBox) ItemsFromEnumList(el []kit.EnumValue, setFirst bool, maxLen i
 	}
 }
 
-// ItemsFromEnum sets the Items list from an enum type, which must be registered on kit.EnumRegistry -- if setFirst then set current item to the first item in the list, and maxLen if > 0 auto-sets the width of the button to the contents, with the given upper limit -- see kit.EnumRegistry, and maxLen if > 0 auto-sets the width of the button to the contents, with the given upper limit
+// ItemsFromEnum sets the Items list from an enum type, which must be registered on kit.EnumRegistry -- if setFirst then set current item to the first item in the list, and maxLen if > 0 auto-sets the width of the button to the contents, with the given upper limit -- see kit.EnumRegistry, and maxLen if > 0 auto-sets the width of the button to the contents, with the given upper limit -- the descriptions of the values are the tooltips of the items
 func (g *ComboBox) ItemsFromEnum(enumtyp reflect.Type, setFirst bool, maxLen int) {
 	g.ItemsFromEnumList(kit.Enums.TypeValues(enumtyp, true), setFirst, maxLen)
 }
@@ -1095,6 +1103,9 @@ func (g *ComboBox) MakeItemsMenu() {
 		nm := fmt.Sprintf("Item_%v", i)
 		ac.SetName(nm)
 		ac.Text = txt
+		if ev, ok := it.(kit.EnumValue); ok {
+			ac.Tooltip = ev.Desc
+		}
 		ac.Data = i // index is the data
 		ac.SetSelected(i == g.CurIndex)
 		ac.SetAsMenu()
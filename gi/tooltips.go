@@ -0,0 +1,86 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gi
+
+import (
+	"image"
+
+	"github.com/rcoreilly/goki/gi/units"
+	"github.com/rcoreilly/goki/ki"
+	"github.com/rcoreilly/goki/ki/bitflag"
+	"github.com/rcoreilly/goki/ki/kit"
+)
+
+// Tooltips are small popups showing the Tooltip text of a widget while the
+// mouse is over it -- unlike other popups, they do not get any events or
+// focus, and are removed when the mouse leaves the widget, or on any click,
+// scroll, or key press -- see Window.SetTooltip
+
+var TooltipFrameProps = ki.Props{
+	"border-width":        units.NewValue(1, units.Px),
+	"border-color":        &Prefs.BorderColor,
+	"background-color":    &Prefs.BackgroundColor,
+	"padding":             units.NewValue(2, units.Px),
+	"box-shadow.h-offset": units.NewValue(2, units.Px),
+	"box-shadow.v-offset": units.NewValue(2, units.Px),
+	"box-shadow.blur":     units.NewValue(2, units.Px),
+	"box-shadow.color":    &Prefs.ShadowColor,
+}
+
+// PopupTooltip pops up a viewport showing the tooltip text at given position
+// in the window -- owner is the node that the tooltip is for, which is passed
+// to Window.DeleteTooltip to remove it -- does nothing and returns nil for
+// empty text
+func PopupTooltip(tooltip string, x, y int, win *Window, owner ki.Ki) *Viewport2D {
+	if win == nil || tooltip == "" {
+		return nil
+	}
+	mainVp := win.Viewport
+	pvp := Viewport2D{}
+	pvp.InitName(&pvp, owner.Name()+"Tooltip")
+	pvp.Win = win
+	updt := pvp.UpdateStart()
+	pvp.Fill = true
+	bitflag.Set(&pvp.Flag, int(VpFlagPopup))
+	bitflag.Set(&pvp.Flag, int(VpFlagPopupDestroyAll))
+
+	pvp.ViewBox.Min = image.Point{x, y}
+	frame := pvp.AddNewChild(KiT_Frame, "Frame").(*Frame)
+	frame.Lay = LayoutCol
+	frame.SetProps(TooltipFrameProps, false)
+	lbl := frame.AddNewChild(KiT_Label, "tooltip").(*Label)
+	lbl.Text = tooltip
+	frame.Init2DTree()
+	frame.Style2DTree()                                // sufficient to get sizes
+	frame.LayData.AllocSize = mainVp.LayData.AllocSize // give it the whole vp initially
+	frame.Size2DTree()                                 // collect sizes
+	pvp.Win = nil
+	vpsz := frame.LayData.Size.Pref.Min(mainVp.LayData.AllocSize).ToPoint()
+	x = kit.MaxInt(0, kit.MinInt(x, mainVp.ViewBox.Size.X-vpsz.X)) // fit
+	y = kit.MaxInt(0, kit.MinInt(y, mainVp.ViewBox.Size.Y-vpsz.Y)) // fit
+	pvp.Resize(vpsz.X, vpsz.Y)
+	pvp.ViewBox.Min = image.Point{x, y}
+	pvp.UpdateEndNoSig(updt)
+
+	win.SetTooltip(pvp.This, owner)
+	return &pvp
+}
+
+// PopupTooltip pops up the Tooltip of this widget, if it has one, just below
+// the widget -- called when the mouse enters the widget
+func (g *WidgetBase) PopupTooltip() {
+	if g.Tooltip == "" {
+		return
+	}
+	PopupTooltip(g.Tooltip, g.WinBBox.Min.X, g.WinBBox.Max.Y, g.ParentWindow(), g.This)
+}
+
+// DeleteTooltip removes the tooltip of this widget, if it is being shown --
+// called when the mouse exits the widget
+func (g *WidgetBase) DeleteTooltip() {
+	if win := g.ParentWindow(); win != nil {
+		win.DeleteTooltip(g.This)
+	}
+}
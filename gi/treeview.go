@@ -41,6 +41,8 @@ const (
 
 //go:generate stringer -type=TreeViewSignals
 
+var KiT_TreeViewSignals = kit.Enums.AddEnum(TreeViewSignalsN, false, nil)
+
 // todo: continuous select, extend select can now be read directly from mouse event
 
 // these extend NodeBase NodeFlags to hold TreeView state
@@ -93,12 +95,19 @@ type TreeView struct {
 	WidgetSize  Vec2D                  `desc:"just the size of our widget -- our alloc includes all of our children, but we only draw us"`
 	Icon        *Icon                  `json:"-" xml:"-" desc:"optional icon, displayed to the the left of the text label"`
 	RootWidget  *TreeView              `json:"-" xml:"-" view:"-" desc:"cached root widget"`
+	ChangeNote  *ki.ChangeNotifier     `json:"-" xml:"-" view:"-" desc:"on the root tree view, the ki.ChangeNotifier for the source tree, if one is attached -- only the nodes that changed are then synced, from the ChangeBatch, instead of re-syncing on each NodeSignalUpdated that the batch covers"`
 }
 
 var KiT_TreeView = kit.Types.AddType(&TreeView{}, TreeViewProps)
 
 func (n *TreeView) New() ki.Ki { return &TreeView{} }
 
+// TreeViewSignal returns the typed interface to the TreeViewSig signal --
+// data is the affected tree view node
+func (tv *TreeView) TreeViewSignal() ki.TypedSignal[TreeViewSignals, ki.Ki] {
+	return ki.Typed[TreeViewSignals, ki.Ki](&tv.TreeViewSig)
+}
+
 //////////////////////////////////////////////////////////////////////////////
 //    End-User API
 
@@ -110,6 +119,10 @@ func (tv *TreeView) SetSrcNode(sk ki.Ki) {
 		tv.SrcNode.Ptr = sk
 	}
 	sk.NodeSignal().Connect(tv.This, SrcNodeSignal) // we recv signals from source
+	if cn := ki.ChangeNotifierFor(sk); cn != nil && tv.RootTreeView() == tv {
+		tv.ChangeNote = cn
+		cn.ChangeSig.Connect(tv.This, SrcChangeSignal)
+	}
 	tv.SyncToSrc()
 	tv.UpdateEnd(updt)
 }
@@ -176,6 +189,9 @@ func (tv *TreeView) SyncToSrc() {
 func SrcNodeSignal(tvki, send ki.Ki, sig int64, data interface{}) {
 	tv := tvki.EmbeddedStruct(KiT_TreeView).(*TreeView)
 	// fmt.Printf("treeview: %v got signal: %v from node: %v  data: %v  flags %v\n", tv.PathUnique(), ki.NodeSignals(sig), send.PathUnique(), data, *send.Flags())
+	if rtv := tv.RootTreeView(); rtv.ChangeNote != nil && rtv.ChangeNote.Covers(send) {
+		return // SrcChangeSignal handled it
+	}
 	if data != nil {
 		dflags := data.(int64)
 		if bitflag.HasMask(dflags, int64(ki.ChildUpdateFlagsMask)) {
@@ -186,6 +202,56 @@ func SrcNodeSignal(tvki, send ki.Ki, sig int64, data interface{}) {
 	}
 }
 
+// function for receiving change signals from the ki.ChangeNotifier for our
+// SrcNode -- only connected on the root tree view
+func SrcChangeSignal(tvki, send ki.Ki, sig int64, data interface{}) {
+	tv := tvki.EmbeddedStruct(KiT_TreeView).(*TreeView)
+	if cb, ok := data.(ki.ChangeBatch); ok {
+		tv.SyncToSrcChanges(cb)
+	}
+}
+
+// SyncToSrcChanges syncs only the tree views for the source nodes that
+// changed in the batch: views of nodes whose children changed are synced
+// (SyncToSrc), and views of nodes whose fields or props changed are updated
+func (tv *TreeView) SyncToSrcChanges(cb ki.ChangeBatch) {
+	done := make(map[*TreeView]bool)
+	for _, cr := range cb {
+		sk := cr.Node
+		if cr.Op == ki.ChangeSetName && sk.Parent() != nil {
+			sk = sk.Parent() // view names are set from the parent
+		}
+		vtv := tv.TreeViewForSrc(sk)
+		if vtv == nil || done[vtv] {
+			continue
+		}
+		done[vtv] = true
+		if cr.Op >= ki.ChangeChildAdded {
+			vtv.SyncToSrc()
+		} else {
+			vtv.UpdateSig()
+		}
+	}
+}
+
+// TreeViewForSrc returns the tree view at or below this one that views given
+// source node -- nil if none
+func (tv *TreeView) TreeViewForSrc(sk ki.Ki) *TreeView {
+	var rtv *TreeView
+	tv.FuncDownMeFirst(0, nil, func(k ki.Ki, level int, d interface{}) bool {
+		if rtv != nil || !k.TypeEmbeds(KiT_TreeView) {
+			return false
+		}
+		nw := k.EmbeddedStruct(KiT_TreeView).(*TreeView)
+		if nw.SrcNode.Ptr == sk {
+			rtv = nw
+			return false
+		}
+		return true
+	})
+	return rtv
+}
+
 // return a list of the currently-selected source nodes
 func (tv *TreeView) SelectedSrcNodes() ki.Slice {
 	sn := make(ki.Slice, 0)
@@ -319,7 +385,7 @@ func (tv *TreeView) Select() {
 	if !tv.IsSelected() {
 		bitflag.Set(&tv.Flag, int(TreeViewFlagSelected))
 		tv.GrabFocus() // focus always follows select  todo: option
-		tv.RootWidget.TreeViewSig.Emit(tv.RootWidget.This, int64(TreeViewSelected), tv.This)
+		tv.RootWidget.TreeViewSignal().Emit(tv.RootWidget.This, TreeViewSelected, tv.This)
 		tv.UpdateSig()
 	}
 }
@@ -327,7 +393,7 @@ func (tv *TreeView) Select() {
 func (tv *TreeView) Unselect() {
 	if tv.IsSelected() {
 		bitflag.Clear(&tv.Flag, int(TreeViewFlagSelected))
-		tv.RootWidget.TreeViewSig.Emit(tv.RootWidget.This, int64(TreeViewUnselected), tv.This)
+		tv.RootWidget.TreeViewSignal().Emit(tv.RootWidget.This, TreeViewUnselected, tv.This)
 		tv.UpdateSig()
 	}
 }
@@ -439,7 +505,7 @@ func (tv *TreeView) Close() {
 			tv.SetFullReRender()
 		}
 		bitflag.Set(&tv.Flag, int(TreeViewFlagClosed))
-		tv.RootWidget.TreeViewSig.Emit(tv.RootWidget.This, int64(TreeViewClosed), tv.This)
+		tv.RootWidget.TreeViewSignal().Emit(tv.RootWidget.This, TreeViewClosed, tv.This)
 		tv.UpdateEnd(updt)
 	}
 }
@@ -451,7 +517,7 @@ func (tv *TreeView) Open() {
 			tv.SetFullReRender()
 		}
 		bitflag.Clear(&tv.Flag, int(TreeViewFlagClosed))
-		tv.RootWidget.TreeViewSig.Emit(tv.RootWidget.This, int64(TreeViewOpened), tv.This)
+		tv.RootWidget.TreeViewSignal().Emit(tv.RootWidget.This, TreeViewOpened, tv.This)
 		tv.UpdateEnd(updt)
 	}
 }
@@ -464,6 +530,13 @@ func (tv *TreeView) ToggleClose() {
 	}
 }
 
+// SrcAllowedChildTypes returns the types of nodes that can be added as
+// children of given node in the source tree, according to its
+// ki.ChildConstraints
+func (tv *TreeView) SrcAllowedChildTypes(par ki.Ki) []reflect.Type {
+	return ki.AllowedChildTypes(par, kit.Types.AllImplementersOf(reflect.TypeOf((*Node2D)(nil)).Elem(), false))
+}
+
 // insert a new node in the source tree
 func (tv *TreeView) SrcInsertAfter() {
 	ttl := "TreeView Insert After"
@@ -479,7 +552,12 @@ func (tv *TreeView) SrcInsertAfter() {
 		return
 	}
 	myidx := sk.Index()
-	NewKiDialog(tv.Viewport, reflect.TypeOf((*Node2D)(nil)).Elem(), ttl, "Number and Type of Items to Insert:", tv.This, func(recv, send ki.Ki, sig int64, data interface{}) {
+	typs := tv.SrcAllowedChildTypes(par)
+	if len(typs) == 0 {
+		PromptDialog(tv.Viewport, ttl, "No more items can be inserted here", true, false, nil, nil)
+		return
+	}
+	NewKiTypesDialog(tv.Viewport, typs, ttl, "Number and Type of Items to Insert:", tv.This, func(recv, send ki.Ki, sig int64, data interface{}) {
 		if sig == int64(DialogAccepted) {
 			tv, _ := recv.EmbeddedStruct(KiT_TreeView).(*TreeView)
 			sk := tv.SrcNode.Ptr
@@ -511,8 +589,12 @@ func (tv *TreeView) SrcInsertBefore() {
 		return
 	}
 	myidx := sk.Index()
-
-	NewKiDialog(tv.Viewport, reflect.TypeOf((*Node2D)(nil)).Elem(), ttl, "Number and Type of Items to Insert:", tv.This, func(recv, send ki.Ki, sig int64, data interface{}) {
+	typs := tv.SrcAllowedChildTypes(par)
+	if len(typs) == 0 {
+		PromptDialog(tv.Viewport, ttl, "No more items can be inserted here", true, false, nil, nil)
+		return
+	}
+	NewKiTypesDialog(tv.Viewport, typs, ttl, "Number and Type of Items to Insert:", tv.This, func(recv, send ki.Ki, sig int64, data interface{}) {
 		if sig == int64(DialogAccepted) {
 			tv, _ := recv.EmbeddedStruct(KiT_TreeView).(*TreeView)
 			sk := tv.SrcNode.Ptr
@@ -532,7 +614,12 @@ func (tv *TreeView) SrcInsertBefore() {
 // add a new child node in the source tree
 func (tv *TreeView) SrcAddChild() {
 	ttl := "TreeView Add Child"
-	NewKiDialog(tv.Viewport, reflect.TypeOf((*Node2D)(nil)).Elem(), ttl, "Number and Type of Items to Add:", tv.This, func(recv, send ki.Ki, sig int64, data interface{}) {
+	typs := tv.SrcAllowedChildTypes(tv.SrcNode.Ptr)
+	if len(typs) == 0 {
+		PromptDialog(tv.Viewport, ttl, "No more children can be added here", true, false, nil, nil)
+		return
+	}
+	NewKiTypesDialog(tv.Viewport, typs, ttl, "Number and Type of Items to Add:", tv.This, func(recv, send ki.Ki, sig int64, data interface{}) {
 		if sig == int64(DialogAccepted) {
 			tv, _ := recv.EmbeddedStruct(KiT_TreeView).(*TreeView)
 			sk := tv.SrcNode.Ptr
@@ -582,6 +669,25 @@ func (tv *TreeView) SrcDuplicate() {
 	par.InsertChild(nwkid, myidx+1)
 }
 
+// undo the last change in source tree -- requires an UndoStack to be
+// attached to the source tree, via ki.AttachUndoStack
+func (tv *TreeView) SrcUndo() {
+	us := ki.UndoStackFor(tv.SrcNode.Ptr)
+	if us == nil {
+		return
+	}
+	us.Undo()
+}
+
+// redo the last undone change in source tree
+func (tv *TreeView) SrcRedo() {
+	us := ki.UndoStackFor(tv.SrcNode.Ptr)
+	if us == nil {
+		return
+	}
+	us.Redo()
+}
+
 func (tv *TreeView) SetContinuousSelect() {
 	rn := tv.RootWidget
 	bitflag.Set(&rn.Flag, int(TreeViewFlagContinuousSelect))
@@ -695,6 +801,14 @@ func (tv *TreeView) MakeMenu(mb *ButtonBase) {
 		tv := recv.EmbeddedStruct(KiT_TreeView).(*TreeView)
 		tv.SrcDelete()
 	})
+	mb.AddMenuText("Undo", tv.This, nil, func(recv, send ki.Ki, sig int64, data interface{}) {
+		tv := recv.EmbeddedStruct(KiT_TreeView).(*TreeView)
+		tv.SrcUndo()
+	})
+	mb.AddMenuText("Redo", tv.This, nil, func(recv, send ki.Ki, sig int64, data interface{}) {
+		tv := recv.EmbeddedStruct(KiT_TreeView).(*TreeView)
+		tv.SrcRedo()
+	})
 }
 
 func (tv *TreeView) ConfigPartsIfNeeded() {
@@ -747,6 +861,12 @@ func (tv *TreeView) Init2D() {
 		case KeyFunInsertAfter:
 			tv.SrcInsertAfter()
 			kt.SetProcessed()
+		case KeyFunUndo:
+			tv.SrcUndo()
+			kt.SetProcessed()
+		case KeyFunRedo:
+			tv.SrcRedo()
+			kt.SetProcessed()
 		}
 	})
 }
@@ -6,6 +6,7 @@ package gi
 
 import (
 	"fmt"
+	"log"
 	"reflect"
 	"strconv"
 
@@ -75,7 +76,11 @@ func ToValueView(it interface{}) ValueView {
 	vk := typ.Kind()
 	switch {
 	case vk >= reflect.Int && vk <= reflect.Uint64:
-		if kit.Enums.TypeRegistered(nptyp) { // todo: bitfield
+		if kit.Enums.IsBitFlag(nptyp) {
+			vv := BitFlagValueView{}
+			vv.Init(&vv)
+			return &vv
+		} else if kit.Enums.TypeRegistered(nptyp) {
 			vv := EnumValueView{}
 			vv.Init(&vv)
 			return &vv
@@ -236,7 +241,9 @@ type ValueView interface {
 	Val() reflect.Value
 
 	// SetValue sets the value (if not Inactive), using Ki.SetField for Ki
-	// types and kit.SetRobust otherwise -- emits a ViewSig signal when set
+	// types and kit.Convert otherwise, which converts structs, maps, slices
+	// etc of other types, logging any fields or elements that could not be
+	// converted -- emits a ViewSig signal when set
 	SetValue(val interface{}) bool
 
 	// ViewFieldTag returns tag associated with this field, if this is a field
@@ -379,19 +386,26 @@ func (vv *ValueViewBase) SetValue(val interface{}) bool {
 				rval = kiv.SetField(vv.Field.Name, val)
 
 			} else {
-				rval = kit.SetRobust(kit.PtrValue(vv.Value).Interface(), val)
+				rval = vv.convert(kit.PtrValue(vv.Value).Interface(), val)
 			}
 		case reflect.Map:
 			ov := kit.NonPtrValue(reflect.ValueOf(vv.Owner))
 			if vv.IsMapKey {
-				nv := reflect.ValueOf(val)                // new key value
+				nv := reflect.New(ov.Type().Key()) // new key value
+				if !vv.convert(nv.Interface(), val) {
+					break
+				}
 				cv := ov.MapIndex(vv.Value)               // get current value
 				ov.SetMapIndex(vv.Value, reflect.Value{}) // delete old key
-				ov.SetMapIndex(nv, cv)                    // set new key to current value
-				vv.Value = nv                             // update value to new key
+				ov.SetMapIndex(nv.Elem(), cv)             // set new key to current value
+				vv.Value = nv.Elem()                      // update value to new key
 				rval = true
 			} else {
-				vv.Value = reflect.ValueOf(val)
+				nv := reflect.New(ov.Type().Elem())
+				if !vv.convert(nv.Interface(), val) {
+					break
+				}
+				vv.Value = nv.Elem()
 				if vv.KeyView != nil {
 					ck := vv.KeyView.Val() // current key value
 					ov.SetMapIndex(ck, vv.Value)
@@ -401,10 +415,10 @@ func (vv *ValueViewBase) SetValue(val interface{}) bool {
 				rval = true
 			}
 		case reflect.Slice:
-			rval = kit.SetRobust(kit.PtrValue(vv.Value).Interface(), val)
+			rval = vv.convert(kit.PtrValue(vv.Value).Interface(), val)
 		}
 	} else {
-		rval = kit.SetRobust(kit.PtrValue(vv.Value).Interface(), val)
+		rval = vv.convert(kit.PtrValue(vv.Value).Interface(), val)
 	}
 	if rval {
 		vv.This.(ValueView).SaveTmp()
@@ -414,6 +428,22 @@ func (vv *ValueViewBase) SetValue(val interface{}) bool {
 	return rval
 }
 
+// convert sets the to value, which must be a pointer, from val using
+// kit.Convert -- converts into a copy of the value, which is only assigned if
+// all of it converted, else the fields or elements that could not be
+// converted are logged and the value is left unchanged
+func (vv *ValueViewBase) convert(to, val interface{}) bool {
+	tv := reflect.ValueOf(to).Elem()
+	tmp := reflect.New(tv.Type())
+	tmp.Elem().Set(tv)
+	if err := kit.Convert(tmp.Interface(), val); err != nil {
+		log.Printf("gi.ValueView SetValue: %v\n", err)
+		return false
+	}
+	tv.Set(tmp.Elem())
+	return true
+}
+
 func (vv *ValueViewBase) SaveTmp() {
 	if vv.TmpSave == nil {
 		return
@@ -455,6 +485,9 @@ func (vv *ValueViewBase) ViewFieldTag(tagName string) string {
 	if !(vv.Owner != nil && vv.OwnKind == reflect.Struct) {
 		return ""
 	}
+	if fm := kit.FieldMetas.Field(reflect.TypeOf(vv.Owner), vv.Field.Name); fm != nil {
+		return fm.Get(tagName)
+	}
 	return vv.Field.Tag.Get(tagName)
 }
 
@@ -960,9 +993,19 @@ func (vv *EnumValueView) UpdateWidget() {
 	sb := vv.Widget.(*ComboBox)
 	npv := kit.NonPtrValue(vv.Value)
 	iv, ok := kit.ToInt(npv.Interface())
-	if ok {
-		sb.SetCurIndex(int(iv)) // todo: currently only working for 0-based values
+	if !ok {
+		return
+	}
+	// values need not be contiguous from 0, so find the item with the value
+	for i, it := range sb.Items {
+		if ev, ok := it.(kit.EnumValue); ok && ev.Value == iv {
+			sb.SetCurIndex(i)
+			sb.Tooltip = ev.Desc
+			return
+		}
 	}
+	sb.SetCurVal(npv.Interface()) // not a defined value -- shown as is
+	sb.Tooltip = ""
 }
 
 func (vv *EnumValueView) ConfigWidget(widg Node2D) {
@@ -981,7 +1024,7 @@ func (vv *EnumValueView) ConfigWidget(widg Node2D) {
 		vvv, _ := recv.EmbeddedStruct(KiT_EnumValueView).(*EnumValueView)
 		cbb := vvv.Widget.(*ComboBox)
 		eval := cbb.CurVal.(kit.EnumValue)
-		if vvv.SetEnumValueFromInt(eval.Value) { // todo: using index
+		if vvv.SetEnumValueFromInt(eval.Value) {
 			vvv.UpdateWidget()
 		}
 	})
@@ -15,9 +15,10 @@ import (
 // Widget base type -- manages control elements and provides standard box model rendering
 type WidgetBase struct {
 	Node2DBase
-	CSS    ki.Props `xml:"css" desc:"cascading style sheet at this level -- these styles apply here and to everything below, until superceded -- use .class and #name Props elements to apply entire styles to given elements"`
-	CSSAgg ki.Props `json:"-" xml:"-" desc:"aggregated css properties from all higher nodes down to me"`
-	Parts  Layout   `json:"-" xml:"-" view-closed:"true" desc:"a separate tree of sub-widgets that implement discrete parts of a widget -- positions are always relative to the parent widget -- fully managed by the widget and not saved"`
+	CSS     ki.Props `xml:"css" desc:"cascading style sheet at this level -- these styles apply here and to everything below, until superceded -- use .class and #name Props elements to apply entire styles to given elements"`
+	CSSAgg  ki.Props `json:"-" xml:"-" desc:"aggregated css properties from all higher nodes down to me"`
+	Parts   Layout   `json:"-" xml:"-" view-closed:"true" desc:"a separate tree of sub-widgets that implement discrete parts of a widget -- positions are always relative to the parent widget -- fully managed by the widget and not saved"`
+	Tooltip string   `json:"-" xml:"-" desc:"text for the tooltip for this widget -- e.g., the description of the current enum value in an EnumValueView -- shown in a popup while the mouse is over button widgets (buttons, actions, checkboxes, and combo boxes) -- see PopupTooltip"`
 }
 
 var KiT_WidgetBase = kit.Types.AddType(&WidgetBase{}, WidgetBaseProps)
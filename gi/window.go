@@ -50,8 +50,11 @@ type Window struct {
 	PopupStack    []ki.Ki                     `jsom:"-" xml:"-" desc:"stack of popups"`
 	FocusStack    []ki.Ki                     `jsom:"-" xml:"-" desc:"stack of focus"`
 	NextPopup     ki.Ki                       `json:"-" xml:"-" desc:"this popup will be pushed at the end of the current event cycle"`
+	Tooltip       ki.Ki                       `json:"-" xml:"-" desc:"current tooltip viewport, drawn over everything else -- does not get any events -- see SetTooltip"`
+	tooltipOwner  ki.Ki                       `json:"-" xml:"-" desc:"node that the current tooltip is for"`
 	stopEventLoop bool                        `json:"-" xml:"-" desc:"signal for communicating all user events (mouse, keyboard, etc)"`
 	DoFullRender  bool                        `json:"-" xml:"-" desc:"triggers a full re-render of the window within the event loop -- cleared once done"`
+	SigQueue      *ki.SignalQueue             `json:"-" xml:"-" desc:"queue of signals that are delivered within the event loop -- connect with ConnectQueue to this queue to safely send signals to GUI nodes from other goroutines"`
 }
 
 var KiT_Window = kit.Types.AddType(&Window{}, nil)
@@ -78,9 +81,36 @@ func NewWindow(name string, opts *oswin.NewWindowOptions) *Window {
 	win.OSWin.SetName(name)
 	win.OSWin.SetParent(win.This)
 	win.NodeSig.Connect(win.This, SignalWindowFlush)
+	win.SigQueue = ki.NewSignalQueue(0, ki.QueueBlock)
+	win.SigQueue.Wake = func() {
+		win.OSWin.Send(&sigQueueEvent{})
+	}
 	return win
 }
 
+// sigQueueEvent is sent to the OSWin to wake up the event loop when signals
+// are added to the SigQueue -- it has an event type beyond EventTypeN, so it
+// is otherwise ignored by the event loop
+type sigQueueEvent struct {
+	oswin.EventBase
+}
+
+func (ev *sigQueueEvent) Type() oswin.EventType {
+	return oswin.EventTypeN + 1
+}
+
+func (ev *sigQueueEvent) HasPos() bool {
+	return false
+}
+
+func (ev *sigQueueEvent) Pos() image.Point {
+	return image.ZP
+}
+
+func (ev *sigQueueEvent) OnFocus() bool {
+	return false
+}
+
 // NewWindow2D creates a new standard 2D window with given name and sizing,
 // with default positioning, and initializes a 2D viewport within it --
 // stdPixels means use standardized "pixel" units for the display size (96 per
@@ -241,6 +271,14 @@ func (w *Window) FullUpdate() {
 			w.WinTex.Upload(r.Min, vp.OSImage, vp.OSImage.Bounds())
 		}
 	}
+	if w.Tooltip != nil {
+		gii, _ := KiToNode2D(w.Tooltip)
+		if gii != nil {
+			vp := gii.AsViewport2D()
+			r := vp.ViewBox.Bounds()
+			w.WinTex.Upload(r.Min, vp.OSImage, vp.OSImage.Bounds())
+		}
+	}
 	pr.End()
 	w.UpdateEnd(updt) // drives the flush
 }
@@ -283,10 +321,22 @@ func (w *Window) ReceiveEventType(recv ki.Ki, et oswin.EventType, fun ki.RecvFun
 	w.EventSigs[et].Connect(recv, fun)
 }
 
+// ReceiveEventTypePrio adds a Signal connection for given event type to
+// given receiver, with given priority -- receivers with higher priority get
+// the event first, and can prevent it from going to lower priority receivers
+// by calling SetProcessed on it -- ReceiveEventType uses priority 0
+func (w *Window) ReceiveEventTypePrio(recv ki.Ki, et oswin.EventType, prio int, fun ki.RecvFunc) {
+	if et >= oswin.EventTypeN {
+		log.Printf("Window ReceiveEventTypePrio type: %v is not a known event type\n", et)
+		return
+	}
+	w.EventSigs[et].ConnectPrio(recv, fun, prio)
+}
+
 // disconnect node from all signals
 func (w *Window) DisconnectNode(recv ki.Ki) {
-	for _, es := range w.EventSigs {
-		es.Disconnect(recv, nil)
+	for i := range w.EventSigs {
+		w.EventSigs[i].Disconnect(recv, nil)
 	}
 }
 
@@ -316,7 +366,9 @@ func (w *Window) IsInScope(gii Node2D, gi *Node2DBase) bool {
 // note that because there is a different EventSig for each event type, we are
 // ONLY looking at nodes that have registered to receive that type of event --
 // the further filtering is just to ensure that they are in the right position
-// to receive the event (focus, popup filtering, etc)
+// to receive the event (focus, popup filtering, etc) -- receivers get the
+// event in order of priority (see ReceiveEventTypePrio), and delivery stops
+// as soon as one of them marks it as processed
 func (w *Window) SendEventSignal(evi oswin.Event) {
 	if evi.IsProcessed() { // someone took care of it
 		return
@@ -335,9 +387,6 @@ func (w *Window) SendEventSignal(evi oswin.Event) {
 		if k.IsDeleted() { // destroyed is filtered upstream
 			return false
 		}
-		if evi.IsProcessed() { // someone took care of it
-			return false
-		}
 		gii, gi := KiToNode2D(k)
 		if gi != nil {
 			if gi.IsInactive() && !bitflag.Has(gi.Flag, int(InactiveEvents)) {
@@ -488,6 +537,10 @@ func (w *Window) EventLoop() {
 	for {
 		evi := w.OSWin.NextEvent()
 
+		if w.SigQueue != nil {
+			w.SigQueue.Drain()
+		}
+
 		// format := "got %#v\n"
 		// if _, ok := evi.(fmt.Stringer); ok {
 		// 	format = "got %v\n"
@@ -578,10 +631,19 @@ func (w *Window) EventLoop() {
 			skippedResize = nil
 		}
 
+		if w.Tooltip != nil {
+			switch et {
+			case oswin.MouseEvent, oswin.MouseScrollEvent, oswin.KeyChordEvent:
+				w.DeleteTooltip(nil)
+			}
+		}
+
 		switch e := evi.(type) {
 		case *lifecycle.Event:
 			if e.To == lifecycle.StageDead {
 				// fmt.Println("close")
+				// receivers of LifeCycleEvent all get the close, e.g., to release resources
+				w.EventSigs[oswin.LifeCycleEvent].Emit(w.This, int64(oswin.LifeCycleEvent), evi)
 				evi.SetProcessed()
 				break
 			} else {
@@ -868,6 +930,39 @@ func (w *Window) PushPopup(pop ki.Ki) {
 	w.PushFocus(pop)
 }
 
+// SetTooltip sets the current tooltip viewport, replacing any existing one
+// -- owner is the node that the tooltip is for -- the tooltip is drawn over
+// everything else, and does not get any events -- see PopupTooltip
+func (w *Window) SetTooltip(tt ki.Ki, owner ki.Ki) {
+	w.DeleteTooltip(nil)
+	tt.SetParent(w.This) // tooltip has parent as window -- draws directly in to assoc vp
+	w.Tooltip = tt
+	w.tooltipOwner = owner
+	_, gi := KiToNode2D(tt)
+	if gi != nil {
+		gi.FullRender2DTree()
+	}
+}
+
+// DeleteTooltip deletes the current tooltip, if it is for given owner, or
+// any current tooltip if owner is nil
+func (w *Window) DeleteTooltip(owner ki.Ki) {
+	if w.Tooltip == nil || (owner != nil && owner != w.tooltipOwner) {
+		return
+	}
+	tt := w.Tooltip
+	w.Tooltip = nil
+	w.tooltipOwner = nil
+	tt.SetParent(nil) // don't redraw the tooltip anymore
+	gii, _ := KiToNode2D(tt)
+	if gii != nil {
+		if vp := gii.AsViewport2D(); vp != nil {
+			vp.DeletePopup()
+		}
+	}
+	w.FullUpdate()
+}
+
 // disconnect given popup -- typically the current one
 func (w *Window) DisconnectPopup(pop ki.Ki) {
 	w.DisconnectNode(pop)
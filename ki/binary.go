@@ -0,0 +1,973 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+
+	"github.com/rcoreilly/goki/ki/kit"
+)
+
+// The binary format is a compact alternative to JSON for saving and loading
+// trees (e.g., checkpoints of large simulations), using SaveBinary /
+// LoadBinary.  The same fields are saved as in JSON (exported, not json:"-"),
+// and a file has the following parts:
+//
+// header: "KiB" + version byte, followed by the name table of all type and
+// field names used in the file, each stored once -- elsewhere names are
+// just indexes into this table
+//
+// enums: the string table of each enum type used in the file -- enum values
+// are stored as ints, and mapped back through their names on loading, so
+// values remain stable when the constants are renumbered (as with
+// kit.EnumMarshalJSON), and bit flags are mapped bit-by-bit
+//
+// body: the root node -- each struct is a list of fields by name, with
+// values prefixed by their length, so fields that no longer exist are
+// skipped -- children are stored as in JSON: a header of type and name for
+// each child, followed by each child node in turn
+//
+// Ptr fields are stored as the index of the node they point to, in the order
+// that nodes appear in the file (including Ki fields) -- pointers outside of
+// the saved tree are stored using their path.  Interface values (e.g., Props)
+// are stored with a tag for their type: basic types, enums, and types
+// registered in kit.Types are stored in binary, and anything else as JSON.
+//
+// Type names are saved with their kit.Migrations schema version, and type
+// and field renames are applied on loading -- migration Fun value transforms
+// operate on JSON maps, and are not applied to binary data
+
+// binMagic is the header of the binary format, including version
+const binMagic = "KiB\x01"
+
+// tags for the types of interface values
+const (
+	binNil byte = iota
+	binEnum
+	binType
+	binProps
+	binJSON
+	binKind = 16 // + reflect.Kind for predeclared basic types
+)
+
+// tags for the kinds of struct fields
+const (
+	binFieldValue byte = iota
+	binFieldKi
+	binFieldKids
+)
+
+// tags for Ptr values
+const (
+	binPtrNil byte = iota
+	binPtrIdx
+	binPtrPath
+)
+
+// binBasicTypes are the predeclared types that are stored with a binKind tag
+// in interface values
+var binBasicTypes = map[reflect.Kind]reflect.Type{}
+
+func init() {
+	for _, v := range []interface{}{false, int(0), int8(0), int16(0), int32(0), int64(0),
+		uint(0), uint8(0), uint16(0), uint32(0), uint64(0), uintptr(0),
+		float32(0), float64(0), complex64(0), complex128(0), ""} {
+		t := reflect.TypeOf(v)
+		binBasicTypes[t.Kind()] = t
+	}
+}
+
+var binPropsType = reflect.TypeOf(Props{})
+
+// binIsIntKind returns true if kind is a signed or unsigned int
+func binIsIntKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Uintptr
+}
+
+// binEnumNames returns the string table for given enum type -- names of
+// each bit for bit flags -- none for enums with explicit values, which may
+// not be contiguous, so their values are saved as is
+func binEnumNames(t reflect.Type) []string {
+	if kit.Enums.IsExplicit(t) {
+		return nil
+	}
+	n, _ := kit.ToInt(kit.Enums.Prop(kit.FullTypeName(t), "N"))
+	nms := make([]string, n)
+	for i := range nms {
+		nms[i] = kit.EnumInt64ToString(int64(i), t)
+	}
+	return nms
+}
+
+// binNumberNodes numbers the nodes in the tree in the order they are
+// encoded, for Ptr indexes
+func binNumberNodes(k Ki, nodes map[Ki]int) {
+	nodes[k] = len(nodes)
+	v := reflect.ValueOf(k).Elem()
+	for _, f := range jsonFields(v.Type()) {
+		fv := v.FieldByIndex(f.index)
+		switch {
+		case f.isKi:
+			binNumberNodes(fv.Addr().Interface().(Ki), nodes)
+		case f.isKids:
+			for _, kid := range fv.Interface().(Slice) {
+				binNumberNodes(kid, nodes)
+			}
+		}
+	}
+}
+
+//////////////////////////////////////////////////////////////////////////
+//  Encoder
+
+// binEncoder encodes a tree in the binary format
+type binEncoder struct {
+	buf       *bytes.Buffer
+	names     map[string]int
+	nameList  []string
+	enums     map[reflect.Type]int
+	enumList  []reflect.Type
+	nodes     map[Ki]int
+	isEnum    map[reflect.Type]bool
+	varintBuf [binary.MaxVarintLen64]byte
+}
+
+func (e *binEncoder) byte(b byte) {
+	e.buf.WriteByte(b)
+}
+
+func (e *binEncoder) uvarint(x uint64) {
+	n := binary.PutUvarint(e.varintBuf[:], x)
+	e.buf.Write(e.varintBuf[:n])
+}
+
+func (e *binEncoder) varint(x int64) {
+	n := binary.PutVarint(e.varintBuf[:], x)
+	e.buf.Write(e.varintBuf[:n])
+}
+
+func (e *binEncoder) bytes(b []byte) {
+	e.uvarint(uint64(len(b)))
+	e.buf.Write(b)
+}
+
+func (e *binEncoder) str(s string) {
+	e.uvarint(uint64(len(s)))
+	e.buf.WriteString(s)
+}
+
+// name writes the index of given name in the name table
+func (e *binEncoder) name(s string) {
+	id, ok := e.names[s]
+	if !ok {
+		id = len(e.nameList)
+		e.names[s] = id
+		e.nameList = append(e.nameList, s)
+	}
+	e.uvarint(uint64(id))
+}
+
+// enumType returns true if given type is a registered enum
+func (e *binEncoder) enumType(t reflect.Type) bool {
+	if !binIsIntKind(t.Kind()) {
+		return false
+	}
+	is, ok := e.isEnum[t]
+	if !ok {
+		is = kit.Enums.TypeRegistered(t)
+		e.isEnum[t] = is
+	}
+	return is
+}
+
+// enum writes the index of the enum type table, and the value
+func (e *binEncoder) enum(v reflect.Value) {
+	t := v.Type()
+	id, ok := e.enums[t]
+	if !ok {
+		id = len(e.enumList)
+		e.enums[t] = id
+		e.enumList = append(e.enumList, t)
+	}
+	e.uvarint(uint64(id))
+	if v.Kind() >= reflect.Uint {
+		e.varint(int64(v.Uint()))
+	} else {
+		e.varint(v.Int())
+	}
+}
+
+// lenPrefixed writes the output of fun prefixed by its length
+func (e *binEncoder) lenPrefixed(fun func() error) error {
+	cur := e.buf
+	e.buf = &bytes.Buffer{}
+	err := fun()
+	sub := e.buf
+	e.buf = cur
+	e.bytes(sub.Bytes())
+	return err
+}
+
+// encodeNode writes node k and its subtree
+func (e *binEncoder) encodeNode(k Ki) error {
+	return e.encodeStruct(reflect.ValueOf(k).Elem())
+}
+
+// encodeStruct writes the fields of a struct, by name -- structs that
+// cannot be walked field-by-field are written as JSON
+func (e *binEncoder) encodeStruct(v reflect.Value) error {
+	if !v.CanAddr() {
+		av := reflect.New(v.Type()).Elem()
+		av.Set(v)
+		v = av
+	}
+	flds := jsonFields(v.Type())
+	if flds == nil {
+		b, err := json.Marshal(v.Addr().Interface())
+		if err != nil {
+			return err
+		}
+		e.byte(1)
+		e.bytes(b)
+		return nil
+	}
+	e.byte(0)
+	e.uvarint(uint64(len(flds)))
+	for _, f := range flds {
+		fv := v.FieldByIndex(f.index)
+		e.name(f.name)
+		var err error
+		switch {
+		case f.isKi:
+			e.byte(binFieldKi)
+			e.name(kit.Migrations.SavedTypeName(fv.Type()))
+			err = e.encodeNode(fv.Addr().Interface().(Ki))
+		case f.isKids:
+			e.byte(binFieldKids)
+			err = e.encodeKids(fv.Interface().(Slice))
+		default:
+			e.byte(binFieldValue)
+			err = e.lenPrefixed(func() error { return e.encodeValue(fv) })
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeKids writes the type and name of each child, followed by each child
+func (e *binEncoder) encodeKids(kids Slice) error {
+	e.uvarint(uint64(len(kids)))
+	for _, kid := range kids {
+		e.name(kit.Migrations.SavedTypeName(reflect.TypeOf(kid).Elem()))
+		e.str(kid.UniqueName())
+	}
+	for _, kid := range kids {
+		if err := e.encodeNode(kid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodePtr writes a Ptr as the index of the node it points to, or its path
+func (e *binEncoder) encodePtr(p Ptr) {
+	if p.Ptr == nil {
+		e.byte(binPtrNil)
+		return
+	}
+	if idx, ok := e.nodes[p.Ptr]; ok {
+		e.byte(binPtrIdx)
+		e.uvarint(uint64(idx))
+		return
+	}
+	e.byte(binPtrPath)
+	e.str(p.Ptr.PathUnique())
+}
+
+// encodeValue writes a value of a known type
+func (e *binEncoder) encodeValue(v reflect.Value) error {
+	t := v.Type()
+	if t == KiT_Ptr {
+		e.encodePtr(v.Interface().(Ptr))
+		return nil
+	}
+	if e.enumType(t) {
+		e.enum(v)
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			e.byte(1)
+		} else {
+			e.byte(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.varint(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.uvarint(v.Uint())
+	case reflect.Float32:
+		var b [4]byte
+		binary.LittleEndian.PutUint32(b[:], math.Float32bits(float32(v.Float())))
+		e.buf.Write(b[:])
+	case reflect.Float64:
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], math.Float64bits(v.Float()))
+		e.buf.Write(b[:])
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		ft := reflect.Float64
+		if t.Kind() == reflect.Complex64 {
+			ft = reflect.Float32
+		}
+		e.encodeValue(reflect.ValueOf(real(c)).Convert(binBasicTypes[ft]))
+		e.encodeValue(reflect.ValueOf(imag(c)).Convert(binBasicTypes[ft]))
+	case reflect.String:
+		e.str(v.String())
+	case reflect.Slice:
+		if v.IsNil() {
+			e.uvarint(0)
+			return nil
+		}
+		e.uvarint(uint64(v.Len() + 1))
+		if t.Elem().Kind() == reflect.Uint8 {
+			e.buf.Write(v.Bytes())
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := e.encodeValue(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := e.encodeValue(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			e.uvarint(0)
+			return nil
+		}
+		e.uvarint(uint64(v.Len() + 1))
+		keys := v.MapKeys()
+		if t.Key().Kind() == reflect.String {
+			sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		}
+		for _, key := range keys {
+			if err := e.encodeValue(key); err != nil {
+				return err
+			}
+			if err := e.encodeValue(v.MapIndex(key)); err != nil {
+				return err
+			}
+		}
+	case reflect.Ptr:
+		if v.IsNil() {
+			e.byte(0)
+			return nil
+		}
+		e.byte(1)
+		return e.encodeValue(v.Elem())
+	case reflect.Interface:
+		return e.encodeIface(v)
+	case reflect.Struct:
+		return e.encodeStruct(v)
+	default:
+		return fmt.Errorf("ki.SaveBinary: unsupported type: %v", t.String())
+	}
+	return nil
+}
+
+// encodeIface writes an interface value, with a tag for its type
+func (e *binEncoder) encodeIface(v reflect.Value) error {
+	if v.IsNil() {
+		e.byte(binNil)
+		return nil
+	}
+	c := v.Elem()
+	t := c.Type()
+	switch {
+	case t == binPropsType:
+		e.byte(binProps)
+		return e.encodeValue(c)
+	case e.enumType(t):
+		e.byte(binEnum)
+		e.enum(c)
+		return nil
+	case binBasicTypes[t.Kind()] == t:
+		e.byte(binKind + byte(t.Kind()))
+		return e.encodeValue(c)
+	case kit.Types.Type(kit.FullTypeName(t)) == t:
+		e.byte(binType)
+		e.name(kit.Migrations.SavedTypeName(t))
+		return e.encodeValue(c)
+	}
+	b, err := json.Marshal(c.Interface())
+	if err != nil {
+		return err
+	}
+	e.byte(binJSON)
+	e.bytes(b)
+	return nil
+}
+
+// SaveBinaryTree returns the binary encoding of the tree starting at k --
+// see Ki.SaveBinary
+func SaveBinaryTree(k Ki) ([]byte, error) {
+	e := &binEncoder{buf: &bytes.Buffer{}, names: make(map[string]int), enums: make(map[reflect.Type]int), nodes: make(map[Ki]int), isEnum: make(map[reflect.Type]bool)}
+	binNumberNodes(k, e.nodes)
+	if err := e.encodeNode(k); err != nil {
+		return nil, err
+	}
+	body := e.buf
+	e.buf = &bytes.Buffer{}
+	e.buf.Grow(body.Len() + 1024)
+	e.buf.WriteString(binMagic)
+	e.uvarint(uint64(len(e.nameList)))
+	for _, nm := range e.nameList {
+		e.str(nm)
+	}
+	e.uvarint(uint64(len(e.enumList)))
+	for _, et := range e.enumList {
+		e.str(kit.FullTypeName(et))
+		if kit.Enums.IsBitFlag(et) {
+			e.byte(1)
+		} else {
+			e.byte(0)
+		}
+		nms := binEnumNames(et)
+		e.uvarint(uint64(len(nms)))
+		for _, nm := range nms {
+			e.str(nm)
+		}
+	}
+	e.buf.Write(body.Bytes())
+	return e.buf.Bytes(), nil
+}
+
+//////////////////////////////////////////////////////////////////////////
+//  Decoder
+
+// binEnumTable is the string table for an enum type, as saved in the file
+type binEnumTable struct {
+	Type    reflect.Type
+	BitFlag bool
+	Names   []string
+}
+
+// binPtrRef records a Ptr to be set to the node at given index
+type binPtrRef struct {
+	ptr *Ptr
+	idx int
+}
+
+// binDecoder decodes a tree from the binary format
+type binDecoder struct {
+	b     []byte
+	pos   int
+	err   error
+	names []string
+	enums []*binEnumTable
+	cur   map[reflect.Type]map[string]int64
+	nodes []Ki
+	ptrs  []binPtrRef
+}
+
+// fail records given error, if it is the first one
+func (d *binDecoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+// need checks that there are n more bytes of data
+func (d *binDecoder) need(n uint64) bool {
+	if d.err != nil {
+		return false
+	}
+	if uint64(len(d.b)-d.pos) < n {
+		d.fail(fmt.Errorf("ki.LoadBinary: unexpected end of data at: %v", d.pos))
+		return false
+	}
+	return true
+}
+
+func (d *binDecoder) byte() byte {
+	if !d.need(1) {
+		return 0
+	}
+	d.pos++
+	return d.b[d.pos-1]
+}
+
+func (d *binDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	x, n := binary.Uvarint(d.b[d.pos:])
+	if n <= 0 {
+		d.fail(fmt.Errorf("ki.LoadBinary: bad varint at: %v", d.pos))
+		return 0
+	}
+	d.pos += n
+	return x
+}
+
+func (d *binDecoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	x, n := binary.Varint(d.b[d.pos:])
+	if n <= 0 {
+		d.fail(fmt.Errorf("ki.LoadBinary: bad varint at: %v", d.pos))
+		return 0
+	}
+	d.pos += n
+	return x
+}
+
+// raw returns the next n bytes, without copying
+func (d *binDecoder) raw(n uint64) []byte {
+	if !d.need(n) {
+		return nil
+	}
+	d.pos += int(n)
+	return d.b[d.pos-int(n) : d.pos]
+}
+
+func (d *binDecoder) bytes() []byte {
+	return d.raw(d.uvarint())
+}
+
+func (d *binDecoder) str() string {
+	return string(d.bytes())
+}
+
+// name reads a name as an index into the name table
+func (d *binDecoder) name() string {
+	id := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+	if id >= uint64(len(d.names)) {
+		d.fail(fmt.Errorf("ki.LoadBinary: bad name index: %v", id))
+		return ""
+	}
+	return d.names[id]
+}
+
+// enum reads an enum value, returning its value in the current numbering
+// of given type, mapped through the saved names
+func (d *binDecoder) enum(t reflect.Type) int64 {
+	id := d.uvarint()
+	iv := d.varint()
+	if d.err != nil {
+		return 0
+	}
+	if id >= uint64(len(d.enums)) {
+		d.fail(fmt.Errorf("ki.LoadBinary: bad enum index: %v", id))
+		return 0
+	}
+	et := d.enums[id]
+	if t == nil {
+		t = et.Type
+	}
+	if t == nil {
+		return iv
+	}
+	cur, ok := d.cur[t]
+	if !ok {
+		cur = make(map[string]int64)
+		for i, nm := range binEnumNames(t) {
+			cur[nm] = int64(i)
+		}
+		d.cur[t] = cur
+	}
+	if !et.BitFlag {
+		if iv >= 0 && iv < int64(len(et.Names)) {
+			if cv, ok := cur[et.Names[iv]]; ok {
+				return cv
+			}
+		}
+		return iv
+	}
+	var res int64
+	for i := 0; i < 64; i++ {
+		if iv&(int64(1)<<uint(i)) == 0 {
+			continue
+		}
+		bit := int64(i)
+		if i < len(et.Names) {
+			if cv, ok := cur[et.Names[i]]; ok {
+				bit = cv
+			}
+		}
+		res |= int64(1) << uint(bit)
+	}
+	return res
+}
+
+// enumTable returns the saved table for the enum at the current position,
+// without consuming it
+func (d *binDecoder) enumTable() *binEnumTable {
+	pos := d.pos
+	id := d.uvarint()
+	d.pos = pos
+	if d.err != nil || id >= uint64(len(d.enums)) {
+		return nil
+	}
+	return d.enums[id]
+}
+
+// decodeNode reads node k and its subtree, saved under given type name
+func (d *binDecoder) decodeNode(k Ki, saved string) error {
+	d.nodes = append(d.nodes, k)
+	return d.decodeStruct(reflect.ValueOf(k).Elem(), saved)
+}
+
+// decodeStruct reads the fields of a struct, skipping those that are not
+// found in the struct -- if saved type name is given, field renames in
+// kit.Migrations are applied
+func (d *binDecoder) decodeStruct(v reflect.Value, saved string) error {
+	if d.byte() == 1 {
+		b := d.bytes()
+		if d.err != nil {
+			return d.err
+		}
+		return json.Unmarshal(b, v.Addr().Interface())
+	}
+	flds := jsonFields(v.Type())
+	n := d.uvarint()
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		nm := d.name()
+		tag := d.byte()
+		f := jsonFieldByName(flds, nm)
+		if f == nil && saved != "" {
+			f = jsonFieldByName(flds, kit.Migrations.FieldName(v.Type(), saved, nm))
+		}
+		if f != nil && ((tag == binFieldKi) != f.isKi || (tag == binFieldKids) != f.isKids) {
+			f = nil
+		}
+		var fv reflect.Value
+		if f != nil {
+			fv = v.FieldByIndex(f.index)
+		}
+		switch tag {
+		case binFieldKi:
+			tn := d.name()
+			if f != nil {
+				d.fail(d.decodeNode(fv.Addr().Interface().(Ki), tn))
+				continue
+			}
+			typ, _ := kit.Migrations.Type(tn)
+			if typ == nil {
+				d.fail(fmt.Errorf("ki.LoadBinary: kit.Types type name not found: %v", tn))
+				continue
+			}
+			skip := NewOfType(typ)
+			skip.Init(skip)
+			d.fail(d.decodeNode(skip, tn))
+		case binFieldKids:
+			if f != nil {
+				d.fail(d.decodeKids(fv.Addr().Interface().(*Slice)))
+			} else {
+				var skip Slice
+				d.fail(d.decodeKids(&skip))
+			}
+		case binFieldValue:
+			ln := d.uvarint()
+			if !d.need(ln) {
+				continue
+			}
+			end := d.pos + int(ln)
+			if f != nil {
+				d.fail(d.decodeValue(fv))
+				if d.pos > end {
+					d.fail(fmt.Errorf("ki.LoadBinary: field %v overran its data", nm))
+				}
+			}
+			d.pos = end
+		default:
+			d.fail(fmt.Errorf("ki.LoadBinary: bad field tag: %v", tag))
+		}
+	}
+	return d.err
+}
+
+// decodeKids reads children, configuring the slice from the header and then
+// loading each child in turn
+func (d *binDecoder) decodeKids(ks *Slice) error {
+	n := d.uvarint()
+	if d.err != nil {
+		return d.err
+	}
+	if n == 0 {
+		*ks = nil
+		return nil
+	}
+	var tnl kit.TypeAndNameList
+	var saved []string
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		tn := d.name()
+		nm := d.str()
+		saved = append(saved, tn)
+		typ, _ := kit.Migrations.Type(tn)
+		if typ == nil {
+			d.fail(fmt.Errorf("ki.LoadBinary: kit.Types type name not found: %v", tn))
+		}
+		tnl = append(tnl, kit.TypeAndName{Type: typ, Name: nm})
+	}
+	if d.err != nil {
+		return d.err
+	}
+	ks.Config(nil, tnl, true) // true = uniq names
+	for i, kid := range *ks {
+		if err := d.decodeNode(kid, saved[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodePtr reads a Ptr -- pointers by index are set after loading
+func (d *binDecoder) decodePtr(p *Ptr) {
+	p.Reset()
+	switch d.byte() {
+	case binPtrIdx:
+		d.ptrs = append(d.ptrs, binPtrRef{p, int(d.uvarint())})
+	case binPtrPath:
+		p.Path = d.str()
+	}
+}
+
+// decodeValue reads a value of a known type into v, which must be settable
+func (d *binDecoder) decodeValue(v reflect.Value) error {
+	t := v.Type()
+	if t == KiT_Ptr {
+		d.decodePtr(v.Addr().Interface().(*Ptr))
+		return d.err
+	}
+	if binIsIntKind(t.Kind()) && kit.Enums.TypeRegistered(t) {
+		ev := d.enum(t)
+		if t.Kind() >= reflect.Uint {
+			v.SetUint(uint64(ev))
+		} else {
+			v.SetInt(ev)
+		}
+		return d.err
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		v.SetBool(d.byte() != 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(d.varint())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		v.SetUint(d.uvarint())
+	case reflect.Float32:
+		if b := d.raw(4); b != nil {
+			v.SetFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(b))))
+		}
+	case reflect.Float64:
+		if b := d.raw(8); b != nil {
+			v.SetFloat(math.Float64frombits(binary.LittleEndian.Uint64(b)))
+		}
+	case reflect.Complex64, reflect.Complex128:
+		ft := binBasicTypes[reflect.Float64]
+		if t.Kind() == reflect.Complex64 {
+			ft = binBasicTypes[reflect.Float32]
+		}
+		re := reflect.New(ft).Elem()
+		im := reflect.New(ft).Elem()
+		d.decodeValue(re)
+		d.decodeValue(im)
+		v.SetComplex(complex(re.Float(), im.Float()))
+	case reflect.String:
+		v.SetString(d.str())
+	case reflect.Slice:
+		n := d.uvarint()
+		if n == 0 {
+			v.Set(reflect.Zero(t))
+			break
+		}
+		n--
+		if t.Elem().Kind() == reflect.Uint8 {
+			if b := d.raw(n); b != nil {
+				v.SetBytes(append([]byte{}, b...))
+			}
+			break
+		}
+		if !d.need(n) { // at least one byte per element
+			break
+		}
+		s := reflect.MakeSlice(t, int(n), int(n))
+		for i := 0; i < int(n) && d.err == nil; i++ {
+			d.fail(d.decodeValue(s.Index(i)))
+		}
+		v.Set(s)
+	case reflect.Array:
+		for i := 0; i < v.Len() && d.err == nil; i++ {
+			d.fail(d.decodeValue(v.Index(i)))
+		}
+	case reflect.Map:
+		n := d.uvarint()
+		if n == 0 {
+			v.Set(reflect.Zero(t))
+			break
+		}
+		n--
+		if !d.need(n) {
+			break
+		}
+		m := reflect.MakeMapWithSize(t, int(n))
+		for i := 0; i < int(n) && d.err == nil; i++ {
+			key := reflect.New(t.Key()).Elem()
+			d.fail(d.decodeValue(key))
+			val := reflect.New(t.Elem()).Elem()
+			d.fail(d.decodeValue(val))
+			m.SetMapIndex(key, val)
+		}
+		v.Set(m)
+	case reflect.Ptr:
+		if d.byte() == 0 {
+			v.Set(reflect.Zero(t))
+			break
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(t.Elem()))
+		}
+		return d.decodeValue(v.Elem())
+	case reflect.Interface:
+		iv := d.decodeIface()
+		if d.err != nil {
+			break
+		}
+		if !iv.IsValid() {
+			v.Set(reflect.Zero(t))
+		} else if iv.Type().AssignableTo(t) {
+			v.Set(iv)
+		} else {
+			d.fail(fmt.Errorf("ki.LoadBinary: type %v is not assignable to %v", iv.Type().String(), t.String()))
+		}
+	case reflect.Struct:
+		return d.decodeStruct(v, "")
+	default:
+		d.fail(fmt.Errorf("ki.LoadBinary: unsupported type: %v", t.String()))
+	}
+	return d.err
+}
+
+// decodeIface reads an interface value, with a tag for its type
+func (d *binDecoder) decodeIface() reflect.Value {
+	tag := d.byte()
+	switch {
+	case d.err != nil || tag == binNil:
+		return reflect.Value{}
+	case tag == binProps:
+		pv := reflect.New(binPropsType).Elem()
+		d.decodeValue(pv)
+		return pv
+	case tag == binEnum:
+		et := d.enumTable()
+		if et != nil && et.Type == nil { // unknown enum type -- use its name
+			d.uvarint()
+			iv := d.varint()
+			if iv >= 0 && iv < int64(len(et.Names)) {
+				return reflect.ValueOf(et.Names[iv])
+			}
+			return reflect.ValueOf(iv)
+		}
+		ev := reflect.Value{}
+		if et != nil {
+			ev = reflect.New(et.Type).Elem()
+			d.decodeValue(ev)
+		} else {
+			d.fail(fmt.Errorf("ki.LoadBinary: bad enum index at: %v", d.pos))
+		}
+		return ev
+	case tag == binType:
+		tn := d.name()
+		typ, _ := kit.Migrations.Type(tn)
+		if typ == nil {
+			d.fail(fmt.Errorf("ki.LoadBinary: kit.Types type name not found: %v", tn))
+			return reflect.Value{}
+		}
+		tv := reflect.New(typ).Elem()
+		if typ.Kind() == reflect.Struct {
+			d.fail(d.decodeStruct(tv, tn))
+		} else {
+			d.decodeValue(tv)
+		}
+		return tv
+	case tag == binJSON:
+		b := d.bytes()
+		var iv interface{}
+		if d.err == nil {
+			d.fail(json.Unmarshal(b, &iv))
+		}
+		return reflect.ValueOf(iv)
+	case tag >= binKind:
+		typ, ok := binBasicTypes[reflect.Kind(tag-binKind)]
+		if ok {
+			tv := reflect.New(typ).Elem()
+			d.decodeValue(tv)
+			return tv
+		}
+	}
+	d.fail(fmt.Errorf("ki.LoadBinary: bad type tag: %v", tag))
+	return reflect.Value{}
+}
+
+// LoadBinaryTree loads the binary encoding of a tree into k, and sets all the
+// Ptr fields saved by index -- does not call UnmarshalPost -- see
+// Ki.LoadBinary
+func LoadBinaryTree(k Ki, b []byte) error {
+	if !bytes.HasPrefix(b, []byte(binMagic)) {
+		return fmt.Errorf("ki.LoadBinary: not in ki binary format, or wrong version")
+	}
+	d := &binDecoder{b: b, pos: len(binMagic), cur: make(map[reflect.Type]map[string]int64)}
+	nn := d.uvarint()
+	for i := uint64(0); i < nn && d.err == nil; i++ {
+		d.names = append(d.names, d.str())
+	}
+	ne := d.uvarint()
+	for i := uint64(0); i < ne && d.err == nil; i++ {
+		etn, _ := kit.Migrations.TypeName(d.str())
+		et := &binEnumTable{Type: kit.Enums.Enum(etn)}
+		et.BitFlag = d.byte() == 1
+		n := d.uvarint()
+		if !d.need(n) {
+			break
+		}
+		for j := uint64(0); j < n && d.err == nil; j++ {
+			et.Names = append(et.Names, d.str())
+		}
+		d.enums = append(d.enums, et)
+	}
+	if d.err != nil {
+		return d.err
+	}
+	if err := d.decodeNode(k, ""); err != nil {
+		return err
+	}
+	k.ParentAllChildren() // needed for paths
+	for _, pi := range d.ptrs {
+		if pi.idx >= len(d.nodes) {
+			return fmt.Errorf("ki.LoadBinary: bad Ptr index: %v", pi.idx)
+		}
+		pi.ptr.Ptr = d.nodes[pi.idx]
+		pi.ptr.GetPath()
+	}
+	return nil
+}
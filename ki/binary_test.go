@@ -0,0 +1,136 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rcoreilly/goki/ki/kit"
+)
+
+type NodeBinary struct {
+	NodeWithField
+	Op    DiffOps
+	Flg   Flags
+	Dur   time.Duration
+	Data  []byte
+	Vals  []float32
+	Map   map[string]int
+	Ptr2  *NodeEmbed
+	Iface interface{}
+}
+
+var KiT_NodeBinary = kit.Types.AddType(&NodeBinary{}, nil)
+
+func (n *NodeBinary) New() Ki { return &NodeBinary{} }
+
+func binaryTestTree() *NodeBinary {
+	parent := NodeBinary{}
+	parent.InitName(&parent, "par1")
+	parent.Mbr1 = "bloop"
+	parent.Mbr2 = -32
+	parent.Op = DiffDelete
+	parent.Flg = Flags(1<<uint(IsField) | 1<<uint(NodeAdded))
+	parent.Dur = 3 * time.Second
+	parent.Data = []byte("data")
+	parent.Vals = []float32{1, 2.5, -3}
+	parent.Map = map[string]int{"a": 1, "b": 2}
+	parent.Ptr2 = &NodeEmbed{Mbr1: "ptr2"}
+	parent.Iface = DiffMove
+	parent.AddNewChild(nil, "child1")
+	child2 := parent.AddNewChild(nil, "child2").(*NodeBinary)
+	schild := child2.AddNewChild(KiT_NodeEmbed, "subchild1")
+	child2.Field1.AddNewChild(KiT_NodeEmbed, "fieldkid")
+	parent.SetProp("floatprop", 3.1415)
+	parent.SetProp("stringprop", "str")
+	parent.SetProp("enumprop", DiffSetProp)
+	parent.SetProp("propsprop", Props{"intprop": 17})
+	parent.SetProp("structprop", NodeEmbed{Mbr2: 42})
+	parent.SetProp("sliceprop", []string{"a", "b"})
+	schild.SetProp("boolprop", true)
+
+	parent.Ptr.Ptr = &child2.Field1
+	child2.Ptr.Ptr = schild
+	return &parent
+}
+
+func TestBinarySaveLoad(t *testing.T) {
+	parent := binaryTestTree()
+	b, err := parent.SaveBinary()
+	if err != nil {
+		t.Error(err)
+	}
+	jb, _ := parent.SaveJSON(false)
+	if len(b) >= len(jb) {
+		t.Errorf("binary size: %v should be smaller than JSON size: %v", len(b), len(jb))
+	}
+
+	tstload := NodeBinary{}
+	tstload.InitName(&tstload, "")
+	err = tstload.LoadBinary(b)
+	if err != nil {
+		t.Error(err)
+	}
+	tstb, _ := tstload.SaveJSON(false)
+	if len(tstb) != len(jb) { // props are not ordered in JSON
+		t.Errorf("LoadBinary: original and loaded json rep are not equivalent:\n%v\nvs:\n%v", string(jb), string(tstb))
+	}
+	if tstload.Ptr.Ptr != &tstload.Child(1).(*NodeBinary).Field1 {
+		t.Errorf("LoadBinary: Ptr not restored from index")
+	}
+	if tstload.Child(1).(*NodeBinary).Ptr.Ptr != tstload.Child(1).Child(0) {
+		t.Errorf("LoadBinary: child Ptr not restored from index")
+	}
+	if tstload.Child(1).Child(0).Parent() != tstload.Child(1) {
+		t.Errorf("LoadBinary: children not parented")
+	}
+	for _, pk := range []string{"floatprop", "enumprop", "propsprop", "structprop"} {
+		pv := tstload.Prop(pk, false, false)
+		if !reflect.DeepEqual(pv, parent.Prop(pk, false, false)) {
+			t.Errorf("LoadBinary: prop %v should be %v, was %v", pk, parent.Prop(pk, false, false), pv)
+		}
+	}
+	// types not registered in kit.Types are loaded as from JSON
+	if pv := tstload.Prop("sliceprop", false, false); !reflect.DeepEqual(pv, []interface{}{"a", "b"}) {
+		t.Errorf("LoadBinary: sliceprop should be [a b], was %#v", pv)
+	}
+	tstload.SetProp("sliceprop", []string{"a", "b"})
+	if diffs := Diff(parent, &tstload); len(diffs) != 0 {
+		t.Errorf("LoadBinary: loaded tree differs from original: %v", diffs)
+	}
+	if tstload.Iface != DiffMove || tstload.Flg != parent.Flg || tstload.Ptr2.Mbr1 != "ptr2" {
+		t.Errorf("LoadBinary: field values not restored")
+	}
+}
+
+func TestBinaryEnumNames(t *testing.T) {
+	parent := binaryTestTree()
+	b, err := parent.SaveBinary()
+	if err != nil {
+		t.Error(err)
+	}
+	// simulate renumbering of the enum constants by swapping their names in
+	// the saved string table -- the value must follow the name
+	b = bytes.Replace(b, []byte("DiffDelete"), []byte("DiffXXXXXX"), -1)
+	b = bytes.Replace(b, []byte("DiffInsert"), []byte("DiffDelete"), -1)
+	b = bytes.Replace(b, []byte("DiffXXXXXX"), []byte("DiffInsert"), -1)
+	b = bytes.Replace(b, []byte("NodeAdded"), []byte("NodeXXXXX"), -1)
+	b = bytes.Replace(b, []byte("NodeMoved"), []byte("NodeAdded"), -1)
+	b = bytes.Replace(b, []byte("NodeXXXXX"), []byte("NodeMoved"), -1)
+	tstload := NodeBinary{}
+	tstload.InitName(&tstload, "")
+	if err = tstload.LoadBinary(b); err != nil {
+		t.Error(err)
+	}
+	if tstload.Op != DiffInsert {
+		t.Errorf("LoadBinary: enum should be mapped by name to DiffInsert, was: %v", tstload.Op)
+	}
+	if flg := Flags(1<<uint(IsField) | 1<<uint(NodeMoved)); tstload.Flg != flg {
+		t.Errorf("LoadBinary: bit flags should be mapped by name to %v, was: %v", flg, tstload.Flg)
+	}
+}
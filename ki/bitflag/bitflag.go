@@ -12,7 +12,7 @@ package bitflag
 // github.com/rcoreily/ki/bitflag
 
 import (
-// "fmt"
+	"sync/atomic"
 )
 
 // todo: can add a global debug level setting and test for overflow in bits --
@@ -21,11 +21,17 @@ import (
 
 // we assume 64bit bitflags by default -- 32 bit methods specifically marked
 
+// the 64 bit methods that modify the bits do so atomically, so that flags
+// can be safely read with HasAtomic from other goroutines -- e.g., the Ki
+// node flags
+
 // set bit value(s) for ordinal bit position flags
 func Set(bits *int64, flags ...int) {
+	var mask int64
 	for _, f := range flags {
-		*bits |= 1 << uint32(f)
+		mask |= 1 << uint32(f)
 	}
+	SetMask(bits, mask)
 }
 
 // set or clear bit value(s) depending on state (on / off) for ordinal bit position flags
@@ -39,18 +45,23 @@ func SetState(bits *int64, state bool, flags ...int) {
 
 // clear bit value(s) for ordinal bit position flags
 func Clear(bits *int64, flags ...int) {
+	var mask int64
 	for _, f := range flags {
-		*bits = *bits & ^(1 << uint32(f)) // note: ^ is unary bitwise negation, not ~ as in C
+		mask |= 1 << uint32(f)
 	}
+	ClearMask(bits, mask)
 }
 
 // toggle state of bit value(s) for ordinal bit position flags
 func Toggle(bits *int64, flags ...int) {
+	var mask int64
 	for _, f := range flags {
-		if Has(*bits, f) {
-			Clear(bits, f)
-		} else {
-			Set(bits, f)
+		mask |= 1 << uint32(f)
+	}
+	for {
+		old := atomic.LoadInt64(bits)
+		if atomic.CompareAndSwapInt64(bits, old, old^mask) {
+			return
 		}
 	}
 }
@@ -60,6 +71,12 @@ func Has(bits int64, flag int) bool {
 	return bits&(1<<uint32(flag)) != 0
 }
 
+// check if given bit value is set for ordinal bit position flag, reading
+// the bits atomically -- for flags that can be modified by other goroutines
+func HasAtomic(bits *int64, flag int) bool {
+	return Has(atomic.LoadInt64(bits), flag)
+}
+
 // check if any of a set of flags are set for ordinal bit position flags (logical OR)
 func HasAny(bits int64, flags ...int) bool {
 	for _, f := range flags {
@@ -84,7 +101,7 @@ func HasAll(bits int64, flags ...int) bool {
 func Mask(flags ...int) int64 {
 	var mask int64
 	for _, f := range flags {
-		Set(&mask, f)
+		mask |= 1 << uint32(f)
 	}
 	return mask
 }
@@ -94,9 +111,24 @@ func HasMask(bits, mask int64) bool {
 	return bits&mask != 0
 }
 
+// set all of the bits in the mask
+func SetMask(bits *int64, mask int64) {
+	for {
+		old := atomic.LoadInt64(bits)
+		if old|mask == old || atomic.CompareAndSwapInt64(bits, old, old|mask) {
+			return
+		}
+	}
+}
+
 // clear all of the bits in the mask
 func ClearMask(bits *int64, mask int64) {
-	*bits = *bits & ^mask
+	for {
+		old := atomic.LoadInt64(bits)
+		if old&^mask == old || atomic.CompareAndSwapInt64(bits, old, old&^mask) { // note: &^ is bit clear
+			return
+		}
+	}
 }
 
 //////////////////////////////
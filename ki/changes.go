@@ -0,0 +1,382 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/rcoreilly/goki/ki/kit"
+)
+
+// ChangeNotifier provides opt-in, field-level change notification for a Ki
+// tree: the NodeSignalUpdated signal only says that something changed,
+// whereas the ChangeNotifier records exactly what changed, as ChangeRec
+// records.  Attach a ChangeNotifier to a node (typically the root) with
+// AttachChangeNotifier, and all field and property changes (SetField,
+// SetProp, DeleteProp) and child changes (AddChild, InsertChild,
+// DeleteChild, MoveChild, ConfigChildren, SetName) made to that node or
+// anywhere below it are recorded.  As with the UndoStack, records accumulate
+// within the outermost UpdateStart / UpdateEnd block, and are then emitted
+// as one ChangeBatch in the data of a NodeSignalChanged signal on ChangeSig,
+// sent from the Root node -- this happens at the start of the UpdateEnd,
+// before the NodeSignalUpdated signal, so receivers that handle the batch
+// can skip the NodeSignalUpdated signal that follows it, if Covers returns
+// true for its sender.  Changes made outside of any update (e.g., a plain
+// SetProp) are each emitted as their own batch.
+//
+// Direct modification of fields, Props, or Kids that bypasses the Ki methods
+// is not recorded, and neither is loading (LoadJSON etc) or CopyFrom -- a
+// batch that includes loading or copying is not complete, and receivers must
+// fully update on the NodeSignalUpdated signal after it, as they must for
+// any UpdateSig.
+type ChangeNotifier struct {
+	Root      Ki          `desc:"node that this notifier is attached to -- changes on it and all nodes below it are recorded"`
+	ChangeSig Signal      `json:"-" xml:"-" desc:"signal emitted from Root with sig = NodeSignalChanged and data = ChangeBatch of the changes"`
+	Mu        sync.Mutex  `desc:"mutex protecting the current batch"`
+	cur       ChangeBatch // batch being accumulated within current update
+	curOwner  Ki          // node whose UpdateStart opened the current batch
+	partial   bool        // current batch has changes that were not recorded
+	covered   Ki          // node whose NodeSignalUpdated is covered by the batch just emitted
+}
+
+// ChangeOps are the types of changes recorded by a ChangeNotifier
+type ChangeOps int32
+
+const (
+	// ChangeSetField records that field Key on Node changed from Old to New
+	ChangeSetField ChangeOps = iota
+
+	// ChangeSetProp records that property Key on Node changed from Old to
+	// New -- Old is nil if the property was not set before
+	ChangeSetProp
+
+	// ChangeDeleteProp records that property Key, with value Old, was
+	// deleted from Node
+	ChangeDeleteProp
+
+	// ChangeChildAdded records that Kid was added to Node children at Idx
+	ChangeChildAdded
+
+	// ChangeChildRemoved records that Kid was removed from Node children at
+	// Idx
+	ChangeChildRemoved
+
+	// ChangeChildMoved records that a child of Node was moved from Idx to
+	// ToIdx
+	ChangeChildMoved
+
+	// ChangeSetName records that the name of Node changed from Old to New
+	ChangeSetName
+
+	ChangeOpsN
+)
+
+//go:generate stringer -type=ChangeOps
+
+var KiT_ChangeOps = kit.Enums.AddEnum(ChangeOpsN, false, nil)
+
+// ChangeRec is one change recorded by a ChangeNotifier
+type ChangeRec struct {
+	Op    ChangeOps   `desc:"type of change"`
+	Node  Ki          `desc:"node that changed -- for child changes, this is the parent"`
+	Key   string      `desc:"field name or property key"`
+	Old   interface{} `desc:"value prior to change"`
+	New   interface{} `desc:"value after change"`
+	Kid   Ki          `desc:"child that was added or removed"`
+	Idx   int         `desc:"index of child added or removed, or from index for move"`
+	ToIdx int         `desc:"to index for move"`
+}
+
+func (cr ChangeRec) String() string {
+	switch cr.Op {
+	case ChangeChildAdded, ChangeChildRemoved:
+		return fmt.Sprintf("%v %v: %v at %v", cr.Op, cr.Node.Name(), cr.Kid.Name(), cr.Idx)
+	case ChangeChildMoved:
+		return fmt.Sprintf("%v %v: %v -> %v", cr.Op, cr.Node.Name(), cr.Idx, cr.ToIdx)
+	case ChangeSetName:
+		return fmt.Sprintf("%v %v -> %v", cr.Op, cr.Old, cr.New)
+	}
+	return fmt.Sprintf("%v %v: %v: %v -> %v", cr.Op, cr.Node.Name(), cr.Key, cr.Old, cr.New)
+}
+
+// ChangeBatch is the list of changes emitted in one NodeSignalChanged signal
+type ChangeBatch []ChangeRec
+
+// Nodes returns the unique nodes that changed in the batch, in order of
+// first change -- for child changes, the parent node is returned
+func (cb ChangeBatch) Nodes() Slice {
+	nodes := make(Slice, 0, len(cb))
+	for _, cr := range cb {
+		if nodes.Index(cr.Node, 0) < 0 {
+			nodes = append(nodes, cr.Node)
+		}
+	}
+	return nodes
+}
+
+// HasChildChanges returns true if the batch has any changes to the children
+// of any node, including names of children
+func (cb ChangeBatch) HasChildChanges() bool {
+	for _, cr := range cb {
+		if cr.Op >= ChangeChildAdded {
+			return true
+		}
+	}
+	return false
+}
+
+// changeNotifiers holds all the ChangeNotifiers, indexed by the node they
+// are attached to
+var changeNotifiers = struct {
+	sync.RWMutex
+	m map[Ki]*ChangeNotifier
+}{}
+
+// AttachChangeNotifier attaches a ChangeNotifier to given node, which will
+// then record all changes on that node and any nodes below it -- returns the
+// existing notifier if one is already attached
+func AttachChangeNotifier(k Ki) *ChangeNotifier {
+	changeNotifiers.Lock()
+	defer changeNotifiers.Unlock()
+	if changeNotifiers.m == nil {
+		changeNotifiers.m = make(map[Ki]*ChangeNotifier)
+	}
+	if cn, ok := changeNotifiers.m[k]; ok {
+		return cn
+	}
+	cn := &ChangeNotifier{Root: k}
+	cn.ChangeSig.SigType = KiT_NodeSignals
+	changeNotifiers.m[k] = cn
+	return cn
+}
+
+// ChangeNotifierFor returns the ChangeNotifier that records changes on given
+// node -- the notifier attached to the node itself or its closest parent --
+// nil if none
+func ChangeNotifierFor(k Ki) *ChangeNotifier {
+	changeNotifiers.RLock()
+	defer changeNotifiers.RUnlock()
+	if len(changeNotifiers.m) == 0 {
+		return nil
+	}
+	for cur := k; cur != nil; cur = cur.Parent() {
+		if cn, ok := changeNotifiers.m[cur]; ok {
+			return cn
+		}
+		if cur.IsRoot() {
+			break
+		}
+	}
+	return nil
+}
+
+// Detach removes this notifier from its node so nothing further is
+// recorded, and disconnects all receivers from ChangeSig
+func (cn *ChangeNotifier) Detach() {
+	changeNotifiers.Lock()
+	if changeNotifiers.m[cn.Root] == cn {
+		delete(changeNotifiers.m, cn.Root)
+	}
+	changeNotifiers.Unlock()
+	cn.Mu.Lock()
+	cn.cur = nil
+	cn.curOwner = nil
+	cn.covered = nil
+	cn.Mu.Unlock()
+	cn.ChangeSig.DisconnectAll()
+}
+
+// Covers returns true if the NodeSignalUpdated signal now being sent from
+// given node is fully covered by the ChangeBatch that was emitted just before
+// it, at the start of the same UpdateEnd -- receivers that handled that batch
+// can then skip the signal, and must otherwise fully update from the node
+func (cn *ChangeNotifier) Covers(k Ki) bool {
+	cn.Mu.Lock()
+	defer cn.Mu.Unlock()
+	return k != nil && cn.covered == k
+}
+
+// record adds records to the current batch, or emits them as their own
+// batch if there is no update in progress
+func (cn *ChangeNotifier) record(recs ...ChangeRec) {
+	cn.Mu.Lock()
+	if cn.curOwner != nil {
+		cn.cur = append(cn.cur, recs...)
+		cn.Mu.Unlock()
+		return
+	}
+	cn.Mu.Unlock()
+	cn.emit(ChangeBatch(recs))
+}
+
+// emit sends the batch on ChangeSig
+func (cn *ChangeNotifier) emit(cb ChangeBatch) {
+	if len(cb) == 0 {
+		return
+	}
+	cn.ChangeSig.Emit(cn.Root, int64(NodeSignalChanged), cb)
+}
+
+// updateStart is called when an UpdateStart on given node returned true --
+// starts a new batch if one is not already in progress
+func (cn *ChangeNotifier) updateStart(k Ki) {
+	cn.Mu.Lock()
+	if cn.curOwner == nil {
+		cn.curOwner = k
+		cn.cur = nil
+		cn.partial = false
+	}
+	cn.Mu.Unlock()
+}
+
+// unrecorded is called when changes that are not recorded are made -- the
+// current batch, if any, then does not cover the update
+func (cn *ChangeNotifier) unrecorded() {
+	cn.Mu.Lock()
+	if cn.curOwner != nil {
+		cn.partial = true
+	}
+	cn.Mu.Unlock()
+}
+
+// updateEnd is called when an UpdateEnd on given node is passed true --
+// emits the current batch if the node is the one that started it
+func (cn *ChangeNotifier) updateEnd(k Ki) {
+	cn.Mu.Lock()
+	if cn.curOwner != k {
+		cn.Mu.Unlock()
+		return
+	}
+	cb := cn.cur
+	if len(cb) > 0 && !cn.partial {
+		cn.covered = k
+	}
+	cn.cur = nil
+	cn.curOwner = nil
+	cn.partial = false
+	cn.Mu.Unlock()
+	cn.emit(cb)
+}
+
+// signaled is called after the NodeSignalUpdated signal at the end of the
+// update of given node has been sent
+func (cn *ChangeNotifier) signaled(k Ki) {
+	cn.Mu.Lock()
+	if cn.covered == k {
+		cn.covered = nil
+	}
+	cn.Mu.Unlock()
+}
+
+// changesDetach detaches the ChangeNotifier attached to given node, if any
+func changesDetach(k Ki) {
+	changeNotifiers.RLock()
+	cn, ok := changeNotifiers.m[k]
+	changeNotifiers.RUnlock()
+	if ok {
+		cn.Detach()
+	}
+}
+
+// changeRecFmUndo returns the ChangeRec for an UndoRec
+func changeRecFmUndo(ur *UndoRec) ChangeRec {
+	cr := ChangeRec{Node: ur.Node, Key: ur.Key, Old: ur.Old, New: ur.New, Kid: ur.Kid, Idx: ur.Idx, ToIdx: ur.ToIdx}
+	switch ur.Op {
+	case UndoInsert:
+		cr.Op = ChangeChildAdded
+	case UndoDelete:
+		cr.Op = ChangeChildRemoved
+	case UndoMove:
+		cr.Op = ChangeChildMoved
+	case UndoSetName:
+		cr.Op = ChangeSetName
+		cr.Key = ""
+	case UndoSetField:
+		cr.Op = ChangeSetField
+	case UndoSetProp:
+		if ur.HasNew {
+			cr.Op = ChangeSetProp
+		} else {
+			cr.Op = ChangeDeleteProp
+		}
+	}
+	return cr
+}
+
+// changesRecord records given edits, which must all be on the same node, in
+// the ChangeNotifier for that node, if there is one
+func changesRecord(recs ...*UndoRec) {
+	if len(recs) == 0 || recs[0].Node.IsDestroyed() {
+		return
+	}
+	cn := ChangeNotifierFor(recs[0].Node)
+	if cn == nil {
+		return
+	}
+	crs := make([]ChangeRec, len(recs))
+	for i, ur := range recs {
+		crs[i] = changeRecFmUndo(ur)
+	}
+	cn.record(crs...)
+}
+
+// recordEdit records given edits, which must all be on the same node, in
+// the ChangeNotifier and UndoStack for that node, if any -- returns true if
+// recorded in an UndoStack
+func recordEdit(recs ...*UndoRec) bool {
+	changesRecord(recs...)
+	return undoRecord(recs...)
+}
+
+// changesUpdateStart notifies the ChangeNotifier for given node, if any, of
+// an UpdateStart that returned true
+func changesUpdateStart(k Ki) {
+	if cn := ChangeNotifierFor(k); cn != nil {
+		cn.updateStart(k)
+	}
+}
+
+// changesUnrecorded notifies the ChangeNotifier for given node, if any, that
+// changes are being made to it that are not recorded
+func changesUnrecorded(k Ki) {
+	if cn := ChangeNotifierFor(k); cn != nil {
+		cn.unrecorded()
+	}
+}
+
+// changesUpdateEnd notifies any ChangeNotifier with a batch started by given
+// node that its update has ended -- the node may no longer be under the
+// notifier (e.g., if it deleted itself) so all notifiers are checked --
+// returns the notifiers, which must be passed to changesSignaled after the
+// NodeSignalUpdated signal is sent
+func changesUpdateEnd(k Ki) []*ChangeNotifier {
+	changeNotifiers.RLock()
+	if len(changeNotifiers.m) == 0 {
+		changeNotifiers.RUnlock()
+		return nil
+	}
+	var owns []*ChangeNotifier
+	for _, cn := range changeNotifiers.m {
+		cn.Mu.Lock()
+		if cn.curOwner == k {
+			owns = append(owns, cn)
+		}
+		cn.Mu.Unlock()
+	}
+	changeNotifiers.RUnlock()
+	for _, cn := range owns {
+		cn.updateEnd(k)
+	}
+	return owns
+}
+
+// changesSignaled notifies the notifiers returned by changesUpdateEnd for
+// given node that its NodeSignalUpdated signal has been sent
+func changesSignaled(owns []*ChangeNotifier, k Ki) {
+	for _, cn := range owns {
+		cn.signaled(k)
+	}
+}
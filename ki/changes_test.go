@@ -0,0 +1,137 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"testing"
+)
+
+func TestChangeNotifier(t *testing.T) {
+	parent := undoTestTree()
+	cn := AttachChangeNotifier(parent)
+	defer cn.Detach()
+	if ChangeNotifierFor(parent.Child(1).Child(0)) != cn {
+		t.Errorf("ChangeNotifierFor did not find notifier from subchild")
+	}
+	var batches []ChangeBatch
+	updts := 0
+	parent.NodeSignal().Connect(parent.This, func(recv, send Ki, sig int64, data interface{}) {
+		if sig == int64(NodeSignalUpdated) {
+			if len(batches) == 0 {
+				t.Errorf("NodeSignalUpdated should be sent after NodeSignalChanged")
+			}
+			updts++
+		}
+	})
+	cn.ChangeSig.Connect(parent.This, func(recv, send Ki, sig int64, data interface{}) {
+		if sig != int64(NodeSignalChanged) || send != parent.This {
+			t.Errorf("change signal: wrong sig %v or sender %v", NodeSignals(sig), send.Name())
+		}
+		batches = append(batches, data.(ChangeBatch))
+	})
+
+	// each of these is its own batch
+	child1 := parent.Child(0)
+	child1.SetProp("stringprop", "str")
+	child1.DeleteProp("stringprop")
+	parent.SetField("Mbr2", 17)
+	if len(batches) != 3 {
+		t.Fatalf("should be 3 batches, was: %v", len(batches))
+	}
+	cr := batches[0][0]
+	if cr.Op != ChangeSetProp || cr.Node != child1 || cr.Key != "stringprop" || cr.Old != nil || cr.New != "str" {
+		t.Errorf("SetProp change wrong: %v", cr)
+	}
+	if cr = batches[1][0]; cr.Op != ChangeDeleteProp || cr.Old != "str" {
+		t.Errorf("DeleteProp change wrong: %v", cr)
+	}
+	if cr = batches[2][0]; cr.Op != ChangeSetField || cr.Key != "Mbr2" || cr.Old != 0 || cr.New != 17 {
+		t.Errorf("SetField change wrong: %v", cr)
+	}
+
+	// everything within an update is one batch
+	batches = nil
+	updts = 0
+	typ := parent.Type()
+	updt := parent.UpdateStart()
+	parent.AddNewChild(typ, "child4")
+	parent.MoveChild(3, 0)
+	parent.DeleteChildAtIndex(2, true)
+	parent.Child(1).SetField("Mbr1", "bloop")
+	parent.Child(2).AddNewChild(typ, "subchild2")
+	parent.UpdateEnd(updt)
+	if len(batches) != 1 || updts != 1 {
+		t.Fatalf("should be 1 batch and 1 update, was: %v, %v", len(batches), updts)
+	}
+	cb := batches[0]
+	ops := []ChangeOps{ChangeChildAdded, ChangeSetName, ChangeChildMoved, ChangeChildRemoved, ChangeSetField, ChangeChildAdded, ChangeSetName}
+	if len(cb) != len(ops) {
+		t.Fatalf("batch should have %v changes, was: %v", len(ops), cb)
+	}
+	for i, op := range ops {
+		if cb[i].Op != op {
+			t.Errorf("change %v should be %v, was: %v", i, op, cb[i])
+		}
+	}
+	if cb[0].Idx != 3 || cb[0].Kid.Name() != "child4" || cb[2].Idx != 3 || cb[2].ToIdx != 0 || cb[3].Idx != 2 || cb[3].Kid.Name() != "child2" {
+		t.Errorf("child change indexes wrong: %v", cb)
+	}
+	if nodes := cb.Nodes(); len(nodes) != 5 || nodes[0] != parent.This {
+		t.Errorf("batch nodes wrong: %v", nodes)
+	}
+	if !cb.HasChildChanges() || batches[0][4:5].HasChildChanges() {
+		t.Errorf("HasChildChanges wrong")
+	}
+
+	// changes outside the notifier are not recorded
+	batches = nil
+	other := undoTestTree()
+	other.SetProp("stringprop", "str")
+	cn.Detach()
+	parent.SetProp("stringprop", "str")
+	if len(batches) != 0 {
+		t.Errorf("changes outside notifier should not be recorded: %v", batches)
+	}
+}
+
+func TestChangeNotifierCovers(t *testing.T) {
+	parent := undoTestTree()
+	cn := AttachChangeNotifier(parent)
+	defer cn.Detach()
+	var covered []bool
+	parent.NodeSignal().Connect(parent.This, func(recv, send Ki, sig int64, data interface{}) {
+		if sig == int64(NodeSignalUpdated) {
+			covered = append(covered, cn.Covers(send))
+		}
+	})
+
+	updt := parent.UpdateStart()
+	parent.SetField("Mbr2", 3)
+	parent.UpdateEnd(updt)
+	if len(covered) != 1 || !covered[0] {
+		t.Errorf("recorded update should be covered: %v", covered)
+	}
+	if cn.Covers(parent.This) {
+		t.Errorf("Covers should be false after the update signal")
+	}
+
+	// direct edits, loading, and empty updates are not covered
+	covered = nil
+	parent.Mbr2 = 4
+	parent.UpdateSig()
+	updt = parent.UpdateStart()
+	parent.UpdateEnd(updt)
+	b, err := parent.SaveJSON(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updt = parent.UpdateStart()
+	parent.SetField("Mbr2", 5)
+	parent.LoadJSON(b)
+	parent.UpdateEnd(updt)
+	if len(covered) != 3 || covered[0] || covered[1] || covered[2] {
+		t.Errorf("unrecorded updates should not be covered: %v", covered)
+	}
+}
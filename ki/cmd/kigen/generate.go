@@ -0,0 +1,228 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Generate returns the formatted generated code for the package -- nil if
+// there is nothing to generate
+func (g *Generator) Generate() ([]byte, error) {
+	if len(g.Types) == 0 {
+		return nil, nil
+	}
+	var body bytes.Buffer
+	imps := make(map[string]bool)
+	for _, ti := range g.Types {
+		g.genType(&body, ti, imps)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s\"; DO NOT EDIT.\n\n", GenHeader)
+	fmt.Fprintf(&buf, "package %s\n\n", g.PkgName)
+	var std, goki []string
+	for imp := range imps {
+		if strings.Contains(imp, ".") {
+			goki = append(goki, imp)
+		} else {
+			std = append(std, imp)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(goki)
+	if len(imps) > 0 {
+		fmt.Fprintf(&buf, "import (\n")
+		for _, imp := range std {
+			fmt.Fprintf(&buf, "\t%q\n", imp)
+		}
+		if len(std) > 0 && len(goki) > 0 {
+			fmt.Fprintf(&buf, "\n")
+		}
+		for _, imp := range goki {
+			fmt.Fprintf(&buf, "\t%q\n", imp)
+		}
+		fmt.Fprintf(&buf, ")\n")
+	}
+	buf.Write(body.Bytes())
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return buf.Bytes(), fmt.Errorf("internal error: invalid Go generated: %s -- compile the output to see the error", err)
+	}
+	return src, nil
+}
+
+// kit returns the qualified name of given kit package name, recording the
+// import
+func (g *Generator) kit(nm string, imps map[string]bool) string {
+	if g.PkgName == "kit" {
+		return nm
+	}
+	imps[KitPath] = true
+	return "kit." + nm
+}
+
+// ki returns the qualified name of given ki package name, recording the
+// import
+func (g *Generator) ki(nm string, imps map[string]bool) string {
+	if g.PkgName == "ki" {
+		return nm
+	}
+	imps[KiPath] = true
+	return "ki." + nm
+}
+
+// genType generates the code for one type
+func (g *Generator) genType(buf *bytes.Buffer, ti *TypeInfo, imps map[string]bool) {
+	props := ti.Props
+	if props == "" {
+		props = "nil"
+	}
+	if ti.Reg {
+		fmt.Fprintf(buf, "\nvar KiT_%s = %s(&%s{}, %s)\n", ti.Name, g.kit("Types.AddType", imps), ti.Name, props)
+	}
+	if ti.New {
+		fmt.Fprintf(buf, "\nfunc (n *%s) New() %s { return &%s{} }\n", ti.Name, g.ki("Ki", imps), ti.Name)
+	}
+	if ei := ti.Enum; ei != nil {
+		if ei.String || ei.FromString {
+			// stringer code may already declare the tables, for FromString
+			nm, idx := g.decls["_"+ti.Name+"_name"], g.decls["_"+ti.Name+"_index"]
+			if g.contiguous(ei) && nm == idx {
+				g.genEnumSlice(buf, ti, !nm, imps)
+			} else {
+				g.genEnumMap(buf, ti, !g.decls["_"+ti.Name+"_map"], imps)
+			}
+		}
+		if ei.Reg {
+			fmt.Fprintf(buf, "\nvar KiT_%s = %s(%sN, %v, %s)\n", ti.Name, g.kit("Enums.AddEnum", imps), ti.Name, ti.BitFlag, props)
+		}
+		if ei.JSON {
+			fmt.Fprintf(buf, "\nfunc (ev %s) MarshalJSON() ([]byte, error)  { return %s(ev) }\n", ti.Name, g.kit("EnumMarshalJSON", imps))
+			fmt.Fprintf(buf, "func (ev *%s) UnmarshalJSON(b []byte) error { return %s(ev, b) }\n", ti.Name, g.kit("EnumUnmarshalJSON", imps))
+		}
+		if ei.Text {
+			fmt.Fprintf(buf, "\nfunc (ev %s) MarshalText() ([]byte, error)  { return %s(ev) }\n", ti.Name, g.kit("EnumMarshalText", imps))
+			fmt.Fprintf(buf, "func (ev *%s) UnmarshalText(b []byte) error { return %s(ev, b) }\n", ti.Name, g.kit("EnumUnmarshalText", imps))
+		}
+	}
+	if ti.Struct {
+		g.genFieldMetas(buf, ti, imps)
+	}
+}
+
+// contiguous returns true if the enum values run from 0 without gaps
+func (g *Generator) contiguous(ei *EnumInfo) bool {
+	for i, v := range ei.Values {
+		if v.Value != int64(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// outOfRange returns the String method expression for an enum value that
+// has no name
+func outOfRange(ti *TypeInfo) string {
+	if ti.Enum.Unsigned {
+		return fmt.Sprintf("%q + strconv.FormatUint(uint64(i), 10) + \")\"", ti.Name+"(")
+	}
+	return fmt.Sprintf("%q + strconv.FormatInt(int64(i), 10) + \")\"", ti.Name+"(")
+}
+
+// genEnumSlice generates the String and FromString methods for contiguous
+// enum values, using a single string of names and index, as stringer does
+// -- tables is whether to generate the tables
+func (g *Generator) genEnumSlice(buf *bytes.Buffer, ti *TypeInfo, tables bool, imps map[string]bool) {
+	ei := ti.Enum
+	var names strings.Builder
+	idx := make([]string, 0, len(ei.Values)+1)
+	idx = append(idx, "0")
+	for _, v := range ei.Values {
+		names.WriteString(v.Name)
+		idx = append(idx, strconv.Itoa(names.Len()))
+	}
+	itype := "uint8"
+	switch {
+	case names.Len() >= 1<<16:
+		itype = "uint32"
+	case names.Len() >= 1<<8:
+		itype = "uint16"
+	}
+	if tables {
+		fmt.Fprintf(buf, "\nconst _%s_name = %q\n", ti.Name, names.String())
+		fmt.Fprintf(buf, "\nvar _%s_index = [...]%s{%s}\n", ti.Name, itype, strings.Join(idx, ", "))
+	}
+	if ei.String {
+		imps["strconv"] = true
+		cond := fmt.Sprintf("i < 0 || i >= %s(len(_%s_index)-1)", ti.Name, ti.Name)
+		if ei.Unsigned {
+			cond = fmt.Sprintf("i >= %s(len(_%s_index)-1)", ti.Name, ti.Name)
+		}
+		fmt.Fprintf(buf, "\nfunc (i %s) String() string {\n", ti.Name)
+		fmt.Fprintf(buf, "\tif %s {\n\t\treturn %s\n\t}\n", cond, outOfRange(ti))
+		fmt.Fprintf(buf, "\treturn _%s_name[_%s_index[i]:_%s_index[i+1]]\n}\n", ti.Name, ti.Name, ti.Name)
+	}
+	if ei.FromString {
+		imps["fmt"] = true
+		fmt.Fprintf(buf, "\nfunc (i *%s) FromString(s string) error {\n", ti.Name)
+		fmt.Fprintf(buf, "\tfor j := 0; j < len(_%s_index)-1; j++ {\n", ti.Name)
+		fmt.Fprintf(buf, "\t\tif s == _%s_name[_%s_index[j]:_%s_index[j+1]] {\n", ti.Name, ti.Name, ti.Name)
+		fmt.Fprintf(buf, "\t\t\t*i = %s(j)\n\t\t\treturn nil\n\t\t}\n\t}\n", ti.Name)
+		fmt.Fprintf(buf, "\treturn fmt.Errorf(\"String %%v is not a valid option for type %s\", s)\n}\n", ti.Name)
+	}
+}
+
+// genEnumMap generates the String and FromString methods for enum values
+// that are not contiguous, using a map from values to names, as stringer
+// does for sparse values -- table is whether to generate the map
+func (g *Generator) genEnumMap(buf *bytes.Buffer, ti *TypeInfo, table bool, imps map[string]bool) {
+	ei := ti.Enum
+	if table {
+		fmt.Fprintf(buf, "\nvar _%s_map = map[%s]string{\n", ti.Name, ti.Name)
+		for _, v := range ei.Values {
+			fmt.Fprintf(buf, "\t%s: %q,\n", v.Name, v.Name)
+		}
+		fmt.Fprintf(buf, "}\n")
+	}
+	if ei.String {
+		imps["strconv"] = true
+		fmt.Fprintf(buf, "\nfunc (i %s) String() string {\n", ti.Name)
+		fmt.Fprintf(buf, "\tif str, ok := _%s_map[i]; ok {\n\t\treturn str\n\t}\n", ti.Name)
+		fmt.Fprintf(buf, "\treturn %s\n}\n", outOfRange(ti))
+	}
+	if ei.FromString {
+		imps["fmt"] = true
+		fmt.Fprintf(buf, "\nfunc (i *%s) FromString(s string) error {\n", ti.Name)
+		fmt.Fprintf(buf, "\tfor v, str := range _%s_map {\n", ti.Name)
+		fmt.Fprintf(buf, "\t\tif s == str {\n\t\t\t*i = v\n\t\t\treturn nil\n\t\t}\n\t}\n")
+		fmt.Fprintf(buf, "\treturn fmt.Errorf(\"String %%v is not a valid option for type %s\", s)\n}\n", ti.Name)
+	}
+}
+
+// genFieldMetas generates the registration of the field metadata table
+func (g *Generator) genFieldMetas(buf *bytes.Buffer, ti *TypeInfo, imps map[string]bool) {
+	fm := g.kit("FieldMeta", imps)
+	fmt.Fprintf(buf, "\nvar _ = %s(&%s{}, []%s{\n", g.kit("FieldMetas.AddFieldMetas", imps), ti.Name, fm)
+	for _, f := range ti.Fields {
+		vals := []string{"Name: " + strconv.Quote(f.Name)}
+		for _, key := range metaTags {
+			if v := tagValue(f.Tag, key); v != "" {
+				vals = append(vals, strings.ToUpper(key[:1])+key[1:]+": "+strconv.Quote(v))
+			}
+		}
+		if f.Tag != "" {
+			vals = append(vals, "Tag: "+f.Tag)
+		}
+		fmt.Fprintf(buf, "\t{%s},\n", strings.Join(vals, ", "))
+	}
+	fmt.Fprintf(buf, "})\n")
+}
@@ -0,0 +1,508 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// KiPath is the import path of the ki package
+	KiPath = "github.com/rcoreilly/goki/ki"
+
+	// KitPath is the import path of the kit package
+	KitPath = "github.com/rcoreilly/goki/ki/kit"
+
+	// GenHeader starts the first line of the generated files
+	GenHeader = "// Code generated by \"kigen"
+)
+
+// DefaultOutput returns the default name of the output file for given package
+func DefaultOutput(pkgName string) string {
+	return strings.ToLower(pkgName) + "_kigen.go"
+}
+
+// IsGenerated returns true if given file exists and was generated by kigen
+func IsGenerated(fname string) bool {
+	f, err := os.Open(fname)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	ln, _ := bufio.NewReader(f).ReadString('\n')
+	return strings.HasPrefix(ln, GenHeader)
+}
+
+// Generator holds the parsed package and the types found in it for which
+// code is generated
+type Generator struct {
+	PkgName string      `desc:"name of the package"`
+	Types   []*TypeInfo `desc:"types in the package that need generated code, in source order"`
+
+	fset    *token.FileSet
+	files   []*ast.File
+	info    *types.Info
+	decls   map[string]bool            // top-level names declared in the package
+	methods map[string]map[string]bool // methods declared, by receiver type name
+}
+
+// TypeInfo records what is generated for one type
+type TypeInfo struct {
+	Name    string      `desc:"name of the type"`
+	Ki      bool        `desc:"type is a Ki type -- embeds ki.Node or other Ki types, or has a kigen:ki directive"`
+	Reg     bool        `desc:"register the type in kit.Types -- Ki types and those with a kigen:type directive, unless already declared"`
+	New     bool        `desc:"generate the New method -- Ki types unless already declared"`
+	Props   string      `desc:"name of the props variable for the type, if declared"`
+	Enum    *EnumInfo   `desc:"enum info, for enum types"`
+	Fields  []FieldInfo `desc:"fields of struct types that get field metadata"`
+	Struct  bool        `desc:"type is a struct with field tags in metaTags, which gets a field metadata table"`
+	BitFlag bool        `desc:"enum is registered as bit flags -- kigen:bitflag directive"`
+
+	spec *ast.TypeSpec
+	dirs map[string]bool
+}
+
+// EnumInfo records the values of an enum type, and what is generated for it
+type EnumInfo struct {
+	Values     []EnumValue `desc:"values, sorted by value, with only the first name for duplicate values"`
+	Unsigned   bool        `desc:"underlying type is unsigned"`
+	String     bool        `desc:"generate the String method"`
+	FromString bool        `desc:"generate the FromString method"`
+	Reg        bool        `desc:"generate the KiT_ enum registration"`
+	JSON       bool        `desc:"generate the MarshalJSON and UnmarshalJSON methods"`
+	Text       bool        `desc:"generate the MarshalText and UnmarshalText methods, used for XML"`
+}
+
+// EnumValue is one named value of an enum
+type EnumValue struct {
+	Name  string
+	Value int64 // the value, as bits for unsigned types
+}
+
+// FieldInfo is one field of a struct, for its field metadata
+type FieldInfo struct {
+	Name string
+	Tag  string // tag literal as written in the source, empty if none
+}
+
+// ParseDir parses the Go package in given directory, with given build tags,
+// excluding test files and any previously generated output -- output is
+// the name of the output file, empty for the default, and analyzes it to
+// determine the code to generate
+func ParseDir(dir string, tags []string, output string) (*Generator, error) {
+	ctx := build.Default
+	ctx.BuildTags = tags
+	bp, err := ctx.ImportDir(dir, 0)
+	if err != nil {
+		return nil, fmt.Errorf("cannot process directory %s: %s", dir, err)
+	}
+	if output == "" {
+		output = filepath.Join(dir, DefaultOutput(bp.Name))
+	}
+	g := &Generator{PkgName: bp.Name, fset: token.NewFileSet()}
+	names := append(append([]string{}, bp.GoFiles...), bp.CgoFiles...)
+	for _, nm := range names {
+		fn := filepath.Join(dir, nm)
+		if sameFile(fn, output) || IsGenerated(fn) {
+			continue
+		}
+		f, err := parser.ParseFile(g.fset, fn, nil, parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("parsing package: %s: %s", fn, err)
+		}
+		g.files = append(g.files, f)
+	}
+	if len(g.files) == 0 {
+		return nil, fmt.Errorf("%s: no buildable Go files", dir)
+	}
+	g.typeCheck(bp.ImportPath)
+	g.collectDecls()
+	g.findTypes()
+	return g, nil
+}
+
+// sameFile returns true if the two file names refer to the same file
+func sameFile(a, b string) bool {
+	aa, err1 := filepath.Abs(a)
+	ba, err2 := filepath.Abs(b)
+	return err1 == nil && err2 == nil && aa == ba
+}
+
+// emptyImporter imports every package as an empty package -- the package
+// is type checked on its own, with errors ignored, just to evaluate its
+// types and constants
+type emptyImporter struct{}
+
+func (im emptyImporter) Import(ipath string) (*types.Package, error) {
+	pkg := types.NewPackage(ipath, path.Base(ipath))
+	pkg.MarkComplete()
+	return pkg, nil
+}
+
+func (g *Generator) typeCheck(ipath string) {
+	g.info = &types.Info{Defs: make(map[*ast.Ident]types.Object)}
+	conf := types.Config{Importer: emptyImporter{}, Error: func(err error) {}}
+	conf.Check(ipath, g.fset, g.files, g.info) // errors are expected, e.g., from generated code
+}
+
+// collectDecls records the top-level names and methods declared in the
+// package
+func (g *Generator) collectDecls() {
+	g.decls = make(map[string]bool)
+	g.methods = make(map[string]map[string]bool)
+	for _, f := range g.files {
+		for _, d := range f.Decls {
+			switch d := d.(type) {
+			case *ast.FuncDecl:
+				if d.Recv == nil || len(d.Recv.List) == 0 {
+					g.decls[d.Name.Name] = true
+					continue
+				}
+				rt := recvTypeName(d.Recv.List[0].Type)
+				if g.methods[rt] == nil {
+					g.methods[rt] = make(map[string]bool)
+				}
+				g.methods[rt][d.Name.Name] = true
+			case *ast.GenDecl:
+				for _, s := range d.Specs {
+					switch s := s.(type) {
+					case *ast.ValueSpec:
+						for _, nm := range s.Names {
+							g.decls[nm.Name] = true
+						}
+					case *ast.TypeSpec:
+						g.decls[s.Name.Name] = true
+					}
+				}
+			}
+		}
+	}
+}
+
+// recvTypeName returns the name of the type of a method receiver
+func recvTypeName(x ast.Expr) string {
+	for {
+		switch t := x.(type) {
+		case *ast.StarExpr:
+			x = t.X
+		case *ast.ParenExpr:
+			x = t.X
+		case *ast.IndexExpr:
+			x = t.X
+		case *ast.Ident:
+			return t.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// hasMethod returns true if the given method is declared for given type
+func (g *Generator) hasMethod(typ, meth string) bool {
+	return g.methods[typ][meth]
+}
+
+// directives returns the kigen: directives in given comment groups
+func directives(cgs ...*ast.CommentGroup) map[string]bool {
+	dirs := make(map[string]bool)
+	for _, cg := range cgs {
+		if cg == nil {
+			continue
+		}
+		for _, c := range cg.List {
+			if strings.HasPrefix(c.Text, "//kigen:") {
+				for _, d := range strings.Fields(strings.TrimPrefix(c.Text, "//kigen:")) {
+					dirs[d] = true
+				}
+			}
+		}
+	}
+	return dirs
+}
+
+// findTypes finds all the types that need generated code
+func (g *Generator) findTypes() {
+	var all []*TypeInfo
+	byName := make(map[string]*TypeInfo)
+	for _, f := range g.files {
+		for _, d := range f.Decls {
+			gd, ok := d.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, s := range gd.Specs {
+				ts := s.(*ast.TypeSpec)
+				if ts.TypeParams != nil || ts.Assign.IsValid() {
+					continue
+				}
+				var doc *ast.CommentGroup
+				if len(gd.Specs) == 1 {
+					doc = gd.Doc
+				}
+				ti := &TypeInfo{Name: ts.Name.Name, spec: ts, dirs: directives(doc, ts.Doc, ts.Comment)}
+				if ti.dirs["skip"] {
+					continue
+				}
+				all = append(all, ti)
+				byName[ti.Name] = ti
+			}
+		}
+	}
+	g.findKiTypes(all, byName)
+	g.findEnums(all)
+	for _, ti := range all {
+		_, isStruct := ti.spec.Type.(*ast.StructType)
+		if ti.Ki || ti.dirs["type"] {
+			ti.Reg = !g.decls["KiT_"+ti.Name]
+			if g.decls[ti.Name+"Props"] {
+				ti.Props = ti.Name + "Props"
+			}
+		}
+		if ti.Ki {
+			ti.New = !g.hasMethod(ti.Name, "New")
+		}
+		if isStruct {
+			ti.Fields = structFields(ti.spec.Type.(*ast.StructType))
+			ti.Struct = hasMetaTags(ti.Fields)
+		}
+		if ti.Reg || ti.New || ti.Struct || ti.Enum != nil {
+			g.Types = append(g.Types, ti)
+		}
+	}
+}
+
+// findKiTypes marks the Ki types -- structs that embed ki.Node, or other
+// Ki types in the package, or have a kigen:ki directive
+func (g *Generator) findKiTypes(all []*TypeInfo, byName map[string]*TypeInfo) {
+	for _, ti := range all {
+		if ti.dirs["ki"] {
+			ti.Ki = true
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, ti := range all {
+			st, ok := ti.spec.Type.(*ast.StructType)
+			if ti.Ki || !ok {
+				continue
+			}
+			for _, fld := range st.Fields.List {
+				if len(fld.Names) > 0 {
+					continue
+				}
+				if g.isKiEmbed(fld.Type, byName) {
+					ti.Ki = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+}
+
+// isKiEmbed returns true if the type of an embedded field is a Ki type
+func (g *Generator) isKiEmbed(x ast.Expr, byName map[string]*TypeInfo) bool {
+	switch t := x.(type) {
+	case *ast.Ident:
+		if g.PkgName == "ki" && t.Name == "Node" {
+			return true
+		}
+		if ti, ok := byName[t.Name]; ok {
+			return ti.Ki
+		}
+	case *ast.SelectorExpr:
+		id, ok := t.X.(*ast.Ident)
+		return ok && t.Sel.Name == "Node" && g.importPath(id) == KiPath
+	}
+	return false
+}
+
+// importPath returns the import path of the package named by given
+// identifier in the file containing it, empty if none
+func (g *Generator) importPath(id *ast.Ident) string {
+	for _, f := range g.files {
+		if f.Pos() > id.Pos() || id.Pos() >= f.End() {
+			continue
+		}
+		for _, is := range f.Imports {
+			ipath, _ := strconv.Unquote(is.Path.Value)
+			nm := path.Base(ipath)
+			if is.Name != nil {
+				nm = is.Name.Name
+			}
+			if nm == id.Name {
+				return ipath
+			}
+		}
+	}
+	return ""
+}
+
+// findEnums finds the enum types -- named integer types with a TypeNameN
+// constant -- and their values
+func (g *Generator) findEnums(all []*TypeInfo) {
+	consts := g.constsByType()
+	for _, ti := range all {
+		obj, ok := g.info.Defs[ti.spec.Name].(*types.TypeName)
+		if !ok {
+			continue
+		}
+		basic, ok := obj.Type().Underlying().(*types.Basic)
+		if !ok || basic.Info()&types.IsInteger == 0 {
+			continue
+		}
+		cs := consts[obj.Type()]
+		isEnum := false
+		for _, c := range cs {
+			if c.Name() == ti.Name+"N" {
+				isEnum = true
+			}
+		}
+		if !isEnum {
+			continue
+		}
+		unsigned := basic.Info()&types.IsUnsigned != 0
+		ei := &EnumInfo{Unsigned: unsigned}
+		seen := make(map[int64]bool)
+		ok = true
+		for _, c := range cs {
+			val := c.Val()
+			if val.Kind() != constant.Int {
+				log.Printf("%s: cannot determine the value of %s -- skipping enum %s", g.fset.Position(c.Pos()), c.Name(), ti.Name)
+				ok = false
+				break
+			}
+			var iv int64
+			if unsigned {
+				u, _ := constant.Uint64Val(val)
+				iv = int64(u)
+			} else {
+				iv, _ = constant.Int64Val(val)
+			}
+			if seen[iv] {
+				continue
+			}
+			seen[iv] = true
+			ei.Values = append(ei.Values, EnumValue{Name: c.Name(), Value: iv})
+		}
+		if !ok {
+			continue
+		}
+		sort.SliceStable(ei.Values, func(i, j int) bool {
+			if unsigned {
+				return uint64(ei.Values[i].Value) < uint64(ei.Values[j].Value)
+			}
+			return ei.Values[i].Value < ei.Values[j].Value
+		})
+		ei.String = !g.hasMethod(ti.Name, "String")
+		ei.FromString = !g.hasMethod(ti.Name, "FromString")
+		ei.Reg = !g.decls["KiT_"+ti.Name]
+		ei.JSON = !g.hasMethod(ti.Name, "MarshalJSON") && !g.hasMethod(ti.Name, "UnmarshalJSON")
+		ei.Text = !g.hasMethod(ti.Name, "MarshalText") && !g.hasMethod(ti.Name, "UnmarshalText")
+		if !(ei.String || ei.FromString || ei.Reg || ei.JSON || ei.Text) {
+			continue
+		}
+		ti.Enum = ei
+		ti.BitFlag = ti.dirs["bitflag"]
+		if g.decls[ti.Name+"Props"] {
+			ti.Props = ti.Name + "Props"
+		}
+	}
+}
+
+// constsByType returns the package-level constants in source order, by type
+func (g *Generator) constsByType() map[types.Type][]*types.Const {
+	consts := make(map[types.Type][]*types.Const)
+	for _, f := range g.files {
+		for _, d := range f.Decls {
+			gd, ok := d.(*ast.GenDecl)
+			if !ok || gd.Tok != token.CONST {
+				continue
+			}
+			for _, s := range gd.Specs {
+				for _, nm := range s.(*ast.ValueSpec).Names {
+					c, ok := g.info.Defs[nm].(*types.Const)
+					if !ok || nm.Name == "_" {
+						continue
+					}
+					consts[c.Type()] = append(consts[c.Type()], c)
+				}
+			}
+		}
+	}
+	return consts
+}
+
+// structFields returns the fields of a struct type, in order
+func structFields(st *ast.StructType) []FieldInfo {
+	var fields []FieldInfo
+	for _, fld := range st.Fields.List {
+		tag := ""
+		if fld.Tag != nil {
+			tag = fld.Tag.Value
+		}
+		if len(fld.Names) == 0 {
+			fields = append(fields, FieldInfo{Name: recvTypeName(embedName(fld.Type)), Tag: tag})
+			continue
+		}
+		for _, nm := range fld.Names {
+			fields = append(fields, FieldInfo{Name: nm.Name, Tag: tag})
+		}
+	}
+	return fields
+}
+
+// embedName returns the type name identifier of an embedded field type
+func embedName(x ast.Expr) ast.Expr {
+	if st, ok := x.(*ast.StarExpr); ok {
+		x = st.X
+	}
+	if se, ok := x.(*ast.SelectorExpr); ok {
+		return se.Sel
+	}
+	return x
+}
+
+// metaTags are the tags parsed into kit.FieldMeta fields
+var metaTags = []string{"desc", "label", "view", "min", "max"}
+
+// tagValue returns the value of given tag in a tag literal
+func tagValue(lit, key string) string {
+	if lit == "" {
+		return ""
+	}
+	tag, err := strconv.Unquote(lit)
+	if err != nil {
+		return ""
+	}
+	return reflect.StructTag(tag).Get(key)
+}
+
+// hasMetaTags returns true if any of the fields have any of the metaTags
+func hasMetaTags(fields []FieldInfo) bool {
+	for _, f := range fields {
+		for _, key := range metaTags {
+			if tagValue(f.Tag, key) != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// TestGolden generates the code for each package in testdata, and compares
+// it with the golden file of the same name -- run with -update to rewrite
+// the golden files
+func TestGolden(t *testing.T) {
+	dirs, err := filepath.Glob("testdata/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range dirs {
+		if filepath.Ext(dir) == ".golden" {
+			continue
+		}
+		g, err := ParseDir(dir, nil, "")
+		if err != nil {
+			t.Errorf("%v: %v", dir, err)
+			continue
+		}
+		src, err := g.Generate()
+		if err != nil {
+			t.Errorf("%v: %v", dir, err)
+			continue
+		}
+		golden := dir + ".golden"
+		if *update {
+			if err := ioutil.WriteFile(golden, src, 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Errorf("%v: %v", dir, err)
+			continue
+		}
+		if !bytes.Equal(src, want) {
+			t.Errorf("%v: generated code does not match %v -- got:\n%s", dir, golden, src)
+		}
+	}
+}
+
+// TestGeneratedExcluded checks that previously generated code is excluded
+// when parsing, so that regenerating gives the same result
+func TestGeneratedExcluded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kigen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src, err := ioutil.ReadFile("testdata/nodes/nodes.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "nodes.go"), src, 0644)
+	g, err := ParseDir(dir, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen1, err := g.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// not the default output name, so it must be detected by its header
+	ioutil.WriteFile(filepath.Join(dir, "gen.go"), gen1, 0644)
+	g, err = ParseDir(dir, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen2, err := g.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(gen1, gen2) {
+		t.Errorf("regenerated code differs:\n%s\n--- vs ---\n%s", gen1, gen2)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Command kigen generates the boilerplate code for the GoKi types in a Go
+// package, which otherwise has to be written by hand:
+//
+// * for Ki types -- structs that embed ki.Node, directly or through other Ki
+// types in the same package -- the KiT_TypeName type registration variable,
+// using TypeNameProps as the type properties if it is declared, and the New
+// method.  Ki types that embed Ki types from other packages (e.g.,
+// gi.WidgetBase) must be marked with a //kigen:ki directive in their doc
+// comment, as other packages are not loaded.  Other types can be registered
+// in kit.Types with a //kigen:type directive.
+//
+// * for enums -- integer types with a TypeNameN constant -- the String and
+// FromString methods (as the stringer command generates), the KiT_TypeName
+// enum registration variable (a //kigen:bitflag directive registers it as
+// bit flags), and the MarshalJSON / UnmarshalJSON and MarshalText /
+// UnmarshalText methods, which use names -- "A|B" lists of names for bit
+// flags -- instead of numbers.  Values need not be contiguous.
+//
+// * for structs with desc, label, view, min, or max field tags -- tables of
+// the kit.FieldMeta metadata for the fields, registered in kit.FieldMetas,
+// so the tags do not have to be parsed using reflection at runtime.
+//
+// Anything that is already declared in the package is not generated, so
+// existing code can be converted incrementally.  A //kigen:skip directive
+// skips a type entirely.  Typical usage is to add to one file in the
+// package:
+//
+//	//go:generate kigen
+//
+// which writes the generated code to pkgname_kigen.go -- or run:
+//
+//	kigen [-output file] [-tags tags] [directory]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	output    = flag.String("output", "", "output file name; default srcdir/<pkgname>_kigen.go")
+	buildTags = flag.String("tags", "", "comma-separated list of build tags to apply")
+)
+
+// Usage is a replacement usage function for the flags package
+func Usage() {
+	fmt.Fprintf(os.Stderr, "Usage of kigen:\n")
+	fmt.Fprintf(os.Stderr, "\tkigen [flags] [directory]\n")
+	fmt.Fprintf(os.Stderr, "Flags:\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("kigen: ")
+	flag.Usage = Usage
+	flag.Parse()
+	dir := "."
+	switch args := flag.Args(); len(args) {
+	case 0:
+	case 1:
+		dir = args[0]
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	var tags []string
+	if *buildTags != "" {
+		tags = strings.Split(*buildTags, ",")
+	}
+
+	g, err := ParseDir(dir, tags, *output)
+	if err != nil {
+		log.Fatal(err)
+	}
+	src, err := g.Generate()
+	if err != nil {
+		log.Fatal(err)
+	}
+	outName := *output
+	if outName == "" {
+		outName = filepath.Join(dir, DefaultOutput(g.PkgName))
+	}
+	if src == nil {
+		// nothing to generate -- remove any previous output
+		if IsGenerated(outName) {
+			if err := os.Remove(outName); err != nil {
+				log.Fatal(err)
+			}
+		}
+		return
+	}
+	if err := ioutil.WriteFile(outName, src, 0644); err != nil {
+		log.Fatalf("writing output: %s", err)
+	}
+}
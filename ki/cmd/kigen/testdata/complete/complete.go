@@ -0,0 +1,15 @@
+// Package complete is test input for kigen, with everything hand written
+package complete
+
+import (
+	"github.com/rcoreilly/goki/ki"
+	"github.com/rcoreilly/goki/ki/kit"
+)
+
+type Done struct {
+	ki.Node
+}
+
+var KiT_Done = kit.Types.AddType(&Done{}, nil)
+
+func (n *Done) New() ki.Ki { return &Done{} }
@@ -0,0 +1,89 @@
+// Package enums is test input for kigen
+package enums
+
+import "strconv"
+
+// Dirs is a contiguous enum
+type Dirs int32
+
+const (
+	Up Dirs = iota
+	Down
+	Left
+	Right
+	DirsN
+)
+
+// Flags are bit flags
+//
+//kigen:bitflag
+type Flags int64
+
+const (
+	FlagA Flags = iota
+	FlagB
+	FlagsN
+)
+
+var FlagsProps = map[string]interface{}{"desc": "flags"}
+
+// Codes is a sparse enum with a duplicate value
+type Codes int
+
+const (
+	CodeOK      Codes = 200
+	CodeMissing Codes = 404
+	CodeLost          = CodeMissing
+	CodeBad     Codes = -1
+	CodesN      Codes = 3
+)
+
+// Bytes is an unsigned enum
+type Bytes uint8
+
+const (
+	ByteA Bytes = iota
+	ByteB
+	BytesN
+)
+
+// Sizes already has its stringer code -- only registration and JSON are
+// generated
+type Sizes int32
+
+const (
+	Small Sizes = iota
+	Large
+	SizesN
+)
+
+func (i Sizes) String() string             { return "" }
+func (i *Sizes) FromString(s string) error { return nil }
+
+// Count has no N constant, so is not an enum
+type Count int
+
+const (
+	One Count = 1
+)
+
+// Modes has stringer code without FromString, which is generated using the
+// stringer tables
+type Modes int32
+
+const (
+	ModeA Modes = iota
+	ModeB
+	ModesN
+)
+
+const _Modes_name = "ModeAModeBModesN"
+
+var _Modes_index = [...]uint8{0, 5, 10, 16}
+
+func (i Modes) String() string {
+	if i < 0 || i >= Modes(len(_Modes_index)-1) {
+		return "Modes(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _Modes_name[_Modes_index[i]:_Modes_index[i+1]]
+}
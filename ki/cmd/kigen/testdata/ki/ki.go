@@ -0,0 +1,17 @@
+// Package ki is test input for kigen, for the ki package itself
+package ki
+
+type Ki interface {
+	Name() string
+}
+
+type Node struct {
+	Nm string `desc:"name"`
+}
+
+func (n *Node) Name() string { return n.Nm }
+
+// Leaf embeds Node unqualified
+type Leaf struct {
+	Node
+}
@@ -0,0 +1,66 @@
+// Package nodes is test input for kigen
+package nodes
+
+import (
+	"github.com/rcoreilly/goki/ki"
+	"github.com/rcoreilly/goki/ki/kit"
+)
+
+// MyNode embeds ki.Node directly
+type MyNode struct {
+	ki.Node
+	Size  int     `desc:"size of the node" min:"0" max:"100"`
+	Scale float32 `view:"slider" label:"Scale Factor"`
+	a, b  int
+}
+
+var MyNodeProps = ki.Props{
+	"background-color": "white",
+}
+
+// SubNode is a Ki type through MyNode
+type SubNode struct {
+	MyNode
+	Extra string `json:"extra" desc:"\"quoted\" extra"`
+}
+
+// HandNode has its registration and New written by hand
+type HandNode struct {
+	*SubNode
+}
+
+var KiT_HandNode = kit.Types.AddType(&HandNode{}, nil)
+
+func (n *HandNode) New() ki.Ki { return &HandNode{} }
+
+// WidgetLike embeds a Ki type in a way that is not detected, as for Ki
+// types from other packages
+//
+//kigen:ki
+type WidgetLike struct {
+	*ki.Node
+}
+
+// Opts is a plain struct with field tags
+type Opts struct {
+	On bool `desc:"turn it on"`
+}
+
+// Plain is a plain struct without field tags -- nothing generated
+type Plain struct {
+	X int `json:"x"`
+}
+
+// Reg is a plain struct registered by directive
+//
+//kigen:type
+type Reg struct {
+	Y int
+}
+
+// Skipped would be a Ki type
+//
+//kigen:skip
+type Skipped struct {
+	ki.Node
+}
@@ -0,0 +1,330 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/rcoreilly/goki/ki/bitflag"
+	"github.com/rcoreilly/goki/ki/kit"
+)
+
+// Diff compares two trees and returns the edit script that transforms tree a
+// into tree b, which can be applied to a (or a copy of a) using Patch.
+// Children are matched by UniqueName and type: children of b that are not in
+// a are inserted (with their full subtree), children of a that are not in b
+// are deleted, and matched children that are in a different position are
+// moved (using the same minimal-update logic as ConfigChildren).  Matched
+// nodes (including Ki fields) are then compared recursively for changes in
+// their field values and Props.  The root nodes of a and b are matched
+// regardless of their names, but must have the same type.
+func Diff(a, b Ki) Diffs {
+	if a.Type() != b.Type() {
+		log.Printf("ki.Diff: root nodes are of different types: %v vs %v\n", a.Type().String(), b.Type().String())
+		return nil
+	}
+	var diffs Diffs
+	diffs.diffNode(a, b, "")
+	return diffs
+}
+
+// Patch applies an edit script produced by Diff to the given tree, within
+// one UpdateStart / UpdateEnd update (so it is recorded as one step in an
+// UndoStack) -- edits that cannot be applied (e.g., the node is not found)
+// are skipped and reported in the returned error, which is for the first
+// such edit
+func Patch(k Ki, diffs Diffs) error {
+	var rerr error
+	updt := k.UpdateStart()
+	for _, df := range diffs {
+		if err := df.Apply(k); err != nil {
+			log.Println(err)
+			if rerr == nil {
+				rerr = err
+			}
+		}
+	}
+	k.UpdateEnd(updt)
+	return rerr
+}
+
+// DiffOps are the types of edits in a Diffs edit script
+type DiffOps int32
+
+const (
+	// DiffInsert inserts child Name of type Type at index Idx in node Path,
+	// with its full subtree given by Value
+	DiffInsert DiffOps = iota
+
+	// DiffDelete deletes child Name (at index Idx) from node Path
+	DiffDelete
+
+	// DiffMove moves child Name of node Path to index Idx
+	DiffMove
+
+	// DiffSetField sets field Name on node Path to Value
+	DiffSetField
+
+	// DiffSetProp sets property Name on node Path to the value in Props
+	DiffSetProp
+
+	// DiffDeleteProp deletes property Name on node Path
+	DiffDeleteProp
+
+	DiffOpsN
+)
+
+//go:generate stringer -type=DiffOps
+
+var KiT_DiffOps = kit.Enums.AddEnum(DiffOpsN, false, nil)
+
+func (ev DiffOps) MarshalJSON() ([]byte, error)  { return kit.EnumMarshalJSON(ev) }
+func (ev *DiffOps) UnmarshalJSON(b []byte) error { return kit.EnumUnmarshalJSON(ev, b) }
+
+// DiffEdit is one edit in a Diffs edit script -- nodes are identified by
+// their unique path relative to the root of the tree, using the same
+// conventions as PathUnique (/ before child names, . before Ki field names)
+// but without the root name, so the root itself is the empty path ""
+type DiffEdit struct {
+	Op    DiffOps         `desc:"type of edit"`
+	Path  string          `desc:"unique path of the node to edit, relative to the root -- for child edits, this is the parent"`
+	Name  string          `desc:"unique name of child for child edits, field name for field edits, or property key for prop edits"`
+	Type  string          `json:",omitempty" desc:"type name of child to insert"`
+	Idx   int             `desc:"index of child to insert, delete, or move to"`
+	Value json.RawMessage `json:",omitempty" desc:"JSON encoding of field value, or of child subtree to insert"`
+	Props Props           `json:",omitempty" desc:"holds the new property value under the Name key, for DiffSetProp"`
+}
+
+// Diffs is an edit script transforming one tree into another, as produced by
+// Diff and applied by Patch -- it can be saved and loaded using standard
+// JSON encoding
+type Diffs []*DiffEdit
+
+func (df *DiffEdit) String() string {
+	return fmt.Sprintf("%v %v %v", df.Op, df.Path, df.Name)
+}
+
+// Apply applies this edit to the tree with given root
+func (df *DiffEdit) Apply(root Ki) error {
+	k := DiffFindPath(root, df.Path)
+	if k == nil {
+		return fmt.Errorf("ki.Patch: %v: node at path not found", df)
+	}
+	switch df.Op {
+	case DiffInsert:
+		typ := kit.Types.Type(df.Type)
+		if typ == nil {
+			return fmt.Errorf("ki.Patch: %v: type %v not found in kit.Types", df, df.Type)
+		}
+		kid := NewOfType(typ)
+		kid.Init(kid)
+		kid.SetNameRaw(df.Name)
+		kid.SetUniqueName(df.Name)
+		if err := k.InsertChild(kid, df.Idx); err != nil {
+			return err
+		}
+		if len(df.Value) > 0 {
+			return kid.LoadJSON(df.Value)
+		}
+	case DiffDelete:
+		idx := k.ChildIndexByUniqueName(df.Name, df.Idx)
+		if idx < 0 {
+			return fmt.Errorf("ki.Patch: %v: child not found", df)
+		}
+		k.DeleteChildAtIndex(idx, true)
+	case DiffMove:
+		idx := k.ChildIndexByUniqueName(df.Name, df.Idx)
+		if idx < 0 {
+			return fmt.Errorf("ki.Patch: %v: child not found", df)
+		}
+		return k.MoveChild(idx, df.Idx)
+	case DiffSetField:
+		fv := kit.FlatFieldValueByName(k, df.Name)
+		if !fv.IsValid() {
+			return fmt.Errorf("ki.Patch: %v: field not found", df)
+		}
+		nv := reflect.New(fv.Type())
+		if err := json.Unmarshal(df.Value, nv.Interface()); err != nil {
+			return err
+		}
+		if pt, ok := nv.Interface().(*Ptr); ok {
+			pt.PtrFmPath(root.Root())
+		}
+		if !k.SetField(df.Name, nv.Elem().Interface()) {
+			return fmt.Errorf("ki.Patch: %v: could not set field", df)
+		}
+	case DiffSetProp:
+		val, ok := df.Props[df.Name]
+		if !ok {
+			return fmt.Errorf("ki.Patch: %v: prop value missing", df)
+		}
+		k.SetProp(df.Name, val)
+		bitflag.Set(k.Flags(), int(PropUpdated))
+	case DiffDeleteProp:
+		k.DeleteProp(df.Name)
+		bitflag.Set(k.Flags(), int(PropUpdated))
+	}
+	return nil
+}
+
+// DiffFindPath returns the node at given path relative to root, using the
+// path conventions of DiffEdit -- nil if not found
+func DiffFindPath(root Ki, path string) Ki {
+	k := root
+	for len(path) > 0 {
+		sep := path[0]
+		path = path[1:]
+		nm := path
+		if ei := strings.IndexAny(path, "/."); ei >= 0 {
+			nm = path[:ei]
+		}
+		path = path[len(nm):]
+		if sep == '.' {
+			k = k.KiFieldByName(nm)
+		} else {
+			idx := k.ChildIndexByUniqueName(nm, 0)
+			if idx < 0 {
+				return nil
+			}
+			k = k.Children()[idx]
+		}
+		if k == nil {
+			return nil
+		}
+	}
+	return k
+}
+
+// diffNode adds the edits for node a vs. b at given path, recursively
+func (diffs *Diffs) diffNode(a, b Ki, path string) {
+	diffs.diffFields(a, b, path)
+	diffs.diffProps(a, b, path)
+	diffs.diffKids(a, b, path)
+}
+
+// diffFields adds the edits for field values of a vs b, which must be the
+// same type, and recursively diffs any Ki fields -- values are compared
+// using their JSON encoding, and fields that are not saved in JSON are
+// skipped
+func (diffs *Diffs) diffFields(a, b Ki, path string) {
+	kit.FlatFieldsTypeFun(a.Type(), func(typ reflect.Type, field reflect.StructField) bool {
+		if typ == KiT_Node || field.PkgPath != "" || field.Tag.Get("json") == "-" {
+			return true
+		}
+		av := kit.FlatFieldValueByName(a, field.Name)
+		bv := kit.FlatFieldValueByName(b, field.Name)
+		if kit.EmbeddedTypeImplements(field.Type, KiType()) {
+			if field.Type.Kind() == reflect.Struct {
+				ak := kit.PtrValue(av).Interface().(Ki)
+				bk := kit.PtrValue(bv).Interface().(Ki)
+				diffs.diffNode(ak, bk, path+"."+field.Name)
+			}
+			return true
+		}
+		ab, err := json.Marshal(av.Interface())
+		if err != nil {
+			return true
+		}
+		bb, err := json.Marshal(bv.Interface())
+		if err != nil || bytes.Equal(ab, bb) {
+			return true
+		}
+		*diffs = append(*diffs, &DiffEdit{Op: DiffSetField, Path: path, Name: field.Name, Value: bb})
+		return true
+	})
+}
+
+// diffProps adds the edits for Props of a vs. b
+func (diffs *Diffs) diffProps(a, b Ki, path string) {
+	ap := a.Properties()
+	bp := b.Properties()
+	for _, key := range diffSortedKeys(bp) {
+		bv := bp[key]
+		if av, ok := ap[key]; ok && reflect.DeepEqual(av, bv) {
+			continue
+		}
+		*diffs = append(*diffs, &DiffEdit{Op: DiffSetProp, Path: path, Name: key, Props: Props{key: bv}})
+	}
+	for _, key := range diffSortedKeys(ap) {
+		if _, ok := bp[key]; !ok {
+			*diffs = append(*diffs, &DiffEdit{Op: DiffDeleteProp, Path: path, Name: key})
+		}
+	}
+}
+
+// diffKids adds the edits for children of a vs. b, and recursively for the
+// children matched by UniqueName and type
+func (diffs *Diffs) diffKids(a, b Ki, path string) {
+	akids := a.Children()
+	bkids := b.Children()
+	bmap := make(map[string]Ki, len(bkids))
+	for _, bk := range bkids {
+		bmap[bk.UniqueName()] = bk
+	}
+	// first delete -- working back from the end as in Slice.Config
+	cur := make([]Ki, 0, len(akids))
+	for i := len(akids) - 1; i >= 0; i-- {
+		ak := akids[i]
+		if bk, ok := bmap[ak.UniqueName()]; !ok || bk.Type() != ak.Type() {
+			*diffs = append(*diffs, &DiffEdit{Op: DiffDelete, Path: path, Name: ak.UniqueName(), Idx: i})
+		} else {
+			cur = append(cur, ak)
+		}
+	}
+	for i, j := 0, len(cur)-1; i < j; i, j = i+1, j-1 {
+		cur[i], cur[j] = cur[j], cur[i]
+	}
+	// then insert and move, in order
+	var matched [][2]Ki
+	for i, bk := range bkids {
+		ci := -1
+		for j, ck := range cur {
+			if ck.UniqueName() == bk.UniqueName() {
+				ci = j
+				break
+			}
+		}
+		if ci < 0 {
+			kb, err := bk.SaveJSON(false)
+			if err != nil {
+				log.Println(err)
+			}
+			*diffs = append(*diffs, &DiffEdit{Op: DiffInsert, Path: path, Name: bk.UniqueName(), Type: kit.FullTypeName(bk.Type()), Idx: i, Value: kb})
+			cur = append(cur, nil)
+			copy(cur[i+1:], cur[i:])
+			cur[i] = bk
+			continue
+		}
+		matched = append(matched, [2]Ki{cur[ci], bk})
+		if ci != i {
+			*diffs = append(*diffs, &DiffEdit{Op: DiffMove, Path: path, Name: bk.UniqueName(), Idx: i})
+			ck := cur[ci]
+			cur = append(cur[:ci], cur[ci+1:]...)
+			cur = append(cur, nil)
+			copy(cur[i+1:], cur[i:])
+			cur[i] = ck
+		}
+	}
+	for _, m := range matched {
+		diffs.diffNode(m[0], m[1], path+"/"+m[1].UniqueName())
+	}
+}
+
+// diffSortedKeys returns the keys of given props in sorted order, so edits
+// are in a deterministic order
+func diffSortedKeys(p Props) []string {
+	keys := make([]string, 0, len(p))
+	for key := range p {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func diffTestTree() *NodeField2 {
+	parent := NodeField2{}
+	parent.InitName(&parent, "par1")
+	typ := KiT_NodeEmbed
+	parent.AddNewChild(typ, "child1")
+	child2 := parent.AddNewChild(typ, "child2")
+	parent.AddNewChild(typ, "child3")
+	child2.AddNewChild(typ, "subchild1")
+	child2.AddNewChild(typ, "subchild2")
+	parent.SetProp("floatprop", 3.1415)
+	parent.SetProp("stringprop", "str")
+	return &parent
+}
+
+func TestDiffPatch(t *testing.T) {
+	a := diffTestTree()
+	b := diffTestTree()
+	b.SetName("par2") // root names are not compared
+
+	if diffs := Diff(a, b); len(diffs) != 0 {
+		t.Errorf("Diff of identical trees should be empty, was: %v", diffs)
+	}
+
+	typ := KiT_NodeEmbed
+	b.DeleteChildAtIndex(0, true)
+	b.AddNewChild(typ, "child4").AddNewChild(typ, "subchild4")
+	b.MoveChild(0, 2)
+	b.Child(2).MoveChild(1, 0)
+	b.Child(2).Child(0).SetName("subchild2b")
+	b.Mbr1 = "bloop"
+	b.Field2.Mbr2 = 42
+	b.Child(1).SetProp("intprop", 17.0)
+	b.SetProp("floatprop", 2.0)
+	b.DeleteProp("stringprop")
+
+	diffs := Diff(a, b)
+	if len(diffs) == 0 {
+		t.Errorf("Diff of different trees should not be empty")
+	}
+
+	// round-trip through JSON
+	jb, err := json.Marshal(diffs)
+	if err != nil {
+		t.Error(err)
+	}
+	var jdiffs Diffs
+	err = json.Unmarshal(jb, &jdiffs)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(jdiffs) != len(diffs) {
+		t.Errorf("Diffs JSON round-trip: length should be %v, was %v", len(diffs), len(jdiffs))
+	}
+
+	err = Patch(a, jdiffs)
+	if err != nil {
+		t.Error(err)
+	}
+	if res := Diff(a, b); len(res) != 0 {
+		t.Errorf("Diff of patched tree should be empty, was: %v\ndiffs: %v", res, string(jb))
+	}
+	if a.Name() != "par1" {
+		t.Errorf("Patch should not change root name, was: %v", a.Name())
+	}
+	if a.Child(2).Child(0).Parent() != a.Child(2) {
+		t.Errorf("Patch: moved subchild has wrong parent")
+	}
+}
+
+func TestDiffPatchUndo(t *testing.T) {
+	a := diffTestTree()
+	b := diffTestTree()
+	b.DeleteChildren(true)
+	b.SetProp("floatprop", 2.0)
+	orig := undoTestPaths(a)
+
+	us := AttachUndoStack(a, 0)
+	defer us.Detach()
+	if err := Patch(a, Diff(a, b)); err != nil {
+		t.Error(err)
+	}
+	if len(us.Undos) != 1 {
+		t.Errorf("Patch should be one undo step, was: %v", len(us.Undos))
+	}
+	us.Undo()
+	if res := undoTestPaths(a); res != orig {
+		t.Errorf("undo Patch: structure should be: %v, was: %v", orig, res)
+	}
+	if a.Prop("floatprop", false, false) != 3.1415 {
+		t.Errorf("undo Patch: floatprop should be 3.1415, was: %v", a.Prop("floatprop", false, false))
+	}
+}
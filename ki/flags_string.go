@@ -26,3 +26,13 @@ func StringToFlags(s string) (Flags, error) {
 	}
 	return 0, fmt.Errorf("String %v is not a valid option for type Flags", s)
 }
+
+func (i *Flags) FromString(s string) error {
+	for j := 0; j < len(_Flags_index)-1; j++ {
+		if s == _Flags_name[_Flags_index[j]:_Flags_index[j+1]] {
+			*i = Flags(j)
+			return nil
+		}
+	}
+	return fmt.Errorf("String %v is not a valid option for type Flags", s)
+}
@@ -0,0 +1,523 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rcoreilly/goki/ki/kit"
+)
+
+// JSONOpts are options for the streaming JSON save method SaveJSONTo
+type JSONOpts struct {
+	Compact bool            `desc:"write compact output with no indentation -- default is indented, as in SaveJSON(true)"`
+	Filter  func(k Ki) bool `desc:"if non-nil, only children for which this returns true are saved, along with their subtrees -- Ki fields are always saved"`
+}
+
+// The streaming JSON encoder and decoder walk the tree incrementally, so
+// memory use is bounded by the size of the individual field values, not the
+// whole tree.  The output is exactly the same as the standard json.Marshal
+// / json.MarshalIndent output used by SaveJSON, including the Slice header
+// format for children, so the two can be used interchangeably -- Kids and
+// Ki fields are walked directly, and all other fields use the standard json
+// encoding, including Props and Ptr MarshalJSON methods.
+
+// jsonField is a field of a Ki struct, as encoded by encoding/json
+type jsonField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+	isKids    bool
+	isKi      bool
+}
+
+// jsonFieldsCache caches the jsonFields for each type
+var jsonFieldsCache = struct {
+	sync.RWMutex
+	m map[reflect.Type][]jsonField
+}{m: make(map[reflect.Type][]jsonField)}
+
+// jsonFields returns the fields of given struct type, in encoding/json order
+// -- nil if the type cannot be walked field-by-field, in which case it is
+// encoded as a whole using the standard encoding/json
+func jsonFields(typ reflect.Type) []jsonField {
+	jsonFieldsCache.RLock()
+	flds, ok := jsonFieldsCache.m[typ]
+	jsonFieldsCache.RUnlock()
+	if ok {
+		return flds
+	}
+	flds = jsonTypeFields(typ)
+	jsonFieldsCache.Lock()
+	jsonFieldsCache.m[typ] = flds
+	jsonFieldsCache.Unlock()
+	return flds
+}
+
+// jsonTypeFields computes the fields for jsonFields, following the
+// encoding/json rules for embedded structs and json tags
+func jsonTypeFields(typ reflect.Type) []jsonField {
+	mtyp := reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	if reflect.PtrTo(typ).Implements(mtyp) {
+		return nil
+	}
+	type fldDepth struct {
+		jsonField
+		depth  int
+		tagged bool
+	}
+	var all []fldDepth
+	ok := true
+	var walk func(t reflect.Type, index []int, depth int)
+	walk = func(t reflect.Type, index []int, depth int) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			idx := append(append([]int{}, index...), i)
+			tag := f.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			tnm := tag
+			opts := ""
+			if ci := strings.Index(tag, ","); ci >= 0 {
+				tnm, opts = tag[:ci], tag[ci:]
+			}
+			if f.Anonymous && tnm == "" {
+				if f.Type.Kind() == reflect.Struct {
+					walk(f.Type, idx, depth+1)
+					continue
+				}
+				if f.Type.Kind() == reflect.Ptr && f.Type.Elem().Kind() == reflect.Struct {
+					ok = false // nil embedded pointers -- too complicated
+					return
+				}
+			}
+			if f.PkgPath != "" {
+				continue
+			}
+			nm := f.Name
+			if tnm != "" {
+				nm = tnm
+			}
+			jf := jsonField{name: nm, index: idx, omitEmpty: strings.Contains(opts, ",omitempty")}
+			if strings.Contains(opts, ",string") {
+				ok = false
+				return
+			}
+			if f.Type == reflect.TypeOf(Slice{}) {
+				jf.isKids = true
+			} else if f.Type.Kind() == reflect.Struct && kit.EmbeddedTypeImplements(f.Type, KiType()) {
+				jf.isKi = true
+			}
+			all = append(all, fldDepth{jf, depth, tnm != ""})
+		}
+	}
+	walk(typ, nil, 0)
+	if !ok {
+		return nil
+	}
+	// resolve name conflicts: shallowest wins, then a unique tagged field
+	var flds []jsonField
+	for i, f := range all {
+		dominant := true
+		for j, o := range all {
+			if j == i || o.name != f.name || o.depth > f.depth {
+				continue
+			}
+			if o.depth < f.depth || o.tagged || !f.tagged {
+				dominant = false
+				break
+			}
+		}
+		if dominant {
+			flds = append(flds, f.jsonField)
+		}
+	}
+	return flds
+}
+
+// jsonIsEmpty returns true if value is empty for the omitempty option
+func jsonIsEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// jsonFieldByName returns the field with given json name -- exact match
+// preferred, otherwise case-insensitive, as in encoding/json
+func jsonFieldByName(flds []jsonField, nm string) *jsonField {
+	for i := range flds {
+		if flds[i].name == nm {
+			return &flds[i]
+		}
+	}
+	for i := range flds {
+		if strings.EqualFold(flds[i].name, nm) {
+			return &flds[i]
+		}
+	}
+	return nil
+}
+
+//////////////////////////////////////////////////////////////////////////
+//  Encoder
+
+// jsonEncoder writes JSON for a tree incrementally
+type jsonEncoder struct {
+	w      io.Writer
+	filter func(k Ki) bool
+	err    error
+}
+
+func (e *jsonEncoder) write(b []byte) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = e.w.Write(b)
+}
+
+func (e *jsonEncoder) writeString(s string) {
+	e.write([]byte(s))
+}
+
+// writeValue writes standard json encoding of given value
+func (e *jsonEncoder) writeValue(v interface{}) {
+	if e.err != nil {
+		return
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		e.err = err
+		return
+	}
+	e.write(b)
+}
+
+// encodeNode writes node k and its subtree
+func (e *jsonEncoder) encodeNode(k Ki) {
+	v := reflect.ValueOf(k).Elem()
+	flds := jsonFields(v.Type())
+	if flds == nil {
+		e.writeValue(k)
+		return
+	}
+	e.writeString("{")
+	first := true
+	for _, f := range flds {
+		fv := v.FieldByIndex(f.index)
+		if f.omitEmpty && jsonIsEmpty(fv) {
+			continue
+		}
+		if !first {
+			e.writeString(",")
+		}
+		first = false
+		e.writeValue(f.name)
+		e.writeString(":")
+		switch {
+		case f.isKids:
+			e.encodeKids(fv.Interface().(Slice))
+		case f.isKi:
+			e.encodeNode(fv.Addr().Interface().(Ki))
+		default:
+			e.writeValue(fv.Addr().Interface())
+		}
+	}
+	e.writeString("}")
+}
+
+// encodeKids writes children in the Slice MarshalJSON format
+func (e *jsonEncoder) encodeKids(kids Slice) {
+	if e.filter != nil {
+		fkids := make(Slice, 0, len(kids))
+		for _, kid := range kids {
+			if e.filter(kid) {
+				fkids = append(fkids, kid)
+			}
+		}
+		kids = fkids
+	}
+	if len(kids) == 0 {
+		e.writeString("null")
+		return
+	}
+	e.writeString("[{\"n\":" + strconv.Itoa(len(kids)))
+	for _, kid := range kids {
+		e.writeString(",\"type\":")
+		e.writeValue(kit.Migrations.SavedTypeName(reflect.TypeOf(kid).Elem()))
+		e.writeString(",\"name\":")
+		e.writeValue(kid.UniqueName())
+	}
+	e.writeString("}")
+	for _, kid := range kids {
+		e.writeString(",")
+		e.encodeNode(kid)
+	}
+	e.writeString("]")
+}
+
+// jsonIndenter is a writer that indents compact JSON written to it, exactly
+// as json.Indent does, with no prefix and two-space indent
+type jsonIndenter struct {
+	w     io.Writer
+	depth int
+	need  bool
+	inStr bool
+	esc   bool
+	buf   []byte
+}
+
+func (ji *jsonIndenter) newline() {
+	ji.buf = append(ji.buf, '\n')
+	for i := 0; i < ji.depth; i++ {
+		ji.buf = append(ji.buf, ' ', ' ')
+	}
+}
+
+func (ji *jsonIndenter) Write(p []byte) (int, error) {
+	ji.buf = ji.buf[:0]
+	for _, c := range p {
+		if ji.inStr {
+			ji.buf = append(ji.buf, c)
+			if ji.esc {
+				ji.esc = false
+			} else if c == '\\' {
+				ji.esc = true
+			} else if c == '"' {
+				ji.inStr = false
+			}
+			continue
+		}
+		if ji.need && c != '}' && c != ']' {
+			ji.need = false
+			ji.depth++
+			ji.newline()
+		}
+		switch c {
+		case '"':
+			ji.inStr = true
+			ji.buf = append(ji.buf, c)
+		case '{', '[':
+			ji.need = true
+			ji.buf = append(ji.buf, c)
+		case ',':
+			ji.buf = append(ji.buf, c)
+			ji.newline()
+		case ':':
+			ji.buf = append(ji.buf, c, ' ')
+		case '}', ']':
+			if ji.need {
+				ji.need = false
+			} else {
+				ji.depth--
+				ji.newline()
+			}
+			ji.buf = append(ji.buf, c)
+		default:
+			ji.buf = append(ji.buf, c)
+		}
+	}
+	_, err := ji.w.Write(ji.buf)
+	return len(p), err
+}
+
+// SaveJSONTree writes the JSON encoding of the tree starting at k to given
+// writer, walking the tree incrementally -- see Ki.SaveJSONTo
+func SaveJSONTree(k Ki, w io.Writer, opts *JSONOpts) error {
+	if opts == nil {
+		opts = &JSONOpts{}
+	}
+	bw := bufio.NewWriter(w)
+	e := &jsonEncoder{w: bw, filter: opts.Filter}
+	if !opts.Compact {
+		e.w = &jsonIndenter{w: bw}
+	}
+	e.encodeNode(k)
+	if e.err != nil {
+		return e.err
+	}
+	return bw.Flush()
+}
+
+//////////////////////////////////////////////////////////////////////////
+//  Decoder
+
+// jsonDecoder reads JSON for a tree incrementally
+type jsonDecoder struct {
+	dec *json.Decoder
+}
+
+// delim reads the next token and checks that it is given delimiter --
+// returns false with no error if it is null instead
+func (d *jsonDecoder) delim(dl json.Delim) (bool, error) {
+	tok, err := d.dec.Token()
+	if err != nil {
+		return false, err
+	}
+	if tok == nil {
+		return false, nil
+	}
+	if td, ok := tok.(json.Delim); !ok || td != dl {
+		return false, fmt.Errorf("ki.LoadJSONFrom: expected %v, got: %v", dl, tok)
+	}
+	return true, nil
+}
+
+// decodeNode reads node k and its subtree
+func (d *jsonDecoder) decodeNode(k Ki) error {
+	v := reflect.ValueOf(k).Elem()
+	flds := jsonFields(v.Type())
+	if flds == nil {
+		return d.dec.Decode(k)
+	}
+	if ok, err := d.delim('{'); !ok {
+		return err
+	}
+	for d.dec.More() {
+		tok, err := d.dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := tok.(string)
+		f := jsonFieldByName(flds, key)
+		if f == nil {
+			var skip json.RawMessage
+			err = d.dec.Decode(&skip)
+		} else {
+			fv := v.FieldByIndex(f.index)
+			switch {
+			case f.isKids:
+				err = d.decodeKids(fv.Addr().Interface().(*Slice))
+			case f.isKi:
+				err = d.decodeNode(fv.Addr().Interface().(Ki))
+			default:
+				err = d.dec.Decode(fv.Addr().Interface())
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := d.delim('}')
+	return err
+}
+
+// decodeKids reads children in the Slice MarshalJSON format, configuring
+// the slice from the header and then loading each child in turn
+func (d *jsonDecoder) decodeKids(ks *Slice) error {
+	ok, err := d.delim('[')
+	if err != nil {
+		return err
+	}
+	if !ok {
+		*ks = nil
+		return nil
+	}
+	if _, err := d.delim('{'); err != nil {
+		return err
+	}
+	n := 0
+	var tnl kit.TypeAndNameList
+	var saved []string // saved type names, for migrations
+	for d.dec.More() {
+		tok, err := d.dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case "n":
+			err = d.dec.Decode(&n)
+		case "type":
+			var tn string
+			if err = d.dec.Decode(&tn); err == nil {
+				typ, _ := kit.Migrations.Type(tn)
+				if typ == nil {
+					return fmt.Errorf("ki.LoadJSONFrom: kit.Types type name not found: %v", tn)
+				}
+				tnl = append(tnl, kit.TypeAndName{Type: typ})
+				saved = append(saved, tn)
+			}
+		case "name":
+			if len(tnl) == 0 {
+				return fmt.Errorf("ki.LoadJSONFrom: child name before type in header")
+			}
+			err = d.dec.Decode(&tnl[len(tnl)-1].Name)
+		default:
+			var skip json.RawMessage
+			err = d.dec.Decode(&skip)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := d.delim('}'); err != nil {
+		return err
+	}
+	if n != len(tnl) {
+		return fmt.Errorf("ki.LoadJSONFrom: header n: %v does not match number of children: %v", n, len(tnl))
+	}
+	ks.Config(nil, tnl, true) // true = uniq names
+	for i := 0; d.dec.More(); i++ {
+		if i >= len(*ks) {
+			return fmt.Errorf("ki.LoadJSONFrom: more children than in header: %v", len(*ks))
+		}
+		if kit.Migrations.NeedsMigrate(tnl[i].Type, saved[i]) {
+			if err := d.decodeMigrate((*ks)[i], saved[i], tnl[i].Name); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := d.decodeNode((*ks)[i]); err != nil {
+			return err
+		}
+	}
+	_, err = d.delim(']')
+	return err
+}
+
+// decodeMigrate reads a child saved with an older schema version or type
+// name, which is read whole and upgraded using kit.Migrations
+func (d *jsonDecoder) decodeMigrate(k Ki, saved, name string) error {
+	var raw json.RawMessage
+	if err := d.dec.Decode(&raw); err != nil {
+		return err
+	}
+	kb, rec, err := MigrateJSON(k.Type(), saved, name, raw)
+	if err != nil {
+		log.Printf("ki.LoadJSONFrom: migration error: %v\n", err)
+	}
+	if rec != nil {
+		addMigrated(k, *rec)
+	}
+	return json.Unmarshal(kb, k)
+}
+
+// LoadJSONTree reads the JSON encoding of a tree into k from given reader,
+// walking the tree incrementally -- does not call UnmarshalPost -- see
+// Ki.LoadJSONFrom
+func LoadJSONTree(k Ki, r io.Reader) error {
+	d := &jsonDecoder{dec: json.NewDecoder(bufio.NewReader(r))}
+	return d.decodeNode(k)
+}
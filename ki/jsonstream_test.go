@@ -0,0 +1,99 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"bytes"
+	"testing"
+)
+
+func jsonStreamTestTree() *NodeField2 {
+	parent := NodeField2{}
+	parent.InitName(&parent, "par1")
+	parent.Mbr1 = "bloop <&>"
+	parent.Mbr2 = 32
+	parent.AddNewChild(nil, "child1")
+	child2 := parent.AddNewChild(nil, "child1").(*NodeField2)
+	parent.AddNewChild(nil, "child1")
+	schild2 := child2.AddNewChild(nil, "subchild1").(*NodeField2)
+	schild2.SetProp("floatprop", 3.1415)
+	child2.Field1.AddNewChild(KiT_NodeEmbed, "fieldkid")
+
+	parent.Ptr.Ptr = &child2.Field1
+	child2.Ptr.Ptr = &schild2.Field2
+	return &parent
+}
+
+func TestJSONStreamSave(t *testing.T) {
+	parent := jsonStreamTestTree()
+	for _, indent := range []bool{false, true} {
+		b, err := parent.SaveJSON(indent)
+		if err != nil {
+			t.Error(err)
+		}
+		var sb bytes.Buffer
+		err = parent.SaveJSONTo(&sb, &JSONOpts{Compact: !indent})
+		if err != nil {
+			t.Error(err)
+		}
+		if !bytes.Equal(sb.Bytes(), b) {
+			t.Errorf("SaveJSONTo indent: %v not same as SaveJSON:\n%v\nvs:\n%v", indent, sb.String(), string(b))
+		}
+	}
+}
+
+func TestJSONStreamLoad(t *testing.T) {
+	parent := jsonStreamTestTree()
+	b, err := parent.SaveJSON(true)
+	if err != nil {
+		t.Error(err)
+	}
+	tstload := NodeField2{}
+	tstload.InitName(&tstload, "")
+	err = tstload.LoadJSONFrom(bytes.NewReader(b))
+	if err != nil {
+		t.Error(err)
+	}
+	tstb, _ := tstload.SaveJSON(true)
+	if !bytes.Equal(tstb, b) {
+		t.Errorf("LoadJSONFrom: original and loaded json rep are not equivalent:\n%v\nvs:\n%v", string(b), string(tstb))
+	}
+	if tstload.Child(1).Child(0).Parent() != tstload.Child(1) {
+		t.Errorf("LoadJSONFrom: children not parented")
+	}
+	if tstload.Ptr.Ptr != &tstload.Child(1).(*NodeField2).Field1 {
+		t.Errorf("LoadJSONFrom: Ptr not restored from path")
+	}
+
+	// compact stream output loads with LoadJSON
+	var sb bytes.Buffer
+	parent.SaveJSONTo(&sb, &JSONOpts{Compact: true})
+	tstload2 := NodeField2{}
+	tstload2.InitName(&tstload2, "")
+	if err = tstload2.LoadJSON(sb.Bytes()); err != nil {
+		t.Error(err)
+	}
+	tstb, _ = tstload2.SaveJSON(true)
+	if !bytes.Equal(tstb, b) {
+		t.Errorf("LoadJSON of SaveJSONTo output: original and loaded json rep are not equivalent")
+	}
+}
+
+func TestJSONStreamFilter(t *testing.T) {
+	parent := jsonStreamTestTree()
+	var sb bytes.Buffer
+	err := parent.SaveJSONTo(&sb, &JSONOpts{Filter: func(k Ki) bool { return k.UniqueName() != "child1_1" }})
+	if err != nil {
+		t.Error(err)
+	}
+	tstload := NodeField2{}
+	tstload.InitName(&tstload, "")
+	if err = tstload.LoadJSONFrom(&sb); err != nil {
+		t.Error(err)
+	}
+	if len(tstload.Kids) != 2 || tstload.Child(1).UniqueName() != "child1_2" {
+		t.Errorf("SaveJSONTo Filter: wrong children: %v", tstload.Children())
+	}
+}
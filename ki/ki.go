@@ -5,8 +5,10 @@
 package ki
 
 import (
+	"io"
 	"log"
 	"reflect"
+	"sync"
 
 	"github.com/rcoreilly/goki/ki/kit"
 )
@@ -120,6 +122,20 @@ type Ki interface {
 	// guaranteed to be unique
 	SetNameRaw(name string)
 
+	// UUID returns the persistent universally-unique id of this node
+	// (Node.Uid), which is empty unless set by SetUUID or EnsureUUID -- used
+	// by Ref references, which survive moves and renames, and can point into
+	// other trees -- saved in JSON, and not copied
+	UUID() string
+
+	// SetUUID sets the persistent universally-unique id of this node -- the
+	// caller must ensure it is unique -- empty to clear
+	SetUUID(uuid string)
+
+	// EnsureUUID returns the UUID of this node, first setting it to a new one
+	// (see NewUUID) if it is empty
+	EnsureUUID() string
+
 	// SetUniqueName sets the unique name of this node based on given name
 	// string -- does not do any further testing that the name is indeed
 	// unique -- should generally only be used by UniquifyNames
@@ -135,7 +151,9 @@ type Ki interface {
 
 	// Flag returns the bit flags for this node -- use bitflag package to
 	// manipulate flags -- see Flags type for standard values used in Ki Node
-	// -- can be extended from FlagsN up to 64 bit capacity
+	// -- can be extended from FlagsN up to 64 bit capacity -- these are
+	// transient state, not saved -- the persistent flags in
+	// PersistFlagsMask are kept in the PFlag field instead
 	Flags() *int64
 
 	// SetFlagMu provides a mutex-locked bit flag update -- use this whenever
@@ -165,9 +183,9 @@ type Ki interface {
 	IsUpdatingMu() bool
 
 	// OnlySelfUpdate checks if this node only applies UpdateStart / End logic
-	// to itself, not its children (which is the default) (via Flag of same
-	// name) -- useful for a parent node that has a different function than
-	// its children
+	// to itself, not its children (which is the default) (via the flag of
+	// same name, in the persistent PFlag flags, so it is saved) -- useful for
+	// a parent node that has a different function than its children
 	OnlySelfUpdate() bool
 
 	// SetOnlySelfUpdate sets the OnlySelfUpdate flag -- see OnlySelfUpdate
@@ -186,6 +204,19 @@ type Ki interface {
 	// nodes may linger should also check this flag and reset those pointers
 	IsDestroyed() bool
 
+	//////////////////////////////////////////////////////////////////////////
+	//  Tree mutex for concurrent access -- see treelock.go
+
+	// TreeMutex returns the optional mutex shared by all the nodes in the
+	// tree, for safe concurrent access -- nil if not set
+	TreeMutex() *sync.RWMutex
+
+	// SetTreeMutex sets the tree mutex on this node and all nodes below it
+	// (nil to turn off locking) -- children added later automatically get
+	// the mutex of their parent -- must be set before any concurrent access,
+	// typically via EnableTreeLock
+	SetTreeMutex(mu *sync.RWMutex)
+
 	//////////////////////////////////////////////////////////////////////////
 	//  Property interface with inheritance -- nodes can inherit props from parents
 
@@ -265,10 +296,30 @@ type Ki interface {
 	// Ki type, and errors if not
 	SetChildType(t reflect.Type) error
 
+	// SetChildConstraints sets the ChildConstraints on the types and numbers
+	// of children allowed for this node (they can also be set on the type, as
+	// a ChildConstraints property) -- enforced when adding children and by
+	// ConfigChildren -- ensures that the types are Ki types (or interfaces),
+	// and errors if not -- empty to remove.  Constraints set on a node are
+	// not saved (e.g., in JSON), nor copied -- they are kept by a node that
+	// is loaded into, and must be set again on newly-made nodes
+	SetChildConstraints(cons ChildConstraints) error
+
+	// ChildConstraints returns the ChildConstraints for the children of this
+	// node, as set by SetChildConstraints, or else from the ChildConstraints
+	// property of its type -- nil if none, in which case any children are
+	// allowed
+	ChildConstraints() ChildConstraints
+
+	// CanAddChild returns an error if a child of given type cannot be added
+	// to this node, according to its ChildConstraints
+	CanAddChild(typ reflect.Type) error
+
 	// AddChild adds a new child at end of children list -- if child is in an
 	// existing tree, it is removed from that parent, and a NodeMoved signal
 	// is emitted for the child -- UniquifyNames is called after adding to
-	// ensure name is unique (assumed to already have a name)
+	// ensure name is unique (assumed to already have a name) -- error if the
+	// child is not allowed by ChildConstraints
 	AddChild(kid Ki) error
 
 	// InsertChild adds a new child at given position in children list -- if
@@ -283,7 +334,8 @@ type Ki interface {
 
 	// AddNewChild creates a new child of given type -- if nil, uses
 	// ChildType, else type of this struct -- and add at end of children list
-	// -- assigns name (can be empty) and enforces UniqueName
+	// -- assigns name (can be empty) and enforces UniqueName -- returns nil
+	// if the child could not be added, e.g., not allowed by ChildConstraints
 	AddNewChild(typ reflect.Type, name string) Ki
 
 	// InsertNewChild creates a new child of given type -- if nil, uses
@@ -326,7 +378,8 @@ type Ki interface {
 	// IMPORTANT: returns whether any modifications were made (mods) AND if
 	// that is true, the result from the corresponding UpdateStart call --
 	// UpdateEnd is NOT called, allowing for further subsequent updates before
-	// you call UpdateEnd(updt)
+	// you call UpdateEnd(updt).  If the config does not satisfy the
+	// ChildConstraints of this node, nothing is done (error is logged).
 	ConfigChildren(config kit.TypeAndNameList, uniqNm bool) (mods, updt bool)
 
 	// ChildIndexByFunc returns index of child based on match function (true
@@ -350,6 +403,19 @@ type Ki interface {
 	// idea where it might be -- can be key speedup for large lists
 	ChildIndexByUniqueName(name string, startIdx int) int
 
+	// EnableNameIndex creates a NameIndex of our children by name, which is
+	// then used by ChildIndexByName, ChildIndexByUniqueName, ConfigChildren,
+	// and UniquifyNames, and maintained incrementally as children are added,
+	// deleted, moved, and renamed -- nodes automatically get an index when
+	// they have NameIndexMin children
+	EnableNameIndex()
+
+	// DisableNameIndex removes our NameIndex, if we have one
+	DisableNameIndex()
+
+	// NameIndex returns our NameIndex, nil if we do not have one
+	NameIndex() *NameIndex
+
 	// ChildIndexByType returns index of child by type -- if embeds is true,
 	// then it looks for any type that embeds the given type at any level of
 	// anonymous embedding -- startIdx arg allows for optimized bidirectional
@@ -466,14 +532,17 @@ type Ki interface {
 	// but other branches can continue
 	FuncDownBreadthFirst(level int, data interface{}, fun Func)
 
-	// GoFuncDown calls concurrent goroutine function on given node and all
-	// the way down to its children, and so on -- does not wait for completion
-	// of the go routines -- returns immediately
+	// GoFuncDown calls function concurrently on given node and all the way
+	// down to its children, and so on, using a pool of runtime.NumCPU()
+	// goroutines (see ParallelFuncDown) -- the return value of fun is ignored
+	// -- does not wait for completion of the go routines -- returns
+	// immediately
 	GoFuncDown(level int, data interface{}, fun Func)
 
-	// GoFuncDownWait calls concurrent goroutine function on given node and
-	// all the way down to its children, and so on -- does wait for the
-	// completion of the go routines before returning
+	// GoFuncDownWait calls function concurrently on given node and all the
+	// way down to its children, and so on, using a pool of runtime.NumCPU()
+	// goroutines (see ParallelFuncDown) -- the return value of fun is ignored
+	// -- does wait for the completion of the go routines before returning
 	GoFuncDownWait(level int, data interface{}, fun Func)
 
 	// Path returns path to this node from Root(), using regular user-given
@@ -500,6 +569,17 @@ type Ki interface {
 	// this node (e.g., Root()) -- returns nil if not found
 	FindPathUnique(path string) Ki
 
+	// FindAll returns all nodes matching given query, in tree order, starting
+	// from this node -- the query is a path of CSS-style selectors with
+	// wildcards, e.g., "/win/**/Button[.class=primary]" -- see ParseQuery for
+	// the full syntax -- returns nil if nothing found or query is invalid
+	// (error is logged)
+	FindAll(query string) Slice
+
+	// FindFirst returns the first node matching given query (see FindAll),
+	// or nil if not found
+	FindFirst(query string) Ki
+
 	//////////////////////////////////////////////////////////////////////////
 	//  State update signaling -- automatically consolidates all changes across
 	//   levels so there is only one update at end (optionally per node or only
@@ -647,6 +727,47 @@ type Ki interface {
 	// SaveJSONFromFile loads the tree from a JSON-encoded file
 	LoadJSONFromFile(filename string) error
 
+	// SaveJSONTo writes the tree to given writer in the same JSON format as
+	// SaveJSON, walking the tree incrementally so that memory use does not
+	// grow with the size of the tree -- opts can be nil for indented output
+	// of the full tree -- see JSONOpts
+	SaveJSONTo(w io.Writer, opts *JSONOpts) error
+
+	// LoadJSONFrom loads the tree from given reader in the JSON format
+	// written by SaveJSON or SaveJSONTo, walking the tree incrementally, and
+	// calls UnmarshalPost to recover pointers from paths
+	LoadJSONFrom(r io.Reader) error
+
+	// SaveBinary saves the tree to a compact binary encoding, which is
+	// smaller and faster than JSON -- see binary.go for the format
+	SaveBinary() ([]byte, error)
+
+	// SaveBinaryToFile saves the tree to a binary-encoded file
+	SaveBinaryToFile(filename string) error
+
+	// LoadBinary loads the tree from the binary encoding written by
+	// SaveBinary, and calls UnmarshalPost to recover pointers
+	LoadBinary(b []byte) error
+
+	// LoadBinaryFromFile loads the tree from a binary-encoded file
+	LoadBinaryFromFile(filename string) error
+
+	// SaveYAML saves the tree to a YAML-encoded byte string, preserving all
+	// the type information in the same way as SaveJSON -- see yaml.go
+	SaveYAML() ([]byte, error)
+
+	// LoadYAML loads the tree from a YAML-encoded byte string, and calls
+	// UnmarshalPost to recover pointers from paths
+	LoadYAML(b []byte) error
+
+	// SaveTOML saves the tree to a TOML-encoded byte string, preserving all
+	// the type information in the same way as SaveJSON -- see yaml.go
+	SaveTOML() ([]byte, error)
+
+	// LoadTOML loads the tree from a TOML-encoded byte string, and calls
+	// UnmarshalPost to recover pointers from paths
+	LoadTOML(b []byte) error
+
 	// SaveXML saves the tree to an XML-encoded byte string
 	SaveXML(indent bool) ([]byte, error)
 
@@ -659,7 +780,8 @@ type Ki interface {
 	ParentAllChildren()
 
 	// UnmarshallPost must be called after an Unmarshal -- calls
-	// SetPtrsFmPaths and ParentAllChildren
+	// ParentAllChildren, SetPtrsFmPaths, and ResolveRefs, logging any
+	// dangling Ref references
 	UnmarshalPost()
 }
 
@@ -777,8 +899,18 @@ const (
 	// Mask for all the update flags -- destroyed is excluded b/c otherwise it
 	// would get cleared
 	UpdateFlagsMask = StruUpdateFlagsMask | ValUpdateFlagsMask
+
+	// Mask for the flags that are persistent settings of a node, rather than
+	// transient state -- these are kept in the PFlag field of the Node
+	// instead of Flag, so they are saved in files and editable in views
+	PersistFlagsMask = (1 << uint32(OnlySelfUpdate))
 )
 
 //go:generate stringer -type=Flags
 
 var KiT_Flags = kit.Enums.AddEnum(FlagsN, true, nil) // true = bitflags
+
+func (ev Flags) MarshalJSON() ([]byte, error)  { return kit.EnumMarshalJSON(ev) }
+func (ev *Flags) UnmarshalJSON(b []byte) error { return kit.EnumUnmarshalJSON(ev, b) }
+func (ev Flags) MarshalText() ([]byte, error)  { return kit.EnumMarshalText(ev) }
+func (ev *Flags) UnmarshalText(b []byte) error { return kit.EnumUnmarshalText(ev, b) }
@@ -13,6 +13,7 @@ import (
 	"math"
 	"reflect"
 	"strconv"
+	"strings"
 )
 
 // Sel implements the "mute" function from here
@@ -207,6 +208,41 @@ func ToFloat32(it interface{}) (float32, bool) {
 	}
 }
 
+// ToComplex robustly converts anything to a Complex128 -- strings can be in
+// the "real,imag" format generated by ToString, or "(real+imagi)"
+func ToComplex(it interface{}) (complex128, bool) {
+	if IfaceIsNil(it) {
+		return 0, false
+	}
+	v := NonPtrValue(reflect.ValueOf(it))
+	vk := v.Kind()
+	switch {
+	case vk >= reflect.Complex64 && vk <= reflect.Complex128:
+		return v.Complex(), true
+	case vk == reflect.String:
+		str := strings.TrimSpace(v.String())
+		if ci := strings.Index(str, ","); ci >= 0 {
+			re, err1 := strconv.ParseFloat(strings.TrimSpace(str[:ci]), 64)
+			im, err2 := strconv.ParseFloat(strings.TrimSpace(str[ci+1:]), 64)
+			if err1 != nil || err2 != nil {
+				return 0, false
+			}
+			return complex(re, im), true
+		}
+		var c complex128
+		if _, err := fmt.Sscan(str, &c); err != nil {
+			return 0, false
+		}
+		return c, true
+	default:
+		f, ok := ToFloat(it)
+		if !ok {
+			return 0, false
+		}
+		return complex(f, 0), true
+	}
+}
+
 // ToString robustly converts anything to a String -- because Stringer is so
 // ubiquitous, and we fall back to fmt.Sprintf(%v) in worst case, this should
 // definitely work in all cases, so there is no bool return value
@@ -229,8 +265,10 @@ func ToString(it interface{}) string {
 		cv := v.Complex()
 		rv := strconv.FormatFloat(real(cv), 'G', -1, 64) + "," + strconv.FormatFloat(imag(cv), 'G', -1, 64)
 		return rv
-	case vk == reflect.String: // todo: what about []byte?
+	case vk == reflect.String:
 		return v.String()
+	case vk == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		return string(v.Bytes())
 	default:
 		strer, ok := it.(fmt.Stringer) // will fail if not impl
 		if !ok {
@@ -241,8 +279,10 @@ func ToString(it interface{}) string {
 }
 
 // SetRobust robustly sets the to value from the from value -- to must be a
-// pointer-to -- only for basic field values -- use copier package for more
-// complex cases
+// pointer-to -- values that are not handled by the basic conversions, such
+// as structs, maps, slices, times, and enum names, are converted using
+// Convert -- use that directly to get the errors for each field that could
+// not be converted
 func SetRobust(to, from interface{}) bool {
 	if IfaceIsNil(to) {
 		return false
@@ -285,22 +325,17 @@ func SetRobust(to, from interface{}) bool {
 			return true
 		}
 	case vk >= reflect.Complex64 && vk <= reflect.Complex128:
-		// cv := v.Complex()
-		// rv := strconv.FormatFloat(real(cv), 'G', -1, 64) + "," + strconv.FormatFloat(imag(cv), 'G', -1, 64)
-		// return rv, true
-	case vk == reflect.String: // todo: what about []byte?
+		fm, ok := ToComplex(from)
+		if ok {
+			vp.Elem().Set(reflect.ValueOf(fm).Convert(typ))
+			return true
+		}
+	case vk == reflect.String:
 		fm := ToString(from)
 		vp.Elem().Set(reflect.ValueOf(fm).Convert(typ))
 		return true
 	}
-
-	fv := reflect.ValueOf(from)
-	// Just set it if possible to assign
-	if fv.Type().AssignableTo(typ) {
-		vp.Elem().Set(fv)
-		return true
-	}
-	return false
+	return ConvertValue(vp.Elem(), from) == nil
 }
 
 // MakeMap makes a map that is actually addressable, getting around the hidden
@@ -316,17 +351,15 @@ func MakeSlice(typ reflect.Type, len, cap int) reflect.Value {
 }
 
 // CloneToType creates a new object of given type, and uses SetRobust to copy
-// an existing value (of perhaps another type) into it -- only expected to
-// work for basic types
+// an existing value (of perhaps another type) into it, deeply converting
+// structs, maps, slices etc -- returns a pointer to the new object -- maps
+// that could not be converted are made empty
 func CloneToType(typ reflect.Type, val interface{}) reflect.Value {
-	if NonPtrType(typ).Kind() == reflect.Map {
-		return MakeMap(typ)
-	} else if NonPtrType(typ).Kind() == reflect.Slice {
-		return MakeSlice(typ, 0, 0)
-	}
 	vn := reflect.New(typ)
 	evi := vn.Interface()
-	SetRobust(evi, val)
+	if !SetRobust(evi, val) && typ.Kind() == reflect.Map && vn.Elem().IsNil() {
+		vn.Elem().Set(reflect.MakeMap(typ))
+	}
 	return vn
 }
 
@@ -0,0 +1,424 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kit
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConvertError records an error converting one field or element of a value
+// in Convert
+type ConvertError struct {
+	Path string `desc:"path to the field or element within the value, e.g., Size.X, Items[2], or Props[color] -- empty for the value itself"`
+	Err  error  `desc:"the error"`
+}
+
+func (ce *ConvertError) Error() string {
+	if ce.Path == "" {
+		return ce.Err.Error()
+	}
+	return ce.Path + ": " + ce.Err.Error()
+}
+
+// ConvertErrors is the list of errors returned by Convert, one for each
+// field or element that could not be converted
+type ConvertErrors []*ConvertError
+
+func (ce ConvertErrors) Error() string {
+	strs := make([]string, len(ce))
+	for i, e := range ce {
+		strs[i] = e.Error()
+	}
+	return "kit.Convert: " + strings.Join(strs, "; ")
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// ConvertTimeLayouts are the layouts tried, in order, for converting strings
+// to time.Time values in Convert
+var ConvertTimeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02", time.RFC1123Z, time.RFC1123}
+
+// Convert deeply converts the from value into the to value, which must be a
+// pointer -- in addition to the "common sense" conversions of the basic
+// kinds done by the ToInt, ToFloat, ToString etc functions, it converts:
+//
+// * maps to structs, setting the fields named by the map keys -- field
+// names, json tag names, or field names ignoring case, including the fields
+// of embedded structs -- structs to maps of field names, and structs to
+// structs of other types, setting the fields with the same names
+//
+// * maps to maps and slices or arrays to slices or arrays of differing key
+// and element types, converting each key and element
+//
+// * strings to time.Duration (as in "1.5s") and time.Time (see
+// ConvertTimeLayouts), and numbers to time.Duration in nanoseconds and
+// time.Time in seconds since the Unix epoch
+//
+// * numbers and strings (as in "1.5,2" or "(1.5+2i)") to complex numbers
+//
+// * names of the values of enum types registered in kit.Enums to enums,
+// including alternative names, and "A|B" lists of names to bit flags
+//
+// * strings to []byte and back, and strings to any type that implements
+// encoding.TextUnmarshaler
+//
+// Nil pointers in the to value are allocated as needed, and a nil from value
+// sets the to value to zero.  The conversion continues past errors: any
+// fields or elements that could not be converted are reported in the
+// returned ConvertErrors, with the path to each within the value -- nil if
+// all were converted.
+func Convert(to, from interface{}) error {
+	if IfaceIsNil(to) {
+		return fmt.Errorf("kit.Convert: to value is nil")
+	}
+	v := reflect.ValueOf(to)
+	if v.Kind() != reflect.Ptr {
+		return fmt.Errorf("kit.Convert: must pass a pointer to the to value, not type: %v", v.Type())
+	}
+	return ConvertValue(v.Elem(), from)
+}
+
+// ConvertValue deeply converts the from value into given settable to value,
+// as Convert does
+func ConvertValue(to reflect.Value, from interface{}) error {
+	if !to.CanSet() {
+		return fmt.Errorf("kit.ConvertValue: to value of type: %v cannot be set", to.Type())
+	}
+	var errs ConvertErrors
+	convertValue(to, reflect.ValueOf(from), "", &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// convertFail records a conversion error for given path
+func convertFail(errs *ConvertErrors, path string, format string, args ...interface{}) {
+	*errs = append(*errs, &ConvertError{Path: path, Err: fmt.Errorf(format, args...)})
+}
+
+// convertPath returns the path to a field within the value at given path
+func convertPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+// convertValue converts from into to, which must be addressable, recording
+// any errors
+func convertValue(to, from reflect.Value, path string, errs *ConvertErrors) {
+	typ := to.Type()
+	for from.IsValid() && (from.Kind() == reflect.Interface || from.Kind() == reflect.Ptr) {
+		if from.Type().AssignableTo(typ) {
+			break
+		}
+		if from.IsNil() {
+			from = reflect.Value{}
+			break
+		}
+		from = from.Elem()
+	}
+	if !from.IsValid() {
+		to.Set(reflect.Zero(typ))
+		return
+	}
+	ftyp := from.Type()
+	if ftyp.AssignableTo(typ) {
+		to.Set(from)
+		return
+	}
+	fk := from.Kind()
+	switch {
+	case typ == durationType:
+		if err := convertDuration(to, from); err != nil {
+			convertFail(errs, path, "%v", err)
+		}
+		return
+	case typ == timeType:
+		if err := convertTime(to, from); err != nil {
+			convertFail(errs, path, "%v", err)
+		}
+		return
+	case fk == reflect.String && Enums.TypeRegistered(typ):
+		if err := convertEnum(to, from.String()); err != nil {
+			convertFail(errs, path, "%v", err)
+		}
+		return
+	case fk == reflect.String && to.Kind() != reflect.String && reflect.PtrTo(typ).Implements(textUnmarshalerType):
+		if err := to.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(from.String())); err != nil {
+			convertFail(errs, path, "%v", err)
+		}
+		return
+	}
+
+	fi := from.Interface()
+	switch vk := to.Kind(); {
+	case vk == reflect.Bool:
+		if b, ok := ToBool(fi); ok {
+			to.SetBool(b)
+			return
+		}
+	case vk >= reflect.Int && vk <= reflect.Int64:
+		if i, ok := ToInt(fi); ok {
+			if to.OverflowInt(i) {
+				convertFail(errs, path, "value %v overflows %v", i, typ)
+				return
+			}
+			to.SetInt(i)
+			return
+		}
+	case vk >= reflect.Uint && vk <= reflect.Uintptr:
+		if i, ok := ToInt(fi); ok {
+			if i < 0 || to.OverflowUint(uint64(i)) {
+				convertFail(errs, path, "value %v out of range for %v", i, typ)
+				return
+			}
+			to.SetUint(uint64(i))
+			return
+		}
+	case vk >= reflect.Float32 && vk <= reflect.Float64:
+		if f, ok := ToFloat(fi); ok {
+			if to.OverflowFloat(f) {
+				convertFail(errs, path, "value %v overflows %v", f, typ)
+				return
+			}
+			to.SetFloat(f)
+			return
+		}
+	case vk >= reflect.Complex64 && vk <= reflect.Complex128:
+		if c, ok := ToComplex(fi); ok {
+			to.SetComplex(c)
+			return
+		}
+	case vk == reflect.String:
+		to.SetString(ToString(fi))
+		return
+	case vk == reflect.Slice:
+		switch {
+		case fk == reflect.String && typ.Elem().Kind() == reflect.Uint8:
+			to.Set(reflect.ValueOf([]byte(from.String())).Convert(typ))
+			return
+		case fk == reflect.Slice || fk == reflect.Array:
+			n := from.Len()
+			ns := reflect.MakeSlice(typ, n, n)
+			for i := 0; i < n; i++ {
+				convertValue(ns.Index(i), from.Index(i), path+"["+strconv.Itoa(i)+"]", errs)
+			}
+			to.Set(ns)
+			return
+		}
+	case vk == reflect.Array:
+		if fk == reflect.Slice || fk == reflect.Array {
+			n := from.Len()
+			if n > to.Len() {
+				convertFail(errs, path, "%v elements do not fit in %v", n, typ)
+				n = to.Len()
+			}
+			for i := 0; i < n; i++ {
+				convertValue(to.Index(i), from.Index(i), path+"["+strconv.Itoa(i)+"]", errs)
+			}
+			return
+		}
+	case vk == reflect.Map:
+		switch fk {
+		case reflect.Map:
+			nm := reflect.MakeMap(typ)
+			for _, key := range from.MapKeys() {
+				kpath := path + "[" + ToString(key.Interface()) + "]"
+				nerr := len(*errs)
+				nk := reflect.New(typ.Key()).Elem()
+				convertValue(nk, key, kpath, errs)
+				if len(*errs) > nerr {
+					continue
+				}
+				nv := reflect.New(typ.Elem()).Elem()
+				convertValue(nv, from.MapIndex(key), kpath, errs)
+				nm.SetMapIndex(nk, nv)
+			}
+			to.Set(nm)
+			return
+		case reflect.Struct:
+			nm := reflect.MakeMap(typ)
+			convertFields(from, func(f reflect.StructField, fv reflect.Value) bool {
+				kpath := path + "[" + f.Name + "]"
+				nerr := len(*errs)
+				nk := reflect.New(typ.Key()).Elem()
+				convertValue(nk, reflect.ValueOf(f.Name), kpath, errs)
+				if len(*errs) > nerr {
+					return true
+				}
+				nv := reflect.New(typ.Elem()).Elem()
+				convertValue(nv, fv, kpath, errs)
+				nm.SetMapIndex(nk, nv)
+				return true
+			})
+			to.Set(nm)
+			return
+		}
+	case vk == reflect.Struct:
+		switch fk {
+		case reflect.Map:
+			for _, key := range from.MapKeys() {
+				nm := ToString(key.Interface())
+				fv, fnm, ok := convertField(to, nm)
+				if !ok {
+					convertFail(errs, convertPath(path, nm), "no field named %v in %v", nm, typ)
+					continue
+				}
+				convertValue(fv, from.MapIndex(key), convertPath(path, fnm), errs)
+			}
+			return
+		case reflect.Struct:
+			convertFields(from, func(f reflect.StructField, fv reflect.Value) bool {
+				if tv, fnm, ok := convertField(to, f.Name); ok {
+					convertValue(tv, fv, convertPath(path, fnm), errs)
+				}
+				return true
+			})
+			return
+		}
+	case vk == reflect.Ptr:
+		if to.IsNil() {
+			to.Set(reflect.New(typ.Elem()))
+		}
+		convertValue(to.Elem(), from, path, errs)
+		return
+	}
+	convertFail(errs, path, "could not convert %v of type %v to %v", ToString(fi), ftyp, typ)
+}
+
+// convertFields calls fun on each exported field of given struct value,
+// including the fields of anonymous embedded structs -- stops if fun returns
+// false
+func convertFields(v reflect.Value, fun func(f reflect.StructField, fv reflect.Value) bool) bool {
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		fv := v.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if !convertFields(fv, fun) {
+				return false
+			}
+			continue
+		}
+		if f.PkgPath != "" || !fv.CanInterface() {
+			continue
+		}
+		if !fun(f, fv) {
+			return false
+		}
+	}
+	return true
+}
+
+// convertField returns the settable field of given struct value for given
+// name -- the field name or json tag name, or else the field name ignoring
+// case -- and the field name
+func convertField(v reflect.Value, nm string) (reflect.Value, string, bool) {
+	var fv, cfv reflect.Value
+	var fnm, cfnm string
+	convertFields(v, func(f reflect.StructField, ffv reflect.Value) bool {
+		if !ffv.CanSet() {
+			return true
+		}
+		jnm := strings.Split(f.Tag.Get("json"), ",")[0]
+		if f.Name == nm || (jnm != "" && jnm != "-" && jnm == nm) {
+			fv, fnm = ffv, f.Name
+			return false
+		}
+		if !cfv.IsValid() && strings.EqualFold(f.Name, nm) {
+			cfv, cfnm = ffv, f.Name
+		}
+		return true
+	})
+	if fv.IsValid() {
+		return fv, fnm, true
+	}
+	if cfv.IsValid() {
+		return cfv, cfnm, true
+	}
+	return fv, "", false
+}
+
+// convertDuration sets a time.Duration value from a string or number
+func convertDuration(to, from reflect.Value) error {
+	switch fk := from.Kind(); {
+	case fk == reflect.String:
+		str := strings.TrimSpace(from.String())
+		if d, err := time.ParseDuration(str); err == nil {
+			to.SetInt(int64(d))
+			return nil
+		}
+		if i, err := strconv.ParseInt(str, 0, 64); err == nil {
+			to.SetInt(i)
+			return nil
+		}
+		return fmt.Errorf("could not convert %q to time.Duration", str)
+	case fk >= reflect.Float32 && fk <= reflect.Float64:
+		to.SetInt(int64(from.Float()))
+		return nil
+	}
+	if i, ok := ToInt(from.Interface()); ok {
+		to.SetInt(i)
+		return nil
+	}
+	return fmt.Errorf("could not convert %v of type %v to time.Duration", ToString(from.Interface()), from.Type())
+}
+
+// convertTime sets a time.Time value from a string (see ConvertTimeLayouts)
+// or number of seconds since the Unix epoch
+func convertTime(to, from reflect.Value) error {
+	switch fk := from.Kind(); {
+	case fk == reflect.String:
+		str := strings.TrimSpace(from.String())
+		for _, lay := range ConvertTimeLayouts {
+			if tm, err := time.Parse(lay, str); err == nil {
+				to.Set(reflect.ValueOf(tm))
+				return nil
+			}
+		}
+		return fmt.Errorf("could not convert %q to time.Time", str)
+	case fk >= reflect.Float32 && fk <= reflect.Float64:
+		secs := from.Float()
+		sec := int64(secs)
+		to.Set(reflect.ValueOf(time.Unix(sec, int64((secs-float64(sec))*1e9))))
+		return nil
+	}
+	if i, ok := ToInt(from.Interface()); ok {
+		to.Set(reflect.ValueOf(time.Unix(i, 0)))
+		return nil
+	}
+	return fmt.Errorf("could not convert %v of type %v to time.Time", ToString(from.Interface()), from.Type())
+}
+
+// convertEnum sets a registered enum value from a value name, alternative
+// name, or "A|B" list of names for bit flags
+func convertEnum(to reflect.Value, str string) error {
+	str = strings.TrimSpace(str)
+	if Enums.IsBitFlag(to.Type()) {
+		var bf int64
+		if err := BitFlagsFromString(&bf, str, to.Interface()); err != nil {
+			return err
+		}
+		return SetEnumValueFromInt64(to.Addr(), bf)
+	}
+	if err := Enums.SetEnumValueFromStringAltFirst(to.Addr(), str); err == nil {
+		return nil
+	}
+	if i, err := strconv.ParseInt(str, 0, 64); err == nil {
+		return SetEnumValueFromInt64(to.Addr(), i)
+	}
+	return fmt.Errorf("%q is not a valid value name for enum type: %v", str, to.Type().Name())
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kit
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type ConvBase struct {
+	ID int `json:"id"`
+}
+
+type ConvTest struct {
+	ConvBase
+	Name    string            `json:"name"`
+	Size    float32           `json:"size"`
+	Vals    []int             `json:"vals"`
+	Pair    [2]float64        `json:"pair"`
+	Props   map[string]string `json:"props"`
+	Wait    time.Duration     `json:"wait"`
+	When    time.Time         `json:"when"`
+	Cplx    complex128        `json:"cplx"`
+	Flag    TestFlags         `json:"flag"`
+	Bits    TestBits          `json:"bits"`
+	Data    []byte            `json:"data"`
+	Sub     *ConvBase         `json:"sub"`
+	private int
+}
+
+func TestConvertMapToStruct(t *testing.T) {
+	from := map[string]interface{}{
+		"id":    "42",
+		"Name":  "conv",
+		"SIZE":  2.5,
+		"vals":  []interface{}{1.0, "2", int8(3)},
+		"pair":  []string{"0.5", "1.5"},
+		"props": map[string]interface{}{"color": "red", "width": 2},
+		"wait":  "1.5s",
+		"when":  "2018-06-01T10:30:00Z",
+		"cplx":  "1.5,2",
+		"flag":  "flag2",
+		"bits":  "Read|Exec",
+		"data":  "bytes",
+		"sub":   map[string]int{"id": 7},
+	}
+	var ct ConvTest
+	if err := Convert(&ct, from); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if ct.ID != 42 || ct.Name != "conv" || ct.Size != 2.5 {
+		t.Errorf("basic fields: %+v", ct)
+	}
+	if !reflect.DeepEqual(ct.Vals, []int{1, 2, 3}) || ct.Pair != [2]float64{0.5, 1.5} {
+		t.Errorf("slices: %v %v", ct.Vals, ct.Pair)
+	}
+	if !reflect.DeepEqual(ct.Props, map[string]string{"color": "red", "width": "2"}) {
+		t.Errorf("map: %v", ct.Props)
+	}
+	if ct.Wait != 1500*time.Millisecond {
+		t.Errorf("duration: %v", ct.Wait)
+	}
+	if !ct.When.Equal(time.Date(2018, 6, 1, 10, 30, 0, 0, time.UTC)) {
+		t.Errorf("time: %v", ct.When)
+	}
+	if ct.Cplx != complex(1.5, 2) {
+		t.Errorf("complex: %v", ct.Cplx)
+	}
+	if ct.Flag != TestFlag2 {
+		t.Errorf("enum alt name: %v", ct.Flag)
+	}
+	if ct.Bits != 1<<uint(TestBitRead)|1<<uint(TestBitExec) {
+		t.Errorf("bit flags: %v", ct.Bits)
+	}
+	if string(ct.Data) != "bytes" {
+		t.Errorf("bytes: %v", ct.Data)
+	}
+	if ct.Sub == nil || ct.Sub.ID != 7 {
+		t.Errorf("pointer: %v", ct.Sub)
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	from := map[string]interface{}{
+		"id":      "x",
+		"name":    "ok",
+		"vals":    []interface{}{1, "two", 3},
+		"flag":    "NoSuchFlag",
+		"wait":    "soon",
+		"missing": 1,
+		"private": 2,
+		"pair":    []int{1, 2, 3},
+	}
+	var ct ConvTest
+	err := Convert(&ct, from)
+	errs, ok := err.(ConvertErrors)
+	if !ok {
+		t.Fatalf("expected ConvertErrors, got %v", err)
+	}
+	paths := make(map[string]bool)
+	for _, e := range errs {
+		paths[e.Path] = true
+	}
+	for _, p := range []string{"ID", "Vals[1]", "Flag", "Wait", "missing", "private", "Pair"} {
+		if !paths[p] {
+			t.Errorf("no error for path %v in: %v", p, err)
+		}
+	}
+	if len(errs) != 7 {
+		t.Errorf("expected 7 errors, got: %v", err)
+	}
+	if ct.Name != "ok" || !reflect.DeepEqual(ct.Vals, []int{1, 0, 3}) || ct.Pair != [2]float64{1, 2} {
+		t.Errorf("fields without errors not converted: %+v", ct)
+	}
+	var i8 int8
+	if err := Convert(&i8, 300); err == nil {
+		t.Errorf("expected overflow error")
+	}
+	if err := Convert(i8, 3); err == nil {
+		t.Errorf("expected error for non-pointer")
+	}
+}
+
+type ConvOther struct {
+	Name string
+	Size int
+	Vals []float64
+	Xtra bool
+}
+
+func TestConvertStructs(t *testing.T) {
+	ct := ConvTest{Name: "src", Size: 3, Vals: []int{4, 5}}
+	ct.ID = 9
+	var co ConvOther
+	if err := Convert(&co, &ct); err != nil {
+		t.Errorf("%v", err)
+	}
+	if co.Name != "src" || co.Size != 3 || !reflect.DeepEqual(co.Vals, []float64{4, 5}) {
+		t.Errorf("struct to struct: %+v", co)
+	}
+	var m map[string]interface{}
+	if err := Convert(&m, co); err != nil {
+		t.Errorf("%v", err)
+	}
+	if m["Name"] != "src" || m["Size"] != 3 || m["Xtra"] != false {
+		t.Errorf("struct to map: %v", m)
+	}
+	var mi map[int]string
+	if err := Convert(&mi, map[string]int{"1": 10, "x": 20}); err == nil {
+		t.Errorf("expected error for map key")
+	}
+	if len(mi) != 1 || mi[1] != "10" {
+		t.Errorf("map to map: %v", mi)
+	}
+}
+
+func TestConvertBasic(t *testing.T) {
+	var d time.Duration
+	if err := Convert(&d, 2000); err != nil || d != 2000 {
+		t.Errorf("duration from int: %v %v", d, err)
+	}
+	var tm time.Time
+	if err := Convert(&tm, 1.5); err != nil || tm.UnixNano() != 1500000000 {
+		t.Errorf("time from float: %v %v", tm, err)
+	}
+	var c complex64
+	if err := Convert(&c, "(1+2i)"); err != nil || c != complex(1, 2) {
+		t.Errorf("complex from string: %v %v", c, err)
+	}
+	if err := Convert(&c, 3); err != nil || c != complex(3, 0) {
+		t.Errorf("complex from int: %v %v", c, err)
+	}
+	var s string
+	if err := Convert(&s, []byte("abc")); err != nil || s != "abc" {
+		t.Errorf("string from bytes: %v %v", s, err)
+	}
+	var f TestFlags
+	if err := Convert(&f, "TestFlag1"); err != nil || f != TestFlag1 {
+		t.Errorf("enum from name: %v %v", f, err)
+	}
+	var ip *int
+	if err := Convert(&ip, "5"); err != nil || ip == nil || *ip != 5 {
+		t.Errorf("pointer from string: %v %v", ip, err)
+	}
+	if err := Convert(&ip, nil); err != nil || ip != nil {
+		t.Errorf("nil: %v %v", ip, err)
+	}
+
+	var co ConvOther
+	if !SetRobust(&co, map[string]interface{}{"name": "robust", "size": "2"}) || co.Name != "robust" || co.Size != 2 {
+		t.Errorf("SetRobust struct from map: %+v", co)
+	}
+	if !SetRobust(&c, "1,-1") || c != complex(1, -1) {
+		t.Errorf("SetRobust complex: %v", c)
+	}
+	mv := CloneToType(reflect.TypeOf(map[string]float32{}), map[string]int{"a": 1})
+	if mp := mv.Elem().Interface().(map[string]float32); mp["a"] != 1 {
+		t.Errorf("CloneToType map: %v", mp)
+	}
+	mv = CloneToType(reflect.TypeOf(map[string]int{}), "x")
+	if mp := mv.Elem().Interface().(map[string]int); mp == nil || len(mp) != 0 {
+		t.Errorf("CloneToType failed map conversion should be empty map: %v", mp)
+	}
+}
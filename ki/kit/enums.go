@@ -12,6 +12,7 @@ import (
 	"log"
 	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/rcoreilly/goki/ki/bitflag"
 )
@@ -44,12 +45,25 @@ import (
 //
 // which automatically registers alternative names as lower-case versions of
 // const names with given prefix removed -- often what is used in e.g., json
-// or xml kinds of formats
+// or xml kinds of formats, OR:
+//
+//     var KiT_MyEnum = kit.Enums.AddEnumValues(MyEnumOK, bitFlag true/false,
+//        TypeNameProps, []kit.EnumValue{{Name: "OK", Value: 200, Desc: "..."}, ...})
+//
+// which registers an explicit list of values, which can have gaps and
+// negative values (e.g., external protocol codes), and descriptions (shown
+// as tooltips in the GUI) -- later names for the same value are aliases,
+// and names can be marked as Deprecated -- both are accepted when converting
+// from strings, but only the first non-deprecated name for each value is
+// generated.  The registry then provides the string conversions for the
+// type, so it need not have stringer-generated methods (its String method
+// can just call kit.EnumToString).
 //
 // special properties:
 //
 // * "N": max value of enum defined -- number of enum entries (assuming
-// ordinal, which is all that is currently supported here)
+// ordinal, which is all that is supported for enums without explicit
+// values) -- for explicit values, the number of distinct values
 //
 // * "BitFlag": true -- each value represents a bit in a set of bit flags, so
 // the string rep of a value contains an or-list of names for each bit set,
@@ -63,10 +77,15 @@ import (
 //
 // Also recommend defining JSON I/O functions for each registered enum -- much
 // safer to save enums as strings than using their raw numerical values, which
-// can change over time:
+// can change over time -- and text I/O functions, which are used for XML:
 //
 //     func (ev TestFlags) MarshalJSON() ([]byte, error) { return kit.EnumMarshalJSON(ev) }
 //     func (ev *TestFlags) UnmarshalJSON() ([]byte, error) { return kit.EnumUnmarshalJSON(ev) }
+//     func (ev TestFlags) MarshalText() ([]byte, error) { return kit.EnumMarshalText(ev) }
+//     func (ev *TestFlags) UnmarshalText(b []byte) error { return kit.EnumUnmarshalText(ev, b) }
+//
+// values of bit flag enum types are saved as the names of the bits that are
+// set, separated by |, e.g., "Flag1|Flag3"
 //
 type EnumRegistry struct {
 	Enums map[string]reflect.Type
@@ -74,27 +93,91 @@ type EnumRegistry struct {
 	Props map[string]map[string]interface{}
 	// Vals contains cached EnumValue representations of the enum values -- used by EnumValues method
 	Vals map[string][]EnumValue
+	// Explicit contains the explicit lists of values, including aliases and deprecated names, of enums registered with AddEnumValues
+	Explicit map[string][]EnumValue
+	// Names maps the names of the values of enums registered with AddEnumValues, including aliases and deprecated names, to the values
+	Names map[string]map[string]EnumValue
+	// Parent is the registry that a scoped registry made by NewScope falls back on for enums that are not registered in it -- nil for the global registry
+	Parent *EnumRegistry
+	// Mu protects the maps -- the methods lock it as needed, so it is only needed for accessing the maps directly
+	Mu sync.RWMutex
+
+	names *typeNames
 }
 
 // Enums is master registry of enum types -- can also create your own package-specific ones
 var Enums EnumRegistry
 
+// NewScope returns a new scoped registry, whose enums are registered only
+// in it, and which falls back on this registry for all other enums -- e.g.,
+// for plugin-like modules or tests that register temporary types -- note
+// that the package-level conversion functions such as EnumToString only use
+// the global Enums registry, so they work for scoped enums that have
+// stringer-generated methods, but not those registered with AddEnumValues
+func (tr *EnumRegistry) NewScope() *EnumRegistry {
+	return &EnumRegistry{Parent: tr, names: &typeNames{parent: tr.typeNames()}}
+}
+
+// typeNames returns the names of the types in this registry
+func (tr *EnumRegistry) typeNames() *typeNames {
+	if tr.names == nil {
+		return &globalNames
+	}
+	return tr.names
+}
+
+// TypeName returns the name of the enum type in this registry -- the same
+// as FullTypeName except for types registered in scoped registries
+func (tr *EnumRegistry) TypeName(typ reflect.Type) string {
+	return tr.typeNames().name(typ)
+}
+
+// scope returns the registry in the chain of scoped registries that has
+// given enum type name -- tr if none
+func (tr *EnumRegistry) scope(enumName string) *EnumRegistry {
+	for r := tr; r != nil; r = r.Parent {
+		r.Mu.RLock()
+		_, ok := r.Enums[enumName]
+		r.Mu.RUnlock()
+		if ok {
+			return r
+		}
+	}
+	return tr
+}
+
 // AddEnum adds a given type to the registry -- requires the N value to set N
 // from and grab type info from -- if bitFlag then sets BitFlag property, and
 // each value represents a bit in a set of bit flags, so the string rep of a
 // value contains an or-list of names for each bit set, separated by | -- can
-// also add additional properties -- they are copied so can be re-used across enums
+// also add additional properties -- they are copied so can be re-used across
+// enums -- logs any error from AddEnumErr
 func (tr *EnumRegistry) AddEnum(en interface{}, bitFlag bool, props map[string]interface{}) reflect.Type {
-	if tr.Enums == nil {
-		tr.Enums = make(map[string]reflect.Type)
-		tr.Props = make(map[string]map[string]interface{})
-		tr.Vals = make(map[string][]EnumValue)
+	typ, err := tr.AddEnumErr(en, bitFlag, props)
+	if err != nil {
+		log.Println(err)
 	}
+	return typ
+}
 
+// AddEnumErr adds a given type to the registry as AddEnum does, returning
+// an error if its short type name is already used by another type -- it is
+// then registered under its full package path name
+func (tr *EnumRegistry) AddEnumErr(en interface{}, bitFlag bool, props map[string]interface{}) (reflect.Type, error) {
 	// get the pointer-to version and elem so it is a settable type!
 	typ := PtrType(reflect.TypeOf(en)).Elem()
 	n := EnumToInt64(en)
-	tn := FullTypeName(typ)
+	tn, err := tr.typeNames().add(typ)
+
+	tr.Mu.Lock()
+	defer tr.Mu.Unlock()
+	if tr.Enums == nil {
+		tr.Enums = make(map[string]reflect.Type)
+		tr.Props = make(map[string]map[string]interface{})
+		tr.Vals = make(map[string][]EnumValue)
+		tr.Explicit = make(map[string][]EnumValue)
+		tr.Names = make(map[string]map[string]EnumValue)
+	}
 	tr.Enums[tn] = typ
 	if props != nil {
 		// make a copy of props for enums -- often shared
@@ -104,14 +187,13 @@ func (tr *EnumRegistry) AddEnum(en interface{}, bitFlag bool, props map[string]i
 		}
 		tr.Props[tn] = nwprops
 	}
-	tp := tr.Properties(tn)
+	tp := tr.properties(tn)
 	tp["N"] = n
 	if bitFlag {
-		tp := tr.Properties(tn)
 		tp["BitFlag"] = true
 	}
 	// fmt.Printf("added enum: %v with n: %v\n", tn, n)
-	return typ
+	return typ, err
 }
 
 // AddEnumAltLower adds a given type to the registry -- requires the N value
@@ -122,28 +204,74 @@ func (tr *EnumRegistry) AddEnum(en interface{}, bitFlag bool, props map[string]i
 func (tr *EnumRegistry) AddEnumAltLower(en interface{}, bitFlag bool, props map[string]interface{}, prefix string) reflect.Type {
 	typ := tr.AddEnum(en, bitFlag, props)
 	n := EnumToInt64(en)
-	tn := FullTypeName(typ)
+	tn := tr.TypeName(typ)
 	alts := make(map[int64]string)
-	tp := tr.Properties(tn)
 	for i := int64(0); i < n; i++ {
 		str := EnumInt64ToString(i, typ)
 		str = strings.ToLower(strings.TrimPrefix(str, prefix))
 		// fmt.Printf("adding enum: %v\n", str)
 		alts[i] = str
 	}
-	tp["AltStrings"] = alts
+	tr.Mu.Lock()
+	tr.properties(tn)["AltStrings"] = alts
+	tr.Mu.Unlock()
+	return typ
+}
+
+// AddEnumValues adds a given type to the registry with an explicit list of
+// values -- en is any value of the enum type, to grab type info from -- see
+// EnumRegistry for the handling of the values -- names must be unique, and
+// an error is logged for any duplicates, which are ignored
+func (tr *EnumRegistry) AddEnumValues(en interface{}, bitFlag bool, props map[string]interface{}, vals []EnumValue) reflect.Type {
+	typ := tr.AddEnum(en, bitFlag, props)
+	tn := tr.TypeName(typ)
+	evs := make([]EnumValue, 0, len(vals))
+	names := make(map[string]EnumValue, len(vals))
+	nvals := make(map[int64]bool, len(vals))
+	for _, ev := range vals {
+		if _, has := names[ev.Name]; has {
+			log.Printf("kit.EnumRegistry AddEnumValues: duplicate name: %v for enum type: %v -- ignored\n", ev.Name, tn)
+			continue
+		}
+		ev.Type = typ
+		names[ev.Name] = ev
+		nvals[ev.Value] = true
+		evs = append(evs, ev)
+	}
+	tr.Mu.Lock()
+	defer tr.Mu.Unlock()
+	tr.Explicit[tn] = evs
+	tr.Names[tn] = names
+	delete(tr.Vals, tn)
+	tr.properties(tn)["N"] = int64(len(nvals))
 	return typ
 }
 
 // Enum finds an enum type based on its type name -- returns nil if not found
+// -- enums can also be found by their full package path names
 func (tr *EnumRegistry) Enum(name string) reflect.Type {
-	return tr.Enums[name]
+	r := tr.scope(name)
+	r.Mu.RLock()
+	typ, ok := r.Enums[name]
+	r.Mu.RUnlock()
+	if ok {
+		return typ
+	}
+	if typ, ok := tr.typeNames().owner(name); ok {
+		if tn := tr.TypeName(typ); tn != name && tr.TypeRegistered(typ) {
+			return typ
+		}
+	}
+	return nil
 }
 
 // TypeRegistered returns true if the given type is registered as an enum type
 func (tr *EnumRegistry) TypeRegistered(typ reflect.Type) bool {
-	enumName := FullTypeName(typ)
-	_, ok := tr.Enums[enumName]
+	enumName := tr.TypeName(typ)
+	r := tr.scope(enumName)
+	r.Mu.RLock()
+	defer r.Mu.RUnlock()
+	_, ok := r.Enums[enumName]
 	// if ok {
 	// 	fmt.Printf("enum type: %v registered\n", enumName)
 	// }
@@ -152,6 +280,18 @@ func (tr *EnumRegistry) TypeRegistered(typ reflect.Type) bool {
 
 // Props returns properties for this type -- makes props map if not already made
 func (tr *EnumRegistry) Properties(enumName string) map[string]interface{} {
+	r := tr.scope(enumName)
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	return r.properties(enumName)
+}
+
+// properties returns properties for this type, making the props map if not
+// already made -- must be called with the lock held
+func (tr *EnumRegistry) properties(enumName string) map[string]interface{} {
+	if tr.Props == nil {
+		tr.Props = make(map[string]map[string]interface{})
+	}
 	tp, ok := tr.Props[enumName]
 	if !ok {
 		tp = make(map[string]interface{})
@@ -163,7 +303,10 @@ func (tr *EnumRegistry) Properties(enumName string) map[string]interface{} {
 // Prop safely finds an enum type property from enum type name and property
 // key -- nil if not found
 func (tr *EnumRegistry) Prop(enumName, propKey string) interface{} {
-	tp, ok := tr.Props[enumName]
+	r := tr.scope(enumName)
+	r.Mu.RLock()
+	defer r.Mu.RUnlock()
+	tp, ok := r.Props[enumName]
 	if !ok {
 		// fmt.Printf("no props for enum type: %v\n", enumName)
 		return nil
@@ -194,10 +337,68 @@ func (tr *EnumRegistry) AltStrings(enumName string) map[int64]string {
 	return m
 }
 
+// IsExplicit returns true if the given enum type was registered with an
+// explicit list of values, using AddEnumValues
+func (tr *EnumRegistry) IsExplicit(typ reflect.Type) bool {
+	enumName := tr.TypeName(typ)
+	r := tr.scope(enumName)
+	r.Mu.RLock()
+	defer r.Mu.RUnlock()
+	_, ok := r.Explicit[enumName]
+	return ok
+}
+
+// ValueByName returns the value with given name, including aliases and
+// deprecated names, for an enum type registered with AddEnumValues -- false
+// if not found
+func (tr *EnumRegistry) ValueByName(typ reflect.Type, name string) (EnumValue, bool) {
+	enumName := tr.TypeName(typ)
+	r := tr.scope(enumName)
+	r.Mu.RLock()
+	defer r.Mu.RUnlock()
+	ev, ok := r.Names[enumName][name]
+	return ev, ok
+}
+
+// ValueByInt returns the value for given integer value, with its first
+// non-deprecated name, for an enum type registered with AddEnumValues --
+// false if not found
+func (tr *EnumRegistry) ValueByInt(typ reflect.Type, ival int64) (EnumValue, bool) {
+	enumName := tr.TypeName(typ)
+	r := tr.scope(enumName)
+	r.Mu.RLock()
+	defer r.Mu.RUnlock()
+	for _, ev := range r.Explicit[enumName] {
+		if ev.Value == ival && !ev.Deprecated {
+			return ev, true
+		}
+	}
+	return EnumValue{}, false
+}
+
+// explicitValues returns the values of an enum registered with
+// AddEnumValues, with the first non-deprecated name for each value
+func (tr *EnumRegistry) explicitValues(enumName string) []EnumValue {
+	r := tr.scope(enumName)
+	r.Mu.RLock()
+	defer r.Mu.RUnlock()
+	evs := r.Explicit[enumName]
+	vals := make([]EnumValue, 0, len(evs))
+	have := make(map[int64]bool, len(evs))
+	for _, ev := range evs {
+		if ev.Deprecated || have[ev.Value] {
+			continue
+		}
+		have[ev.Value] = true
+		vals = append(vals, ev)
+	}
+	return vals
+}
+
 // NVals returns the number of defined enum values
 func (tr *EnumRegistry) NVals(eval interface{}) int64 {
 	typ := reflect.TypeOf(eval)
-	n, _ := ToInt(tr.Prop(FullTypeName(typ), "N"))
+	n, _ := ToInt(tr.Prop(tr.TypeName(typ), "N"))
 	return n
 }
 
@@ -205,7 +406,7 @@ func (tr *EnumRegistry) NVals(eval interface{}) int64 {
 // values -- checks BitFlag property -- if true string rep of a value contains
 // an or-list of names for each bit set, separated by |
 func (tr *EnumRegistry) IsBitFlag(typ reflect.Type) bool {
-	tn := FullTypeName(typ)
+	tn := tr.TypeName(typ)
 	b, _ := ToBool(tr.Prop(tn, "BitFlag"))
 	return b
 }
@@ -270,8 +471,17 @@ func EnumInt64ToString(ival int64, typ reflect.Type) string {
 
 // EnumToString converts an enum value to its corresponding string value --
 // you could just call fmt.Sprintf("%v") too but this is slightly faster, and
-// it also works for bitflags which regular stringer does not
+// it also works for bitflags which regular stringer does not -- enums
+// registered with AddEnumValues use their registered names, so their
+// String method can call this function
 func EnumToString(eval interface{}) string {
+	if et := reflect.TypeOf(eval); et != nil && Enums.IsExplicit(et) {
+		ival := EnumToInt64(eval)
+		if ev, ok := Enums.ValueByInt(et, ival); ok {
+			return ev.Name
+		}
+		return fmt.Sprintf("%v(%v)", et.Name(), ival)
+	}
 	strer, ok := eval.(fmt.Stringer) // will fail if not impl
 	if !ok {
 		log.Printf("kit.EnumToString: fmt.Stringer interface not supported by type %v\n", reflect.TypeOf(eval).Name())
@@ -285,8 +495,19 @@ func EnumToString(eval interface{}) string {
 // and also provides the type name for looking up strings
 func BitFlagsToString(bflg int64, en interface{}) string {
 	et := PtrType(reflect.TypeOf(en)).Elem()
-	n := int(EnumToInt64(en))
 	str := ""
+	if Enums.IsExplicit(et) {
+		for _, ev := range Enums.explicitValues(Enums.TypeName(et)) {
+			if ev.Value >= 0 && ev.Value < 64 && bitflag.Has(bflg, int(ev.Value)) {
+				if str != "" {
+					str += "|"
+				}
+				str += ev.Name
+			}
+		}
+		return str
+	}
+	n := int(EnumToInt64(en))
 	for i := 0; i < n; i++ {
 		if bitflag.Has(bflg, i) {
 			evs := EnumInt64ToString(int64(i), et)
@@ -300,6 +521,32 @@ func BitFlagsToString(bflg int64, en interface{}) string {
 	return str
 }
 
+// BitFlagsFromString sets an int64 of bit flags from a string representation
+// of the bits that are set, as names separated by | (as BitFlagsToString
+// generates) -- en is any value of the enum type, for looking up the names
+// -- all the bits are set from the string, and an error is returned if any
+// of the names are not valid
+func BitFlagsFromString(bflg *int64, str string, en interface{}) error {
+	et := NonPtrType(reflect.TypeOf(en))
+	*bflg = 0
+	for _, nm := range strings.Split(str, "|") {
+		nm = strings.TrimSpace(nm)
+		if nm == "" {
+			continue
+		}
+		evn := reflect.New(et)
+		if err := SetEnumValueFromString(evn, nm); err != nil {
+			return fmt.Errorf("kit.BitFlagsFromString: string: %v is not a valid flag name for type: %v\n", nm, et.Name())
+		}
+		bit := EnumToInt64(evn.Interface())
+		if bit < 0 || bit >= 64 {
+			return fmt.Errorf("kit.BitFlagsFromString: flag: %v value %v is out of range for bit flags of type: %v\n", nm, bit, et.Name())
+		}
+		bitflag.Set(bflg, int(bit))
+	}
+	return nil
+}
+
 // note: convenience methods b/c it is easier to find on registry type
 
 // EnumToString converts an enum value to its corresponding string value --
@@ -314,7 +561,7 @@ func (tr *EnumRegistry) EnumToAltString(eval interface{}) string {
 		eval = reflect.ValueOf(eval).Elem() // deref the pointer
 	}
 	et := reflect.TypeOf(eval)
-	tn := FullTypeName(et)
+	tn := tr.TypeName(et)
 	alts := tr.AltStrings(tn)
 	if alts == nil {
 		log.Printf("kit.EnumToAltString: no alternative string map for type %v\n", tn)
@@ -344,7 +591,9 @@ func (tr *EnumRegistry) EnumInt64ToAltString(ival int64, typnm string) string {
 
 // SetEnumValueFromString sets enum value from string using reflect.Value
 // IMPORTANT: requires the modified stringer go generate utility
-// that generates a StringToTypeName method
+// that generates a StringToTypeName method -- except for enums registered
+// with AddEnumValues, which accept any of their registered names, including
+// aliases and deprecated names
 func SetEnumValueFromString(eval reflect.Value, str string) error {
 	etp := eval.Type()
 	if etp.Kind() != reflect.Ptr {
@@ -353,6 +602,13 @@ func SetEnumValueFromString(eval reflect.Value, str string) error {
 		return err
 	}
 	et := etp.Elem()
+	if Enums.IsExplicit(et) {
+		ev, ok := Enums.ValueByName(et, str)
+		if !ok {
+			return fmt.Errorf("kit.SetEnumValueFromString: string: %v is not a valid option for type: %v\n", str, et.Name())
+		}
+		return SetEnumValueFromInt64(eval, ev.Value)
+	}
 	methnm := "FromString"
 	meth := eval.MethodByName(methnm)
 	if ValueIsZero(meth) || meth.IsNil() {
@@ -363,8 +619,12 @@ func SetEnumValueFromString(eval reflect.Value, str string) error {
 	sv := reflect.ValueOf(str)
 	args := make([]reflect.Value, 1)
 	args[0] = sv
-	meth.Call(args)
-	// fmt.Printf("return from FromString method: %v\n", rv[0].Interface())
+	rv := meth.Call(args)
+	if len(rv) == 1 {
+		if err, ok := rv[0].Interface().(error); ok && err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -405,7 +665,7 @@ func (tr *EnumRegistry) SetEnumValueFromAltString(eval reflect.Value, str string
 		return err
 	}
 	et := etp.Elem()
-	tn := FullTypeName(et)
+	tn := tr.TypeName(et)
 	alts := tr.AltStrings(tn)
 	if alts == nil {
 		err := fmt.Errorf("kit.SetEnumValueFromAltString: no alternative string map for type %v\n", tn)
@@ -457,9 +717,11 @@ func (tr *EnumRegistry) SetEnumValueFromInt64(eval reflect.Value, ival int64) er
 
 // EnumValue represents enum values, in common int64 terms, e.g., for GUI
 type EnumValue struct {
-	Name  string       `desc:"name for this value"`
-	Value int64        `desc:"integer value"`
-	Type  reflect.Type `desc:"the enum type that this value belongs to"`
+	Name       string       `desc:"name for this value"`
+	Value      int64        `desc:"integer value"`
+	Type       reflect.Type `desc:"the enum type that this value belongs to"`
+	Desc       string       `desc:"description of this value, e.g., shown as a tooltip in the GUI"`
+	Deprecated bool         `desc:"for values registered with AddEnumValues: this name is deprecated -- it is still accepted when converting from strings, but is not otherwise used"`
 }
 
 // Set sets the values of the EnumValue struct
@@ -475,31 +737,82 @@ func (ev EnumValue) String() string {
 }
 
 // Values returns an EnumValue slice for all the values of an enum type -- if
-// alt is true and alt names exist, then those are used
+// alt is true and alt names exist, then those are used -- for enums
+// registered with AddEnumValues, there is one value for each distinct
+// value, with its first non-deprecated name, in the order registered, and
+// otherwise values from 0 to N-1 that have no name (as generated by
+// stringer) are skipped
 func (tr *EnumRegistry) Values(enumName string, alt bool) []EnumValue {
-	vals, ok := tr.Vals[enumName]
+	r := tr.scope(enumName)
+	r.Mu.RLock()
+	vals, ok := r.Vals[enumName]
+	et := r.Enums[enumName]
+	_, explicit := r.Explicit[enumName]
+	r.Mu.RUnlock()
 	if ok {
 		return vals
 	}
-	alts := tr.AltStrings(enumName)
-	et := tr.Enums[enumName]
-	n := tr.Prop(enumName, "N").(int64)
-	vals = make([]EnumValue, n)
+	alts := r.AltStrings(enumName)
+	if explicit {
+		vals = r.explicitValues(enumName)
+		if alt && alts != nil {
+			for i := range vals {
+				if str, ok := alts[vals[i].Value]; ok {
+					vals[i].Name = str
+				}
+			}
+		}
+		r.setVals(enumName, vals)
+		return vals
+	}
+	n := r.Prop(enumName, "N").(int64)
+	vals = make([]EnumValue, 0, n)
 	for i := int64(0); i < n; i++ {
-		str := EnumInt64ToString(i, et) // todo: what happens when no string for given values?
+		str := EnumInt64ToString(i, et)
+		if str == "" || str == fmt.Sprintf("%v(%v)", et.Name(), i) {
+			continue // no name for this value
+		}
 		if alt && alts != nil {
 			str = alts[i]
 		}
-		vals[i].Set(str, i, et)
+		vals = append(vals, EnumValue{Name: str, Value: i, Type: et})
 	}
-	tr.Vals[enumName] = vals
+	r.setVals(enumName, vals)
 	return vals
 }
 
+// setVals caches the values of given enum type
+func (tr *EnumRegistry) setVals(enumName string, vals []EnumValue) {
+	tr.Mu.Lock()
+	defer tr.Mu.Unlock()
+	if tr.Vals == nil {
+		tr.Vals = make(map[string][]EnumValue)
+	}
+	tr.Vals[enumName] = vals
+}
+
 // TypeValues returns an EnumValue slice for all the values of an enum type --
 // if alt is true and alt names exist, then those are used
 func (tr *EnumRegistry) TypeValues(et reflect.Type, alt bool) []EnumValue {
-	return tr.Values(FullTypeName(et), alt)
+	return tr.Values(tr.TypeName(et), alt)
+}
+
+// AllEnums returns a list of all registered enum types, including those of
+// the parent registries for scoped registries
+func (tr *EnumRegistry) AllEnums() []reflect.Type {
+	tl := make([]reflect.Type, 0)
+	have := make(map[string]bool)
+	for r := tr; r != nil; r = r.Parent {
+		r.Mu.RLock()
+		for tn, typ := range r.Enums {
+			if !have[tn] {
+				have[tn] = true
+				tl = append(tl, typ)
+			}
+		}
+		r.Mu.RUnlock()
+	}
+	return tl
 }
 
 // AllTagged returns a list of all registered enum types that include a given
@@ -507,8 +820,8 @@ func (tr *EnumRegistry) TypeValues(et reflect.Type, alt bool) []EnumValue {
 // its existence
 func (tr *EnumRegistry) AllTagged(key string) []reflect.Type {
 	tl := make([]reflect.Type, 0)
-	for _, typ := range tr.Enums {
-		tp := tr.Prop(FullTypeName(typ), key)
+	for _, typ := range tr.AllEnums() {
+		tp := tr.Prop(tr.TypeName(typ), key)
 		if tp == nil {
 			continue
 		}
@@ -520,28 +833,54 @@ func (tr *EnumRegistry) AllTagged(key string) []reflect.Type {
 ///////////////////////////////////////////////////////////////////////////////
 //  JSON Marshal
 
+// EnumMarshalJSON saves an enum value as its name, or the names of the bits
+// that are set, separated by |, for bit flags -- for the MarshalJSON method
+// of registered enum types
 func EnumMarshalJSON(eval interface{}) ([]byte, error) {
-	et := reflect.TypeOf(eval)
-	b := make([]byte, 0, 50)
-	b = append(b, []byte("\"")...)
-	if Enums.IsBitFlag(et) {
-		b = append(b, []byte(BitFlagsToString(EnumToInt64(eval), eval))...)
-	} else {
-		b = append(b, []byte(EnumToString(eval))...)
+	s, err := EnumMarshalText(eval)
+	if err != nil {
+		return nil, err
 	}
-	b = append(b, []byte("\"")...)
+	b := make([]byte, 0, len(s)+2)
+	b = append(b, '"')
+	b = append(b, s...)
+	b = append(b, '"')
 	return b, nil
 }
 
+// EnumUnmarshalJSON loads an enum value saved by EnumMarshalJSON -- eval
+// must be a pointer to the enum -- for the UnmarshalJSON method of
+// registered enum types
 func EnumUnmarshalJSON(eval interface{}, b []byte) error {
-	et := reflect.TypeOf(eval)
-	noq := string(bytes.Trim(b, "\""))
+	return EnumUnmarshalText(eval, bytes.Trim(b, "\""))
+}
+
+// EnumMarshalText returns an enum value as its name, or the names of the
+// bits that are set, separated by |, for bit flags -- for the MarshalText
+// method of registered enum types, which is used for XML
+func EnumMarshalText(eval interface{}) ([]byte, error) {
+	et := NonPtrType(reflect.TypeOf(eval))
 	if Enums.IsBitFlag(et) {
-		// todo!
-		return nil
-	} else {
-		return SetEnumFromString(eval, noq)
+		n := EnumIfaceFromInt64(Enums.NVals(EnumIfaceFromInt64(0, et)), et)
+		return []byte(BitFlagsToString(EnumToInt64(eval), n)), nil
+	}
+	return []byte(EnumToString(eval)), nil
+}
+
+// EnumUnmarshalText sets an enum value from text saved by EnumMarshalText
+// -- eval must be a pointer to the enum -- for the UnmarshalText method of
+// registered enum types, which is used for XML
+func EnumUnmarshalText(eval interface{}, b []byte) error {
+	et := NonPtrType(reflect.TypeOf(eval))
+	str := strings.TrimSpace(string(b))
+	if Enums.IsBitFlag(et) {
+		var bf int64
+		if err := BitFlagsFromString(&bf, str, eval); err != nil {
+			return err
+		}
+		return SetEnumValueFromInt64(reflect.ValueOf(eval), bf)
 	}
+	return SetEnumFromString(eval, str)
 }
 
 /////////////////////////////////////////////////////////////
@@ -563,3 +902,5 @@ var KiT_TestFlags = Enums.AddEnumAltLower(TestFlagsN, false, nil, "Test")
 
 func (ev TestFlags) MarshalJSON() ([]byte, error)  { return EnumMarshalJSON(ev) }
 func (ev *TestFlags) UnmarshalJSON(b []byte) error { return EnumUnmarshalJSON(ev, b) }
+func (ev TestFlags) MarshalText() ([]byte, error)  { return EnumMarshalText(ev) }
+func (ev *TestFlags) UnmarshalText(b []byte) error { return EnumUnmarshalText(ev, b) }
@@ -6,7 +6,9 @@ package kit
 
 import (
 	"encoding/json"
+	"encoding/xml"
 	"reflect"
+	"strconv"
 	"testing"
 
 	"github.com/rcoreilly/goki/ki/bitflag"
@@ -133,3 +135,183 @@ func TestEnumJSON(t *testing.T) {
 		t.Errorf("EnumJSON error, saved as: %v after loading value should be TestFlag1, is: %v\n", string(b), et)
 	}
 }
+
+// TestCodes is a sparse enum registered with explicit values
+type TestCodes int32
+
+const (
+	TestCodeBad     TestCodes = -1
+	TestCodeOK      TestCodes = 200
+	TestCodeMissing TestCodes = 404
+)
+
+var KiT_TestCodes = Enums.AddEnumValues(TestCodeOK, false, nil, []EnumValue{
+	{Name: "OK", Value: 200, Desc: "request succeeded"},
+	{Name: "Success", Value: 200},
+	{Name: "Okay", Value: 200, Deprecated: true},
+	{Name: "NotFound", Value: 404, Desc: "resource not found"},
+	{Name: "Bad", Value: -1},
+	{Name: "OK", Value: 201}, // duplicate name -- ignored
+})
+
+func (ev TestCodes) String() string                { return EnumToString(ev) }
+func (ev TestCodes) MarshalJSON() ([]byte, error)  { return EnumMarshalJSON(ev) }
+func (ev *TestCodes) UnmarshalJSON(b []byte) error { return EnumUnmarshalJSON(ev, b) }
+
+func TestEnumValues(t *testing.T) {
+	vals := Enums.TypeValues(KiT_TestCodes, true)
+	if len(vals) != 3 {
+		t.Fatalf("Values: expected 3 values, got: %v", vals)
+	}
+	if vals[0].Name != "OK" || vals[0].Value != 200 || vals[0].Desc != "request succeeded" || vals[2].Name != "Bad" || vals[2].Value != -1 {
+		t.Errorf("Values: bad values: %+v", vals)
+	}
+	if n := Enums.NVals(TestCodeOK); n != 3 {
+		t.Errorf("NVals: expected 3, got: %v", n)
+	}
+
+	if s := TestCodeMissing.String(); s != "NotFound" {
+		t.Errorf("EnumToString: expected NotFound, got: %v", s)
+	}
+	if s := TestCodes(7).String(); s != "TestCodes(7)" {
+		t.Errorf("EnumToString undefined: expected TestCodes(7), got: %v", s)
+	}
+
+	var ec TestCodes
+	for _, nm := range []string{"OK", "Success", "Okay"} {
+		ec = TestCodeBad
+		if err := SetEnumFromString(&ec, nm); err != nil || ec != TestCodeOK {
+			t.Errorf("SetEnumFromString %v: got: %v err: %v", nm, ec, err)
+		}
+	}
+	if err := SetEnumFromString(&ec, "Nope"); err == nil {
+		t.Errorf("SetEnumFromString: expected error for undefined name")
+	}
+
+	b, err := json.Marshal(TestCodeBad)
+	if err != nil || string(b) != `"Bad"` {
+		t.Errorf("EnumMarshalJSON: got: %v err: %v", string(b), err)
+	}
+	if err := json.Unmarshal([]byte(`"Okay"`), &ec); err != nil || ec != TestCodeOK {
+		t.Errorf("EnumUnmarshalJSON deprecated name: got: %v err: %v", ec, err)
+	}
+}
+
+// TestGaps is an ordinal enum with no name for value 1, as stringer does
+// for a gap in the values
+type TestGaps int32
+
+const (
+	TestGap0  TestGaps = 0
+	TestGap2  TestGaps = 2
+	TestGapsN TestGaps = 3
+)
+
+var KiT_TestGaps = Enums.AddEnum(TestGapsN, false, nil)
+
+func (i TestGaps) String() string {
+	switch i {
+	case TestGap0:
+		return "TestGap0"
+	case TestGap2:
+		return "TestGap2"
+	}
+	return "TestGaps(" + strconv.FormatInt(int64(i), 10) + ")"
+}
+
+func TestEnumValuesGaps(t *testing.T) {
+	vals := Enums.TypeValues(KiT_TestGaps, false)
+	if len(vals) != 2 || vals[0].Name != "TestGap0" || vals[1].Name != "TestGap2" || vals[1].Value != 2 {
+		t.Errorf("Values: expected TestGap0 and TestGap2, got: %v", vals)
+	}
+}
+
+// TestBits is a bit flag enum registered with explicit values
+type TestBits int32
+
+const (
+	TestBitRead  TestBits = 0
+	TestBitWrite TestBits = 1
+	TestBitExec  TestBits = 5
+)
+
+var KiT_TestBits = Enums.AddEnumValues(TestBitRead, true, nil, []EnumValue{
+	{Name: "Read", Value: 0, Desc: "can be read"},
+	{Name: "Write", Value: 1, Desc: "can be written"},
+	{Name: "Exec", Value: 5, Desc: "can be executed"},
+})
+
+func (ev TestBits) String() string                { return EnumToString(ev) }
+func (ev TestBits) MarshalJSON() ([]byte, error)  { return EnumMarshalJSON(ev) }
+func (ev *TestBits) UnmarshalJSON(b []byte) error { return EnumUnmarshalJSON(ev, b) }
+func (ev TestBits) MarshalText() ([]byte, error)  { return EnumMarshalText(ev) }
+func (ev *TestBits) UnmarshalText(b []byte) error { return EnumUnmarshalText(ev, b) }
+
+func TestBitFlagsString(t *testing.T) {
+	var bf int64
+	err := BitFlagsFromString(&bf, "TestFlag2 | TestFlag1", TestFlagsN)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	if es := BitFlagsToString(bf, TestFlagsN); es != "TestFlag1|TestFlag2" {
+		t.Errorf("BitFlagsFromString round trip failed: %v", es)
+	}
+	if err := BitFlagsFromString(&bf, "", TestFlagsN); err != nil || bf != 0 {
+		t.Errorf("BitFlagsFromString empty: %v, %v", bf, err)
+	}
+	if err := BitFlagsFromString(&bf, "TestFlag1|Bogus", TestFlagsN); err == nil {
+		t.Errorf("BitFlagsFromString: expected error for invalid name")
+	}
+	if err := BitFlagsFromString(&bf, "Bad", TestCodeOK); err == nil {
+		t.Errorf("BitFlagsFromString: expected error for out of range bit")
+	}
+	et := TestFlag1
+	if err := SetEnumValueFromString(reflect.ValueOf(&et), "Bogus"); err == nil {
+		t.Errorf("SetEnumValueFromString: expected error for invalid name")
+	}
+}
+
+type testBitsHolder struct {
+	XMLName xml.Name `xml:"holder"`
+	Bits    TestBits `xml:"bits,attr"`
+	Flag    TestFlags
+}
+
+func TestBitFlagsMarshal(t *testing.T) {
+	bits := TestBits(0)
+	bitflag.Set32((*int32)(&bits), int(TestBitRead), int(TestBitExec))
+
+	b, err := json.Marshal(bits)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	if string(b) != `"Read|Exec"` {
+		t.Errorf("bit flag MarshalJSON: got %v", string(b))
+	}
+	var nb TestBits
+	if err := json.Unmarshal(b, &nb); err != nil {
+		t.Errorf("%v", err)
+	}
+	if nb != bits {
+		t.Errorf("bit flag JSON round trip: %v != %v", nb, bits)
+	}
+	if err := json.Unmarshal([]byte(`"Read|Bogus"`), &nb); err == nil {
+		t.Errorf("bit flag UnmarshalJSON: expected error for invalid name")
+	}
+
+	h := testBitsHolder{Bits: bits, Flag: TestFlag2}
+	b, err = xml.Marshal(&h)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	if exp := `<holder bits="Read|Exec"><Flag>TestFlag2</Flag></holder>`; string(b) != exp {
+		t.Errorf("bit flag MarshalText: got %v, expected %v", string(b), exp)
+	}
+	var nh testBitsHolder
+	if err := xml.Unmarshal(b, &nh); err != nil {
+		t.Errorf("%v", err)
+	}
+	if nh.Bits != bits || nh.Flag != TestFlag2 {
+		t.Errorf("XML round trip: %+v != %+v", nh, h)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kit
+
+import (
+	"reflect"
+	"sync"
+)
+
+// FieldMeta is the metadata for a field of a struct type, from the struct
+// tags that are used by the GoKi system -- tables of these are generated by
+// the kigen command and registered in FieldMetas, so that the tags do not
+// need to be parsed using reflection at runtime
+type FieldMeta struct {
+	Name  string            `desc:"name of the field -- the type name for embedded fields"`
+	Desc  string            `desc:"desc tag -- description of the field, e.g., for tooltips"`
+	Label string            `desc:"label tag -- label to use for the field in views, instead of its name"`
+	View  string            `desc:"view tag -- how to view the field in the GUI -- - = do not view"`
+	Min   string            `desc:"min tag -- minimum value of the field"`
+	Max   string            `desc:"max tag -- maximum value of the field"`
+	Tag   reflect.StructTag `desc:"full struct tag of the field, for any other tags"`
+}
+
+// Get returns the value of given tag for this field, using the parsed
+// values for the standard tags
+func (fm *FieldMeta) Get(tag string) string {
+	switch tag {
+	case "desc":
+		return fm.Desc
+	case "label":
+		return fm.Label
+	case "view":
+		return fm.View
+	case "min":
+		return fm.Min
+	case "max":
+		return fm.Max
+	}
+	return fm.Tag.Get(tag)
+}
+
+// NewFieldMeta returns the FieldMeta for a field with given name and struct
+// tag
+func NewFieldMeta(name string, tag reflect.StructTag) FieldMeta {
+	return FieldMeta{Name: name, Desc: tag.Get("desc"), Label: tag.Get("label"), View: tag.Get("view"), Min: tag.Get("min"), Max: tag.Get("max"), Tag: tag}
+}
+
+// FieldMetaRegistry records the FieldMeta for the direct fields of struct
+// types (not flattened into embedded types), in field order -- types that
+// are not registered get their metadata from reflection the first time it
+// is needed, which is then cached
+type FieldMetaRegistry struct {
+	// Fields is a map from type to the metadata for its fields
+	Fields map[reflect.Type][]FieldMeta
+
+	mu sync.RWMutex
+}
+
+// FieldMetas is the master registry of field metadata
+var FieldMetas FieldMetaRegistry
+
+// AddFieldMetas registers the metadata for the fields of a struct type --
+// like TypeRegistry.AddType, requires an empty object passed as a pointer to
+// grab the type from
+func (fr *FieldMetaRegistry) AddFieldMetas(obj interface{}, fields []FieldMeta) reflect.Type {
+	typ := reflect.TypeOf(obj).Elem()
+	fr.mu.Lock()
+	if fr.Fields == nil {
+		fr.Fields = make(map[reflect.Type][]FieldMeta)
+	}
+	fr.Fields[typ] = fields
+	fr.mu.Unlock()
+	return typ
+}
+
+// TypeFields returns the metadata for the fields of given struct type (a
+// pointer type is dereferenced) -- nil if not a struct
+func (fr *FieldMetaRegistry) TypeFields(typ reflect.Type) []FieldMeta {
+	typ = NonPtrType(typ)
+	if typ.Kind() != reflect.Struct {
+		return nil
+	}
+	fr.mu.RLock()
+	fields, ok := fr.Fields[typ]
+	fr.mu.RUnlock()
+	if ok {
+		return fields
+	}
+	fields = make([]FieldMeta, typ.NumField())
+	for i := range fields {
+		f := typ.Field(i)
+		fields[i] = NewFieldMeta(f.Name, f.Tag)
+	}
+	fr.mu.Lock()
+	if fr.Fields == nil {
+		fr.Fields = make(map[reflect.Type][]FieldMeta)
+	}
+	fr.Fields[typ] = fields
+	fr.mu.Unlock()
+	return fields
+}
+
+// Field returns the metadata for the field of given name in given struct
+// type, or embedded structs within it -- nil if not found
+func (fr *FieldMetaRegistry) Field(typ reflect.Type, nm string) *FieldMeta {
+	typ = NonPtrType(typ)
+	if typ.Kind() != reflect.Struct {
+		return nil
+	}
+	fields := fr.TypeFields(typ)
+	for i := range fields {
+		if fields[i].Name == nm {
+			return &fields[i]
+		}
+	}
+	sf, ok := typ.FieldByName(nm)
+	if !ok || len(sf.Index) < 2 {
+		return nil
+	}
+	// field of an embedded struct -- get it from the type that declares it
+	for _, i := range sf.Index[:len(sf.Index)-1] {
+		typ = NonPtrType(typ.Field(i).Type)
+	}
+	fields = fr.TypeFields(typ)
+	idx := sf.Index[len(sf.Index)-1]
+	if idx >= len(fields) {
+		return nil
+	}
+	return &fields[idx]
+}
+
+// FieldTag returns the value of given tag of the field of given name in
+// given struct type, or embedded structs within it, using FieldMetas --
+// empty string if not set or field not found
+func FieldTag(typ reflect.Type, nm, tag string) string {
+	fm := FieldMetas.Field(typ, nm)
+	if fm == nil {
+		return ""
+	}
+	return fm.Get(tag)
+}
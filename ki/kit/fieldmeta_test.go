@@ -0,0 +1,64 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kit
+
+import (
+	"reflect"
+	"testing"
+)
+
+type FMBase struct {
+	Off float32 `desc:"offset" min:"-1" max:"1"`
+}
+
+type FMTest struct {
+	FMBase
+	Size  int    `desc:"the size" view:"slider" min:"0" max:"100"`
+	Label string `label:"The Label" view:"-" json:"lbl"`
+	none  bool
+}
+
+func TestFieldMetaReflect(t *testing.T) {
+	typ := reflect.TypeOf(FMTest{})
+	fields := FieldMetas.TypeFields(reflect.PtrTo(typ))
+	if len(fields) != 4 {
+		t.Fatalf("TypeFields: expected 4 fields, got %v", len(fields))
+	}
+	sz := fields[1]
+	if sz.Name != "Size" || sz.Desc != "the size" || sz.View != "slider" || sz.Min != "0" || sz.Max != "100" {
+		t.Errorf("TypeFields: bad metadata for Size: %+v", sz)
+	}
+	if fields[0].Name != "FMBase" || fields[3].Name != "none" {
+		t.Errorf("TypeFields: bad field names: %v, %v", fields[0].Name, fields[3].Name)
+	}
+	if tg := FieldTag(typ, "Label", "json"); tg != "lbl" {
+		t.Errorf("FieldTag json: got %q", tg)
+	}
+	if tg := FieldTag(typ, "Label", "label"); tg != "The Label" {
+		t.Errorf("FieldTag label: got %q", tg)
+	}
+	if tg := FieldTag(typ, "Off", "min"); tg != "-1" {
+		t.Errorf("FieldTag embedded min: got %q", tg)
+	}
+	if fm := FieldMetas.Field(typ, "Missing"); fm != nil {
+		t.Errorf("Field: expected nil for missing field, got %+v", fm)
+	}
+	if fs := FieldMetas.TypeFields(reflect.TypeOf(0)); fs != nil {
+		t.Errorf("TypeFields: expected nil for non-struct, got %v", fs)
+	}
+}
+
+type FMGen struct {
+	A int `desc:"from reflect"`
+}
+
+func TestFieldMetaRegistered(t *testing.T) {
+	FieldMetas.AddFieldMetas(&FMGen{}, []FieldMeta{
+		{Name: "A", Desc: "from table", Tag: `desc:"from reflect"`},
+	})
+	if tg := FieldTag(reflect.TypeOf(FMGen{}), "A", "desc"); tg != "from table" {
+		t.Errorf("FieldTag registered desc: got %q", tg)
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kit
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MigrationRegistry records the schema version of types and the migrations
+// needed to load data saved with earlier versions, or under earlier type
+// names -- the version is saved along with the type name, as TypeName@N
+// (only for versions > 0, so types without a schema are saved as before),
+// and loading code (e.g., ki.Slice and ki.Props UnmarshalJSON) calls Type to
+// resolve a saved type name, and Migrate to upgrade the decoded map of
+// field values, before loading it into the object.  To declare a schema:
+//
+//	var KiT_TypeName = kit.Types.AddType(&TypeName{}, nil)
+//
+//	var _ = kit.Migrations.AddSchema(KiT_TypeName, 2,
+//	    &kit.Migration{Version: 1, TypeName: "oldpkg.OldTypeName"},
+//	    &kit.Migration{Version: 2, Fields: map[string]string{"OldField": "NewField"},
+//	        Fun: func(m map[string]interface{}) error { ... }})
+//
+// Migrate returns a MigrationRecord of what it did, for each object, which
+// the loading code reports for each load (e.g., ki.OnMigratedHook).
+type MigrationRegistry struct {
+	Schemas  map[string]*Schema `desc:"schemas by current type name"`
+	OldNames map[string]string  `desc:"map from old type names to current type names"`
+	Mu       sync.RWMutex       `desc:"mutex protecting the registry"`
+}
+
+// Migrations is the master registry of type schemas and migrations
+var Migrations MigrationRegistry
+
+// Schema records the current schema version of a type, and the migrations
+// that upgrade data saved with previous versions
+type Schema struct {
+	Type       reflect.Type `desc:"the type"`
+	Version    int          `desc:"current schema version of the type -- 0 is the original, unversioned, schema"`
+	Migrations []*Migration `desc:"migrations, in order of Version"`
+}
+
+// Migration describes how to upgrade the saved data for a type from the
+// previous schema version to Version -- any or all of the changes can be
+// specified, and they are applied in order: type rename, field renames, and
+// then Fun
+type Migration struct {
+	Version  int                                  `desc:"schema version that this migration upgrades to"`
+	Desc     string                               `desc:"description of the change, for the report"`
+	TypeName string                               `desc:"previous type name, if the type was renamed -- data saved under this name is loaded as the current type, and all migrations for versions after the saved one are applied"`
+	Fields   map[string]string                    `desc:"field renames, from old field name to new field name"`
+	Fun      func(m map[string]interface{}) error `desc:"transforms the values in the map of decoded field values (as decoded by encoding/json into a map[string]interface{}), after the field renames"`
+}
+
+// MigrationRecord records the migration of one saved object
+type MigrationRecord struct {
+	Type    string   `desc:"current type name"`
+	Name    string   `desc:"name of the object -- child name, or property key"`
+	From    int      `desc:"version the object was saved with"`
+	To      int      `desc:"version the object was migrated to"`
+	Changes []string `desc:"description of each change made"`
+}
+
+func (mr MigrationRecord) String() string {
+	return fmt.Sprintf("%v %v: v%v -> v%v: %v", mr.Type, mr.Name, mr.From, mr.To, strings.Join(mr.Changes, "; "))
+}
+
+// AddSchema sets the schema version of a type, and the migrations from
+// previous versions, which can be in any order -- returns the schema
+func (tr *MigrationRegistry) AddSchema(typ reflect.Type, version int, migs ...*Migration) *Schema {
+	tr.Mu.Lock()
+	defer tr.Mu.Unlock()
+	if tr.Schemas == nil {
+		tr.Schemas = make(map[string]*Schema)
+		tr.OldNames = make(map[string]string)
+	}
+	tn := FullTypeName(typ)
+	sc := &Schema{Type: typ, Version: version, Migrations: migs}
+	sort.SliceStable(sc.Migrations, func(i, j int) bool { return sc.Migrations[i].Version < sc.Migrations[j].Version })
+	for _, mg := range migs {
+		if mg.TypeName != "" {
+			tr.OldNames[mg.TypeName] = tn
+		}
+	}
+	tr.Schemas[tn] = sc
+	return sc
+}
+
+// Schema returns the schema for given type -- nil if none
+func (tr *MigrationRegistry) Schema(typ reflect.Type) *Schema {
+	tr.Mu.RLock()
+	defer tr.Mu.RUnlock()
+	return tr.Schemas[FullTypeName(typ)]
+}
+
+// SavedTypeName returns the name to save for given type, including the
+// schema version, if > 0, as TypeName@N
+func (tr *MigrationRegistry) SavedTypeName(typ reflect.Type) string {
+	tn := FullTypeName(typ)
+	tr.Mu.RLock()
+	sc := tr.Schemas[tn]
+	tr.Mu.RUnlock()
+	if sc == nil || sc.Version == 0 {
+		return tn
+	}
+	return tn + "@" + strconv.Itoa(sc.Version)
+}
+
+// TypeName parses a saved type name, returning the current name of the type
+// (following any renames) and the version it was saved with
+func (tr *MigrationRegistry) TypeName(saved string) (string, int) {
+	saved, ver := splitSavedTypeName(saved)
+	tr.Mu.RLock()
+	defer tr.Mu.RUnlock()
+	for i := 0; i < 100; i++ { // follow chain of renames, avoiding loops
+		nw, ok := tr.OldNames[saved]
+		if !ok {
+			break
+		}
+		saved = nw
+	}
+	return saved, ver
+}
+
+// splitSavedTypeName splits a saved type name into the name and version
+func splitSavedTypeName(saved string) (string, int) {
+	if ai := strings.LastIndex(saved, "@"); ai >= 0 {
+		if v, err := strconv.Atoi(saved[ai+1:]); err == nil {
+			return saved[:ai], v
+		}
+	}
+	return saved, 0
+}
+
+// Type returns the registered type in Types for a saved type name, and the
+// version it was saved with -- nil if not found
+func (tr *MigrationRegistry) Type(saved string) (reflect.Type, int) {
+	tn, ver := tr.TypeName(saved)
+	return Types.Type(tn), ver
+}
+
+// NeedsMigrate returns true if data saved under given type name needs to be
+// migrated (Migrate) to load into the current version of given type
+func (tr *MigrationRegistry) NeedsMigrate(typ reflect.Type, saved string) bool {
+	saved, ver := splitSavedTypeName(saved)
+	tr.Mu.RLock()
+	defer tr.Mu.RUnlock()
+	sc := tr.Schemas[FullTypeName(typ)]
+	if sc == nil {
+		return false
+	}
+	return saved != FullTypeName(typ) || ver < sc.Version
+}
+
+// Migrate upgrades the map of decoded field values m for an object of given
+// type and name, saved under given type name (including version), to the
+// current schema version -- returns a record of what was done, and the
+// first error from a migration Fun -- field renames are applied in order of
+// the old field names
+func (tr *MigrationRegistry) Migrate(typ reflect.Type, saved, name string, m map[string]interface{}) (MigrationRecord, error) {
+	saved, ver := splitSavedTypeName(saved)
+	tn := FullTypeName(typ)
+	tr.Mu.RLock()
+	sc := tr.Schemas[tn]
+	tr.Mu.RUnlock()
+	rec := MigrationRecord{Type: tn, Name: name, From: ver, To: ver}
+	if saved != tn {
+		rec.Changes = append(rec.Changes, fmt.Sprintf("type renamed from %v", saved))
+	}
+	var rerr error
+	if sc != nil {
+		for _, mg := range sc.Migrations {
+			if mg.Version <= ver {
+				continue
+			}
+			rec.To = mg.Version
+			if mg.Desc != "" {
+				rec.Changes = append(rec.Changes, mg.Desc)
+			}
+			olds := make([]string, 0, len(mg.Fields))
+			for old := range mg.Fields {
+				olds = append(olds, old)
+			}
+			sort.Strings(olds)
+			for _, old := range olds {
+				nw := mg.Fields[old]
+				if val, ok := m[old]; ok {
+					m[nw] = val
+					delete(m, old)
+					rec.Changes = append(rec.Changes, fmt.Sprintf("field %v renamed to %v", old, nw))
+				}
+			}
+			if mg.Fun != nil {
+				if err := mg.Fun(m); err != nil {
+					rec.Changes = append(rec.Changes, fmt.Sprintf("v%v error: %v", mg.Version, err))
+					if rerr == nil {
+						rerr = err
+					}
+				}
+			}
+		}
+		if sc.Version > rec.To {
+			rec.To = sc.Version
+		}
+	}
+	return rec, rerr
+}
+
+// FieldName returns the current name for a field of given type, saved under
+// given type name (including version), following any field renames in the
+// migrations -- for formats that do not decode into a map
+func (tr *MigrationRegistry) FieldName(typ reflect.Type, saved, field string) string {
+	_, ver := splitSavedTypeName(saved)
+	tr.Mu.RLock()
+	sc := tr.Schemas[FullTypeName(typ)]
+	tr.Mu.RUnlock()
+	if sc == nil {
+		return field
+	}
+	for _, mg := range sc.Migrations {
+		if mg.Version <= ver {
+			continue
+		}
+		if nw, ok := mg.Fields[field]; ok {
+			field = nw
+		}
+	}
+	return field
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kit
+
+import (
+	"strings"
+	"testing"
+)
+
+type MigrateA struct {
+	Name  string
+	Value float64
+}
+
+var KiT_MigrateA = Types.AddType(&MigrateA{}, nil)
+
+func TestMigrations(t *testing.T) {
+	if tn := Migrations.SavedTypeName(KiT_MigrateA); tn != "kit.MigrateA" {
+		t.Errorf("SavedTypeName without schema should be kit.MigrateA, was: %v", tn)
+	}
+	Migrations.AddSchema(KiT_MigrateA, 3,
+		&Migration{Version: 2, Fields: map[string]string{"Val": "Value"}},
+		&Migration{Version: 1, TypeName: "kit.OldA", Fields: map[string]string{"Nm": "Name"}},
+		&Migration{Version: 3, Desc: "Value doubled", Fun: func(m map[string]interface{}) error {
+			if v, ok := m["Value"].(float64); ok {
+				m["Value"] = v * 2
+			}
+			return nil
+		}})
+
+	if tn := Migrations.SavedTypeName(KiT_MigrateA); tn != "kit.MigrateA@3" {
+		t.Errorf("SavedTypeName should be kit.MigrateA@3, was: %v", tn)
+	}
+	if typ, ver := Migrations.Type("kit.OldA"); typ != KiT_MigrateA || ver != 0 {
+		t.Errorf("Type(kit.OldA) should be MigrateA v0, was: %v v%v", typ, ver)
+	}
+	if typ, ver := Migrations.Type("kit.MigrateA@2"); typ != KiT_MigrateA || ver != 2 {
+		t.Errorf("Type(kit.MigrateA@2) should be MigrateA v2, was: %v v%v", typ, ver)
+	}
+	if Migrations.NeedsMigrate(KiT_MigrateA, "kit.MigrateA@3") {
+		t.Errorf("NeedsMigrate should be false for current version")
+	}
+	if !Migrations.NeedsMigrate(KiT_MigrateA, "kit.MigrateA@2") || !Migrations.NeedsMigrate(KiT_MigrateA, "kit.OldA") {
+		t.Errorf("NeedsMigrate should be true for old version and type name")
+	}
+	if fn := Migrations.FieldName(KiT_MigrateA, "kit.OldA", "Nm"); fn != "Name" {
+		t.Errorf("FieldName(Nm) should be Name, was: %v", fn)
+	}
+
+	m := map[string]interface{}{"Nm": "a", "Val": 1.5}
+	rec, err := Migrations.Migrate(KiT_MigrateA, "kit.OldA", "a", m)
+	if err != nil {
+		t.Error(err)
+	}
+	if m["Name"] != "a" || m["Value"] != 3.0 || len(m) != 2 {
+		t.Errorf("Migrate from kit.OldA gave wrong result: %v", m)
+	}
+	trg := "kit.MigrateA a: v0 -> v3: type renamed from kit.OldA; field Nm renamed to Name; field Val renamed to Value; Value doubled"
+	if rec.String() != trg {
+		t.Errorf("Migrate record should be: %v, was: %v", trg, rec)
+	}
+	m = map[string]interface{}{"Name": "b", "Value": 1.5}
+	rec, _ = Migrations.Migrate(KiT_MigrateA, "kit.MigrateA@2", "b", m)
+	if m["Value"] != 3.0 {
+		t.Errorf("Migrate from v2 gave wrong result: %v", m)
+	}
+	if rec.From != 2 || rec.To != 3 || len(rec.Changes) != 1 {
+		t.Errorf("Migrate record from v2 is wrong: %v", rec)
+	}
+}
+
+type MigrateB struct {
+	A, B, C, D string
+}
+
+var KiT_MigrateB = Types.AddType(&MigrateB{}, nil)
+
+func TestMigrationFieldOrder(t *testing.T) {
+	Migrations.AddSchema(KiT_MigrateB, 1, &Migration{Version: 1, Fields: map[string]string{"W": "D", "X": "A", "Y": "B", "Z": "C"}})
+	trg := "field W renamed to D; field X renamed to A; field Y renamed to B; field Z renamed to C"
+	for i := 0; i < 20; i++ {
+		m := map[string]interface{}{"W": "w", "X": "x", "Y": "y", "Z": "z"}
+		rec, _ := Migrations.Migrate(KiT_MigrateB, "kit.MigrateB", "b", m)
+		if chg := strings.Join(rec.Changes, "; "); chg != trg {
+			t.Fatalf("field renames should be in order: %v, were: %v", trg, chg)
+		}
+	}
+}
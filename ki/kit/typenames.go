@@ -0,0 +1,126 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kit
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"sync"
+)
+
+// typeNames records the names that types are registered under, in the
+// TypeRegistry and EnumRegistry -- with ShortTypeNames, the first type
+// registered gets the short "pkg.Type" name, and any later type with the
+// same short name, from another package with the same base name, gets its
+// full package path name instead -- types can always be looked up by their
+// full package path names too.  Scoped registries (see NewScope) have their
+// own typeNames that fall back on the parent, so temporary types do not
+// take names in the global registries.
+type typeNames struct {
+	parent *typeNames
+	names  map[reflect.Type]string
+	owners map[string]reflect.Type
+	mu     sync.RWMutex
+}
+
+// globalNames are the names of the types in the Types and Enums registries
+var globalNames typeNames
+
+// shortName returns the "pkg.Type" name of a type
+func shortName(typ reflect.Type) string {
+	return path.Base(typ.PkgPath()) + "." + typ.Name()
+}
+
+// pathName returns the full package path name of a type
+func pathName(typ reflect.Type) string {
+	return typ.PkgPath() + "." + typ.Name()
+}
+
+// registered returns the name that a type is registered under, in this or
+// any parent names -- false if it is not registered
+func (tn *typeNames) registered(typ reflect.Type) (string, bool) {
+	for t := tn; t != nil; t = t.parent {
+		t.mu.RLock()
+		nm, ok := t.names[typ]
+		t.mu.RUnlock()
+		if ok {
+			return nm, true
+		}
+	}
+	return "", false
+}
+
+// owner returns the type that is registered under given name, which can be
+// its short or full package path name, in this or any parent names -- false
+// if none
+func (tn *typeNames) owner(nm string) (reflect.Type, bool) {
+	for t := tn; t != nil; t = t.parent {
+		t.mu.RLock()
+		typ, ok := t.owners[nm]
+		t.mu.RUnlock()
+		if ok {
+			return typ, true
+		}
+	}
+	return nil, false
+}
+
+// name returns the name for a type -- the name it is registered under, and
+// otherwise the short name if ShortTypeNames and no other type is
+// registered under that name, else the full package path name
+func (tn *typeNames) name(typ reflect.Type) string {
+	if nm, ok := tn.registered(typ); ok {
+		return nm
+	}
+	if !ShortTypeNames {
+		return pathName(typ)
+	}
+	snm := shortName(typ)
+	if otyp, ok := tn.owner(snm); ok && otyp != typ {
+		return pathName(typ)
+	}
+	return snm
+}
+
+// add registers a type, returning the name it is registered under -- if its
+// short name is already used by another type, it is registered under its
+// full package path name, and an error describing the collision is returned
+func (tn *typeNames) add(typ reflect.Type) (string, error) {
+	if tn.parent != nil {
+		if nm, ok := tn.parent.registered(typ); ok {
+			return nm, nil
+		}
+	}
+	tn.mu.Lock()
+	defer tn.mu.Unlock()
+	if nm, ok := tn.names[typ]; ok {
+		return nm, nil
+	}
+	pnm := pathName(typ)
+	nm := pnm
+	var err error
+	if ShortTypeNames {
+		nm = shortName(typ)
+		otyp, ok := tn.owners[nm]
+		if !ok && tn.parent != nil {
+			otyp, ok = tn.parent.owner(nm)
+		}
+		if ok && otyp != typ {
+			err = fmt.Errorf("kit: type name %v of type %v is already used by type %v -- using full name %v instead", nm, pnm, pathName(otyp), pnm)
+			nm = pnm
+		}
+	}
+	if tn.names == nil {
+		tn.names = make(map[reflect.Type]string)
+		tn.owners = make(map[string]reflect.Type)
+	}
+	tn.names[typ] = nm
+	tn.owners[nm] = typ
+	if _, has := tn.owners[pnm]; !has {
+		tn.owners[pnm] = typ
+	}
+	return nm, err
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kit
+
+import (
+	htmltemplate "html/template"
+	"reflect"
+	"sync"
+	"testing"
+	"text/template"
+)
+
+type ScopedType struct {
+	A int
+}
+
+func TestTypeScope(t *testing.T) {
+	sc := Types.NewScope()
+	typ := sc.AddType(&ScopedType{}, map[string]interface{}{"scoped": true})
+	tn := sc.TypeName(typ)
+	if tn != "kit.ScopedType" {
+		t.Errorf("TypeName: got %v", tn)
+	}
+	if sc.Type(tn) != typ {
+		t.Errorf("scoped Type not found: %v", tn)
+	}
+	if Types.Type(tn) != nil {
+		t.Errorf("scoped type %v found in global registry", tn)
+	}
+	if sc.Type("github.com/rcoreilly/goki/ki/kit.ScopedType") != typ {
+		t.Errorf("scoped Type not found by full path name")
+	}
+	if b, _ := ToBool(sc.Prop(typ, "scoped")); !b {
+		t.Errorf("scoped Prop not found")
+	}
+	if sc.Type(".int") != reflect.TypeOf(0) {
+		t.Errorf("scope does not fall back on parent for basic type int")
+	}
+	if b, _ := ToBool(sc.Prop(reflect.TypeOf(0), "basic-type")); !b {
+		t.Errorf("scope does not fall back on parent for props")
+	}
+	if tl := sc.AllTagged("scoped"); len(tl) != 1 || tl[0] != typ {
+		t.Errorf("AllTagged: got %v", tl)
+	}
+	if tl := Types.AllTagged("scoped"); len(tl) != 0 {
+		t.Errorf("AllTagged global: got %v", tl)
+	}
+}
+
+func TestTypeCollision(t *testing.T) {
+	sc := Types.NewScope()
+	ttyp, err := sc.AddTypeErr(&template.Template{}, nil)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	htyp, err := sc.AddTypeErr(&htmltemplate.Template{}, nil)
+	if err == nil {
+		t.Errorf("AddTypeErr: expected error for colliding type name")
+	}
+	if tn := sc.TypeName(ttyp); tn != "template.Template" {
+		t.Errorf("TypeName: got %v", tn)
+	}
+	if tn := sc.TypeName(htyp); tn != "html/template.Template" {
+		t.Errorf("TypeName of colliding type: got %v", tn)
+	}
+	if sc.Type("template.Template") != ttyp || sc.Type("html/template.Template") != htyp {
+		t.Errorf("Type of colliding types failed")
+	}
+	if sc.Type("text/template.Template") != ttyp {
+		t.Errorf("Type by full path name failed")
+	}
+	if FullTypeName(htyp) != "template.Template" {
+		t.Errorf("scoped collision changed global name: %v", FullTypeName(htyp))
+	}
+	if _, err := sc.AddTypeErr(&template.Template{}, nil); err != nil {
+		t.Errorf("re-adding same type: %v", err)
+	}
+}
+
+type ScopedEnum int32
+
+func TestEnumScope(t *testing.T) {
+	sc := Enums.NewScope()
+	typ := sc.AddEnumValues(ScopedEnum(0), true, nil, []EnumValue{
+		{Name: "First", Value: 0},
+		{Name: "Second", Value: 3},
+	})
+	if !sc.TypeRegistered(typ) || !sc.IsBitFlag(typ) || !sc.IsExplicit(typ) {
+		t.Errorf("scoped enum not registered")
+	}
+	if Enums.TypeRegistered(typ) {
+		t.Errorf("scoped enum registered in global registry")
+	}
+	if sc.Enum("kit.ScopedEnum") != typ || sc.Enum("github.com/rcoreilly/goki/ki/kit.ScopedEnum") != typ {
+		t.Errorf("scoped Enum lookup failed")
+	}
+	if ev, ok := sc.ValueByName(typ, "Second"); !ok || ev.Value != 3 {
+		t.Errorf("scoped ValueByName failed: %v", ev)
+	}
+	if vals := sc.TypeValues(typ, false); len(vals) != 2 {
+		t.Errorf("scoped TypeValues: got %v", vals)
+	}
+	if !sc.TypeRegistered(KiT_TestFlags) {
+		t.Errorf("scope does not fall back on parent")
+	}
+	if es := sc.EnumToAltString(TestFlag2); es != "flag2" {
+		t.Errorf("scoped EnumToAltString from parent: got %v", es)
+	}
+}
+
+func TestRegistryConcurrent(t *testing.T) {
+	sc := Types.NewScope()
+	es := Enums.NewScope()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				sc.AddType(&ScopedType{}, nil)
+				sc.Properties(reflect.TypeOf(ScopedType{}), true)
+				sc.AllTypes()
+				es.AddEnum(TestFlagsN, false, nil)
+				es.TypeValues(KiT_TestFlags, false)
+				es.AllTagged("N")
+				FullTypeName(KiT_TestFlags)
+			}
+		}()
+	}
+	wg.Wait()
+	if sc.Type("kit.ScopedType") == nil || !es.TypeRegistered(KiT_TestFlags) {
+		t.Errorf("concurrent registration failed")
+	}
+}
@@ -19,6 +19,15 @@
 // background-color property.  KiT_TypeName variable can be conveniently used
 // wherever a reflect.Type of that type is needed.
 //
+// Types are registered under their short "pkg.Type" names (see
+// ShortTypeNames) -- if another type with the same short name, from another
+// package with the same base name, is registered, an error is logged and
+// the later type is registered under its full package path name instead --
+// all types can also be looked up using their full package path names.  The
+// registries are safe for concurrent use, and NewScope creates a scoped
+// registry, e.g., for plugins or tests, that registers its own types while
+// falling back on the global registry for everything else.
+//
 // * kit.EnumRegistry (enums.go) that registers constant int iota (aka enum) types, and
 // provides general conversion utilities to / from string, int64, general
 // properties associated with enum types, and deals with bit flags
@@ -26,10 +35,24 @@
 // * kit.Type (type.go) struct provides JSON and XML Marshal / Unmarshal functions for
 // saving / loading reflect.Type using registrered type names.
 //
+// * kit.MigrationRegistry (migrate.go) records schema versions for types, and
+// migrations (type renames, field renames, and value transforms) that upgrade
+// data saved with earlier versions -- applied when loading ki trees.
+//
+// * kit.FieldMetaRegistry (fieldmeta.go) records the FieldMeta desc,
+// label, view, min, and max tags of struct fields -- tables generated by the
+// kigen command are registered in kit.FieldMetas, avoiding parsing the tags
+// using reflection at runtime, and other types fall back on reflection.
+//
 // * convert.go: robust interface{}-based type conversion routines that are
 // useful in more lax user-interface contexts where "common sense" conversions
 // between strings, numbers etc are useful
 //
+// * deepconvert.go: Convert deeply converts between maps and structs, slices
+// and maps of differing element types, strings and times, durations,
+// complex numbers and enum names, reporting the path of each field or
+// element that could not be converted in its ConvertErrors
+//
 // * embeds.go: various functions for managing embedded struct types, e.g.,
 // determining if a given type embeds another type (directly or indirectly),
 // and iterating over fields to flatten the otherwise nested nature of the
@@ -42,8 +65,8 @@ import (
 	// "fmt"
 	// "log"
 	"log"
-	"path"
 	"reflect"
+	"sync"
 )
 
 // TypeRegistry is a map from type name (package path + "." + type name) to
@@ -69,6 +92,18 @@ type TypeRegistry struct {
 
 	// Insts contain an instance of each type (the one passed during AddType)
 	Insts map[string]interface{}
+
+	// Parent is the registry that a scoped registry made by NewScope falls
+	// back on for types that are not registered in it -- nil for the global
+	// registry
+	Parent *TypeRegistry
+
+	// Mu protects the maps -- the methods lock it as needed, so it is only
+	// needed for accessing the maps directly
+	Mu sync.RWMutex
+
+	names    *typeNames
+	initOnce sync.Once
 }
 
 // Types is master registry of types that embed Ki Nodes
@@ -76,16 +111,41 @@ var Types TypeRegistry
 
 // if ShortTypeNames is true, we just use the standard "base".TypeName instead
 // of the full path that PgkPath returns -- this should work unless there are
-// conflicts but the savings in JSON files etc is probably worth it..
+// conflicts but the savings in JSON files etc is probably worth it.. -- types
+// whose short names conflict with a type registered earlier use the full
+// path name
 var ShortTypeNames = true
 
 // FullTypeName returns the full package-qualified type name -- this is what
-// is used for encoding type names in the registry
+// is used for encoding type names in the registry -- it is the name the type
+// is registered under in the global Types and Enums registries (see
+// TypeRegistry.TypeName for scoped registries)
 func FullTypeName(typ reflect.Type) string {
-	if ShortTypeNames {
-		return path.Base(typ.PkgPath()) + "." + typ.Name()
+	return globalNames.name(typ)
+}
+
+// NewScope returns a new scoped registry, whose types are registered only
+// in it, and which falls back on this registry for all other types -- e.g.,
+// for plugin-like modules or tests that register temporary types -- type
+// names used in this registry are also not used in the global registry
+func (tr *TypeRegistry) NewScope() *TypeRegistry {
+	sc := &TypeRegistry{Parent: tr, names: &typeNames{parent: tr.typeNames()}}
+	sc.Init()
+	return sc
+}
+
+// typeNames returns the names of the types in this registry
+func (tr *TypeRegistry) typeNames() *typeNames {
+	if tr.names == nil {
+		return &globalNames
 	}
-	return typ.PkgPath() + "." + typ.Name()
+	return tr.names
+}
+
+// TypeName returns the name of the type in this registry -- the same as
+// FullTypeName except for types registered in scoped registries
+func (tr *TypeRegistry) TypeName(typ reflect.Type) string {
+	return tr.typeNames().name(typ)
 }
 
 // AddType adds a given type to the registry -- requires an empty object to
@@ -93,27 +153,71 @@ func FullTypeName(typ reflect.Type) string {
 // pointer to ensure that it is an addressable, settable type -- also optional
 // properties that can be associated with the type and accessible e.g. for
 // view-specific properties etc -- these props MUST be specific to this type
-// as they are used directly, not copied!!
+// as they are used directly, not copied!! -- logs any error from AddTypeErr
 func (tr *TypeRegistry) AddType(obj interface{}, props map[string]interface{}) reflect.Type {
-	if tr.Types == nil {
-		tr.Init()
+	typ, err := tr.AddTypeErr(obj, props)
+	if err != nil {
+		log.Println(err)
 	}
+	return typ
+}
+
+// AddTypeErr adds a given type to the registry as AddType does, returning an
+// error if its short type name is already used by another type -- it is
+// then registered under its full package path name
+func (tr *TypeRegistry) AddTypeErr(obj interface{}, props map[string]interface{}) (reflect.Type, error) {
+	tr.initOnce.Do(func() {
+		tr.Mu.RLock()
+		made := tr.Types != nil
+		tr.Mu.RUnlock()
+		if !made {
+			tr.Init()
+		}
+	})
+	return tr.addType(obj, props)
+}
 
+// addType adds a given type to the registry, which must be initialized
+func (tr *TypeRegistry) addType(obj interface{}, props map[string]interface{}) (reflect.Type, error) {
 	typ := reflect.TypeOf(obj).Elem()
-	tn := FullTypeName(typ)
+	tn, err := tr.typeNames().add(typ)
+	tr.Mu.Lock()
+	defer tr.Mu.Unlock()
 	tr.Types[tn] = typ
 	tr.Insts[tn] = obj
 	if props != nil {
 		tr.Props[tn] = props
 	}
-	return typ
+	return typ, err
+}
+
+// scope returns the registry in the chain of scoped registries that has
+// given type name -- nil if none
+func (tr *TypeRegistry) scope(typeName string) *TypeRegistry {
+	for r := tr; r != nil; r = r.Parent {
+		r.Mu.RLock()
+		_, ok := r.Types[typeName]
+		r.Mu.RUnlock()
+		if ok {
+			return r
+		}
+	}
+	return nil
 }
 
 // Type returns the reflect.Type based on its name (package path + "." + type
-// name) -- returns nil if not found
+// name) -- returns nil if not found -- types can also be found by their full
+// package path names
 func (tr *TypeRegistry) Type(typeName string) reflect.Type {
-	if typ, ok := tr.Types[typeName]; ok {
-		return typ
+	if r := tr.scope(typeName); r != nil {
+		r.Mu.RLock()
+		defer r.Mu.RUnlock()
+		return r.Types[typeName]
+	}
+	if typ, ok := tr.typeNames().owner(typeName); ok {
+		if tn := tr.TypeName(typ); tn != typeName && tr.scope(tn) != nil {
+			return typ
+		}
 	}
 	return nil
 }
@@ -121,8 +225,10 @@ func (tr *TypeRegistry) Type(typeName string) reflect.Type {
 // InstByName returns the interface{} instance of given type (it is a pointer
 // to that type) -- returns nil if not found
 func (tr *TypeRegistry) InstByName(typeName string) interface{} {
-	if inst, ok := tr.Insts[typeName]; ok {
-		return inst
+	if r := tr.scope(typeName); r != nil {
+		r.Mu.RLock()
+		defer r.Mu.RUnlock()
+		return r.Insts[typeName]
 	}
 	return nil
 }
@@ -130,7 +236,7 @@ func (tr *TypeRegistry) InstByName(typeName string) interface{} {
 // Inst returns the interface{} instance of given type (it is a pointer
 // to that type) -- returns nil if not found
 func (tr *TypeRegistry) Inst(typ reflect.Type) interface{} {
-	typeName := FullTypeName(typ)
+	typeName := tr.TypeName(typ)
 	return tr.InstByName(typeName)
 }
 
@@ -138,11 +244,24 @@ func (tr *TypeRegistry) Inst(typ reflect.Type) interface{} {
 // if not already made -- can use this to register properties for types that
 // are not registered
 func (tr *TypeRegistry) PropsByName(typeName string, makeNew bool) map[string]interface{} {
+	for r := tr; r != nil; r = r.Parent {
+		r.Mu.RLock()
+		tp, ok := r.Props[typeName]
+		r.Mu.RUnlock()
+		if ok {
+			return tp
+		}
+	}
+	if !makeNew {
+		return nil
+	}
+	tr.Mu.Lock()
+	defer tr.Mu.Unlock()
+	if tr.Props == nil {
+		tr.Props = make(map[string]map[string]interface{})
+	}
 	tp, ok := tr.Props[typeName]
 	if !ok {
-		if !makeNew {
-			return nil
-		}
 		tp = make(map[string]interface{})
 		tr.Props[typeName] = tp
 	}
@@ -153,14 +272,14 @@ func (tr *TypeRegistry) PropsByName(typeName string, makeNew bool) map[string]in
 // if not already made -- can use this to register properties for types that
 // are not registered
 func (tr *TypeRegistry) Properties(typ reflect.Type, makeNew bool) map[string]interface{} {
-	typeName := FullTypeName(typ)
+	typeName := tr.TypeName(typ)
 	return tr.PropsByName(typeName, makeNew)
 }
 
 // PropByName safely finds a type property from type name and property key -- nil if not found
 func (tr *TypeRegistry) PropByName(typeName, propKey string) interface{} {
-	tp, ok := tr.Props[typeName]
-	if !ok {
+	tp := tr.PropsByName(typeName, false)
+	if tp == nil {
 		// fmt.Printf("no props for type: %v\n", typeName)
 		return nil
 	}
@@ -174,10 +293,28 @@ func (tr *TypeRegistry) PropByName(typeName, propKey string) interface{} {
 
 // Prop safely finds a type property from type and property key -- nil if not found
 func (tr *TypeRegistry) Prop(typ reflect.Type, propKey string) interface{} {
-	typeName := FullTypeName(typ)
+	typeName := tr.TypeName(typ)
 	return tr.PropByName(typeName, propKey)
 }
 
+// AllTypes returns a list of all registered types, including those of the
+// parent registries for scoped registries
+func (tr *TypeRegistry) AllTypes() []reflect.Type {
+	tl := make([]reflect.Type, 0)
+	have := make(map[string]bool)
+	for r := tr; r != nil; r = r.Parent {
+		r.Mu.RLock()
+		for tn, typ := range r.Types {
+			if !have[tn] {
+				have[tn] = true
+				tl = append(tl, typ)
+			}
+		}
+		r.Mu.RUnlock()
+	}
+	return tl
+}
+
 // AllImplementersOf returns a list of all registered types that implement the
 // given interface type at any level of embedding -- must pass a type
 // constructed like this: reflect.TypeOf((*gi.Node2D)(nil)).Elem() --
@@ -189,7 +326,7 @@ func (tr *TypeRegistry) AllImplementersOf(iface reflect.Type, includeBases bool)
 		return nil
 	}
 	tl := make([]reflect.Type, 0)
-	for _, typ := range tr.Types {
+	for _, typ := range tr.AllTypes() {
 		if !includeBases {
 			btp := tr.Prop(typ, "base-type")
 			btpb, _ := ToBool(btp)
@@ -215,7 +352,7 @@ func (tr *TypeRegistry) AllImplementersOf(iface reflect.Type, includeBases bool)
 // user-facing type selection
 func (tr *TypeRegistry) AllEmbedsOf(embed reflect.Type, inclusive, includeBases bool) []reflect.Type {
 	tl := make([]reflect.Type, 0)
-	for _, typ := range tr.Types {
+	for _, typ := range tr.AllTypes() {
 		if !inclusive && typ == embed {
 			continue
 		}
@@ -238,7 +375,7 @@ func (tr *TypeRegistry) AllEmbedsOf(embed reflect.Type, inclusive, includeBases
 // its existence
 func (tr *TypeRegistry) AllTagged(key string) []reflect.Type {
 	tl := make([]reflect.Type, 0)
-	for _, typ := range tr.Types {
+	for _, typ := range tr.AllTypes() {
 		tp := tr.Prop(typ, key)
 		if tp == nil {
 			continue
@@ -248,90 +385,96 @@ func (tr *TypeRegistry) AllTagged(key string) []reflect.Type {
 	return tl
 }
 
-// Init initializes the type registry, including adding basic types
+// Init initializes the type registry, including adding basic types, except
+// for scoped registries, which get them from the parent
 func (tr *TypeRegistry) Init() {
+	tr.Mu.Lock()
 	tr.Types = make(map[string]reflect.Type, 1000)
 	tr.Insts = make(map[string]interface{}, 1000)
 	tr.Props = make(map[string]map[string]interface{}, 1000)
+	tr.Mu.Unlock()
+	if tr.Parent != nil {
+		return
+	}
 
 	{
 		var BoolProps = map[string]interface{}{
 			"basic-type": true,
 		}
 		ob := false
-		tr.AddType(&ob, BoolProps)
+		tr.addType(&ob, BoolProps)
 	}
 	{
 		var IntProps = map[string]interface{}{
 			"basic-type": true,
 		}
 		ob := int(0)
-		tr.AddType(&ob, IntProps)
+		tr.addType(&ob, IntProps)
 	}
 	{
 		ob := int8(0)
-		tr.AddType(&ob, nil)
+		tr.addType(&ob, nil)
 	}
 	{
 		ob := int16(0)
-		tr.AddType(&ob, nil)
+		tr.addType(&ob, nil)
 	}
 	{
 		ob := int32(0)
-		tr.AddType(&ob, nil)
+		tr.addType(&ob, nil)
 	}
 	{
 		ob := int64(0)
-		tr.AddType(&ob, nil)
+		tr.addType(&ob, nil)
 	}
 	{
 		ob := uint(0)
-		tr.AddType(&ob, nil)
+		tr.addType(&ob, nil)
 	}
 	{
 		ob := uint8(0)
-		tr.AddType(&ob, nil)
+		tr.addType(&ob, nil)
 	}
 	{
 		ob := uint16(0)
-		tr.AddType(&ob, nil)
+		tr.addType(&ob, nil)
 	}
 	{
 		ob := uint32(0)
-		tr.AddType(&ob, nil)
+		tr.addType(&ob, nil)
 	}
 	{
 		ob := uint64(0)
-		tr.AddType(&ob, nil)
+		tr.addType(&ob, nil)
 	}
 	{
 		ob := uintptr(0)
-		tr.AddType(&ob, nil)
+		tr.addType(&ob, nil)
 	}
 	{
 		ob := float32(0)
-		tr.AddType(&ob, nil)
+		tr.addType(&ob, nil)
 	}
 	{
 		var Float64Props = map[string]interface{}{
 			"basic-type": true,
 		}
 		ob := float64(0)
-		tr.AddType(&ob, Float64Props)
+		tr.addType(&ob, Float64Props)
 	}
 	{
 		ob := complex64(0)
-		tr.AddType(&ob, nil)
+		tr.addType(&ob, nil)
 	}
 	{
 		ob := complex128(0)
-		tr.AddType(&ob, nil)
+		tr.addType(&ob, nil)
 	}
 	{
 		var StringProps = map[string]interface{}{
 			"basic-type": true,
 		}
 		ob := string(0)
-		tr.AddType(&ob, StringProps)
+		tr.addType(&ob, StringProps)
 	}
 }
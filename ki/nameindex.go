@@ -0,0 +1,290 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"sync"
+)
+
+// NameIndex is an index of the children of a node by Name and UniqueName,
+// for fast lookup in large lists of children: ChildIndexByName,
+// ChildIndexByUniqueName, ConfigChildren, and UniquifyNames all use it when
+// a node has one.  The index is maintained incrementally as children are
+// added, deleted, moved, and renamed, so it is transparent to callers -- it
+// is created automatically when a node gets NameIndexMin children, or
+// explicitly with EnableNameIndex.  Direct modification of Kids that
+// bypasses the Ki methods is detected when the number of children changes,
+// or a stale entry is found, and the index is then rebuilt.
+//
+// The index has its own mutex, and is only accessed outside of the tree lock
+// (see EnableTreeLock), which it takes as needed to access the children.
+type NameIndex struct {
+	mu    sync.Mutex
+	names map[string][]nameIndexEntry
+	uniq  map[string][]nameIndexEntry
+	n     int // number of children indexed
+	ndup  int // number of extra entries in uniq buckets, plus empty unique names
+}
+
+// nameIndexEntry is a child in the index, with a hint of its index in the
+// children, updated when looked up
+type nameIndexEntry struct {
+	kid  Ki
+	hint int
+}
+
+// NameIndexMin is the number of children at which a node automatically gets
+// a NameIndex -- 0 to never create them automatically
+var NameIndexMin = 1024
+
+func (n *Node) EnableNameIndex() {
+	if n.NameIndex() != nil {
+		return
+	}
+	ix := &NameIndex{}
+	ix.build(n.Children())
+	if mu := n.TreeMu; mu != nil {
+		mu.Lock()
+		defer mu.Unlock()
+	}
+	n.nameIdx = ix
+}
+
+func (n *Node) DisableNameIndex() {
+	if mu := n.TreeMu; mu != nil {
+		mu.Lock()
+		defer mu.Unlock()
+	}
+	n.nameIdx = nil
+}
+
+func (n *Node) NameIndex() *NameIndex {
+	if mu := n.TreeMu; mu != nil {
+		mu.RLock()
+		defer mu.RUnlock()
+	}
+	return n.nameIdx
+}
+
+// nameIndexAdded updates the index of given node for a child added at given
+// index, creating the index if there are now NameIndexMin children
+func nameIndexAdded(n Ki, kid Ki, idx int) {
+	if ix := n.NameIndex(); ix != nil {
+		ix.add(kid, idx)
+		return
+	}
+	if NameIndexMin > 0 && len(n.Children()) >= NameIndexMin {
+		n.EnableNameIndex()
+	}
+}
+
+// nameIndexRemoved updates the index of given node, if it has one, for the
+// removal of given child
+func nameIndexRemoved(n Ki, kid Ki) {
+	if ix := n.NameIndex(); ix != nil {
+		ix.remove(kid)
+	}
+}
+
+// nameIndexRenamed updates the index of the parent of given node, if it has
+// one, for a change of name from given old names
+func nameIndexRenamed(kid Ki, oldNm, oldUniqNm string) {
+	par := kid.Parent()
+	if par == nil {
+		return
+	}
+	if ix := par.NameIndex(); ix != nil {
+		ix.rename(kid, oldNm, oldUniqNm)
+	}
+}
+
+// buildLocked rebuilds the index from given children -- lock must be held
+func (ix *NameIndex) buildLocked(kids Slice) {
+	ix.names = make(map[string][]nameIndexEntry, len(kids))
+	ix.uniq = make(map[string][]nameIndexEntry, len(kids))
+	ix.n = 0
+	ix.ndup = 0
+	for i, kid := range kids {
+		ix.addLocked(kid, i)
+	}
+}
+
+// build rebuilds the index from given children
+func (ix *NameIndex) build(kids Slice) {
+	ix.mu.Lock()
+	ix.buildLocked(kids)
+	ix.mu.Unlock()
+}
+
+// add adds a child to the index
+func (ix *NameIndex) add(kid Ki, idx int) {
+	ix.mu.Lock()
+	ix.addLocked(kid, idx)
+	ix.mu.Unlock()
+}
+
+func (ix *NameIndex) addLocked(kid Ki, idx int) {
+	ent := nameIndexEntry{kid: kid, hint: idx}
+	nm := kid.Name()
+	ix.names[nm] = append(ix.names[nm], ent)
+	ix.addUniqLocked(ent, kid.UniqueName())
+	ix.n++
+}
+
+func (ix *NameIndex) addUniqLocked(ent nameIndexEntry, unm string) {
+	if unm == "" || len(ix.uniq[unm]) > 0 {
+		ix.ndup++
+	}
+	ix.uniq[unm] = append(ix.uniq[unm], ent)
+}
+
+// remove removes a child from the index
+func (ix *NameIndex) remove(kid Ki) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if _, ok := nameIndexTake(ix.names, kid.Name(), kid); !ok {
+		ix.n = -1 // not found, so must rebuild
+		return
+	}
+	ix.removeUniqLocked(kid, kid.UniqueName())
+	ix.n--
+}
+
+func (ix *NameIndex) removeUniqLocked(kid Ki, unm string) (nameIndexEntry, bool) {
+	ent, ok := nameIndexTake(ix.uniq, unm, kid)
+	if ok && (unm == "" || len(ix.uniq[unm]) > 0) {
+		ix.ndup--
+	}
+	return ent, ok
+}
+
+// rename updates the index for a child that was renamed from given old names
+func (ix *NameIndex) rename(kid Ki, oldNm, oldUniqNm string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if nm := kid.Name(); nm != oldNm {
+		ent, ok := nameIndexTake(ix.names, oldNm, kid)
+		if !ok {
+			ix.n = -1
+			return
+		}
+		ix.names[nm] = append(ix.names[nm], ent)
+	}
+	if unm := kid.UniqueName(); unm != oldUniqNm {
+		ent, ok := ix.removeUniqLocked(kid, oldUniqNm)
+		if !ok {
+			ix.n = -1
+			return
+		}
+		ix.addUniqLocked(ent, unm)
+	}
+}
+
+// reset removes all the children from the index
+func (ix *NameIndex) reset() {
+	ix.build(nil)
+}
+
+// nameIndexTake removes the entry for given child from the bucket for given
+// key, returning it and whether it was found
+func nameIndexTake(m map[string][]nameIndexEntry, key string, kid Ki) (nameIndexEntry, bool) {
+	ents := m[key]
+	for i, ent := range ents {
+		if ent.kid == kid {
+			if len(ents) == 1 {
+				delete(m, key)
+			} else {
+				m[key] = append(ents[:i:i], ents[i+1:]...)
+			}
+			return ent, true
+		}
+	}
+	return nameIndexEntry{}, false
+}
+
+// checkLocked rebuilds the index from the children of given node if the
+// number of children has changed -- lock must be held
+func (ix *NameIndex) checkLocked(n *Node) {
+	if mu := n.TreeMu; mu != nil {
+		mu.RLock()
+		nk := len(n.Kids)
+		mu.RUnlock()
+		if ix.n != nk {
+			ix.buildLocked(n.Children())
+		}
+		return
+	}
+	if ix.n != len(n.Kids) {
+		ix.buildLocked(n.Kids)
+	}
+}
+
+// hasDups returns true if there may be children of given node with duplicate
+// or empty unique names
+func (ix *NameIndex) hasDups(n *Node) bool {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.checkLocked(n)
+	return ix.ndup > 0
+}
+
+// index returns the index in the children of given node of the child with
+// given name (or unique name), searching as Slice.IndexByFunc does from
+// startIdx, -1 if not found
+func (ix *NameIndex) index(n *Node, name string, startIdx int, uniq bool) int {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	for try := 0; try < 2; try++ {
+		ix.checkLocked(n)
+		m := ix.names
+		if uniq {
+			m = ix.uniq
+		}
+		ents := m[name]
+		best, bestRank, stale := -1, 0, false
+		mu := n.TreeMu
+		if mu != nil {
+			mu.RLock()
+		}
+		for i := range ents {
+			ent := &ents[i]
+			idx := n.Kids.Index(ent.kid, ent.hint)
+			if idx < 0 {
+				stale = true
+				break
+			}
+			ent.hint = idx
+			rank := nameIndexRank(idx, startIdx, len(n.Kids))
+			if best < 0 || rank < bestRank {
+				best, bestRank = idx, rank
+			}
+		}
+		if mu != nil {
+			mu.RUnlock()
+		}
+		if !stale {
+			return best
+		}
+		ix.n = -1 // rebuild and try again
+	}
+	return -1
+}
+
+// nameIndexRank returns the rank of given index in the order in which
+// Slice.IndexByFunc searches from startIdx: forward from 0 if startIdx is
+// 0, otherwise alternating up and down, in the order startIdx+1, startIdx,
+// startIdx+2, startIdx-1, etc
+func nameIndexRank(idx, startIdx, sz int) int {
+	if startIdx == 0 {
+		return idx
+	}
+	if startIdx >= sz {
+		startIdx = sz - 1
+	}
+	if idx > startIdx {
+		return 2*(idx-startIdx) - 2
+	}
+	return 2*(startIdx-idx) + 1
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/rcoreilly/goki/ki/kit"
+)
+
+// checkNameIndex checks that lookups using the index of par give the same
+// results as linear search, for all the names of the children plus a
+// missing one
+func checkNameIndex(t *testing.T, par *Node, rnd *rand.Rand, what string) {
+	t.Helper()
+	kids := par.Children()
+	names := []string{"missing"}
+	for _, kid := range kids {
+		names = append(names, kid.Name(), kid.UniqueName())
+	}
+	for _, nm := range names {
+		st := 0
+		if len(kids) > 0 && rnd.Intn(2) == 0 {
+			st = rnd.Intn(len(kids) + 2)
+		}
+		if ix, lx := par.ChildIndexByName(nm, st), kids.IndexByName(nm, st); ix != lx {
+			t.Fatalf("%v: ChildIndexByName(%v, %v) = %v, linear search = %v", what, nm, st, ix, lx)
+		}
+		if ix, lx := par.ChildIndexByUniqueName(nm, st), kids.IndexByUniqueName(nm, st); ix != lx {
+			t.Fatalf("%v: ChildIndexByUniqueName(%v, %v) = %v, linear search = %v", what, nm, st, ix, lx)
+		}
+	}
+}
+
+func TestNameIndex(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	par := &Node{}
+	par.InitName(par, "par")
+	par.EnableNameIndex()
+	if par.NameIndex() == nil {
+		t.Fatalf("EnableNameIndex did not create index")
+	}
+	for i := 0; i < 50; i++ {
+		par.AddNewChild(nil, fmt.Sprintf("c%d", rnd.Intn(30))) // some duplicates
+	}
+	checkNameIndex(t, par, rnd, "add")
+
+	for i := 0; i < 300; i++ {
+		nk := len(par.Kids)
+		switch op := rnd.Intn(6); op {
+		case 0:
+			par.InsertNewChild(nil, rnd.Intn(nk+1), fmt.Sprintf("c%d", rnd.Intn(60)))
+		case 1:
+			if nk > 0 {
+				par.DeleteChildAtIndex(rnd.Intn(nk), true)
+			}
+		case 2:
+			if nk > 0 {
+				par.MoveChild(rnd.Intn(nk), rnd.Intn(nk))
+			}
+		case 3:
+			if nk > 0 {
+				par.Child(rnd.Intn(nk)).SetName(fmt.Sprintf("c%d", rnd.Intn(60)))
+			}
+		case 4:
+			if nk > 0 {
+				par.Child(rnd.Intn(nk)).SetNameRaw(fmt.Sprintf("r%d", rnd.Intn(10)))
+			}
+		case 5:
+			var config kit.TypeAndNameList
+			for j := 0; j < 20+rnd.Intn(40); j++ {
+				config.Add(KiT_Node, fmt.Sprintf("c%d", rnd.Intn(60)))
+			}
+			par.ConfigChildren(config, rnd.Intn(2) == 0)
+		}
+		checkNameIndex(t, par, rnd, fmt.Sprintf("op %v", i))
+	}
+
+	// unique names are unique
+	seen := make(map[string]bool)
+	for _, kid := range par.Kids {
+		if seen[kid.UniqueName()] {
+			t.Errorf("duplicate unique name: %v", kid.UniqueName())
+		}
+		seen[kid.UniqueName()] = true
+	}
+
+	// direct modification of Kids is detected
+	par.Kids = par.Kids[:len(par.Kids)/2]
+	checkNameIndex(t, par, rnd, "direct")
+
+	// loading rebuilds the index
+	b, err := par.SaveJSON(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := par.LoadJSON(b); err != nil {
+		t.Fatal(err)
+	}
+	checkNameIndex(t, par, rnd, "load")
+
+	par.DeleteChildren(true)
+	checkNameIndex(t, par, rnd, "delete all")
+	par.DisableNameIndex()
+	if par.NameIndex() != nil {
+		t.Errorf("DisableNameIndex did not remove index")
+	}
+}
+
+func TestNameIndexAuto(t *testing.T) {
+	par := &Node{}
+	par.InitName(par, "par")
+	var config kit.TypeAndNameList
+	for i := 0; i < NameIndexMin; i++ {
+		config.Add(KiT_Node, fmt.Sprintf("c%d", i))
+	}
+	par.ConfigChildren(config, false)
+	if par.NameIndex() == nil {
+		t.Fatalf("node with NameIndexMin children should have an index")
+	}
+	checkNameIndex(t, par, rand.New(rand.NewSource(2)), "auto")
+}
+
+func benchmarkConfig(b *testing.B, n int, index bool) {
+	min := NameIndexMin
+	defer func() { NameIndexMin = min }()
+	if !index {
+		NameIndexMin = 0
+	}
+	var config kit.TypeAndNameList
+	for i := 0; i < n; i++ {
+		config.Add(KiT_Node, fmt.Sprintf("c%d", i))
+	}
+	// shifted config: delete the first, add one at the end
+	shift := append(kit.TypeAndNameList{}, config[1:]...)
+	shift.Add(KiT_Node, fmt.Sprintf("c%d", n))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		par := &Node{}
+		par.InitName(par, "par")
+		if index {
+			par.EnableNameIndex()
+		}
+		par.ConfigChildren(config, false)
+		par.ConfigChildren(shift, false)
+	}
+}
+
+// BenchmarkConfigChildren shows how ConfigChildren scales with the number of
+// children, with and without the NameIndex
+func BenchmarkConfigChildren(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000} {
+		b.Run(fmt.Sprintf("linear-%d", n), func(b *testing.B) { benchmarkConfig(b, n, false) })
+		b.Run(fmt.Sprintf("index-%d", n), func(b *testing.B) { benchmarkConfig(b, n, true) })
+	}
+}
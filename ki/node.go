@@ -9,12 +9,15 @@ package ki
 
 import (
 	"bytes"
+	"context"
 	"encoding/gob"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"sync"
+	"sync/atomic"
 	"unsafe"
 
 	"log"
@@ -44,16 +47,22 @@ var UseJsonIter bool = false
 // -- Ki makes extensive use of such tags.
 //
 type Node struct {
-	Nm       string     `copy:"-" label:"Name" desc:"Ki.Name() user-supplied name of this node -- can be empty or non-unique"`
-	UniqueNm string     `copy:"-" view:"-" label:"UniqueName" desc:"Ki.UniqueName() automatically-updated version of Name that is guaranteed to be unique within the slice of Children within one Node -- used e.g., for saving Unique Paths in Ptr pointers"`
-	Flag     int64      `copy:"-" json:"-" xml:"-" view:"-" desc:"bit flags for internal node state"`
-	Props    Props      `xml:"-" copy:"-" label:"Properties" desc:"Ki.Properties() property map for arbitrary extensible properties, including style properties"`
-	Par      Ki         `copy:"-" json:"-" xml:"-" label:"Parent" view:"-" desc:"Ki.Parent() parent of this node -- set automatically when this node is added as a child of parent"`
-	Kids     Slice      `copy:"-" label:"Children" desc:"Ki.Children() list of children of this node -- all are set to have this node as their parent -- can reorder etc but generally use Ki Node methods to Add / Delete to ensure proper usage"`
-	NodeSig  Signal     `copy:"-" json:"-" xml:"-" desc:"Ki.NodeSignal() signal for node structure / state changes -- emits NodeSignals signals -- can also extend to custom signals (see signal.go) but in general better to create a new Signal instead"`
-	This     Ki         `copy:"-" json:"-" xml:"-" view:"-" desc:"we need a pointer to ourselves as a Ki, which can always be used to extract the true underlying type of object when Node is embedded in other structs -- function receivers do not have this ability so this is necessary"`
-	FlagMu   sync.Mutex `copy:"-" json:"-" xml:"-" view:"-" desc:"mutex protecting flag updates"`
-	index    int        `desc:"last value of our index -- used as a starting point for finding us in our parent next time -- is not guaranteed to be accurate!  use Index() method`
+	Nm        string           `copy:"-" label:"Name" desc:"Ki.Name() user-supplied name of this node -- can be empty or non-unique"`
+	UniqueNm  string           `copy:"-" view:"-" label:"UniqueName" desc:"Ki.UniqueName() automatically-updated version of Name that is guaranteed to be unique within the slice of Children within one Node -- used e.g., for saving Unique Paths in Ptr pointers"`
+	Uid       string           `copy:"-" json:",omitempty" xml:",omitempty" view:"-" label:"UUID" desc:"Ki.UUID() optional persistent universally-unique id of this node, used by Ref references, which survive moves and renames -- set by EnsureUUID when a Ref to this node is made -- not copied"`
+	Flag      int64            `copy:"-" json:"-" xml:"-" view:"-" desc:"bit flags for internal node state -- transient, so not saved -- persistent flags are in PFlag"`
+	PFlag     Flags            `json:",omitempty" xml:",omitempty" label:"Flags" desc:"bit flags for persistent settings of this node (see PersistFlagsMask), as opposed to the transient state in Flag -- saved in files as flag names, and editable in views"`
+	Props     Props            `xml:"-" copy:"-" label:"Properties" desc:"Ki.Properties() property map for arbitrary extensible properties, including style properties"`
+	Par       Ki               `copy:"-" json:"-" xml:"-" label:"Parent" view:"-" desc:"Ki.Parent() parent of this node -- set automatically when this node is added as a child of parent"`
+	Kids      Slice            `copy:"-" label:"Children" desc:"Ki.Children() list of children of this node -- all are set to have this node as their parent -- can reorder etc but generally use Ki Node methods to Add / Delete to ensure proper usage"`
+	NodeSig   Signal           `copy:"-" json:"-" xml:"-" desc:"Ki.NodeSignal() signal for node structure / state changes -- emits NodeSignals signals -- can also extend to custom signals (see signal.go) but in general better to create a new Signal instead"`
+	This      Ki               `copy:"-" json:"-" xml:"-" view:"-" desc:"we need a pointer to ourselves as a Ki, which can always be used to extract the true underlying type of object when Node is embedded in other structs -- function receivers do not have this ability so this is necessary"`
+	FlagMu    sync.Mutex       `copy:"-" json:"-" xml:"-" view:"-" desc:"mutex protecting flag updates"`
+	TreeMu    *sync.RWMutex    `copy:"-" json:"-" xml:"-" view:"-" desc:"optional mutex shared by all the nodes in the tree, for safe concurrent access to the tree -- see SetTreeMutex"`
+	index     int              `desc:"last value of our index -- used as a starting point for finding us in our parent next time -- is not guaranteed to be accurate!  use Index() method`
+	nameIdx   *NameIndex       `desc:"optional index of our children by name -- see NameIndex"`
+	childCons ChildConstraints `desc:"constraints on our children, set by SetChildConstraints -- not saved or copied"`
+	refIdx    *UUIDIndex       `desc:"index of our tree by UUID, cached when we are the root of a tree that is not registered with AddRefTree, for resolving Ref's"`
 }
 
 // must register all new types so type names can be looked up by name -- also props
@@ -146,6 +155,10 @@ func (n *Node) EmbeddedStruct(t reflect.Type) Ki {
 }
 
 func (n *Node) Parent() Ki {
+	if mu := n.TreeMu; mu != nil {
+		mu.RLock()
+		defer mu.RUnlock()
+	}
 	return n.Par
 }
 
@@ -162,79 +175,165 @@ func (n *Node) HasParent(par Ki) bool {
 }
 
 func (n *Node) Child(idx int) Ki {
+	if mu := n.TreeMu; mu != nil {
+		mu.RLock()
+		defer mu.RUnlock()
+	}
 	return n.Kids.Elem(idx)
 }
 
 func (n *Node) Children() Slice {
+	if mu := n.TreeMu; mu != nil {
+		mu.RLock()
+		defer mu.RUnlock()
+		return append(Slice(nil), n.Kids...) // copy, as Kids can be modified
+	}
 	return n.Kids
 }
 
 func (n *Node) IsValidIndex(idx int) bool {
+	if mu := n.TreeMu; mu != nil {
+		mu.RLock()
+		defer mu.RUnlock()
+	}
 	return n.Kids.IsValidIndex(idx)
 }
 
 func (n *Node) Name() string {
+	if mu := n.TreeMu; mu != nil {
+		mu.RLock()
+		defer mu.RUnlock()
+	}
 	return n.Nm
 }
 
 func (n *Node) UniqueName() string {
+	if mu := n.TreeMu; mu != nil {
+		mu.RLock()
+		defer mu.RUnlock()
+	}
 	return n.UniqueNm
 }
 
 // set name and unique name, ensuring unique name is unique..
 func (n *Node) SetName(name string) bool {
+	mu := n.TreeMu
+	if mu != nil {
+		mu.Lock()
+	}
 	if n.Nm == name {
+		if mu != nil {
+			mu.Unlock()
+		}
 		return false
 	}
+	rec := &UndoRec{Op: UndoSetName, Node: n.This, Key: n.UniqueNm, Old: n.Nm, New: name}
+	oldNm, oldUniqNm := n.Nm, n.UniqueNm
 	n.Nm = name
 	n.UniqueNm = name
-	if n.Par != nil {
-		n.Par.UniquifyNames()
+	par := n.Par
+	if mu != nil {
+		mu.Unlock()
+	}
+	recordEdit(rec)
+	if par != nil {
+		nameIndexRenamed(n.This, oldNm, oldUniqNm)
+		par.UniquifyNames()
 	}
 	return true
 }
 
+func (n *Node) UUID() string {
+	if mu := n.TreeMu; mu != nil {
+		mu.RLock()
+		defer mu.RUnlock()
+	}
+	return n.Uid
+}
+
+func (n *Node) SetUUID(uuid string) {
+	if mu := n.TreeMu; mu != nil {
+		mu.Lock()
+		defer mu.Unlock()
+	}
+	n.Uid = uuid
+}
+
+func (n *Node) EnsureUUID() string {
+	if mu := n.TreeMu; mu != nil {
+		mu.Lock()
+		defer mu.Unlock()
+	}
+	if n.Uid == "" {
+		n.Uid = NewUUID()
+	}
+	return n.Uid
+}
+
 func (n *Node) SetNameRaw(name string) {
+	mu := n.TreeMu
+	if mu != nil {
+		mu.Lock()
+	}
+	oldNm, par := n.Nm, n.Par
 	n.Nm = name
+	if mu != nil {
+		mu.Unlock()
+	}
+	if par != nil && oldNm != name {
+		nameIndexRenamed(n.This, oldNm, n.UniqueName())
+	}
 }
 
 func (n *Node) SetUniqueName(name string) {
+	mu := n.TreeMu
+	if mu != nil {
+		mu.Lock()
+	}
+	oldUniqNm, par := n.UniqueNm, n.Par
 	n.UniqueNm = name
+	if mu != nil {
+		mu.Unlock()
+	}
+	if par != nil && oldUniqNm != name {
+		nameIndexRenamed(n.This, n.Name(), oldUniqNm)
+	}
 }
 
-// make sure that the names are unique -- n^2 ish
+// make sure that the names are unique -- each child that has the same unique
+// name as a prior one gets a numbered suffix -- uses the NameIndex if we have
+// one to skip the whole process if there are no duplicates
 func (n *Node) UniquifyNames() {
+	if ix := n.NameIndex(); ix != nil && !ix.hasDups(n) {
+		return
+	}
 	pr := prof.Start("ki.Node.UniquifyNames")
-	for i, child := range n.Kids {
+	kids := n.Children()
+	par := n.Parent()
+	prior := make(map[string]struct{}, len(kids))
+	for i, child := range kids {
 		if len(child.UniqueName()) == 0 {
-			if n.Par != nil {
-				child.SetUniqueName(n.Par.UniqueName())
+			if par != nil {
+				child.SetUniqueName(par.UniqueName())
 			} else {
 				child.SetUniqueName(fmt.Sprintf("c%04d", i))
 			}
 		}
-		for { // changed
-			changed := false
-			for j := i - 1; j >= 0; j-- { // check all prior
-				if child.UniqueName() == n.Kids[j].UniqueName() {
-					if idx := strings.LastIndex(child.UniqueName(), "_"); idx >= 0 {
-						curnum, err := strconv.ParseInt(child.UniqueName()[idx+1:], 10, 64)
-						if err == nil { // it was a number
-							curnum++
-							child.SetUniqueName(child.UniqueName()[:idx+1] +
-								strconv.FormatInt(curnum, 10))
-							changed = true
-							break
-						}
-					}
-					child.SetUniqueName(child.UniqueName() + "_1")
-					changed = true
-					break
-				}
-			}
-			if !changed {
+		for {
+			unm := child.UniqueName()
+			if _, has := prior[unm]; !has {
+				prior[unm] = struct{}{}
 				break
 			}
+			if idx := strings.LastIndex(unm, "_"); idx >= 0 {
+				curnum, err := strconv.ParseInt(unm[idx+1:], 10, 64)
+				if err == nil { // it was a number
+					curnum++
+					child.SetUniqueName(unm[:idx+1] + strconv.FormatInt(curnum, 10))
+					continue
+				}
+			}
+			child.SetUniqueName(unm + "_1")
 		}
 	}
 	pr.End()
@@ -267,33 +366,61 @@ func (n *Node) ClearFlagMu(flag ...int) {
 
 func (n *Node) IsUpdatingMu() bool {
 	n.FlagMu.Lock()
-	rval := bitflag.Has(n.Flag, int(Updating))
+	rval := bitflag.HasAtomic(&n.Flag, int(Updating))
 	n.FlagMu.Unlock()
 	return rval
 }
 
 func (n *Node) IsUpdating() bool {
-	return bitflag.Has(n.Flag, int(Updating))
+	return bitflag.HasAtomic(&n.Flag, int(Updating))
 }
 
 func (n *Node) IsField() bool {
-	return bitflag.Has(n.Flag, int(IsField))
+	return bitflag.HasAtomic(&n.Flag, int(IsField))
 }
 
 func (n *Node) OnlySelfUpdate() bool {
-	return bitflag.Has(n.Flag, int(OnlySelfUpdate))
+	return bitflag.Has32(atomic.LoadInt32((*int32)(&n.PFlag)), int(OnlySelfUpdate))
 }
 
 func (n *Node) SetOnlySelfUpdate() {
-	bitflag.Set(&n.Flag, int(OnlySelfUpdate))
+	n.FlagMu.Lock()
+	pf := atomic.LoadInt32((*int32)(&n.PFlag))
+	bitflag.Set32(&pf, int(OnlySelfUpdate))
+	atomic.StoreInt32((*int32)(&n.PFlag), pf)
+	n.FlagMu.Unlock()
 }
 
 func (n *Node) IsDeleted() bool {
-	return bitflag.Has(n.Flag, int(NodeDeleted))
+	return bitflag.HasAtomic(&n.Flag, int(NodeDeleted))
 }
 
 func (n *Node) IsDestroyed() bool {
-	return bitflag.Has(n.Flag, int(NodeDestroyed))
+	return bitflag.HasAtomic(&n.Flag, int(NodeDestroyed))
+}
+
+//////////////////////////////////////////////////////////////////////////
+//  Tree mutex for concurrent access
+
+func (n *Node) TreeMutex() *sync.RWMutex {
+	return n.TreeMu
+}
+
+func (n *Node) SetTreeMutex(mu *sync.RWMutex) {
+	n.FuncDownMeFirst(0, nil, func(k Ki, level int, d interface{}) bool {
+		k.EmbeddedStruct(KiT_Node).(*Node).TreeMu = mu
+		return true
+	})
+}
+
+// thisLocked returns the This pointer, read-locked if there is a tree mutex,
+// as it is reset when the node is destroyed
+func (n *Node) thisLocked() Ki {
+	if mu := n.TreeMu; mu != nil {
+		mu.RLock()
+		defer mu.RUnlock()
+	}
+	return n.This
 }
 
 //////////////////////////////////////////////////////////////////////////
@@ -304,19 +431,39 @@ func (n *Node) Properties() Props {
 }
 
 func (n *Node) SetProp(key string, val interface{}) {
+	mu := n.TreeMu
+	if mu != nil {
+		mu.Lock()
+	}
 	if n.Props == nil {
 		n.Props = make(Props)
 	}
+	old, had := n.Props[key]
 	n.Props[key] = val
+	if mu != nil {
+		mu.Unlock()
+	}
+	recordEdit(&UndoRec{Op: UndoSetProp, Node: n.This, Key: key, Old: old, New: val, HadOld: had, HasNew: true})
 }
 
 func (n *Node) SetProps(props Props, update bool) {
+	mu := n.TreeMu
+	if mu != nil {
+		mu.Lock()
+	}
 	if n.Props == nil {
 		n.Props = make(Props)
 	}
+	recs := make([]*UndoRec, 0, len(props))
 	for key, val := range props {
+		old, had := n.Props[key]
+		recs = append(recs, &UndoRec{Op: UndoSetProp, Node: n.This, Key: key, Old: old, New: val, HadOld: had, HasNew: true})
 		n.Props[key] = val
 	}
+	if mu != nil {
+		mu.Unlock()
+	}
+	recordEdit(recs...)
 	if update {
 		bitflag.Set(n.Flags(), int(PropUpdated))
 		n.UpdateSig()
@@ -330,20 +477,26 @@ func (n *Node) SetPropUpdate(key string, val interface{}) {
 }
 
 func (n *Node) SetPropChildren(key string, val interface{}) {
-	for _, k := range n.Kids {
+	for _, k := range n.Children() {
 		k.SetProp(key, val)
 	}
 }
 
 func (n *Node) Prop(key string, inherit, typ bool) interface{} {
-	if n.Props != nil {
-		v, ok := n.Props[key]
-		if ok {
-			return v
-		}
+	mu := n.TreeMu
+	if mu != nil {
+		mu.RLock()
 	}
-	if inherit && n.Par != nil {
-		pv := n.Par.Prop(key, inherit, typ)
+	v, ok := n.Props[key]
+	par := n.Par
+	if mu != nil {
+		mu.RUnlock()
+	}
+	if ok {
+		return v
+	}
+	if inherit && par != nil {
+		pv := par.Prop(key, inherit, typ)
 		if pv != nil {
 			return pv
 		}
@@ -355,13 +508,28 @@ func (n *Node) Prop(key string, inherit, typ bool) interface{} {
 }
 
 func (n *Node) DeleteProp(key string) {
-	if n.Props == nil {
+	mu := n.TreeMu
+	if mu != nil {
+		mu.Lock()
+	}
+	old, had := n.Props[key]
+	if had {
+		delete(n.Props, key)
+	}
+	if mu != nil {
+		mu.Unlock()
+	}
+	if !had {
 		return
 	}
-	delete(n.Props, key)
+	recordEdit(&UndoRec{Op: UndoSetProp, Node: n.This, Key: key, Old: old, HadOld: true})
 }
 
 func (n *Node) DeleteAllProps(cap int) {
+	if mu := n.TreeMu; mu != nil {
+		mu.Lock()
+		defer mu.Unlock()
+	}
 	if n.Props != nil {
 		n.Props = make(Props, cap)
 	}
@@ -406,7 +574,18 @@ func (n *Node) CopyPropsFrom(from Ki, deep bool) error {
 
 // set parent of node -- does not remove from existing parent -- use Add / Insert / Delete
 func (n *Node) SetParent(parent Ki) {
-	n.Par = parent
+	if mu := n.TreeMu; mu != nil {
+		mu.Lock()
+		n.Par = parent
+		mu.Unlock()
+	} else {
+		n.Par = parent
+	}
+	if parent != nil {
+		if pmu := parent.TreeMutex(); pmu != nil && pmu != n.TreeMu {
+			n.SetTreeMutex(pmu) // join the tree
+		}
+	}
 	if parent != nil && !parent.OnlySelfUpdate() {
 		parup := parent.IsUpdating()
 		n.FuncDownMeFirst(0, nil, func(k Ki, level int, d interface{}) bool {
@@ -417,14 +596,15 @@ func (n *Node) SetParent(parent Ki) {
 }
 
 func (n *Node) IsRoot() bool {
-	return (n.Par == nil || !n.Par.ThisOk() || n.This == nil) // extra safe
+	par := n.Parent()
+	return (par == nil || !par.ThisOk() || n.This == nil) // extra safe
 }
 
 func (n *Node) Root() Ki {
 	if n.IsRoot() {
 		return n.This
 	}
-	return n.Par.Root()
+	return n.Parent().Root()
 }
 
 func (n *Node) FieldRoot() Ki {
@@ -448,14 +628,22 @@ func (n *Node) FieldRoot() Ki {
 }
 
 func (n *Node) HasChildren() bool {
+	if mu := n.TreeMu; mu != nil {
+		mu.RLock()
+		defer mu.RUnlock()
+	}
 	return len(n.Kids) > 0
 }
 
 func (n *Node) Index() int {
-	if n.Par == nil {
+	par := n.Parent()
+	if par == nil {
 		return -1
 	}
-	n.index = n.Par.ChildIndex(n.This, n.index) // very fast if index is close..
+	if n.TreeMu != nil { // cached index is not safe for concurrent use
+		return par.ChildIndex(n.This, 0)
+	}
+	n.index = par.ChildIndex(n.This, n.index) // very fast if index is close..
 	return n.index
 }
 
@@ -490,9 +678,14 @@ func (n *Node) addChildImplPost(kid Ki) {
 	if oldPar != nil {
 		oldPar.DeleteChild(kid, false)
 		bitflag.Set(kid.Flags(), int(ChildMoved))
+		if oldPar == n.This {
+			return
+		}
+		callOnRemoved(kid, oldPar)
 	} else {
 		bitflag.Set(kid.Flags(), int(ChildAdded))
 	}
+	callOnAdded(kid, n.This)
 }
 
 func (n *Node) AddChildImpl(kid Ki) error {
@@ -502,9 +695,25 @@ func (n *Node) AddChildImpl(kid Ki) error {
 	if err := n.AddChildCheck(kid); err != nil {
 		return err
 	}
+	if err := n.canAddKid(kid); err != nil {
+		return err
+	}
 	kid.Init(kid)
+	mu := n.TreeMu
+	if mu != nil && kid.TreeMutex() == nil {
+		kid.SetTreeMutex(mu) // join the tree before it is visible in Kids
+	}
+	if mu != nil {
+		mu.Lock()
+	}
 	n.Kids = append(n.Kids, kid)
+	idx := len(n.Kids) - 1
+	if mu != nil {
+		mu.Unlock()
+	}
+	recordEdit(&UndoRec{Op: UndoInsert, Node: n.This, Kid: kid, Idx: idx})
 	n.addChildImplPost(kid)
+	nameIndexAdded(n.This, kid, idx)
 	return nil
 }
 
@@ -515,9 +724,25 @@ func (n *Node) InsertChildImpl(kid Ki, at int) error {
 	if err := n.AddChildCheck(kid); err != nil {
 		return err
 	}
+	if err := n.canAddKid(kid); err != nil {
+		return err
+	}
 	kid.Init(kid)
+	mu := n.TreeMu
+	if mu != nil && kid.TreeMutex() == nil {
+		kid.SetTreeMutex(mu) // join the tree before it is visible in Kids
+	}
+	if mu != nil {
+		mu.Lock()
+	}
 	n.Kids.Insert(kid, at)
+	idx := n.Kids.Index(kid, at)
+	if mu != nil {
+		mu.Unlock()
+	}
+	recordEdit(&UndoRec{Op: UndoInsert, Node: n.This, Kid: kid, Idx: idx})
 	n.addChildImplPost(kid)
+	nameIndexAdded(n.This, kid, idx)
 	return nil
 }
 
@@ -576,6 +801,9 @@ func (n *Node) AddNewChild(typ reflect.Type, name string) Ki {
 		bitflag.Set(&n.Flag, int(ChildAdded))
 	}
 	n.UpdateEnd(updt)
+	if err != nil {
+		return nil
+	}
 	return kid
 }
 
@@ -588,6 +816,9 @@ func (n *Node) InsertNewChild(typ reflect.Type, at int, name string) Ki {
 		bitflag.Set(&n.Flag, int(ChildAdded))
 	}
 	n.UpdateEnd(updt)
+	if err != nil {
+		return nil
+	}
 	return kid
 }
 
@@ -601,13 +832,26 @@ func (n *Node) InsertNewChildUnique(typ reflect.Type, at int, name string) Ki {
 		bitflag.Set(&n.Flag, int(ChildAdded))
 	}
 	n.UpdateEnd(updt)
+	if err != nil {
+		return nil
+	}
 	return kid
 }
 
 func (n *Node) MoveChild(from, to int) error {
 	updt := n.UpdateStart()
+	mu := n.TreeMu
+	if mu != nil {
+		mu.Lock()
+	}
+	fi, _ := n.Kids.ValidIndex(from) // normalized indexes for undo
+	ti, _ := n.Kids.ValidIndex(to)
 	err := n.Kids.Move(from, to)
-	if err == nil {
+	if mu != nil {
+		mu.Unlock()
+	}
+	if err == nil && fi != ti {
+		recordEdit(&UndoRec{Op: UndoMove, Node: n.This, Idx: fi, ToIdx: ti})
 		bitflag.Set(&n.Flag, int(ChildMoved))
 	}
 	n.UpdateEnd(updt)
@@ -616,7 +860,7 @@ func (n *Node) MoveChild(from, to int) error {
 
 func (n *Node) SetNChildren(trgn int, typ reflect.Type, nameStub string) (mods, updt bool) {
 	mods, updt = false, false
-	sz := len(n.Kids)
+	sz := len(n.Children())
 	if trgn == sz {
 		return
 	}
@@ -641,6 +885,16 @@ func (n *Node) SetNChildren(trgn int, typ reflect.Type, nameStub string) (mods,
 }
 
 func (n *Node) ConfigChildren(config kit.TypeAndNameList, uniqNm bool) (mods, updt bool) {
+	if cons := n.ChildConstraints(); len(cons) > 0 {
+		typs := make([]reflect.Type, len(config))
+		for i, tn := range config {
+			typs[i] = tn.Type
+		}
+		if err := cons.Check(typs, true); err != nil {
+			log.Printf("Ki Node %v ConfigChildren: config not allowed: %v\n", n.PathUnique(), err)
+			return false, false
+		}
+	}
 	return n.Kids.Config(n.This, config, uniqNm)
 }
 
@@ -648,65 +902,79 @@ func (n *Node) ConfigChildren(config kit.TypeAndNameList, uniqNm bool) (mods, up
 //  Find child / parent by..
 
 func (n *Node) ChildIndexByFunc(startIdx int, match func(ki Ki) bool) int {
-	return n.Kids.IndexByFunc(startIdx, match)
+	kids := n.Children()
+	return kids.IndexByFunc(startIdx, match)
 }
 
 func (n *Node) ChildIndex(kid Ki, startIdx int) int {
-	return n.Kids.Index(kid, startIdx)
+	kids := n.Children()
+	return kids.Index(kid, startIdx)
 }
 
 func (n *Node) ChildIndexByName(name string, startIdx int) int {
-	return n.Kids.IndexByName(name, startIdx)
+	if ix := n.NameIndex(); ix != nil {
+		return ix.index(n, name, startIdx, false)
+	}
+	kids := n.Children()
+	return kids.IndexByName(name, startIdx)
 }
 
 func (n *Node) ChildIndexByUniqueName(name string, startIdx int) int {
-	return n.Kids.IndexByUniqueName(name, startIdx)
+	if ix := n.NameIndex(); ix != nil {
+		return ix.index(n, name, startIdx, true)
+	}
+	kids := n.Children()
+	return kids.IndexByUniqueName(name, startIdx)
 }
 
 func (n *Node) ChildIndexByType(t reflect.Type, embeds bool, startIdx int) int {
-	return n.Kids.IndexByType(t, embeds, startIdx)
+	kids := n.Children()
+	return kids.IndexByType(t, embeds, startIdx)
 }
 
 func (n *Node) ChildByName(name string, startIdx int) Ki {
-	idx := n.Kids.IndexByName(name, startIdx)
+	idx := n.ChildIndexByName(name, startIdx)
 	if idx < 0 {
 		return nil
 	}
-	return n.Kids[idx]
+	return n.Child(idx)
 }
 
 func (n *Node) ChildByType(t reflect.Type, embeds bool, startIdx int) Ki {
-	idx := n.Kids.IndexByType(t, embeds, startIdx)
+	kids := n.Children()
+	idx := kids.IndexByType(t, embeds, startIdx)
 	if idx < 0 {
 		return nil
 	}
-	return n.Kids[idx]
+	return kids[idx]
 }
 
 func (n *Node) ParentByName(name string) Ki {
 	if n.IsRoot() {
 		return nil
 	}
-	if n.Par.Name() == name {
-		return n.Par
+	par := n.Parent()
+	if par.Name() == name {
+		return par
 	}
-	return n.Par.ParentByName(name)
+	return par.ParentByName(name)
 }
 
 func (n *Node) ParentByType(t reflect.Type, embeds bool) Ki {
 	if n.IsRoot() {
 		return nil
 	}
+	par := n.Parent()
 	if embeds {
-		if n.Par.TypeEmbeds(t) {
-			return n.Par
+		if par.TypeEmbeds(t) {
+			return par
 		}
 	} else {
-		if n.Par.Type() == t {
-			return n.Par
+		if par.Type() == t {
+			return par
 		}
 	}
-	return n.Par.ParentByType(t, embeds)
+	return par.ParentByType(t, embeds)
 }
 
 func (n *Node) KiFieldByName(name string) Ki {
@@ -725,15 +993,26 @@ func (n *Node) KiFieldByName(name string) Ki {
 //  Deleting
 
 func (n *Node) DeleteChildAtIndex(idx int, destroy bool) {
+	mu := n.TreeMu
+	if mu != nil {
+		mu.RLock()
+	}
 	idx, err := n.Kids.ValidIndex(idx)
+	var child Ki
+	if err == nil {
+		child = n.Kids[idx]
+	}
+	if mu != nil {
+		mu.RUnlock()
+	}
 	if err != nil {
 		log.Print("Ki Node DeleteChildAtIndex -- attempt to delete item in empty children slice")
 		return
 	}
-	child := n.Kids[idx]
 	updt := n.UpdateStart()
 	bitflag.Set(&n.Flag, int(ChildDeleted))
-	if child.Parent() == n.This {
+	removed := child.Parent() == n.This
+	if removed {
 		// only deleting if we are still parent -- change parent first to
 		// signal move delete is always sent live to affected node without
 		// update blocking note: children of child etc will not send a signal
@@ -743,11 +1022,31 @@ func (n *Node) DeleteChildAtIndex(idx int, destroy bool) {
 		child.NodeSignal().Emit(child, int64(NodeSignalDeleting), nil)
 		child.SetParent(nil)
 	}
-	_ = n.Kids.DeleteAtIndex(idx)
-	if destroy {
+	if mu != nil {
+		mu.Lock()
+	}
+	if idx >= len(n.Kids) || n.Kids[idx] != child {
+		idx = n.Kids.Index(child, idx) // moved by a concurrent edit
+	}
+	if idx >= 0 {
+		_ = n.Kids.DeleteAtIndex(idx)
+	}
+	if mu != nil {
+		mu.Unlock()
+	}
+	if idx < 0 { // already deleted by a concurrent edit
+		n.UpdateEnd(updt)
+		return
+	}
+	nameIndexRemoved(n.This, child)
+	// if recorded, the undo history holds the child and destroys it later
+	if !recordEdit(&UndoRec{Op: UndoDelete, Node: n.This, Kid: child, Idx: idx, Destroy: destroy}) && destroy {
 		DelMgr.Add(child)
 	}
 	child.UpdateReset() // it won't get the UpdateEnd from us anymore -- init fresh in any case
+	if removed {
+		callOnRemoved(child, n.This)
+	}
 	n.UpdateEnd(updt)
 }
 
@@ -764,7 +1063,7 @@ func (n *Node) DeleteChildByName(name string, destroy bool) Ki {
 	if idx < 0 {
 		return nil
 	}
-	child := n.Kids[idx]
+	child := n.Child(idx)
 	n.DeleteChildAtIndex(idx, destroy)
 	return child
 }
@@ -772,16 +1071,34 @@ func (n *Node) DeleteChildByName(name string, destroy bool) Ki {
 func (n *Node) DeleteChildren(destroy bool) {
 	updt := n.UpdateStart()
 	bitflag.Set(&n.Flag, int(ChildrenDeleted))
-	for _, child := range n.Kids {
+	mu := n.TreeMu
+	if mu != nil {
+		mu.Lock()
+	}
+	kids := append(Slice(nil), n.Kids...)
+	n.Kids = n.Kids[:0] // preserves capacity of list
+	if mu != nil {
+		mu.Unlock()
+	}
+	if ix := n.NameIndex(); ix != nil {
+		ix.reset()
+	}
+	recs := make([]*UndoRec, 0, len(kids))
+	for i := len(kids) - 1; i >= 0; i-- {
+		recs = append(recs, &UndoRec{Op: UndoDelete, Node: n.This, Kid: kids[i], Idx: i, Destroy: destroy})
+	}
+	for _, child := range kids {
 		bitflag.Set(child.Flags(), int(NodeDeleted))
 		child.NodeSignal().Emit(child, int64(NodeSignalDeleting), nil)
 		child.SetParent(nil)
 		child.UpdateReset()
 	}
-	if destroy {
-		DelMgr.Add(n.Kids...)
+	if !recordEdit(recs...) && destroy {
+		DelMgr.Add(kids...)
+	}
+	for _, child := range kids {
+		callOnRemoved(child, n.This)
 	}
-	n.Kids = n.Kids[:0] // preserves capacity of list
 	n.UpdateEnd(updt)
 }
 
@@ -802,6 +1119,8 @@ func (n *Node) Destroy() {
 	}
 	n.NodeSig.Emit(n.This, int64(NodeSignalDestroying), nil)
 	bitflag.Set(&n.Flag, int(NodeDestroyed))
+	undoDetach(n.This)
+	changesDetach(n.This)
 	n.DisconnectAll()
 	n.DeleteChildren(true) // first delete all my children
 	// and destroy all my fields
@@ -811,6 +1130,10 @@ func (n *Node) Destroy() {
 	})
 	DelMgr.DestroyDeleted() // then destroy all those kids
 	// extra step to delete all the slices and maps -- super friendly to GC :)
+	if mu := n.TreeMu; mu != nil {
+		mu.Lock()
+		defer mu.Unlock()
+	}
 	FlatFieldsValueFunc(n.This, func(stru interface{}, typ reflect.Type, field reflect.StructField, fieldVal reflect.Value) bool {
 		if fieldVal.Kind() == reflect.Slice || fieldVal.Kind() == reflect.Map {
 			fieldVal.Set(reflect.Zero(fieldVal.Type())) // set to nil
@@ -823,22 +1146,38 @@ func (n *Node) Destroy() {
 //////////////////////////////////////////////////////////////////////////
 //  Tree walking and state updating
 
+// fieldOffs caches the offsets of the Ki fields of each type, for Fields --
+// guarded by a mutex as traversals can run concurrently
+var fieldOffs = struct {
+	sync.RWMutex
+	m map[reflect.Type][]uintptr
+}{m: make(map[reflect.Type][]uintptr)}
+
 func (n *Node) Fields() []uintptr {
-	// we store the offsets for the fields in type properties
-	tprops := kit.Types.Properties(n.Type(), true) // true = makeNew
-	pnm := "__FieldOffs"
-	if foff, ok := tprops[pnm]; ok {
-		return foff.([]uintptr)
+	return fieldOffsFor(n.This)
+}
+
+// fieldOffsFor returns the offsets of the Ki fields of given node, computing
+// them the first time for each type
+func fieldOffsFor(this Ki) []uintptr {
+	typ := reflect.TypeOf(this).Elem()
+	fieldOffs.RLock()
+	foff, ok := fieldOffs.m[typ]
+	fieldOffs.RUnlock()
+	if ok {
+		return foff
 	}
-	foff := make([]uintptr, 0)
+	foff = make([]uintptr, 0)
 	kitype := KiType()
-	FlatFieldsValueFunc(n.This, func(stru interface{}, typ reflect.Type, field reflect.StructField, fieldVal reflect.Value) bool {
+	FlatFieldsValueFunc(this, func(stru interface{}, typ reflect.Type, field reflect.StructField, fieldVal reflect.Value) bool {
 		if fieldVal.Kind() == reflect.Struct && kit.EmbeddedTypeImplements(field.Type, kitype) {
 			foff = append(foff, field.Offset)
 		}
 		return true
 	})
-	tprops[pnm] = foff
+	fieldOffs.Lock()
+	fieldOffs.m[typ] = foff
+	fieldOffs.Unlock()
 	return foff
 }
 
@@ -876,8 +1215,12 @@ func FlatFieldsValueFunc(stru interface{}, fun func(stru interface{}, typ reflec
 }
 
 func (n *Node) FuncFields(level int, data interface{}, fun Func) {
-	op := reflect.ValueOf(n.This).Pointer()
-	foffs := n.Fields()
+	this := n.thisLocked()
+	if this == nil {
+		return
+	}
+	op := reflect.ValueOf(this).Pointer()
+	foffs := fieldOffsFor(this)
 	for _, fo := range foffs {
 		fn := (*Node)(unsafe.Pointer(op + fo))
 		fun(fn.This, level, data)
@@ -885,8 +1228,12 @@ func (n *Node) FuncFields(level int, data interface{}, fun Func) {
 }
 
 func (n *Node) GoFuncFields(level int, data interface{}, fun Func) {
-	op := reflect.ValueOf(n.This).Pointer()
-	foffs := n.Fields()
+	this := n.thisLocked()
+	if this == nil {
+		return
+	}
+	op := reflect.ValueOf(this).Pointer()
+	foffs := fieldOffsFor(this)
 	for _, fo := range foffs {
 		fn := (*Node)(unsafe.Pointer(op + fo))
 		go fun(fn.This, level, data)
@@ -894,12 +1241,13 @@ func (n *Node) GoFuncFields(level int, data interface{}, fun Func) {
 }
 
 func (n *Node) FuncUp(level int, data interface{}, fun Func) bool {
-	if !fun(n.This, level, data) { // false return means stop
+	this := n.thisLocked()
+	if !fun(this, level, data) { // false return means stop
 		return false
 	}
 	level++
-	if n.Parent() != nil && n.Parent() != n.This { // prevent loops
-		return n.Parent().FuncUp(level, data, fun)
+	if par := n.Parent(); par != nil && par != this { // prevent loops
+		return par.FuncUp(level, data, fun)
 	}
 	return true
 }
@@ -908,15 +1256,20 @@ func (n *Node) FuncUpParent(level int, data interface{}, fun Func) bool {
 	if n.IsRoot() {
 		return true
 	}
-	if !fun(n.Parent(), level, data) { // false return means stop
+	par := n.Parent()
+	if !fun(par, level, data) { // false return means stop
 		return false
 	}
 	level++
-	return n.Parent().FuncUpParent(level, data, fun)
+	return par.FuncUpParent(level, data, fun)
 }
 
 func (n *Node) FuncDownMeFirst(level int, data interface{}, fun Func) bool {
-	if !fun(n.This, level, data) { // false return means stop
+	this := n.thisLocked()
+	if this == nil { // destroyed
+		return false
+	}
+	if !fun(this, level, data) { // false return means stop
 		return false
 	}
 	level++
@@ -945,7 +1298,9 @@ func (n *Node) FuncDownDepthFirst(level int, data interface{}, doChildTestFunc F
 		return true
 	})
 	level--
-	fun(n.This, level, data) // can't use the return value at this point
+	if this := n.thisLocked(); this != nil {
+		fun(this, level, data) // can't use the return value at this point
+	}
 }
 
 func (n *Node) FuncDownBreadthFirst(level int, data interface{}, fun Func) {
@@ -971,66 +1326,62 @@ func (n *Node) FuncDownBreadthFirst(level int, data interface{}, fun Func) {
 }
 
 func (n *Node) GoFuncDown(level int, data interface{}, fun Func) {
-	go fun(n.This, level, data)
-	level++
-	n.GoFuncFields(level, data, fun)
-	for _, child := range n.Children() {
-		child.GoFuncDown(level, data, fun)
-	}
+	go n.GoFuncDownWait(level, data, fun)
 }
 
 func (n *Node) GoFuncDownWait(level int, data interface{}, fun Func) {
-	// todo: use channel or something to wait
-	go fun(n.This, level, data)
-	level++
-	n.GoFuncFields(level, data, fun)
-	for _, child := range n.Children() {
-		child.GoFuncDown(level, data, fun)
+	this := n.thisLocked()
+	if this == nil {
+		return
 	}
+	ParallelFuncDown(context.Background(), this, 0, level, data, func(k Ki, level int, d interface{}) bool {
+		fun(k, level, d) // return value ignored, as for all Go versions
+		return true
+	})
 }
 
 func (n *Node) Path() string {
-	if n.Par != nil {
+	if mypar := n.Parent(); mypar != nil {
 		if n.IsField() {
-			return n.Par.Path() + "." + n.Nm
+			return mypar.Path() + "." + n.Name()
 		} else {
-			return n.Par.Path() + "/" + n.Nm
+			return mypar.Path() + "/" + n.Name()
 		}
 	}
-	return "/" + n.Nm
+	return "/" + n.Name()
 }
 
 func (n *Node) PathUnique() string {
-	if n.Par != nil {
+	if mypar := n.Parent(); mypar != nil {
 		if n.IsField() {
-			return n.Par.PathUnique() + "." + n.UniqueNm
+			return mypar.PathUnique() + "." + n.UniqueName()
 		} else {
-			return n.Par.PathUnique() + "/" + n.UniqueNm
+			return mypar.PathUnique() + "/" + n.UniqueName()
 		}
 	}
-	return "/" + n.UniqueNm
+	return "/" + n.UniqueName()
 }
 
 func (n *Node) PathFrom(par Ki) string {
-	if n.Par != nil && n.Par != par {
+	if mypar := n.Parent(); mypar != nil && mypar != par {
 		if n.IsField() {
-			return n.Par.PathFrom(par) + "." + n.Nm
+			return mypar.PathFrom(par) + "." + n.Name()
 		} else {
-			return n.Par.PathFrom(par) + "/" + n.Nm
+			return mypar.PathFrom(par) + "/" + n.Name()
 		}
 	}
-	return "/" + n.Nm
+	return "/" + n.Name()
 }
 
 func (n *Node) PathFromUnique(par Ki) string {
-	if n.Par != nil && n.Par != par {
+	if mypar := n.Parent(); mypar != nil && mypar != par {
 		if n.IsField() {
-			return n.Par.PathFromUnique(par) + "." + n.Nm
+			return mypar.PathFromUnique(par) + "." + n.Name()
 		} else {
-			return n.Par.PathFromUnique(par) + "/" + n.Nm
+			return mypar.PathFromUnique(par) + "/" + n.Name()
 		}
 	}
-	return "/" + n.Nm
+	return "/" + n.Name()
 }
 
 func (n *Node) FindPathUnique(path string) Ki {
@@ -1070,6 +1421,24 @@ func (n *Node) FindPathUnique(path string) Ki {
 	return curn
 }
 
+func (n *Node) FindAll(query string) Slice {
+	q, err := ParseQuery(query)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	return q.FindAll(n.This)
+}
+
+func (n *Node) FindFirst(query string) Ki {
+	q, err := ParseQuery(query)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	return q.FindFirst(n.This)
+}
+
 //////////////////////////////////////////////////////////////////////////
 //  State update signaling -- automatically consolidates all changes across
 //   levels so there is only one update at highest level of modification
@@ -1085,7 +1454,7 @@ func (n *Node) UpdateStart() bool {
 	if n.IsUpdatingMu() {
 		return false
 	}
-	if bitflag.Has(n.Flag, int(NodeDestroyed)) {
+	if n.IsDestroyed() {
 		return false
 	}
 	if n.OnlySelfUpdate() {
@@ -1101,6 +1470,8 @@ func (n *Node) UpdateStart() bool {
 			}
 		})
 	}
+	undoUpdateStart(n.This)
+	changesUpdateStart(n.This)
 	return true
 }
 
@@ -1108,21 +1479,23 @@ func (n *Node) UpdateEnd(updt bool) {
 	if !updt {
 		return
 	}
+	undoUpdateEnd(n.This)
+	defer changesSignaled(changesUpdateEnd(n.This), n.This)
 	if n.IsDestroyed() || n.IsDeleted() {
 		return
 	}
-	if bitflag.HasAny(n.Flag, int(ChildDeleted), int(ChildrenDeleted)) {
+	if bitflag.HasAny(atomic.LoadInt64(&n.Flag), int(ChildDeleted), int(ChildrenDeleted)) {
 		DelMgr.DestroyDeleted()
 	}
 	if n.OnlySelfUpdate() {
 		n.ClearFlagMu(int(Updating))
-		n.NodeSignal().Emit(n.This, int64(NodeSignalUpdated), n.Flag)
+		n.NodeSignal().Emit(n.This, int64(NodeSignalUpdated), atomic.LoadInt64(&n.Flag))
 	} else {
 		n.FuncDownMeFirst(0, nil, func(k Ki, level int, d interface{}) bool {
 			k.ClearFlagMu(int(Updating)) // todo: could check first and break here but good to ensure all clear
 			return true
 		})
-		n.NodeSignal().Emit(n.This, int64(NodeSignalUpdated), n.Flag)
+		n.NodeSignal().Emit(n.This, int64(NodeSignalUpdated), atomic.LoadInt64(&n.Flag))
 	}
 }
 
@@ -1130,10 +1503,12 @@ func (n *Node) UpdateEndNoSig(updt bool) {
 	if !updt {
 		return
 	}
+	undoUpdateEnd(n.This)
+	changesSignaled(changesUpdateEnd(n.This), n.This)
 	if n.IsDestroyed() || n.IsDeleted() {
 		return
 	}
-	if bitflag.HasAny(n.Flag, int(ChildDeleted), int(ChildrenDeleted)) {
+	if bitflag.HasAny(atomic.LoadInt64(&n.Flag), int(ChildDeleted), int(ChildrenDeleted)) {
 		DelMgr.DestroyDeleted()
 	}
 	if n.OnlySelfUpdate() {
@@ -1152,10 +1527,10 @@ func (n *Node) UpdateSig() bool {
 	if n.IsUpdatingMu() {
 		return false
 	}
-	if bitflag.Has(n.Flag, int(NodeDestroyed)) {
+	if n.IsDestroyed() {
 		return false
 	}
-	n.NodeSignal().Emit(n.This, int64(NodeSignalUpdated), n.Flag)
+	n.NodeSignal().Emit(n.This, int64(NodeSignalUpdated), atomic.LoadInt64(&n.Flag))
 	return true
 }
 
@@ -1172,6 +1547,10 @@ func (n *Node) UpdateReset() {
 
 func (n *Node) Disconnect() {
 	n.NodeSig.DisconnectAll()
+	if mu := n.TreeMu; mu != nil {
+		mu.Lock()
+		defer mu.Unlock()
+	}
 	FlatFieldsValueFunc(n.This, func(stru interface{}, typ reflect.Type, field reflect.StructField, fieldVal reflect.Value) bool {
 		switch {
 		case fieldVal.Kind() == reflect.Interface:
@@ -1211,8 +1590,21 @@ func (n *Node) SetField(field string, val interface{}) bool {
 		return false
 	}
 	updt := n.UpdateStart()
+	mu := n.TreeMu
+	if mu != nil {
+		mu.Lock()
+	}
+	var old interface{}
+	if fv.CanInterface() {
+		old = undoCopy(fv.Interface())
+	}
 	ok := kit.SetRobust(kit.PtrValue(fv).Interface(), val)
+	nw := undoCopy(fv.Interface())
+	if mu != nil {
+		mu.Unlock()
+	}
 	if ok {
+		recordEdit(&UndoRec{Op: UndoSetField, Node: n.This, Key: field, Old: old, New: nw})
 		bitflag.Set(n.Flags(), int(FieldUpdated))
 	}
 	n.UpdateEnd(updt)
@@ -1238,6 +1630,10 @@ func (n *Node) SetFieldUp(field string, val interface{}) {
 }
 
 func (n *Node) FieldByName(field string) interface{} {
+	if mu := n.TreeMu; mu != nil {
+		mu.RLock()
+		defer mu.RUnlock()
+	}
 	return kit.FlatFieldInterfaceByName(n.This, field)
 }
 
@@ -1265,6 +1661,7 @@ func (n *Node) CopyFrom(from Ki) error {
 		return err
 	}
 	updt := n.UpdateStart()
+	changesUnrecorded(n.This)
 	bitflag.Set(&n.Flag, int(NodeCopied))
 	sameTree := (n.Root() == from.Root())
 	from.GetPtrPaths()
@@ -1463,6 +1860,7 @@ func (n *Node) LoadJSON(b []byte) error {
 		log.Println(err)
 	}
 	updt := n.UpdateStart()
+	changesUnrecorded(n.This)
 	if UseJsonIter {
 		err = jsoniter.Unmarshal(b, n.This) // key use of this!
 	} else {
@@ -1470,6 +1868,8 @@ func (n *Node) LoadJSON(b []byte) error {
 	}
 	if err == nil {
 		n.UnmarshalPost()
+	} else {
+		takeMigratedTree(n.This) // drop records of failed load
 	}
 	bitflag.Set(&n.Flag, int(ChildAdded)) // this might not be set..
 	n.UpdateEnd(updt)
@@ -1485,6 +1885,130 @@ func (n *Node) LoadJSONFromFile(filename string) error {
 	return n.LoadJSON(b)
 }
 
+func (n *Node) SaveJSONTo(w io.Writer, opts *JSONOpts) error {
+	if err := n.ThisCheck(); err != nil {
+		log.Println(err)
+		return err
+	}
+	return SaveJSONTree(n.This, w, opts)
+}
+
+func (n *Node) LoadJSONFrom(r io.Reader) error {
+	var err error
+	if err = n.ThisCheck(); err != nil {
+		log.Println(err)
+		return err
+	}
+	updt := n.UpdateStart()
+	changesUnrecorded(n.This)
+	err = LoadJSONTree(n.This, r)
+	if err == nil {
+		n.UnmarshalPost()
+	} else {
+		takeMigratedTree(n.This) // drop records of failed load
+	}
+	bitflag.Set(&n.Flag, int(ChildAdded)) // this might not be set..
+	n.UpdateEnd(updt)
+	return err
+}
+
+func (n *Node) SaveBinary() ([]byte, error) {
+	if err := n.ThisCheck(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return SaveBinaryTree(n.This)
+}
+
+func (n *Node) SaveBinaryToFile(filename string) error {
+	b, err := n.SaveBinary()
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	err = ioutil.WriteFile(filename, b, 0644)
+	if err != nil {
+		log.Println(err)
+	}
+	return err
+}
+
+func (n *Node) LoadBinary(b []byte) error {
+	var err error
+	if err = n.ThisCheck(); err != nil {
+		log.Println(err)
+		return err
+	}
+	updt := n.UpdateStart()
+	changesUnrecorded(n.This)
+	err = LoadBinaryTree(n.This, b)
+	if err == nil {
+		n.UnmarshalPost()
+	}
+	bitflag.Set(&n.Flag, int(ChildAdded)) // this might not be set..
+	n.UpdateEnd(updt)
+	return err
+}
+
+func (n *Node) LoadBinaryFromFile(filename string) error {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	return n.LoadBinary(b)
+}
+
+func (n *Node) SaveYAML() ([]byte, error) {
+	if err := n.ThisCheck(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	b, err := n.SaveJSON(false)
+	if err != nil {
+		return nil, err
+	}
+	return YAMLFmJSON(b)
+}
+
+func (n *Node) LoadYAML(b []byte) error {
+	if err := n.ThisCheck(); err != nil {
+		log.Println(err)
+		return err
+	}
+	jb, err := JSONFmYAML(b)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	return n.LoadJSON(jb)
+}
+
+func (n *Node) SaveTOML() ([]byte, error) {
+	if err := n.ThisCheck(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	b, err := n.SaveJSON(false)
+	if err != nil {
+		return nil, err
+	}
+	return TOMLFmJSON(b)
+}
+
+func (n *Node) LoadTOML(b []byte) error {
+	if err := n.ThisCheck(); err != nil {
+		log.Println(err)
+		return err
+	}
+	jb, err := JSONFmTOML(b)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	return n.LoadJSON(jb)
+}
+
 func (n *Node) SaveXML(indent bool) ([]byte, error) {
 	if err := n.ThisCheck(); err != nil {
 		log.Println(err)
@@ -1504,6 +2028,7 @@ func (n *Node) LoadXML(b []byte) error {
 		return err
 	}
 	updt := n.UpdateStart()
+	changesUnrecorded(n.This)
 	err = xml.Unmarshal(b, n.This) // key use of this!
 	if err == nil {
 		n.UnmarshalPost()
@@ -1528,6 +2053,25 @@ func (n *Node) ParentAllChildren() {
 func (n *Node) UnmarshalPost() {
 	n.ParentAllChildren()
 	n.SetPtrsFmPaths()
+	if drs := ResolveRefs(n.This); len(drs) > 0 {
+		for _, dr := range drs {
+			log.Printf("Ki Node UnmarshalPost: %v\n", dr.Error())
+		}
+	}
+	n.FuncDownMeFirst(0, nil, func(k Ki, level int, d interface{}) bool {
+		if ix := k.NameIndex(); ix != nil { // children were replaced
+			ix.build(k.Children())
+		}
+		if h, ok := k.(OnLoadedHook); ok {
+			h.OnLoaded()
+		}
+		return true
+	})
+	if recs := takeMigratedTree(n.This); len(recs) > 0 {
+		if h, ok := n.This.(OnMigratedHook); ok {
+			h.OnMigrated(recs)
+		}
+	}
 }
 
 // Deleted manages all the deleted Ki elements, that are destined to then be
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/rcoreilly/goki/ki/kit"
@@ -436,6 +437,48 @@ func TestNodeXMLSave(t *testing.T) {
 	}
 }
 
+func TestNodePersistFlags(t *testing.T) {
+	parent := NodeEmbed{}
+	parent.InitName(&parent, "par1")
+	child := parent.AddNewChild(nil, "child1")
+	child.SetOnlySelfUpdate()
+	if !child.OnlySelfUpdate() || parent.OnlySelfUpdate() {
+		t.Errorf("OnlySelfUpdate not set on child only")
+	}
+
+	b, err := parent.SaveJSON(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"PFlag":"OnlySelfUpdate"`) {
+		t.Errorf("persistent flags not saved as names: %v", string(b))
+	}
+	tstload := NodeEmbed{}
+	tstload.InitName(&tstload, "")
+	if err := tstload.LoadJSON(b); err != nil {
+		t.Fatal(err)
+	}
+	if !tstload.Child(0).OnlySelfUpdate() || tstload.OnlySelfUpdate() {
+		t.Errorf("persistent flags not loaded from JSON")
+	}
+
+	b, err = parent.SaveXML(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "<PFlag>OnlySelfUpdate</PFlag>") {
+		t.Errorf("persistent flags not saved as names: %v", string(b))
+	}
+	tstload = NodeEmbed{}
+	tstload.InitName(&tstload, "")
+	if err := tstload.LoadXML(b); err != nil {
+		t.Fatal(err)
+	}
+	if !tstload.Child(0).OnlySelfUpdate() || tstload.OnlySelfUpdate() {
+		t.Errorf("persistent flags not loaded from XML")
+	}
+}
+
 //////////////////////////////////////////
 //  function calling
 
@@ -748,6 +791,116 @@ func TestNodeFieldJSonSave(t *testing.T) {
 	}
 }
 
+type NodeMigrate struct {
+	NodeEmbed
+	NewFld string
+	Scaled float64
+}
+
+var KiT_NodeMigrate = kit.Types.AddType(&NodeMigrate{}, nil)
+
+func (n *NodeMigrate) New() Ki { return &NodeMigrate{} }
+
+var _ = kit.Migrations.AddSchema(KiT_NodeMigrate, 2,
+	&kit.Migration{Version: 1, TypeName: "ki.NodeMigrateOld"},
+	&kit.Migration{Version: 2, Fields: map[string]string{"OldFld": "NewFld"}, Fun: func(m map[string]interface{}) error {
+		if v, ok := m["Scaled"].(float64); ok {
+			m["Scaled"] = v * 2
+		}
+		return nil
+	}})
+
+var nodeMigrateOldJSON = `{"Nm":"par1","UniqueNm":"par1","Props":null,"Kids":[{"n":1,"type":"ki.NodeMigrateOld","name":"child1"},
+{"Nm":"child1","UniqueNm":"child1","Props":{"__type:stru":"ki.NodeMigrateOld","stru":{"OldFld":"prop","Scaled":2}},"Kids":null,"Ptr":null,"Mbr1":"","Mbr2":0,"OldFld":"bloop","Scaled":1.5}],
+"Ptr":null,"Mbr1":"","Mbr2":0}`
+
+// NodeMigrated records the migrations applied in loading it
+type NodeMigrated struct {
+	NodeEmbed
+	Recs []kit.MigrationRecord `json:"-"`
+}
+
+var KiT_NodeMigrated = kit.Types.AddType(&NodeMigrated{}, nil)
+
+func (n *NodeMigrated) OnMigrated(recs []kit.MigrationRecord) {
+	n.Recs = append(n.Recs, recs...)
+}
+
+func TestNodeJSonMigrate(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		tstload := NodeMigrated{}
+		tstload.InitName(&tstload, "")
+		var err error
+		if stream {
+			err = tstload.LoadJSONFrom(strings.NewReader(nodeMigrateOldJSON))
+		} else {
+			err = tstload.LoadJSON([]byte(nodeMigrateOldJSON))
+		}
+		if err != nil {
+			t.Error(err)
+		}
+		kid, ok := tstload.Child(0).(*NodeMigrate)
+		if !ok {
+			t.Fatalf("migrated child should be of type NodeMigrate, was: %v", tstload.Child(0).Type())
+		}
+		if kid.NewFld != "bloop" || kid.Scaled != 3 {
+			t.Errorf("migrated child has wrong values: NewFld: %v Scaled: %v", kid.NewFld, kid.Scaled)
+		}
+		if pv, ok := kid.Prop("stru", false, false).(*NodeMigrate); !ok || pv.NewFld != "prop" || pv.Scaled != 4 {
+			t.Errorf("migrated prop has wrong value: %v", kid.Prop("stru", false, false))
+		}
+		if rep := tstload.Recs; len(rep) != 2 || rep[0].Name != "child1" || rep[1].Name != "stru" || rep[0].From != 0 || rep[0].To != 2 {
+			t.Errorf("migration report should have records for child1 then stru, was: %v", rep)
+		}
+
+		// re-saved at current version, so no further migration
+		b, _ := tstload.SaveJSON(false)
+		if !bytes.Contains(b, []byte(`"type":"ki.NodeMigrate@2"`)) {
+			t.Errorf("saved type name should include version: %v", string(b))
+		}
+		tstload2 := NodeMigrated{}
+		tstload2.InitName(&tstload2, "")
+		tstload2.LoadJSON(b)
+		if rep := tstload2.Recs; len(rep) != 0 || tstload2.Child(0).(*NodeMigrate).Scaled != 3 {
+			t.Errorf("current version should not be migrated: %v", rep)
+		}
+	}
+}
+
+func TestNodeXMLMigrate(t *testing.T) {
+	parent := NodeEmbed{}
+	parent.InitName(&parent, "par1")
+	kid := parent.AddNewChild(KiT_NodeMigrate, "child1").(*NodeMigrate)
+	kid.NewFld = "bloop"
+	kid.Scaled = 1.5
+	b, err := parent.SaveXML(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(b, []byte("<Type>ki.NodeMigrate@2</Type>")) {
+		t.Errorf("saved type name should include version: %v", string(b))
+	}
+
+	// as saved under the old type name, before the field rename
+	ob := bytes.Replace(b, []byte("ki.NodeMigrate@2"), []byte("ki.NodeMigrateOld"), 1)
+	ob = bytes.Replace(ob, []byte("NewFld>"), []byte("OldFld>"), 2)
+	tstload := NodeEmbed{}
+	tstload.InitName(&tstload, "")
+	if err := tstload.LoadXML(ob); err != nil {
+		t.Error(err)
+	}
+	nkid, ok := tstload.Child(0).(*NodeMigrate)
+	if !ok {
+		t.Fatalf("migrated child should be of type NodeMigrate, was: %v", tstload.Child(0).Type())
+	}
+	if nkid.NewFld != "bloop" || nkid.Scaled != 1.5 {
+		t.Errorf("migrated child has wrong values: NewFld: %v Scaled: %v", nkid.NewFld, nkid.Scaled)
+	}
+	if tb, _ := tstload.SaveXML(false); !bytes.Equal(tb, b) {
+		t.Errorf("migrated XML should save as current version:\n%v\n%v", string(tb), string(b))
+	}
+}
+
 func TestNodeFieldSet(t *testing.T) {
 	parent := NodeField2{}
 	parent.InitName(&parent, "par1")
@@ -7,9 +7,9 @@ import (
 	"strconv"
 )
 
-const _NodeSignals_name = "NodeSignalNilNodeSignalUpdatedNodeSignalDeletingNodeSignalDestroyingNodeSignalsN"
+const _NodeSignals_name = "NodeSignalNilNodeSignalUpdatedNodeSignalDeletingNodeSignalDestroyingNodeSignalChangedNodeSignalsN"
 
-var _NodeSignals_index = [...]uint8{0, 13, 30, 48, 68, 80}
+var _NodeSignals_index = [...]uint8{0, 13, 30, 48, 68, 85, 97}
 
 func (i NodeSignals) String() string {
 	if i < 0 || i >= NodeSignals(len(_NodeSignals_index)-1) {
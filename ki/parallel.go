@@ -0,0 +1,337 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Parallel traversals visit the nodes of a tree using a bounded pool of
+// worker goroutines sharing a queue of nodes to visit, instead of one
+// goroutine per node -- nodes are visited in the same MeFirst order as
+// FuncDownMeFirst within each branch (a node is always visited before its
+// fields and children), but different branches are visited concurrently, so
+// the functions must be safe for concurrent use -- if the tree can be
+// modified at the same time, use EnableTreeLock (see treelock.go).  The
+// whole traversal stops at the next node when the function returns false,
+// or when the context is cancelled, e.g., by the function itself -- unlike
+// FuncDownMeFirst, where false only stops processing that branch.  The nodes
+// already being visited by other workers are finished.
+
+// ErrFunc is a function to call on ki objects walking the tree, for
+// ParallelFuncDownErr -- return bool = false stops the whole traversal, and
+// any error is collected
+type ErrFunc func(ki Ki, level int, data interface{}) (bool, error)
+
+// ReduceFunc is the map function for ParallelReduce, called on each node to
+// get its value
+type ReduceFunc func(ki Ki, level int, data interface{}) interface{}
+
+// CombineFunc is the reduce function for ParallelReduce, combining two values
+type CombineFunc func(a, b interface{}) interface{}
+
+// NodeError is an error returned by the function for a given node in a
+// parallel traversal
+type NodeError struct {
+	Node Ki    `desc:"node for which the error was returned"`
+	Err  error `desc:"the error"`
+}
+
+func (ne *NodeError) Error() string {
+	return ne.Node.PathUnique() + ": " + ne.Err.Error()
+}
+
+// NodeErrors is the list of all the errors returned in a parallel traversal,
+// or by ValidateTree
+type NodeErrors []*NodeError
+
+func (ne NodeErrors) Error() string {
+	switch len(ne) {
+	case 0:
+		return "no errors"
+	case 1:
+		return ne[0].Error()
+	}
+	return fmt.Sprintf("%v (and %v more errors)", ne[0].Error(), len(ne)-1)
+}
+
+// ParallelFuncDown calls function on given node and all the way down to its
+// fields and children, and so on, using nWorkers goroutines (runtime.NumCPU()
+// if <= 0) -- level is incremented for the fields and children as in
+// FuncDownMeFirst, but if fun returns false then the whole traversal stops,
+// not just that branch -- returns nil when all nodes have been visited or fun
+// returned false, or ctx.Err() if the context is cancelled first
+func ParallelFuncDown(ctx context.Context, k Ki, nWorkers, level int, data interface{}, fun Func) error {
+	return ParallelFuncDownErr(ctx, k, nWorkers, level, data, func(k Ki, level int, d interface{}) (bool, error) {
+		return fun(k, level, d), nil
+	})
+}
+
+// ParallelFuncDownErr is ParallelFuncDown for a function that can return an
+// error -- errors do not stop the traversal (the function can return false
+// for that), and all of them are returned as NodeErrors, in the order they
+// occurred -- else returns ctx.Err() if the context is cancelled before all
+// nodes have been visited, or nil
+func ParallelFuncDownErr(ctx context.Context, k Ki, nWorkers, level int, data interface{}, fun ErrFunc) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	tctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var halted int32 // fun returned false
+	tp := newTravPool(tctx)
+	tp.visit = func(it *travItem) bool {
+		cont, err := fun(it.k, it.level, data)
+		if err != nil {
+			tp.addErr(it.k, err)
+		}
+		if !cont {
+			atomic.StoreInt32(&halted, 1)
+			cancel()
+		}
+		return cont
+	}
+	tp.run(k, nWorkers, level)
+	err := tp.err()
+	if err == context.Canceled && atomic.LoadInt32(&halted) == 1 && ctx.Err() == nil {
+		return nil
+	}
+	return err
+}
+
+// ParallelReduce is a parallel map-reduce over the tree: the value of each
+// node and all the nodes below it is fn called on the node, combined with
+// the values of each of its fields and then children in order -- i.e.,
+// combine(combine(fn(node), val(kid0)), val(kid1)) etc -- and the value of
+// given node is returned -- the values are computed in parallel using
+// nWorkers goroutines (runtime.NumCPU() if <= 0), and combined as soon as
+// all the values below a node are done, with the same ordering as a
+// sequential traversal, so combine only needs to be associative for the
+// result to not depend on the shape of the tree -- returns nil and ctx.Err()
+// if the context is cancelled before all nodes have been visited
+func ParallelReduce(ctx context.Context, k Ki, nWorkers, level int, data interface{}, fn ReduceFunc, combine CombineFunc) (interface{}, error) {
+	tp := newTravPool(ctx)
+	tp.combine = combine
+	tp.visit = func(it *travItem) bool {
+		it.val = fn(it.k, it.level, data)
+		return true
+	}
+	tp.run(k, nWorkers, level)
+	if err := tp.err(); err != nil {
+		return nil, err
+	}
+	if _, skip := tp.result.(reduceSkip); skip {
+		return nil, nil
+	}
+	return tp.result, nil
+}
+
+// travItem is one node to visit in a parallel traversal
+type travItem struct {
+	k     Ki
+	level int
+	par   *travItem     // item for parent node, for reduce
+	idx   int           // index within parent res
+	val   interface{}   // value of this node, for reduce
+	res   []interface{} // values of fields and children, for reduce
+	left  int32         // number of res values not yet done
+}
+
+// reduceSkip is the value passed up for a destroyed node in reduce, which is
+// skipped in combining
+type reduceSkip struct{}
+
+// travPool is a bounded pool of workers traversing a tree
+type travPool struct {
+	ctx     context.Context
+	visit   func(it *travItem) bool // visits node, returns true to visit kids
+	combine CombineFunc             // for reduce, else nil
+	result  interface{}             // reduce result
+	mu      sync.Mutex              // protects all below
+	cond    *sync.Cond              // signaled when queue or pending changes
+	queue   []*travItem             // nodes waiting to be visited
+	pending int                     // number of nodes queued or being visited
+	stop    bool                    // context was cancelled
+	errs    NodeErrors
+}
+
+func newTravPool(ctx context.Context) *travPool {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	tp := &travPool{ctx: ctx}
+	tp.cond = sync.NewCond(&tp.mu)
+	return tp
+}
+
+// run visits all the nodes starting at k using nWorkers workers, and returns
+// when done or cancelled
+func (tp *travPool) run(k Ki, nWorkers, level int) {
+	if nWorkers <= 0 {
+		nWorkers = runtime.NumCPU()
+	}
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-tp.ctx.Done():
+			tp.mu.Lock()
+			tp.stop = true
+			tp.cond.Broadcast()
+			tp.mu.Unlock()
+		case <-done:
+		}
+	}()
+	tp.push(&travItem{k: k, level: level})
+	var wg sync.WaitGroup
+	wg.Add(nWorkers)
+	for i := 0; i < nWorkers; i++ {
+		go tp.worker(&wg)
+	}
+	wg.Wait()
+	close(done)
+}
+
+// worker visits nodes from the queue until there are none left
+func (tp *travPool) worker(wg *sync.WaitGroup) {
+	defer wg.Done()
+	for {
+		it := tp.next()
+		if it == nil {
+			return
+		}
+		tp.process(it)
+		tp.mu.Lock()
+		tp.pending--
+		if tp.pending == 0 {
+			tp.cond.Broadcast()
+		}
+		tp.mu.Unlock()
+	}
+}
+
+// next returns the next node to visit, waiting for one if others are still
+// being visited -- nil if all done or cancelled
+func (tp *travPool) next() *travItem {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	for len(tp.queue) == 0 && tp.pending > 0 && !tp.stop {
+		tp.cond.Wait()
+	}
+	if tp.ctx.Err() != nil {
+		tp.stop = true // don't wait for the watcher to notice
+	}
+	if tp.stop || len(tp.queue) == 0 {
+		return nil
+	}
+	// last in, first out, so the traversal is depth-first and the queue stays small
+	last := len(tp.queue) - 1
+	it := tp.queue[last]
+	tp.queue[last] = nil
+	tp.queue = tp.queue[:last]
+	return it
+}
+
+// push adds nodes to the queue
+func (tp *travPool) push(its ...*travItem) {
+	tp.mu.Lock()
+	tp.pending += len(its)
+	// pushed in reverse so that they are visited in order
+	for i := len(its) - 1; i >= 0; i-- {
+		tp.queue = append(tp.queue, its[i])
+	}
+	if len(its) == 1 {
+		tp.cond.Signal()
+	} else {
+		tp.cond.Broadcast()
+	}
+	tp.mu.Unlock()
+}
+
+// process visits given node and queues its fields and children
+func (tp *travPool) process(it *travItem) {
+	if it.k.IsDestroyed() {
+		if tp.combine != nil {
+			it.val = reduceSkip{}
+			tp.reduced(it)
+		}
+		return
+	}
+	if !tp.visit(it) {
+		if tp.combine != nil {
+			tp.reduced(it)
+		}
+		return
+	}
+	var kids []*travItem
+	add := func(k Ki) {
+		kids = append(kids, &travItem{k: k, level: it.level + 1, par: it, idx: len(kids)})
+	}
+	it.k.FuncFields(it.level+1, nil, func(k Ki, level int, d interface{}) bool {
+		add(k)
+		return true
+	})
+	for _, k := range it.k.Children() {
+		add(k)
+	}
+	if tp.combine != nil {
+		it.res = make([]interface{}, len(kids))
+		it.left = int32(len(kids))
+		if len(kids) == 0 {
+			tp.reduced(it)
+			return
+		}
+	}
+	if len(kids) > 0 {
+		tp.push(kids...)
+	}
+}
+
+// reduced is called for reduce when the values of all fields and children
+// of given node are done -- combines them with the node value, and passes
+// that up to the parent
+func (tp *travPool) reduced(it *travItem) {
+	for ; it != nil; it = it.par {
+		val := it.val
+		for _, r := range it.res {
+			if _, skip := r.(reduceSkip); !skip {
+				val = tp.combine(val, r)
+			}
+		}
+		it.res = nil
+		if it.par == nil {
+			tp.result = val
+			return
+		}
+		it.par.res[it.idx] = val
+		if atomic.AddInt32(&it.par.left, -1) != 0 {
+			return // others still to do
+		}
+	}
+}
+
+// addErr records an error for given node
+func (tp *travPool) addErr(k Ki, err error) {
+	tp.mu.Lock()
+	tp.errs = append(tp.errs, &NodeError{Node: k, Err: err})
+	tp.mu.Unlock()
+}
+
+// err returns the errors, if any, else the context error if the traversal
+// was cancelled before visiting all nodes
+func (tp *travPool) err() error {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	if len(tp.errs) > 0 {
+		return tp.errs
+	}
+	if tp.stop && tp.pending > 0 {
+		return tp.ctx.Err()
+	}
+	return nil
+}
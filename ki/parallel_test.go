@@ -0,0 +1,146 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// parallelTestTree makes a tree with nper children per node down to depth
+// levels below the root
+func parallelTestTree(nper, depth int) *Node {
+	root := &Node{}
+	root.InitName(root, "root")
+	var addKids func(par Ki, lev int)
+	addKids = func(par Ki, lev int) {
+		if lev == depth {
+			return
+		}
+		for i := 0; i < nper; i++ {
+			kid := par.AddNewChild(nil, fmt.Sprintf("%v_%v", par.Name(), i))
+			addKids(kid, lev+1)
+		}
+	}
+	addKids(root, 0)
+	return root
+}
+
+func TestParallelFuncDown(t *testing.T) {
+	root := parallelTestTree(10, 4) // 11111 nodes
+	nnodes := 0
+	root.FuncDownMeFirst(0, nil, func(k Ki, level int, d interface{}) bool {
+		nnodes++
+		return true
+	})
+	if nnodes != 11111 {
+		t.Fatalf("tree should have 11111 nodes, has: %v", nnodes)
+	}
+
+	var cnt, lvls int64
+	err := ParallelFuncDown(context.Background(), root, 4, 0, nil, func(k Ki, level int, d interface{}) bool {
+		atomic.AddInt64(&cnt, 1)
+		atomic.AddInt64(&lvls, int64(level))
+		return true
+	})
+	if err != nil || cnt != 11111 || lvls != 10+200+3000+40000 {
+		t.Errorf("ParallelFuncDown visited %v nodes, level sum %v, err: %v", cnt, lvls, err)
+	}
+
+	// false return stops the whole traversal, not just the branch
+	cnt = 0
+	err = ParallelFuncDown(nil, root, 1, 0, nil, func(k Ki, level int, d interface{}) bool {
+		return atomic.AddInt64(&cnt, 1) < 100
+	})
+	if err != nil || cnt != 100 {
+		t.Errorf("ParallelFuncDown stopped by false visited %v nodes, should be 100, err: %v", cnt, err)
+	}
+	cnt = 0
+	err = ParallelFuncDown(nil, root, 4, 0, nil, func(k Ki, level int, d interface{}) bool {
+		atomic.AddInt64(&cnt, 1)
+		return level < 2
+	})
+	if err != nil || cnt >= 111 {
+		t.Errorf("ParallelFuncDown stopped by false visited %v nodes, err: %v", cnt, err)
+	}
+
+	// errors are collected
+	err = ParallelFuncDownErr(context.Background(), root, 0, 0, nil, func(k Ki, level int, d interface{}) (bool, error) {
+		if level == 1 {
+			return true, errors.New("level 1")
+		}
+		return true, nil
+	})
+	nerrs, ok := err.(NodeErrors)
+	if !ok || len(nerrs) != 10 || nerrs[0].Err.Error() != "level 1" || !strings.HasSuffix(nerrs[0].Error(), ": level 1") {
+		t.Errorf("ParallelFuncDownErr errors wrong: %v", err)
+	}
+
+	// cancellation from within function
+	cnt = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	err = ParallelFuncDown(ctx, root, 4, 0, nil, func(k Ki, level int, d interface{}) bool {
+		if atomic.AddInt64(&cnt, 1) == 100 {
+			cancel()
+		}
+		return true
+	})
+	if err != context.Canceled || cnt >= 11111 {
+		t.Errorf("cancelled ParallelFuncDown visited %v nodes, err: %v", cnt, err)
+	}
+
+	// GoFuncDownWait keeps the plain Func semantics
+	cnt = 0
+	root.GoFuncDownWait(0, nil, func(k Ki, level int, d interface{}) bool {
+		atomic.AddInt64(&cnt, 1)
+		return false
+	})
+	if cnt != 11111 {
+		t.Errorf("GoFuncDownWait visited %v nodes, should be 11111", cnt)
+	}
+}
+
+func TestParallelReduce(t *testing.T) {
+	root := parallelTestTree(10, 4)
+	sum, err := ParallelReduce(context.Background(), root, 8, 0, nil, func(k Ki, level int, d interface{}) interface{} {
+		return 1
+	}, func(a, b interface{}) interface{} {
+		return a.(int) + b.(int)
+	})
+	if err != nil || sum != 11111 {
+		t.Errorf("ParallelReduce count was %v, err: %v", sum, err)
+	}
+
+	// order of combining is the same as sequential traversal
+	root = parallelTestTree(4, 3)
+	seq := ""
+	root.FuncDownMeFirst(0, nil, func(k Ki, level int, d interface{}) bool {
+		seq += k.Name() + " "
+		return true
+	})
+	names, err := ParallelReduce(context.Background(), root, 4, 0, nil, func(k Ki, level int, d interface{}) interface{} {
+		return k.Name() + " "
+	}, func(a, b interface{}) interface{} {
+		return a.(string) + b.(string)
+	})
+	if err != nil || names != seq {
+		t.Errorf("ParallelReduce names:\n%v\nnot same as sequential:\n%v\n", names, seq)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res, err := ParallelReduce(ctx, root, 4, 0, nil, func(k Ki, level int, d interface{}) interface{} {
+		return 1
+	}, func(a, b interface{}) interface{} {
+		return a.(int) + b.(int)
+	})
+	if res != nil || err != context.Canceled {
+		t.Errorf("cancelled ParallelReduce should return nil, context.Canceled, was: %v, %v", res, err)
+	}
+}
@@ -66,7 +66,7 @@ func (p Props) MarshalJSON() ([]byte, error) {
 		vt := kit.NonPtrType(reflect.TypeOf(val))
 		vk := vt.Kind()
 		if vk == reflect.Struct {
-			knm := kit.FullTypeName(vt)
+			knm := kit.Migrations.SavedTypeName(vt)
 			tstr := fmt.Sprintf("\"%v%v\": \"%v\",", struTypeKey, key, knm)
 			b = append(b, []byte(tstr)...)
 		}
@@ -104,7 +104,8 @@ func (p Props) MarshalJSON() ([]byte, error) {
 
 // UnmarshalJSON parses the type information in the map to restore actual
 // objects -- this is super inefficient and really needs a native parser, but
-// props are likely to be relatively small
+// props are likely to be relatively small -- structs saved with an older
+// schema version or type name are upgraded using kit.Migrations
 func (p *Props) UnmarshalJSON(b []byte) error {
 	// fmt.Printf("json in: %v\n", string(b))
 	if bytes.Equal(b, []byte("null")) {
@@ -128,11 +129,18 @@ func (p *Props) UnmarshalJSON(b []byte) error {
 			pkey := strings.TrimLeft(key, struTypeKey)
 			rval := tmp[pkey]
 			tn := val.(string)
-			typ := kit.Types.Type(tn)
+			typ, _ := kit.Migrations.Type(tn)
 			if typ == nil {
 				log.Printf("ki.Props: cannot load struct of type %v -- not registered in kit.Types\n", tn)
 				continue
 			}
+			if rm, ok := rval.(map[string]interface{}); ok && kit.Migrations.NeedsMigrate(typ, tn) {
+				rec, err := kit.Migrations.Migrate(typ, tn, pkey, rm)
+				if err != nil {
+					log.Printf("ki.Props: migration error for struct of type %v: %v\n", typ.String(), err)
+				}
+				addMigrated(p, rec)
+			}
 			if IsKi(typ) { // note: not really a good idea to store ki's in maps, but..
 				kival := NewOfType(typ)
 				kival.Init(kival)
@@ -175,6 +183,7 @@ func (p *Props) UnmarshalJSON(b []byte) error {
 			if err != nil {
 				log.Printf("ki.Props failed to load sub-Props with error: %v\n", err)
 			}
+			addMigrated(p, takeMigrated(&subp)...)
 			(*p)[key] = subp
 		} else { // straight copy
 			if sval, ok := val.(string); ok {
@@ -183,7 +192,8 @@ func (p *Props) UnmarshalJSON(b []byte) error {
 					rpi := strings.Index(tn, ")")
 					str := tn[rpi+1:]
 					tn = tn[1:rpi]
-					etyp := kit.Enums.Enum(tn)
+					ctn, _ := kit.Migrations.TypeName(tn)
+					etyp := kit.Enums.Enum(ctn)
 					if etyp != nil {
 						eval := kit.EnumIfaceFromString(str, etyp)
 						(*p)[key] = eval
@@ -0,0 +1,492 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rcoreilly/goki/ki/kit"
+)
+
+// Query is a parsed path query for finding nodes in a Ki tree -- see
+// ParseQuery for the syntax
+type Query struct {
+	Abs   bool        `desc:"query is absolute (starts with /) -- first step matches the root of the tree -- otherwise first step matches children of the node the query is run on"`
+	Steps []*Selector `desc:"selectors for each step of the path"`
+}
+
+// Selector selects nodes at one step of a Query path -- it uses the same
+// grammar as CSS selectors for styling (type, .class, #name) so it can be
+// used to match style selectors as well -- see ParseQuery for the syntax
+type Selector struct {
+	Any      bool      `desc:"* -- matches any node"`
+	AnyDepth bool      `desc:"** -- matches the node and all of its descendants, at any depth"`
+	Ident    string    `desc:"bare identifier -- matches node name, or type name (case insensitive) of the node type or any type it embeds"`
+	Preds    []SelPred `desc:"additional predicates that must all match, applied in order"`
+}
+
+// SelPredKinds are the kinds of predicates in a Selector
+type SelPredKinds int32
+
+const (
+	// SelClass is .class -- matches if the "class" property or Class field
+	// contains Val as one of its space-separated words (case insensitive)
+	SelClass SelPredKinds = iota
+
+	// SelName is #name -- matches node name (case insensitive), as in CSS
+	// styling
+	SelName
+
+	// SelIndex is [Idx] -- selects the Idx'th node of those matched so far
+	// among the children of each parent -- negative counts from the end
+	SelIndex
+
+	// SelAttr is [Key Op Val] -- compares name, type, or a property or
+	// field (.key) to Val using Op (=, !=, ~= for regexp match) -- with no
+	// Op, matches if the property or field exists
+	SelAttr
+
+	SelPredKindsN
+)
+
+//go:generate stringer -type=SelPredKinds
+
+var KiT_SelPredKinds = kit.Enums.AddEnum(SelPredKindsN, false, nil)
+
+// SelPred is one predicate in a Selector
+type SelPred struct {
+	Kind SelPredKinds   `desc:"kind of predicate"`
+	Key  string         `desc:"for SelAttr: name, type, or .key for property or field"`
+	Op   string         `desc:"for SelAttr: =, !=, ~=, or empty for existence"`
+	Val  string         `desc:"value to compare to"`
+	Idx  int            `desc:"for SelIndex: index"`
+	Re   *regexp.Regexp `desc:"for ~= op: compiled regexp of Val"`
+}
+
+// ParseQuery parses a path query for finding nodes in a Ki tree.  A query is
+// a list of selectors separated by /, matching nodes at successive levels
+// of the tree (Ki fields are included as children, ahead of the Children
+// list, as in FuncDownMeFirst).  A query starting with / is absolute: its
+// first selector matches the root of the tree.  Otherwise, its first
+// selector matches the children of the node the query is run on.  Each
+// selector is (all parts optional, but at least one is required):
+//
+//	ident          matches node name, or type name (case insensitive) of the
+//	               node's type or any type it embeds (e.g., Button, button)
+//	*              matches any node
+//	**             (by itself) matches the node and all its descendants
+//	.class         "class" property or Class field contains class word
+//	#name          node name is name
+//	[name=val]     name is exactly val -- also != for not equal, ~= for
+//	               regexp match
+//	[type=val]     type or any embedded type has kit.Types name val (e.g.,
+//	               gi.Button), or short name val -- also !=, ~=
+//	[.key]         property (or else field) key exists and is non-nil
+//	[.key=val]     property (or else field) key has value val -- also !=, ~=
+//	[n]            n'th node of those selected so far, among the children of
+//	               each parent -- negative from end, e.g., [-1] is last
+//
+// The .class and #name are case insensitive, as in the CSS styling of gi
+// nodes, which lower-cases class and node names (see StyleCSSWidget), so a
+// selector matches the same nodes in both -- use [name=val] for an exact
+// match.  Values can be quoted with ' or " to include special chars.
+// Predicates are applied in order, so e.g., Button[0] is the first Button child, while
+// *[0][type=Button] is the first child if it is a Button.  Example:
+// /win/**/Button[.class=primary] finds all Buttons with class primary
+// anywhere under the root node named (or of type) win.
+func ParseQuery(query string) (*Query, error) {
+	query = strings.TrimSpace(query)
+	q := &Query{}
+	if strings.HasPrefix(query, "/") {
+		q.Abs = true
+		query = query[1:]
+	}
+	if query == "" {
+		return nil, fmt.Errorf("ki.ParseQuery: empty query")
+	}
+	steps, err := querySplit(query)
+	if err != nil {
+		return nil, err
+	}
+	for _, st := range steps {
+		sel, err := ParseSelector(st)
+		if err != nil {
+			return nil, err
+		}
+		q.Steps = append(q.Steps, sel)
+	}
+	return q, nil
+}
+
+// ParseSelector parses one selector step of a query -- see ParseQuery for
+// the syntax
+func ParseSelector(sel string) (*Selector, error) {
+	s := &Selector{}
+	str := strings.TrimSpace(sel)
+	if str == "" {
+		return nil, fmt.Errorf("ki.ParseSelector: empty selector in query")
+	}
+	if str == "**" {
+		s.AnyDepth = true
+		return s, nil
+	}
+	pos := 0
+	if str[0] == '*' {
+		s.Any = true
+		pos++
+	} else {
+		s.Ident = queryIdent(str, &pos)
+	}
+	for pos < len(str) {
+		c := str[pos]
+		pos++
+		switch c {
+		case '.', '#':
+			id := queryIdent(str, &pos)
+			if id == "" {
+				return nil, fmt.Errorf("ki.ParseSelector: %v: missing name after %c", sel, c)
+			}
+			if c == '.' {
+				s.Preds = append(s.Preds, SelPred{Kind: SelClass, Val: id})
+			} else {
+				s.Preds = append(s.Preds, SelPred{Kind: SelName, Val: id})
+			}
+		case '[':
+			end := queryBracketEnd(str, pos-1)
+			if end < 0 {
+				return nil, fmt.Errorf("ki.ParseSelector: %v: missing ]", sel)
+			}
+			pr, err := parseSelPred(str[pos:end])
+			if err != nil {
+				return nil, fmt.Errorf("ki.ParseSelector: %v: %v", sel, err)
+			}
+			s.Preds = append(s.Preds, pr)
+			pos = end + 1
+		default:
+			return nil, fmt.Errorf("ki.ParseSelector: %v: unexpected character: %c", sel, c)
+		}
+	}
+	return s, nil
+}
+
+// parseSelPred parses the contents of a [ ] predicate
+func parseSelPred(str string) (SelPred, error) {
+	str = strings.TrimSpace(str)
+	if idx, err := strconv.Atoi(str); err == nil {
+		return SelPred{Kind: SelIndex, Idx: idx}, nil
+	}
+	pr := SelPred{Kind: SelAttr}
+	if oi := strings.Index(str, "="); oi > 0 {
+		pr.Key = strings.TrimSpace(str[:oi])
+		pr.Op = "="
+		if c := pr.Key[len(pr.Key)-1]; c == '!' || c == '~' {
+			pr.Op = string(c) + "="
+			pr.Key = strings.TrimSpace(pr.Key[:len(pr.Key)-1])
+		}
+		pr.Val = queryUnquote(strings.TrimSpace(str[oi+1:]))
+	} else {
+		pr.Key = str
+	}
+	switch {
+	case pr.Key == "name" || pr.Key == "type":
+		if pr.Op == "" {
+			return pr, fmt.Errorf("predicate [%v] requires a value", str)
+		}
+	case strings.HasPrefix(pr.Key, ".") && len(pr.Key) > 1:
+	default:
+		return pr, fmt.Errorf("predicate [%v] must be an index, name, type, or .key", str)
+	}
+	if pr.Op == "~=" {
+		re, err := regexp.Compile(pr.Val)
+		if err != nil {
+			return pr, err
+		}
+		pr.Re = re
+	}
+	return pr, nil
+}
+
+// querySplit splits query into steps at / outside of [ ] and quotes
+func querySplit(query string) ([]string, error) {
+	var steps []string
+	st := 0
+	for i := 0; i < len(query); i++ {
+		switch query[i] {
+		case '[':
+			end := queryBracketEnd(query, i)
+			if end < 0 {
+				return nil, fmt.Errorf("ki.ParseQuery: %v: missing ]", query)
+			}
+			i = end
+		case '/':
+			steps = append(steps, query[st:i])
+			st = i + 1
+		}
+	}
+	return append(steps, query[st:]), nil
+}
+
+// queryBracketEnd returns the index of the ] matching the [ at given
+// position, skipping quoted strings -- -1 if not found
+func queryBracketEnd(str string, st int) int {
+	var quote byte
+	for i := st + 1; i < len(str); i++ {
+		c := str[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+// queryIdent reads an identifier starting at pos, advancing pos past it
+func queryIdent(str string, pos *int) string {
+	st := *pos
+	for *pos < len(str) && !strings.ContainsRune(".#[]/* \t", rune(str[*pos])) {
+		*pos++
+	}
+	return str[st:*pos]
+}
+
+// queryUnquote removes matching ' or " quotes around value
+func queryUnquote(val string) string {
+	if len(val) >= 2 && (val[0] == '\'' || val[0] == '"') && val[len(val)-1] == val[0] {
+		return val[1 : len(val)-1]
+	}
+	return val
+}
+
+// FindAll returns all nodes matching the query, starting from given node,
+// in tree order
+func (q *Query) FindAll(k Ki) Slice {
+	var ctx []Ki
+	deep := false
+	for si, sel := range q.Steps {
+		var cands [][]Ki
+		switch {
+		case si == 0 && q.Abs:
+			cands = [][]Ki{{k.Root()}}
+		case si == 0 && sel.AnyDepth:
+			cands = [][]Ki{{k}} // ** includes the context node itself
+		case si == 0:
+			cands = [][]Ki{queryKids(k)}
+		case sel.AnyDepth:
+			cands = [][]Ki{ctx}
+		default:
+			cands = make([][]Ki, len(ctx))
+			for i, c := range ctx {
+				cands[i] = queryKids(c)
+			}
+		}
+		if sel.AnyDepth {
+			deep = true
+		}
+		seen := make(map[Ki]bool)
+		var nctx []Ki
+		for _, kids := range cands {
+			for _, m := range sel.Filter(kids) {
+				if !seen[m] {
+					seen[m] = true
+					nctx = append(nctx, m)
+				}
+			}
+		}
+		ctx = nctx
+		if len(ctx) == 0 {
+			return nil
+		}
+	}
+	if deep && len(ctx) > 1 { // results of ** may be out of tree order
+		st := k
+		if q.Abs {
+			st = k.Root()
+		}
+		inres := make(map[Ki]bool, len(ctx))
+		for _, m := range ctx {
+			inres[m] = true
+		}
+		ctx = ctx[:0]
+		st.FuncDownMeFirst(0, nil, func(kn Ki, level int, d interface{}) bool {
+			if inres[kn] {
+				ctx = append(ctx, kn)
+			}
+			return true
+		})
+	}
+	return Slice(ctx)
+}
+
+// FindFirst returns the first node matching the query, starting from given
+// node, or nil if none
+func (q *Query) FindFirst(k Ki) Ki {
+	res := q.FindAll(k)
+	if len(res) == 0 {
+		return nil
+	}
+	return res[0]
+}
+
+// Filter returns the nodes in given list of siblings that match the
+// selector -- for **, the nodes and all their descendants
+func (sel *Selector) Filter(kids []Ki) []Ki {
+	if sel.AnyDepth {
+		var res []Ki
+		for _, k := range kids {
+			k.FuncDownMeFirst(0, nil, func(kn Ki, level int, d interface{}) bool {
+				res = append(res, kn)
+				return true
+			})
+		}
+		return res
+	}
+	res := make([]Ki, 0, len(kids))
+	for _, k := range kids {
+		if sel.Any || sel.Ident == "" || k.Name() == sel.Ident || queryTypeMatch(k.Type(), sel.Ident) {
+			res = append(res, k)
+		}
+	}
+	for _, pr := range sel.Preds {
+		if pr.Kind == SelIndex {
+			idx, err := Slice(res).ValidIndex(pr.Idx)
+			if err != nil {
+				return nil
+			}
+			res = res[idx : idx+1]
+			continue
+		}
+		fres := res[:0]
+		for _, k := range res {
+			if pr.Match(k) {
+				fres = append(fres, k)
+			}
+		}
+		res = fres
+	}
+	return res
+}
+
+// Match returns true if given node matches the selector -- any index
+// predicates are evaluated relative to the node's siblings
+func (sel *Selector) Match(k Ki) bool {
+	sibs := []Ki{k}
+	for _, pr := range sel.Preds {
+		if pr.Kind == SelIndex {
+			if par := k.Parent(); par != nil {
+				sibs = queryKids(par)
+			}
+			break
+		}
+	}
+	for _, m := range sel.Filter(sibs) {
+		if m == k {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns true if the node matches this predicate -- always true for
+// index predicates, which are applied by Selector.Filter
+func (pr *SelPred) Match(k Ki) bool {
+	switch pr.Kind {
+	case SelClass:
+		cls, ok := queryAttr(k, "class")
+		if !ok {
+			return false
+		}
+		for _, c := range strings.Fields(cls) {
+			if strings.EqualFold(c, pr.Val) {
+				return true
+			}
+		}
+		return false
+	case SelName:
+		return strings.EqualFold(k.Name(), pr.Val)
+	case SelAttr:
+		if pr.Key == "type" && pr.Op != "~=" {
+			return queryTypeMatch(k.Type(), pr.Val) == (pr.Op == "=")
+		}
+		var val string
+		var ok bool
+		switch pr.Key {
+		case "name":
+			val, ok = k.Name(), true
+		case "type":
+			val, ok = kit.FullTypeName(k.Type()), true
+		default:
+			val, ok = queryAttr(k, pr.Key[1:])
+		}
+		switch pr.Op {
+		case "":
+			return ok
+		case "=":
+			return ok && val == pr.Val
+		case "!=":
+			return !ok || val != pr.Val
+		case "~=":
+			return ok && pr.Re.MatchString(val)
+		}
+	}
+	return true
+}
+
+// queryKids returns the Ki fields and then the children of given node
+func queryKids(k Ki) []Ki {
+	var kids []Ki
+	k.FuncFields(0, nil, func(fk Ki, level int, d interface{}) bool {
+		kids = append(kids, fk)
+		return true
+	})
+	return append(kids, k.Children()...)
+}
+
+// queryAttr returns the string value of property key on node (not
+// inherited), or if not set, of field key (or Key with first letter upper
+// case) -- false if neither exists or is nil
+func queryAttr(k Ki, key string) (string, bool) {
+	if pv := k.Prop(key, false, false); pv != nil {
+		return kit.ToString(pv), true
+	}
+	fv := kit.FlatFieldValueByName(k, key)
+	if !fv.IsValid() && key != "" {
+		fv = kit.FlatFieldValueByName(k, strings.ToUpper(key[:1])+key[1:])
+	}
+	if !fv.IsValid() || !fv.CanInterface() {
+		return "", false
+	}
+	return kit.ToString(fv.Interface()), true
+}
+
+// queryTypeMatch returns true if type, or any type it embeds, has given
+// kit.Types full name or short name (case insensitive)
+func queryTypeMatch(typ reflect.Type, nm string) bool {
+	typ = kit.NonPtrType(typ)
+	if strings.EqualFold(typ.Name(), nm) || strings.EqualFold(kit.FullTypeName(typ), nm) {
+		return true
+	}
+	if typ.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.Anonymous && kit.NonPtrType(f.Type).Kind() == reflect.Struct {
+			if queryTypeMatch(f.Type, nm) {
+				return true
+			}
+		}
+	}
+	return false
+}
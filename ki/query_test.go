@@ -0,0 +1,107 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"testing"
+)
+
+func queryTestTree() *NodeEmbed {
+	parent := NodeEmbed{}
+	parent.InitName(&parent, "par1")
+	child1 := parent.AddNewChild(KiT_NodeEmbed, "child1")
+	child2 := parent.AddNewChild(KiT_NodeWithField, "child2")
+	parent.AddNewChild(KiT_NodeEmbed, "child3").(*NodeEmbed).Mbr1 = "bloop"
+	child1.AddNewChild(KiT_NodeWithField, "subchild1")
+	child2.AddNewChild(KiT_NodeEmbed, "subchild1").SetProp("class", "primary big")
+	child2.AddNewChild(KiT_NodeEmbed, "subchild2").SetProp("class", "secondary")
+	child2.Child(0).AddNewChild(KiT_NodeWithField, "subsub1").SetProp("class", "primary")
+	return &parent
+}
+
+func queryResNames(res Slice) string {
+	nms := ""
+	for i, k := range res {
+		if i > 0 {
+			nms += " "
+		}
+		nms += k.PathUnique()
+	}
+	return nms
+}
+
+func TestQuery(t *testing.T) {
+	parent := queryTestTree()
+	tests := []struct {
+		query string
+		res   string
+	}{
+		{"child1", "/par1/child1"},
+		{"/par1/child2/subchild1", "/par1/child2/subchild1"},
+		{"/NodeEmbed/child3", "/par1/child3"},
+		{"*/subchild1", "/par1/child1/subchild1 /par1/child2/subchild1"},
+		{"*", "/par1/child1 /par1/child2 /par1/child3"},
+		{"*[-1]", "/par1/child3"},
+		{"*[1]", "/par1/child2"},
+		{"*/*[0]", "/par1/child1/subchild1 /par1/child2.Field1"},
+		{"NodeWithField", "/par1/child2"},
+		{"nodewithfield", "/par1/child2"},
+		{"*[type=ki.NodeWithField]", "/par1/child2"},
+		{"*[type!=NodeWithField]", "/par1/child1 /par1/child3"},
+		{"/**/NodeWithField", "/par1/child1/subchild1 /par1/child2 /par1/child2/subchild1/subsub1"},
+		{"**/*[.class=primary]", "/par1/child2/subchild1/subsub1"},
+		{"**/.primary", "/par1/child2/subchild1 /par1/child2/subchild1/subsub1"},
+		{"/par1/**/NodeEmbed.big", "/par1/child2/subchild1"},
+		{"/par1/**/child2", "/par1/child2"},
+		{"/par1/**/NodeWithField", "/par1/child1/subchild1 /par1/child2 /par1/child2/subchild1/subsub1"},
+		{"**/NodeWithField", "/par1/child1/subchild1 /par1/child2 /par1/child2/subchild1/subsub1"},
+		{"**/child3", "/par1/child3"},
+		{"child2/**/subchild2", "/par1/child2/subchild2"},
+		{"**/*[.class]", "/par1/child2/subchild1 /par1/child2/subchild1/subsub1 /par1/child2/subchild2"},
+		{"**/*[name~=^sub.*2$]", "/par1/child2/subchild2"},
+		{"**/#subchild1", "/par1/child1/subchild1 /par1/child2/subchild1"},
+		{"**/#SubChild1", "/par1/child1/subchild1 /par1/child2/subchild1"},
+		{"**/*[name=SubChild1]", ""},
+		{"**/.Primary", "/par1/child2/subchild1 /par1/child2/subchild1/subsub1"},
+		{"*[.Mbr1=bloop]", "/par1/child3"},
+		{"*[.mbr1='bloop']", "/par1/child3"},
+		{"child2/Field1", "/par1/child2.Field1"},
+		{"child4", ""},
+	}
+	for _, ts := range tests {
+		res := queryResNames(parent.FindAll(ts.query))
+		if res != ts.res {
+			t.Errorf("FindAll(%v): should be:\n%v\nwas:\n%v", ts.query, ts.res, res)
+		}
+	}
+	if fk := parent.FindFirst("**/NodeWithField"); fk != parent.Child(0).Child(0) {
+		t.Errorf("FindFirst(**/NodeWithField) should be /par1/child1/subchild1, was: %v", fk)
+	}
+
+	bad := []string{"", "/", "a//b", "a[", "a[foo]", "a[name]", "a[name~=(]", "a*"}
+	for _, q := range bad {
+		if _, err := ParseQuery(q); err == nil {
+			t.Errorf("ParseQuery(%v) should give error", q)
+		}
+	}
+}
+
+func TestSelectorMatch(t *testing.T) {
+	parent := queryTestTree()
+	sel, err := ParseSelector("NodeEmbed.primary#subchild1")
+	if err != nil {
+		t.Error(err)
+	}
+	if !sel.Match(parent.Child(1).Child(0)) {
+		t.Errorf("selector should match /par1/child2/subchild1")
+	}
+	if sel.Match(parent.Child(0).Child(0)) {
+		t.Errorf("selector should not match /par1/child1/subchild1")
+	}
+	sel, _ = ParseSelector("*[-1]")
+	if !sel.Match(parent.Child(2)) || sel.Match(parent.Child(1)) {
+		t.Errorf("selector *[-1] should match only last child")
+	}
+}
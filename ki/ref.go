@@ -0,0 +1,336 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/rcoreilly/goki/ki/kit"
+)
+
+// Ref is an ID-based reference to a Ki node, as an alternative to Ptr, which
+// is based on the unique path to the node: Ref saves the UUID of the node
+// (see Ki.UUID), which is set when the reference is made (Set), so it
+// survives moves and renames of the node or its parents, and can point to
+// nodes in other trees, registered with AddRefTree.  After loading, refs are
+// resolved (see ResolveRefs, called in UnmarshalPost) by looking up the UUID
+// in the tree containing the ref, and then in the registered trees, with any
+// refs that could not be resolved reported as DanglingRef's, instead of being
+// silently nil -- a dangling ref keeps its UUID, so it is saved again, and
+// can be resolved later, e.g., after the tree it points into is loaded.
+type Ref struct {
+	Ptr  Ki     `json:"-" xml:"-"`
+	UUID string `desc:"UUID of the node pointed to"`
+}
+
+var KiT_Ref = kit.Types.AddType(&Ref{}, nil)
+
+// NewUUID returns a new random (version 4) UUID string
+func NewUUID() string {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		panic(err) // crypto/rand does not fail in practice
+	}
+	u[6] = (u[6] & 0x0f) | 0x40 // version 4
+	u[8] = (u[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:])
+}
+
+// Set sets the ref to point to given node, setting the UUID of the node if
+// it does not already have one -- nil to reset
+func (r *Ref) Set(k Ki) {
+	if k == nil {
+		r.Reset()
+		return
+	}
+	r.Ptr = k
+	r.UUID = k.EnsureUUID()
+}
+
+// Reset resets the ref to nil, and the UUID to empty
+func (r *Ref) Reset() {
+	r.Ptr = nil
+	r.UUID = ""
+}
+
+// IsDangling returns true if the ref has a UUID but no node pointer, e.g.,
+// because it could not be resolved
+func (r *Ref) IsDangling() bool {
+	return r.Ptr == nil && r.UUID != ""
+}
+
+// Resolve finds and sets the Ptr value based on the UUID, looking in the
+// tree containing given node, and then in the trees registered with
+// AddRefTree -- returns true if found, or if the ref is empty
+func (r *Ref) Resolve(from Ki) bool {
+	if r.UUID == "" {
+		r.Ptr = nil
+		return true
+	}
+	var own *UUIDIndex
+	if from != nil {
+		own = refTreeIndex(from.Root())
+	}
+	r.Ptr = refFind(r.UUID, own, nil)
+	return r.Ptr != nil
+}
+
+// MarshalJSON saves the UUID of the ref as a string, null if empty
+func (r Ref) MarshalJSON() ([]byte, error) {
+	uuid := r.UUID
+	if r.Ptr != nil && r.Ptr.UUID() != "" {
+		uuid = r.Ptr.UUID()
+	}
+	if uuid == "" {
+		return []byte("null"), nil
+	}
+	return json.Marshal(uuid)
+}
+
+// UnmarshalJSON loads the UUID of the ref -- the Ptr must then be set by
+// Resolve, or ResolveRefs, after all the trees have been loaded
+func (r *Ref) UnmarshalJSON(b []byte) error {
+	r.Ptr = nil
+	r.UUID = ""
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+	return json.Unmarshal(b, &r.UUID)
+}
+
+// MarshalXML saves the UUID of the ref, null if empty
+func (r Ref) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	uuid := r.UUID
+	if r.Ptr != nil && r.Ptr.UUID() != "" {
+		uuid = r.Ptr.UUID()
+	}
+	if uuid == "" {
+		uuid = "null"
+	}
+	return e.EncodeElement(uuid, start)
+}
+
+// UnmarshalXML loads the UUID of the ref -- see UnmarshalJSON
+func (r *Ref) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	r.Ptr = nil
+	r.UUID = ""
+	var uuid string
+	if err := d.DecodeElement(&uuid, &start); err != nil {
+		return err
+	}
+	if uuid = string(bytes.TrimSpace([]byte(uuid))); uuid != "null" {
+		r.UUID = uuid
+	}
+	return nil
+}
+
+// DanglingRef is a Ref that could not be resolved, as reported by
+// ResolveRefs
+type DanglingRef struct {
+	Node  Ki     `desc:"node that has the ref"`
+	Field string `desc:"name of the Ref field on the node"`
+	UUID  string `desc:"UUID that could not be found"`
+}
+
+// Error returns a description of the dangling ref
+func (dr *DanglingRef) Error() string {
+	return fmt.Sprintf("dangling ki.Ref %v.%v to UUID: %v", dr.Node.PathUnique(), dr.Field, dr.UUID)
+}
+
+// ResolveRefs resolves all the Ref fields of the nodes in the tree from given
+// node down, looking in the tree containing the node, and then in the trees
+// registered with AddRefTree -- returns the refs that could not be resolved,
+// which have a nil Ptr but keep their UUID -- each index is rebuilt at most
+// once, so dangling refs do not each rebuild them
+func ResolveRefs(k Ki) []DanglingRef {
+	var drs []DanglingRef
+	var own *UUIDIndex // found on demand, if there are any refs
+	rebuilt := make(map[*UUIDIndex]bool)
+	k.FuncDownMeFirst(0, nil, func(kn Ki, level int, d interface{}) bool {
+		FlatFieldsValueFunc(kn, func(stru interface{}, typ reflect.Type, field reflect.StructField, fieldVal reflect.Value) bool {
+			if !fieldVal.CanInterface() {
+				return true
+			}
+			r, ok := kit.PtrValue(fieldVal).Interface().(*Ref)
+			if !ok {
+				return true
+			}
+			if r.UUID == "" {
+				r.Ptr = nil
+				return true
+			}
+			if own == nil {
+				own = refTreeIndex(k.Root())
+			}
+			r.Ptr = refFind(r.UUID, own, rebuilt)
+			if r.Ptr == nil {
+				drs = append(drs, DanglingRef{Node: kn, Field: field.Name, UUID: r.UUID})
+			}
+			return true
+		})
+		return true
+	})
+	return drs
+}
+
+//////////////////////////////////////////////////////////////////////////
+//  UUIDIndex
+
+// UUIDIndex is an index of the nodes in a tree by their UUID, for resolving
+// Ref's -- the index is rebuilt automatically when a UUID is not found, or
+// the node found is no longer in the tree (e.g., it was deleted)
+type UUIDIndex struct {
+	Root   Ki           `desc:"root of the indexed tree"`
+	Mu     sync.RWMutex `json:"-" xml:"-" desc:"mutex protecting the index"`
+	nodes  map[string]Ki
+	nBuild int // number of times built
+}
+
+// NewUUIDIndex returns a new index for the tree under given root, built
+// from the current tree
+func NewUUIDIndex(root Ki) *UUIDIndex {
+	ix := &UUIDIndex{Root: root}
+	ix.Build()
+	return ix
+}
+
+// Build rebuilds the index from the current tree
+func (ix *UUIDIndex) Build() {
+	nodes := make(map[string]Ki)
+	ix.Root.FuncDownMeFirst(0, nil, func(k Ki, level int, d interface{}) bool {
+		if uuid := k.UUID(); uuid != "" {
+			nodes[uuid] = k
+		}
+		return true
+	})
+	ix.Mu.Lock()
+	ix.nodes = nodes
+	ix.nBuild++
+	ix.Mu.Unlock()
+}
+
+// Node returns the node in the tree with given UUID, rebuilding the index if
+// not found -- nil if not in the tree
+func (ix *UUIDIndex) Node(uuid string) Ki {
+	if k := ix.find(uuid); k != nil {
+		return k
+	}
+	ix.Build()
+	return ix.find(uuid)
+}
+
+// find looks up the UUID in the index as it is, checking that the node is
+// still in the tree with the same UUID
+func (ix *UUIDIndex) find(uuid string) Ki {
+	ix.Mu.RLock()
+	k, ok := ix.nodes[uuid]
+	ix.Mu.RUnlock()
+	if !ok || k.IsDestroyed() || k.UUID() != uuid || (k != ix.Root && !k.HasParent(ix.Root)) {
+		return nil
+	}
+	return k
+}
+
+// refTrees are the trees registered with AddRefTree, with their indexes
+var refTrees struct {
+	mu  sync.RWMutex
+	ixs []*UUIDIndex
+}
+
+// AddRefTree registers the tree under given root for resolving Ref's, so
+// refs in other trees can point into it -- the tree is indexed, and the index
+// is kept for resolving refs in the tree itself -- call DeleteRefTree when
+// the tree is no longer needed
+func AddRefTree(root Ki) *UUIDIndex {
+	refTrees.mu.Lock()
+	defer refTrees.mu.Unlock()
+	for _, ix := range refTrees.ixs {
+		if ix.Root == root {
+			return ix
+		}
+	}
+	ix := NewUUIDIndex(root)
+	refTrees.ixs = append(refTrees.ixs, ix)
+	return ix
+}
+
+// DeleteRefTree unregisters the tree under given root -- see AddRefTree
+func DeleteRefTree(root Ki) {
+	refTrees.mu.Lock()
+	defer refTrees.mu.Unlock()
+	for i, ix := range refTrees.ixs {
+		if ix.Root == root {
+			refTrees.ixs = append(refTrees.ixs[:i], refTrees.ixs[i+1:]...)
+			return
+		}
+	}
+}
+
+// refTreeIndex returns the registered index for given root, or else the
+// index cached on the root, which is made the first time
+func refTreeIndex(root Ki) *UUIDIndex {
+	refTrees.mu.RLock()
+	for _, ix := range refTrees.ixs {
+		if ix.Root == root {
+			refTrees.mu.RUnlock()
+			return ix
+		}
+	}
+	refTrees.mu.RUnlock()
+	rn, ok := root.EmbeddedStruct(KiT_Node).(*Node)
+	if !ok {
+		return NewUUIDIndex(root)
+	}
+	refTrees.mu.Lock()
+	defer refTrees.mu.Unlock()
+	if rn.refIdx == nil {
+		rn.refIdx = NewUUIDIndex(root)
+	}
+	return rn.refIdx
+}
+
+// refFind finds the node with given UUID in own index (if non-nil), and then
+// in the registered trees -- the indexes are only rebuilt if it is not found
+// in any of them as they are, and not if already in rebuilt (which are
+// added to it, nil to rebuild each at most once for this call) -- nil if not
+// found
+func refFind(uuid string, own *UUIDIndex, rebuilt map[*UUIDIndex]bool) Ki {
+	ixs := []*UUIDIndex{}
+	if own != nil {
+		ixs = append(ixs, own)
+	}
+	refTrees.mu.RLock()
+	for _, ix := range refTrees.ixs {
+		if ix != own {
+			ixs = append(ixs, ix)
+		}
+	}
+	refTrees.mu.RUnlock()
+	for _, ix := range ixs {
+		if k := ix.find(uuid); k != nil {
+			return k
+		}
+	}
+	if rebuilt == nil {
+		rebuilt = make(map[*UUIDIndex]bool)
+	}
+	for _, ix := range ixs {
+		if rebuilt[ix] {
+			continue
+		}
+		ix.Build()
+		rebuilt[ix] = true
+		if k := ix.find(uuid); k != nil {
+			return k
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/rcoreilly/goki/ki/kit"
+)
+
+type RefTestNode struct {
+	Node
+	Target Ref `desc:"reference to another node"`
+}
+
+var KiT_RefTestNode = kit.Types.AddType(&RefTestNode{}, nil)
+
+func (n *RefTestNode) New() Ki { return &RefTestNode{} }
+
+func TestNewUUID(t *testing.T) {
+	u1, u2 := NewUUID(), NewUUID()
+	re := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if !re.MatchString(u1) || u1 == u2 {
+		t.Errorf("bad UUIDs: %v %v", u1, u2)
+	}
+}
+
+func TestRef(t *testing.T) {
+	root := &RefTestNode{}
+	root.InitName(root, "root")
+	typ := root.Type()
+	a := root.AddNewChild(typ, "a").(*RefTestNode)
+	b := root.AddNewChild(typ, "b").(*RefTestNode)
+	bk := b.AddNewChild(typ, "bk").(*RefTestNode)
+	a.Target.Set(bk)
+	if bk.UUID() == "" || a.Target.UUID != bk.UUID() || root.UUID() != "" {
+		t.Errorf("Set did not set UUID: %v %v", bk.UUID(), a.Target.UUID)
+	}
+
+	// moves and renames do not affect refs
+	b.DeleteChild(bk, false)
+	a.AddChild(bk)
+	bk.SetName("moved")
+	b.SetName("b2")
+	nroot, err := root.LoadJSONCopy(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	na := nroot.Child(0).(*RefTestNode)
+	if na.Target.Ptr == nil || na.Target.Ptr.Name() != "moved" || na.Target.Ptr.Parent() != Ki(na) {
+		t.Errorf("ref not resolved after load: %v", na.Target.Ptr)
+	}
+
+	// refs into another tree
+	other := &RefTestNode{}
+	other.InitName(other, "other")
+	ok := other.AddNewChild(typ, "ok").(*RefTestNode)
+	root.Target.Set(ok)
+	jb, _ := root.SaveJSON(false)
+	nr := &RefTestNode{}
+	nr.InitName(nr, "root")
+	nr.LoadJSON(jb) // dangling: other tree not registered
+	if !nr.Target.IsDangling() {
+		t.Errorf("ref into unregistered tree should be dangling")
+	}
+	AddRefTree(other)
+	defer DeleteRefTree(other)
+	if drs := ResolveRefs(nr); len(drs) != 0 || nr.Target.Ptr != Ki(ok) {
+		t.Errorf("ref into registered tree not resolved: %v", drs)
+	}
+
+	// deleted targets are reported as dangling
+	ok.Parent().DeleteChild(ok, true)
+	drs := ResolveRefs(nr)
+	if len(drs) != 1 || drs[0].Node != Ki(nr) || drs[0].Field != "Target" || !strings.Contains(drs[0].Error(), "/root.Target") {
+		t.Errorf("dangling refs not reported: %v", drs)
+	}
+	if nr.Target.UUID == "" {
+		t.Errorf("dangling ref lost its UUID")
+	}
+}
+
+func TestResolveRefsRebuild(t *testing.T) {
+	root := &RefTestNode{}
+	root.InitName(root, "root")
+	typ := root.Type()
+	tgt := root.AddNewChild(typ, "tgt").(*RefTestNode)
+	for i := 0; i < 20; i++ {
+		kid := root.AddNewChild(typ, fmt.Sprintf("kid%v", i)).(*RefTestNode)
+		if i%2 == 0 {
+			kid.Target.Set(tgt)
+		} else {
+			kid.Target.UUID = NewUUID() // dangling
+		}
+	}
+	other := &RefTestNode{}
+	other.InitName(other, "other")
+	oix := AddRefTree(other)
+	defer DeleteRefTree(other)
+
+	// the index of the unregistered root is cached, and rebuilt at most once
+	// per pass, however many dangling refs there are
+	ix := refTreeIndex(root)
+	if refTreeIndex(root) != ix {
+		t.Errorf("index of unregistered root should be cached")
+	}
+	ib, ob := ix.nBuild, oix.nBuild
+	if drs := ResolveRefs(root); len(drs) != 10 {
+		t.Errorf("should be 10 dangling refs, were: %v", len(drs))
+	}
+	if ix.nBuild != ib+1 || oix.nBuild != ob+1 {
+		t.Errorf("indexes should be rebuilt once per pass, were: %v %v", ix.nBuild-ib, oix.nBuild-ob)
+	}
+
+	// resolving a found ref does not rebuild
+	kid := root.Child(1).(*RefTestNode)
+	ib = ix.nBuild
+	if !kid.Target.Resolve(kid) || kid.Target.Ptr != Ki(tgt) || ix.nBuild != ib {
+		t.Errorf("Resolve should use cached index without rebuilding: %v", ix.nBuild-ib)
+	}
+}
+
+// LoadJSONCopy saves the tree to JSON and loads it into a new tree
+func (n *RefTestNode) LoadJSONCopy(t *testing.T) (*RefTestNode, error) {
+	jb, err := n.SaveJSON(true)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(string(jb), `"Target": "`+n.Child(0).(*RefTestNode).Target.UUID+`"`) {
+		t.Errorf("ref not saved as UUID:\n%v", string(jb))
+	}
+	nn := &RefTestNode{}
+	nn.InitName(nn, "root")
+	err = nn.LoadJSON(jb)
+	return nn, err
+}
@@ -9,6 +9,9 @@ import (
 	"fmt"
 	"log"
 	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
 
 	"github.com/rcoreilly/goki/ki/kit"
 )
@@ -16,8 +19,8 @@ import (
 // Implements general signal passing between Ki objects, like Qt's Signal /
 // Slot system started from: github.com/tucnak/meta/
 //
-// todo: Once I learn more about channels and concurrency, could add a channel
-// as an alternative method of sending signals too, perhaps
+// Signals are delivered by directly calling the receiver function, or
+// asynchronously through a SignalQueue or channel -- see sigqueue.go
 //
 // A receivier has to connect to a given signal on a sender to receive those
 // signals, when the signal is emitted.  To make more efficient use of signal
@@ -53,11 +56,18 @@ const (
 	// status and delivered immediately
 	NodeSignalDestroying
 
+	// NodeSignalChanged is sent on the ChangeSig of a ChangeNotifier (not on
+	// the NodeSig) from the node the notifier is attached to, with data =
+	// ChangeBatch of the specific changes made -- see ChangeNotifier
+	NodeSignalChanged
+
 	NodeSignalsN
 )
 
 //go:generate stringer -type=NodeSignals
 
+var KiT_NodeSignals = kit.Enums.AddEnum(NodeSignalsN, false, nil)
+
 // set this to true to automatically print out a trace of the signals as they are sent
 var SignalTrace bool = false
 
@@ -65,6 +75,10 @@ var SignalTrace bool = false
 // otherwise just goes to stdout
 var SignalTraceString *string
 
+// signalTraceMu protects SignalTraceString, as signals can be sent from
+// multiple goroutines
+var signalTraceMu sync.Mutex
+
 // RecvFunc is a receiver function type for signals -- gets the full
 // connection information and signal, data as specified by the sender.  It is
 // good practice to avoid closures in these functions, which can be numerous
@@ -75,7 +89,10 @@ type RecvFunc func(recv, send Ki, sig int64, data interface{})
 // Signal structure -- add one of these to your struct for each signal a node
 // can emit
 type Signal struct {
-	Cons []Connection
+	Cons    []Connection
+	SigType reflect.Type `json:"-" xml:"-" desc:"enum type of the signal codes sent on this signal, registered with kit.Enums -- used to show signal names in traces -- set by SetSigType or Typed"`
+	Mu      sync.RWMutex `json:"-" xml:"-" desc:"mutex protecting the connections, so signals can be emitted from, and connected in, different goroutines -- receiver functions are called without the lock held"`
+	lastID  uint64       // last connection ID assigned
 }
 
 var KiT_Signal = kit.Types.AddType(&Signal{}, nil)
@@ -87,52 +104,190 @@ type Connection struct {
 	Recv Ki
 	// function on the receiver node that will receive the signal
 	Func RecvFunc
+	// if set, signals are delivered asynchronously by pushing onto this
+	// queue, and Func is called when the queue is drained -- see ConnectQueue
+	Queue *SignalQueue
+	// if set, signals are delivered by sending a SignalMsg on this channel
+	// instead of calling Func -- see ConnectChan
+	Chan chan SignalMsg
+	// what to do when Chan is full
+	ChanMode QueueModes
+	// original typed receiver function, for connections made by
+	// TypedSignal, which Func calls -- used for finding the connection
+	Orig interface{}
+	// priority of the receiver -- receivers are called in order of
+	// decreasing priority, and in order of connection within the same
+	// priority -- see ConnectPrio
+	Prio int
+	// if true, the connection is removed when the first signal is
+	// delivered over it -- see ConnectOnce
+	Once bool
+	// unique id of the connection within the signal, for SignalConn
+	ID uint64
 	// todo: path to Recv node (PathUnique), used for copying / moving nodes -- not copying yet
 	// RecvPath string
 }
 
-// SendSig sends the signal over this connection
+// SendSig sends the signal over this connection -- calling the receiver
+// function directly, or through the queue or channel if set
 func (con *Connection) SendSig(sender Ki, sig int64, data interface{}) {
-	con.Func(con.Recv, sender, sig, data)
+	switch {
+	case con.Queue != nil:
+		con.Queue.Push(SignalMsg{Recv: con.Recv, Send: sender, Sig: sig, Data: data, Func: con.Func})
+	case con.Chan != nil:
+		con.sendChan(SignalMsg{Recv: con.Recv, Send: sender, Sig: sig, Data: data})
+	default:
+		con.Func(con.Recv, sender, sig, data)
+	}
+}
+
+// SignalConn is a handle to one connection of a Signal, returned by Connect
+// etc, which can be used to disconnect it -- the zero value is not connected
+type SignalConn struct {
+	Sig *Signal
+	ID  uint64
+}
+
+// Disconnect removes the connection from the signal -- returns false if it
+// was not connected
+func (sc SignalConn) Disconnect() bool {
+	if sc.Sig == nil {
+		return false
+	}
+	return sc.Sig.takeConnection(sc.ID)
+}
+
+// IsConnected returns true if the connection is still connected to the signal
+func (sc SignalConn) IsConnected() bool {
+	if sc.Sig == nil {
+		return false
+	}
+	sc.Sig.Mu.RLock()
+	defer sc.Sig.Mu.RUnlock()
+	return sc.Sig.connectionIndexByID(sc.ID) >= 0
+}
+
+// SignalConsumable is an interface for signal data that can be consumed by a
+// receiver, stopping delivery of the signal to any further, lower-priority
+// receivers -- e.g., the oswin.Event GUI events, which are consumed by
+// SetProcessed
+type SignalConsumable interface {
+	// IsProcessed returns true if the signal has been consumed
+	IsProcessed() bool
+
+	// SetProcessed consumes the signal
+	SetProcessed()
 }
 
 // ConnectOnly first deletes any existing connections and then attaches a new
 // receiver to the signal -- checks to make sure connection does not already
 // exist -- error if not ok
-func (sig *Signal) ConnectOnly(recv Ki, fun RecvFunc) error {
+func (sig *Signal) ConnectOnly(recv Ki, fun RecvFunc) (SignalConn, error) {
 	sig.DisconnectAll()
 	return sig.Connect(recv, fun)
 }
 
 // Connect attaches a new receiver to the signal -- checks to make sure
-// connection does not already exist -- error if not ok
-func (sig *Signal) Connect(recv Ki, fun RecvFunc) error {
+// connection does not already exist, in which case the existing connection
+// is returned -- returns a handle to the connection, error if not ok
+func (sig *Signal) Connect(recv Ki, fun RecvFunc) (SignalConn, error) {
+	return sig.connect(recv, fun, 0, false)
+}
+
+// ConnectPrio attaches a new receiver to the signal with given priority --
+// receivers are called in order of decreasing priority, and in order of
+// connection within the same priority (Connect uses priority 0) -- a
+// receiver can stop delivery of the signal to lower-priority receivers by
+// consuming the data, if it is a SignalConsumable -- checks to make sure
+// connection does not already exist, in which case the existing connection
+// is returned -- returns a handle to the connection, error if not ok
+func (sig *Signal) ConnectPrio(recv Ki, fun RecvFunc, prio int) (SignalConn, error) {
+	return sig.connect(recv, fun, prio, false)
+}
+
+// ConnectOnce attaches a new receiver to the signal with given priority (see
+// ConnectPrio), which is automatically disconnected after the first signal is
+// delivered to it -- returns a handle to the connection, error if not ok
+func (sig *Signal) ConnectOnce(recv Ki, fun RecvFunc, prio int) (SignalConn, error) {
+	return sig.connect(recv, fun, prio, true)
+}
+
+// connect is the implementation of all the Connect methods
+func (sig *Signal) connect(recv Ki, fun RecvFunc, prio int, once bool) (SignalConn, error) {
 	if recv == nil {
 		err := errors.New("ki Signal Connect: no recv node provided\n")
 		log.Println(err)
-		return err
+		return SignalConn{}, err
 	}
 	if fun == nil {
 		err := errors.New("ki Signal Connect: no recv func provided\n")
 		log.Println(err)
-		return err
+		return SignalConn{}, err
 	}
 
-	if sig.FindConnectionIndex(recv, fun) >= 0 {
+	sig.Mu.Lock()
+	defer sig.Mu.Unlock()
+	if i := sig.findConnectionIndex(recv, fun); i >= 0 {
 		// fmt.Printf("Already found connection to recv %v fun %v\n", recv.Name(), reflect.ValueOf(fun))
-		return nil
+		return SignalConn{sig, sig.Cons[i].ID}, nil
 	}
 
-	con := Connection{recv, fun}
-	sig.Cons = append(sig.Cons, con)
-
 	// fmt.Printf("added connection to recv %v fun %v", recv.Name(), reflect.ValueOf(fun))
 
-	return nil
+	return sig.addConnection(Connection{Recv: recv, Func: fun, Prio: prio, Once: once}), nil
+}
+
+// addConnection adds the connection in order of priority, assigning its ID
+// -- lock must be held
+func (sig *Signal) addConnection(con Connection) SignalConn {
+	sig.lastID++
+	con.ID = sig.lastID
+	i := len(sig.Cons)
+	for i > 0 && sig.Cons[i-1].Prio < con.Prio {
+		i--
+	}
+	sig.Cons = append(sig.Cons, Connection{})
+	copy(sig.Cons[i+1:], sig.Cons[i:])
+	sig.Cons[i] = con
+	return SignalConn{sig, con.ID}
+}
+
+// connectionIndexByID returns the index of the connection with given ID, -1
+// if not found -- lock must be held
+func (sig *Signal) connectionIndexByID(id uint64) int {
+	for i := range sig.Cons {
+		if sig.Cons[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// takeConnection removes the connection with given ID -- returns false if
+// not found, e.g., if a Once connection was already taken for delivery by
+// another goroutine
+func (sig *Signal) takeConnection(id uint64) bool {
+	sig.Mu.Lock()
+	defer sig.Mu.Unlock()
+	i := sig.connectionIndexByID(id)
+	if i < 0 {
+		return false
+	}
+	copy(sig.Cons[i:], sig.Cons[i+1:])
+	sig.Cons[len(sig.Cons)-1] = Connection{}
+	sig.Cons = sig.Cons[:len(sig.Cons)-1]
+	return true
 }
 
 // Find any existing signal connection for given recv and fun
 func (sig *Signal) FindConnectionIndex(recv Ki, fun RecvFunc) int {
+	sig.Mu.RLock()
+	defer sig.Mu.RUnlock()
+	return sig.findConnectionIndex(recv, fun)
+}
+
+// findConnectionIndex is FindConnectionIndex without the lock
+func (sig *Signal) findConnectionIndex(recv Ki, fun RecvFunc) int {
 	rfref := reflect.ValueOf(fun).Pointer()
 	for i, con := range sig.Cons {
 		if con.Recv == recv && rfref == reflect.ValueOf(con.Func).Pointer() {
@@ -147,6 +302,8 @@ func (sig *Signal) FindConnectionIndex(recv Ki, fun RecvFunc) int {
 // other -- both nil means disconnect from all, but more efficient to use
 // DisconnectAll
 func (sig *Signal) Disconnect(recv Ki, fun RecvFunc) bool {
+	sig.Mu.Lock()
+	defer sig.Mu.Unlock()
 	rfref := reflect.ValueOf(fun).Pointer()
 	sz := len(sig.Cons)
 	got := false
@@ -168,19 +325,83 @@ func (sig *Signal) Disconnect(recv Ki, fun RecvFunc) bool {
 
 // Disconnect all connections
 func (sig *Signal) DisconnectAll() {
+	sig.Mu.Lock()
 	sig.Cons = sig.Cons[:0]
+	sig.Mu.Unlock()
+}
+
+// liveCons removes any connections to destroyed receivers, and returns a
+// copy of the remaining connections, which can then be sent to without
+// holding the lock
+func (s *Signal) liveCons() []Connection {
+	s.Mu.Lock()
+	defer s.Mu.Unlock()
+	deleted := 0 // using this construct from https://stackoverflow.com/questions/20545743/delete-entries-from-a-slice-while-iterating-over-it-in-go
+	for i := range s.Cons {
+		j := i - deleted
+		con := s.Cons[j]
+		if con.Recv.IsDestroyed() {
+			// fmt.Printf("ki.Signal deleting destroyed receiver: %v type %T\n", con.Recv.Name(), con.Recv)
+			s.Cons = s.Cons[:j+copy(s.Cons[j:], s.Cons[j+1:])]
+			deleted++
+		}
+	}
+	return append([]Connection(nil), s.Cons...)
+}
+
+// SetSigType sets the enum type of the signal codes sent on this signal,
+// which should be registered with kit.Enums, for showing signal names in
+// traces
+func (s *Signal) SetSigType(typ reflect.Type) {
+	s.Mu.Lock()
+	s.SigType = typ
+	s.Mu.Unlock()
 }
 
-// EmitTrace records a trace of signal being emitted
+// SigName returns the name of given signal code, using the SigType enum if
+// set, or else just the number
+func (s *Signal) SigName(sig int64) string {
+	s.Mu.RLock()
+	typ := s.SigType
+	s.Mu.RUnlock()
+	if typ == nil {
+		return strconv.FormatInt(sig, 10)
+	}
+	return kit.Enums.EnumInt64ToString(sig, typ)
+}
+
+// senderSigName returns the name of given signal code as sent by sender,
+// using SigName, with the NodeSig of the sender defaulting to NodeSignals
+func (s *Signal) senderSigName(sender Ki, sig int64) string {
+	s.Mu.RLock()
+	typ := s.SigType
+	s.Mu.RUnlock()
+	if typ == nil && sender.NodeSignal() == s {
+		typ = KiT_NodeSignals
+	}
+	if typ == nil {
+		return strconv.FormatInt(sig, 10)
+	}
+	return kit.Enums.EnumInt64ToString(sig, typ)
+}
+
+// EmitTrace records a trace of signal being emitted -- the signal code is
+// shown using SigName, with the NodeSig of the sender defaulting to
+// NodeSignals
 func (s *Signal) EmitTrace(sender Ki, sig int64, data interface{}) {
+	signm := s.senderSigName(sender, sig)
 	if SignalTraceString != nil {
-		*SignalTraceString += fmt.Sprintf("ki.Signal Emit from: %v sig: %v data: %v\n", sender.Name(), NodeSignals(sig), data)
+		signalTraceMu.Lock()
+		defer signalTraceMu.Unlock()
+		*SignalTraceString += fmt.Sprintf("ki.Signal Emit from: %v sig: %v data: %v\n", sender.Name(), signm, data)
 	} else {
-		fmt.Printf("ki.Signal Emit from: %v sig: %v data: %v\n", sender.PathUnique(), NodeSignals(sig), data)
+		fmt.Printf("ki.Signal Emit from: %v sig: %v data: %v\n", sender.PathUnique(), signm, data)
 	}
 }
 
-// Emit sends the signal across all the connections to the receivers -- sequential
+// Emit sends the signal across all the connections to the receivers --
+// sequential, in order of priority -- stops when the data is consumed, if it
+// is a SignalConsumable
 func (s *Signal) Emit(sender Ki, sig int64, data interface{}) {
 	if sender == nil || sender.IsDestroyed() { // dead nodes don't talk..
 		return
@@ -188,21 +409,11 @@ func (s *Signal) Emit(sender Ki, sig int64, data interface{}) {
 	if SignalTrace {
 		s.EmitTrace(sender, sig, data)
 	}
-	deleted := 0 // using this construct from https://stackoverflow.com/questions/20545743/delete-entries-from-a-slice-while-iterating-over-it-in-go
-	for i := range s.Cons {
-		j := i - deleted
-		con := s.Cons[j]
-		if con.Recv.IsDestroyed() {
-			// fmt.Printf("ki.Signal deleting destroyed receiver: %v type %T\n", con.Recv.Name(), con.Recv)
-			s.Cons = s.Cons[:j+copy(s.Cons[j:], s.Cons[j+1:])]
-			deleted++
-			continue
-		}
-		con.Func(con.Recv, sender, sig, data)
-	}
+	s.send(sender, sig, data, nil, false)
 }
 
-// EmitGo concurrent version -- sends the signal across all the connections to the receivers
+// EmitGo concurrent version -- sends the signal across all the connections to
+// the receivers -- the data cannot be consumed in this case
 func (s *Signal) EmitGo(sender Ki, sig int64, data interface{}) {
 	if sender == nil || sender.IsDestroyed() { // dead nodes don't talk..
 		return
@@ -210,54 +421,54 @@ func (s *Signal) EmitGo(sender Ki, sig int64, data interface{}) {
 	if SignalTrace {
 		s.EmitTrace(sender, sig, data)
 	}
-	deleted := 0
-	for i := range s.Cons {
-		j := i - deleted
-		con := s.Cons[j]
-		if con.Recv.IsDestroyed() {
-			s.Cons = s.Cons[:j+copy(s.Cons[j:], s.Cons[j+1:])]
-			deleted++
-			continue
-		}
-		go con.Func(con.Recv, sender, sig, data)
-	}
+	s.send(sender, sig, data, nil, true)
 }
 
 // SignalFilterFunc is the function type for filtering signals before they are
 // sent -- returns false to prevent sending, and true to allow sending
 type SignalFilterFunc func(ki Ki, idx int, con *Connection) bool
 
-// EmitFiltered calls function on each item only sends signal if function returns true
+// EmitFiltered calls function on each item only sends signal if function
+// returns true -- stops when the data is consumed, as in Emit
 func (s *Signal) EmitFiltered(sender Ki, sig int64, data interface{}, fun SignalFilterFunc) {
-	deleted := 0
-	for i := range s.Cons {
-		j := i - deleted
-		con := s.Cons[j]
-		if con.Recv.IsDestroyed() {
-			s.Cons = s.Cons[:j+copy(s.Cons[j:], s.Cons[j+1:])]
-			deleted++
-			continue
-		}
-		if fun(con.Recv, j, &con) {
-			con.Func(con.Recv, sender, sig, data)
-		}
-	}
+	s.send(sender, sig, data, fun, false)
 }
 
 // EmitGoFiltered calls function on each item only sends signal if function
 // returns true -- concurrent version
 func (s *Signal) EmitGoFiltered(sender Ki, sig int64, data interface{}, fun SignalFilterFunc) {
-	deleted := 0
-	for i := range s.Cons {
-		j := i - deleted
-		con := s.Cons[j]
-		if con.Recv.IsDestroyed() {
-			s.Cons = s.Cons[:j+copy(s.Cons[j:], s.Cons[j+1:])]
-			deleted++
+	s.send(sender, sig, data, fun, true)
+}
+
+// send sends the signal to all the (filtered) connections, in order --
+// removes Once connections on delivery, and stops when the data is consumed
+// -- if goFunc, receiver functions are called in separate goroutines, except
+// for queued and channel connections, which are sent in order
+func (s *Signal) send(sender Ki, sig int64, data interface{}, fun SignalFilterFunc, goFunc bool) {
+	var recs []*signalRecEmit
+	if atomic.LoadInt32(&nSignalRecorders) > 0 {
+		if recs = startSignalRecords(s, sender, sig, data); recs != nil {
+			defer endSignalRecords(recs)
+		}
+	}
+	cd, consumable := data.(SignalConsumable)
+	for j, con := range s.liveCons() {
+		if consumable && !goFunc && cd.IsProcessed() {
+			return
+		}
+		if fun != nil && !fun(con.Recv, j, &con) {
 			continue
 		}
-		if fun(con.Recv, j, &con) {
+		if con.Once && !s.takeConnection(con.ID) {
+			continue // already delivered by another emit
+		}
+		if recs != nil {
+			recordSignal(recs, con.Recv)
+		}
+		if goFunc && !con.IsAsync() {
 			go con.Func(con.Recv, sender, sig, data)
+		} else {
+			con.SendSig(sender, sig, data)
 		}
 	}
 }
@@ -7,6 +7,8 @@ package ki
 import (
 	"fmt"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
 	// "time"
 	"github.com/rcoreilly/goki/ki/kit"
@@ -71,3 +73,89 @@ func TestSignalNameToInt(t *testing.T) {
 		t.Errorf("could not convert from signal type name %v -- got: %v -- maybe need to run go generate?", str, st.String())
 	}
 }
+
+// testConsumable is signal data that can be consumed by a receiver
+type testConsumable struct {
+	processed bool
+}
+
+func (tc *testConsumable) IsProcessed() bool { return tc.processed }
+func (tc *testConsumable) SetProcessed()     { tc.processed = true }
+
+func TestSignalConnectPrio(t *testing.T) {
+	parent := TestNode{}
+	parent.InitName(&parent, "par1")
+	child1 := parent.AddNewChild(nil, "child1")
+	child2 := parent.AddNewChild(nil, "child2")
+	child3 := parent.AddNewChild(nil, "child3")
+
+	var res []string
+	recv := func(receiver, sender Ki, sig int64, data interface{}) {
+		res = append(res, receiver.Name())
+		if tc, ok := data.(*testConsumable); ok && receiver.Name() == "child2" {
+			tc.SetProcessed()
+		}
+	}
+
+	// higher priority first, connection order within same priority
+	sc1, _ := parent.sig1.Connect(child1, recv)
+	parent.sig1.ConnectPrio(child2, recv, 10)
+	sc3, _ := parent.sig1.Connect(child3, recv)
+	if scd, _ := parent.sig1.Connect(child1, recv); scd != sc1 || len(parent.sig1.Cons) != 3 {
+		t.Errorf("duplicate connection should return existing connection: %v, %v", scd, sc1)
+	}
+	parent.sig1.Emit(&parent, int64(NodeSignalNil), nil)
+	if !reflect.DeepEqual(res, []string{"child2", "child1", "child3"}) {
+		t.Errorf("priority order wrong: %v", res)
+	}
+
+	// consumed data stops delivery to lower priority receivers
+	res = nil
+	parent.sig1.Emit(&parent, int64(NodeSignalNil), &testConsumable{})
+	if !reflect.DeepEqual(res, []string{"child2"}) {
+		t.Errorf("consumed signal delivered to: %v", res)
+	}
+
+	// disconnect through handle
+	if !sc3.IsConnected() || !sc3.Disconnect() || sc3.IsConnected() || sc3.Disconnect() {
+		t.Errorf("SignalConn disconnect failed")
+	}
+	if (SignalConn{}).IsConnected() {
+		t.Errorf("zero SignalConn should not be connected")
+	}
+	res = nil
+	parent.sig1.Emit(&parent, int64(NodeSignalNil), nil)
+	if !reflect.DeepEqual(res, []string{"child2", "child1"}) {
+		t.Errorf("after disconnect delivered to: %v", res)
+	}
+}
+
+func TestSignalConnectOnce(t *testing.T) {
+	parent := TestNode{}
+	parent.InitName(&parent, "par1")
+	child1 := parent.AddNewChild(nil, "child1")
+	child2 := parent.AddNewChild(nil, "child2")
+
+	var n1, n2 int64
+	sc, _ := parent.sig1.ConnectOnce(child1, func(receiver, sender Ki, sig int64, data interface{}) {
+		atomic.AddInt64(&n1, 1)
+	}, 0)
+	parent.sig1.Connect(child2, func(receiver, sender Ki, sig int64, data interface{}) {
+		atomic.AddInt64(&n2, 1)
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			parent.sig1.Emit(&parent, int64(NodeSignalNil), nil)
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	if n1 != 1 || n2 != 20 {
+		t.Errorf("once connection got %v signals, other got %v", n1, n2)
+	}
+	if sc.IsConnected() || len(parent.sig1.Cons) != 1 {
+		t.Errorf("once connection still connected after delivery")
+	}
+}
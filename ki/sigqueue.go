@@ -0,0 +1,299 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"errors"
+	"log"
+	"reflect"
+	"sync"
+
+	"github.com/rcoreilly/goki/ki/kit"
+)
+
+// Asynchronous signal delivery: instead of the receiver function being
+// called directly in the goroutine that emits the signal, a connection can
+// deliver the signal into a SignalQueue owned by the receiver (ConnectQueue),
+// or a Go channel (ConnectChan).  The queue is then drained on whatever
+// goroutine the receiver chooses, by calling Drain -- e.g., gi.Window has a
+// SigQueue that is drained in its event loop, so signals emitted from worker
+// goroutines can be safely delivered to GUI nodes.  Signals are delivered in
+// the order they were emitted, per queue (and thus per receiver), and the
+// QueueModes determine what happens when the queue is full.
+
+// QueueModes determine what happens when a signal is sent to a full
+// SignalQueue (or channel)
+type QueueModes int32
+
+const (
+	// QueueBlock blocks the sender until there is space in the queue -- the
+	// goroutine that drains the queue must not emit signals into it when
+	// full, or it will deadlock
+	QueueBlock QueueModes = iota
+
+	// QueueDropOldest drops the oldest signal in the queue to make space for
+	// the new one
+	QueueDropOldest
+
+	// QueueCoalesce replaces the data of any identical signal already in the
+	// queue (same receiver, sender, signal, and function) with that of the
+	// new one, keeping its place in the queue, instead of adding a new one --
+	// this is always done, even when the queue is not full -- if the queue
+	// is full and there is no identical signal, the oldest is dropped --
+	// not supported for channels
+	QueueCoalesce
+
+	QueueModesN
+)
+
+//go:generate stringer -type=QueueModes
+
+var KiT_QueueModes = kit.Enums.AddEnum(QueueModesN, false, nil)
+
+// SignalMsg is one signal, as delivered through a SignalQueue or channel
+type SignalMsg struct {
+	Recv Ki          `desc:"receiver of the signal"`
+	Send Ki          `desc:"sender of the signal"`
+	Sig  int64       `desc:"the signal"`
+	Data interface{} `desc:"data sent with the signal"`
+	Func RecvFunc    `desc:"receiver function of the connection -- nil for channel connections"`
+}
+
+// Deliver calls the receiver function with the signal, unless the receiver
+// has been destroyed since the signal was sent
+func (sm *SignalMsg) Deliver() {
+	if sm.Func == nil || sm.Recv.IsDestroyed() {
+		return
+	}
+	sm.Func(sm.Recv, sm.Send, sm.Sig, sm.Data)
+}
+
+// Same returns true if the signals are identical apart from their data --
+// same receiver, sender, signal, and function
+func (sm *SignalMsg) Same(osm *SignalMsg) bool {
+	return sm.Recv == osm.Recv && sm.Send == osm.Send && sm.Sig == osm.Sig &&
+		reflect.ValueOf(sm.Func).Pointer() == reflect.ValueOf(osm.Func).Pointer()
+}
+
+// SignalQueue is a queue of signals for asynchronous delivery, which are
+// delivered by calling Drain -- see ConnectQueue
+type SignalQueue struct {
+	Mode   QueueModes  `desc:"what to do when a signal is sent to a full queue"`
+	Cap    int         `desc:"maximum number of signals in the queue, above which Mode applies -- 0 = unlimited"`
+	Wake   func()      `json:"-" xml:"-" desc:"if set, this is called when a signal is added to an empty queue -- e.g., to wake up the goroutine that drains the queue -- called without the lock held, in the sending goroutine"`
+	Mu     sync.Mutex  `json:"-" xml:"-" desc:"mutex protecting the queue"`
+	msgs   []SignalMsg // the signals waiting for delivery
+	space  *sync.Cond  // signaled when there is space in the queue, for QueueBlock
+	notify chan struct{}
+	closed bool
+}
+
+// NewSignalQueue returns a new queue with given capacity (0 = unlimited) and
+// mode for what to do when full
+func NewSignalQueue(cap int, mode QueueModes) *SignalQueue {
+	return &SignalQueue{Cap: cap, Mode: mode}
+}
+
+// Push adds a signal to the queue, applying the Mode if full -- returns
+// false if the signal was not added because the queue is closed
+func (q *SignalQueue) Push(msg SignalMsg) bool {
+	q.Mu.Lock()
+	if q.closed {
+		q.Mu.Unlock()
+		return false
+	}
+	if q.Mode == QueueCoalesce {
+		for i := range q.msgs {
+			if q.msgs[i].Same(&msg) {
+				q.msgs[i].Data = msg.Data
+				q.Mu.Unlock()
+				return true
+			}
+		}
+	}
+	for q.Cap > 0 && len(q.msgs) >= q.Cap {
+		if q.Mode == QueueBlock {
+			if q.space == nil {
+				q.space = sync.NewCond(&q.Mu)
+			}
+			q.space.Wait()
+			if q.closed {
+				q.Mu.Unlock()
+				return false
+			}
+			continue
+		}
+		copy(q.msgs, q.msgs[1:])
+		q.msgs[len(q.msgs)-1] = SignalMsg{}
+		q.msgs = q.msgs[:len(q.msgs)-1]
+	}
+	wasEmpty := len(q.msgs) == 0
+	q.msgs = append(q.msgs, msg)
+	wake := q.Wake
+	if wasEmpty && q.notify != nil {
+		select {
+		case q.notify <- struct{}{}:
+		default:
+		}
+	}
+	q.Mu.Unlock()
+	if wasEmpty && wake != nil {
+		wake()
+	}
+	return true
+}
+
+// Pop removes and returns the first signal in the queue, false if empty
+func (q *SignalQueue) Pop() (SignalMsg, bool) {
+	q.Mu.Lock()
+	defer q.Mu.Unlock()
+	if len(q.msgs) == 0 {
+		return SignalMsg{}, false
+	}
+	msg := q.msgs[0]
+	q.msgs[0] = SignalMsg{}
+	q.msgs = q.msgs[1:]
+	if q.space != nil {
+		q.space.Broadcast()
+	}
+	return msg, true
+}
+
+// Drain delivers all the signals in the queue, in order, on the calling
+// goroutine, including any that are added during delivery -- returns the
+// number of signals delivered -- only one goroutine should drain a given
+// queue, for the signals to be delivered in order
+func (q *SignalQueue) Drain() int {
+	n := 0
+	for {
+		q.Mu.Lock()
+		msgs := q.msgs
+		q.msgs = nil
+		if q.space != nil {
+			q.space.Broadcast()
+		}
+		q.Mu.Unlock()
+		if len(msgs) == 0 {
+			return n
+		}
+		for i := range msgs {
+			msgs[i].Deliver()
+		}
+		n += len(msgs)
+	}
+}
+
+// Len returns the number of signals waiting in the queue
+func (q *SignalQueue) Len() int {
+	q.Mu.Lock()
+	defer q.Mu.Unlock()
+	return len(q.msgs)
+}
+
+// Notify returns a channel that receives a value when a signal is added to
+// the empty queue, for use in a select loop that calls Drain
+func (q *SignalQueue) Notify() <-chan struct{} {
+	q.Mu.Lock()
+	defer q.Mu.Unlock()
+	if q.notify == nil {
+		q.notify = make(chan struct{}, 1)
+		if len(q.msgs) > 0 {
+			q.notify <- struct{}{}
+		}
+	}
+	return q.notify
+}
+
+// Close closes the queue, so any further signals are dropped, and any
+// senders blocked on a full queue return -- signals already in the queue
+// can still be drained
+func (q *SignalQueue) Close() {
+	q.Mu.Lock()
+	q.closed = true
+	if q.space != nil {
+		q.space.Broadcast()
+	}
+	q.Mu.Unlock()
+}
+
+// ConnectQueue attaches a new receiver to the signal, as in Connect, but
+// with signals delivered asynchronously through given queue -- fun is called
+// when the queue is drained -- if the connection already exists, it is
+// changed to deliver through the queue -- returns a handle to the
+// connection, error if not ok
+func (sig *Signal) ConnectQueue(recv Ki, fun RecvFunc, q *SignalQueue) (SignalConn, error) {
+	if q == nil {
+		err := errors.New("ki Signal ConnectQueue: no queue provided\n")
+		log.Println(err)
+		return SignalConn{}, err
+	}
+	sc, err := sig.Connect(recv, fun)
+	if err != nil {
+		return sc, err
+	}
+	sig.Mu.Lock()
+	if i := sig.connectionIndexByID(sc.ID); i >= 0 {
+		sig.Cons[i].Queue = q
+	}
+	sig.Mu.Unlock()
+	return sc, nil
+}
+
+// ConnectChan attaches a new receiver to the signal, with signals delivered
+// as SignalMsg's to given channel, which the receiver reads from -- mode
+// determines what happens when a buffered channel is full: QueueBlock or
+// QueueDropOldest (QueueCoalesce is not supported) -- returns a handle to the
+// connection, which is the existing one if already connected to this
+// channel, error if not ok
+func (sig *Signal) ConnectChan(recv Ki, ch chan SignalMsg, mode QueueModes) (SignalConn, error) {
+	if recv == nil {
+		err := errors.New("ki Signal ConnectChan: no recv node provided\n")
+		log.Println(err)
+		return SignalConn{}, err
+	}
+	if ch == nil {
+		err := errors.New("ki Signal ConnectChan: no channel provided\n")
+		log.Println(err)
+		return SignalConn{}, err
+	}
+	if mode == QueueCoalesce {
+		err := errors.New("ki Signal ConnectChan: QueueCoalesce mode not supported for channels\n")
+		log.Println(err)
+		return SignalConn{}, err
+	}
+	sig.Mu.Lock()
+	defer sig.Mu.Unlock()
+	for _, con := range sig.Cons {
+		if con.Recv == recv && con.Chan == ch {
+			return SignalConn{sig, con.ID}, nil
+		}
+	}
+	return sig.addConnection(Connection{Recv: recv, Chan: ch, ChanMode: mode}), nil
+}
+
+// IsAsync returns true if the connection delivers signals through a queue
+// or channel, instead of calling the receiver function directly
+func (con *Connection) IsAsync() bool {
+	return con.Queue != nil || con.Chan != nil
+}
+
+// sendChan sends the signal to the connection channel, applying ChanMode
+func (con *Connection) sendChan(msg SignalMsg) {
+	if con.ChanMode != QueueDropOldest {
+		con.Chan <- msg
+		return
+	}
+	for {
+		select {
+		case con.Chan <- msg:
+			return
+		default:
+		}
+		select { // full: drop oldest and try again
+		case <-con.Chan:
+		default:
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSignalQueue(t *testing.T) {
+	parent := TestNode{}
+	parent.InitName(&parent, "par1")
+	child1 := parent.AddNewChild(nil, "child1")
+	child2 := parent.AddNewChild(nil, "child2")
+
+	var res []int
+	recv := func(receiver, sender Ki, sig int64, data interface{}) {
+		res = append(res, data.(int))
+	}
+
+	// unlimited queue: nothing delivered until drained, then all in order
+	q := NewSignalQueue(0, QueueBlock)
+	parent.sig1.ConnectQueue(child1, recv, q)
+	for i := 0; i < 5; i++ {
+		parent.sig1.Emit(&parent, int64(NodeSignalNil), i)
+	}
+	if len(res) != 0 || q.Len() != 5 {
+		t.Errorf("queued signals delivered before drain: %v, len: %v", res, q.Len())
+	}
+	if n := q.Drain(); n != 5 || !reflect.DeepEqual(res, []int{0, 1, 2, 3, 4}) {
+		t.Errorf("drain delivered %v: %v", n, res)
+	}
+
+	// drop oldest
+	res = nil
+	q.Mode = QueueDropOldest
+	q.Cap = 3
+	for i := 0; i < 5; i++ {
+		parent.sig1.Emit(&parent, int64(NodeSignalNil), i)
+	}
+	q.Drain()
+	if !reflect.DeepEqual(res, []int{2, 3, 4}) {
+		t.Errorf("drop oldest delivered: %v", res)
+	}
+
+	// coalesce identical signals, keeping their order
+	res = nil
+	q.Mode = QueueCoalesce
+	parent.sig1.Emit(&parent, int64(NodeSignalNil), 0)
+	parent.sig1.Emit(&parent, int64(NodeSignalUpdated), 1)
+	parent.sig1.Emit(&parent, int64(NodeSignalNil), 2)
+	if q.Len() != 2 {
+		t.Errorf("coalesce queue should have 2 signals, has: %v", q.Len())
+	}
+	q.Drain()
+	if !reflect.DeepEqual(res, []int{2, 1}) {
+		t.Errorf("coalesce delivered: %v", res)
+	}
+
+	// destroyed receivers do not get queued signals
+	res = nil
+	parent.sig1.Emit(&parent, int64(NodeSignalNil), 0)
+	parent.DeleteChild(child1, true)
+	if n := q.Drain(); n != 1 || len(res) != 0 {
+		t.Errorf("signal delivered to destroyed receiver: %v", res)
+	}
+
+	// blocking, with emitting from workers and draining on this goroutine
+	res = nil
+	q = NewSignalQueue(2, QueueBlock)
+	parent.sig2.ConnectQueue(child2, recv, q)
+	wdone := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			parent.sig2.EmitGo(&parent, int64(NodeSignalNil), i)
+		}
+		close(wdone)
+	}()
+	notify := q.Notify()
+	for running := true; running; {
+		select {
+		case <-notify:
+			q.Drain()
+		case <-wdone:
+			running = false
+		}
+	}
+	q.Drain()
+	if len(res) != 100 {
+		t.Fatalf("blocking queue delivered %v signals, should be 100", len(res))
+	}
+	for i, r := range res {
+		if r != i {
+			t.Errorf("blocking queue delivered out of order at %v: %v", i, r)
+			break
+		}
+	}
+	q.Close()
+	if q.Push(SignalMsg{Recv: child2}) {
+		t.Errorf("push to closed queue should fail")
+	}
+}
+
+func TestSignalChan(t *testing.T) {
+	parent := TestNode{}
+	parent.InitName(&parent, "par1")
+	child1 := parent.AddNewChild(nil, "child1")
+
+	ch := make(chan SignalMsg, 2)
+	if _, err := parent.sig1.ConnectChan(child1, ch, QueueCoalesce); err == nil {
+		t.Errorf("QueueCoalesce should not be supported for channels")
+	}
+	parent.sig1.ConnectChan(child1, ch, QueueDropOldest)
+	parent.sig1.ConnectChan(child1, ch, QueueDropOldest)
+	if len(parent.sig1.Cons) != 1 {
+		t.Errorf("duplicate channel connection made")
+	}
+	for i := 0; i < 4; i++ {
+		parent.sig1.Emit(&parent, int64(NodeSignalUpdated), i)
+	}
+	m1, m2 := <-ch, <-ch
+	if m1.Data != 2 || m2.Data != 3 || m1.Recv != child1 || m1.Send != parent.This || NodeSignals(m1.Sig) != NodeSignalUpdated {
+		t.Errorf("channel drop oldest got: %v, %v", m1, m2)
+	}
+
+	parent.sig1.Disconnect(child1, nil)
+	ch = make(chan SignalMsg)
+	parent.sig1.ConnectChan(child1, ch, QueueBlock)
+	go func() {
+		for i := 0; i < 10; i++ {
+			parent.sig1.Emit(&parent, int64(NodeSignalNil), i)
+		}
+		close(ch)
+	}()
+	i := 0
+	for msg := range ch {
+		if msg.Data != i {
+			t.Errorf("channel signal %v out of order: %v", i, msg.Data)
+		}
+		i++
+	}
+	if i != 10 {
+		t.Errorf("channel delivered %v signals, should be 10", i)
+	}
+}
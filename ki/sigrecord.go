@@ -0,0 +1,467 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/rcoreilly/goki/ki/kit"
+)
+
+// Signal recording: a SignalRecorder records all the signals emitted by
+// nodes within a subtree, as a SignalLog of SignalRecord's, which can be
+// saved to / loaded from JSON, filtered by path or signal, and replayed
+// against another tree with the same structure (e.g., freshly loaded from
+// JSON) -- replaying the top-level signals of a log recorded from a GUI
+// interaction, while recording again, and comparing the logs, gives a
+// regression test for the interaction flow.
+//
+// Paths in the log are relative to the root of the recorder, with the root
+// itself being "/" -- nodes outside the subtree (e.g., receivers elsewhere)
+// have their full PathUnique prefixed with "!".
+
+// SignalRecord is one recorded signal delivery
+type SignalRecord struct {
+	Seq      int             `desc:"sequence number of the emission -- all the deliveries of the same emitted signal have the same Seq"`
+	Depth    int             `desc:"nesting depth of the emission -- 0 for a top-level emission, > 0 for signals emitted by receivers while handling another signal -- only accurate if signals are emitted on one goroutine"`
+	Time     time.Time       `desc:"time when the signal was delivered"`
+	Sender   string          `desc:"path of the sender"`
+	SigField string          `desc:"name of the Signal field on the sender that emitted the signal -- empty if not found"`
+	Recv     string          `desc:"path of the receiver -- empty if the signal was not delivered to any receiver"`
+	Sig      int64           `desc:"the signal code"`
+	SigName  string          `desc:"name of the signal code, from the SigType of the signal -- see Signal.SigName"`
+	DataType string          `desc:"type of the data sent with the signal, empty if nil -- type name as registered in kit.Types or kit.Enums, with * prefix for pointers"`
+	DataPath string          `desc:"if the data is a Ki node, its path"`
+	Data     json.RawMessage `desc:"data sent with the signal, as JSON, if it could be encoded (and was not a Ki node)"`
+}
+
+// String returns a one-line summary of the record, without the time
+func (sr *SignalRecord) String() string {
+	data := string(sr.Data)
+	if sr.DataPath != "" {
+		data = sr.DataPath
+	}
+	return fmt.Sprintf("%v: %v.%v -> %v sig: %v data: %v %v", sr.Seq, sr.Sender, sr.SigField, sr.Recv, sr.SigName, sr.DataType, data)
+}
+
+// Same returns true if the records are the same apart from their times and
+// sequence numbers
+func (sr *SignalRecord) Same(osr *SignalRecord) bool {
+	return sr.Depth == osr.Depth && sr.Sender == osr.Sender && sr.SigField == osr.SigField &&
+		sr.Recv == osr.Recv && sr.Sig == osr.Sig && sr.DataType == osr.DataType &&
+		sr.DataPath == osr.DataPath && string(sr.Data) == string(osr.Data)
+}
+
+// SignalLog is a list of recorded signals, in order of delivery
+type SignalLog []SignalRecord
+
+// SaveJSON returns the log encoded as JSON
+func (sl SignalLog) SaveJSON(indent bool) ([]byte, error) {
+	if indent {
+		return json.MarshalIndent(sl, "", "  ")
+	}
+	return json.Marshal(sl)
+}
+
+// SaveJSONToFile saves the log to a JSON file
+func (sl SignalLog) SaveJSONToFile(filename string) error {
+	b, err := sl.SaveJSON(true)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	err = ioutil.WriteFile(filename, b, 0644)
+	if err != nil {
+		log.Println(err)
+	}
+	return err
+}
+
+// LoadJSON loads the log from JSON, replacing any existing records
+func (sl *SignalLog) LoadJSON(b []byte) error {
+	*sl = nil
+	return json.Unmarshal(b, sl)
+}
+
+// LoadJSONFromFile loads the log from a JSON file, replacing any existing
+// records
+func (sl *SignalLog) LoadJSONFromFile(filename string) error {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	return sl.LoadJSON(b)
+}
+
+// Filter returns the records for which fun returns true
+func (sl SignalLog) Filter(fun func(sr *SignalRecord) bool) SignalLog {
+	var fl SignalLog
+	for i := range sl {
+		if fun(&sl[i]) {
+			fl = append(fl, sl[i])
+		}
+	}
+	return fl
+}
+
+// FilterPath returns the records whose sender or receiver is at or below
+// given path
+func (sl SignalLog) FilterPath(path string) SignalLog {
+	under := func(p string) bool {
+		return p == path || strings.HasPrefix(p, strings.TrimSuffix(path, "/")+"/") || strings.HasPrefix(p, path+".")
+	}
+	return sl.Filter(func(sr *SignalRecord) bool {
+		return under(sr.Sender) || under(sr.Recv)
+	})
+}
+
+// FilterSig returns the records with any of given signal names (SigName)
+func (sl SignalLog) FilterSig(signms ...string) SignalLog {
+	return sl.Filter(func(sr *SignalRecord) bool {
+		for _, nm := range signms {
+			if sr.SigName == nm {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Compare compares the log with another one, e.g., recorded while replaying
+// this one, ignoring times and sequence numbers -- returns an error
+// describing the first difference, nil if the same
+func (sl SignalLog) Compare(osl SignalLog) error {
+	for i := range sl {
+		if i >= len(osl) {
+			return fmt.Errorf("ki SignalLog Compare: other log ends at record %v, missing: %v", i, sl[i].String())
+		}
+		if !sl[i].Same(&osl[i]) {
+			return fmt.Errorf("ki SignalLog Compare: record %v differs:\n%v\nvs.\n%v", i, sl[i].String(), osl[i].String())
+		}
+	}
+	if len(osl) > len(sl) {
+		return fmt.Errorf("ki SignalLog Compare: other log has extra record %v: %v", len(sl), osl[len(sl)].String())
+	}
+	return nil
+}
+
+// Replay emits the top-level (Depth 0) signals in the log, in order, from
+// the senders found at the same paths under root -- signals emitted by
+// receivers in response are not replayed, as they are emitted again by the
+// receivers -- data is decoded using the types registered in kit.Types and
+// kit.Enums, in addition to the basic builtin types -- returns an error if
+// any sender, signal, or data could not be found, after replaying the rest
+func (sl SignalLog) Replay(root Ki) error {
+	var errs []string
+	lastSeq := -1
+	for i := range sl {
+		sr := &sl[i]
+		if sr.Depth > 0 || sr.Seq == lastSeq {
+			continue
+		}
+		lastSeq = sr.Seq
+		send := signalRecordNode(root, sr.Sender)
+		if send == nil {
+			errs = append(errs, fmt.Sprintf("sender not found: %v", sr.String()))
+			continue
+		}
+		sig := signalByFieldName(send, sr.SigField)
+		if sig == nil {
+			errs = append(errs, fmt.Sprintf("signal field not found: %v", sr.String()))
+			continue
+		}
+		data, err := sr.decodeData(root)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%v: %v", err, sr.String()))
+			continue
+		}
+		sig.Emit(send, sr.Sig, data)
+	}
+	if len(errs) > 0 {
+		err := errors.New("ki SignalLog Replay errors:\n" + strings.Join(errs, "\n"))
+		log.Println(err)
+		return err
+	}
+	return nil
+}
+
+// signalRecordBuiltins are the builtin data types that can be decoded
+var signalRecordBuiltins = map[string]reflect.Type{
+	"bool":    reflect.TypeOf(false),
+	"int":     reflect.TypeOf(int(0)),
+	"int32":   reflect.TypeOf(int32(0)),
+	"int64":   reflect.TypeOf(int64(0)),
+	"uint":    reflect.TypeOf(uint(0)),
+	"uint32":  reflect.TypeOf(uint32(0)),
+	"uint64":  reflect.TypeOf(uint64(0)),
+	"float32": reflect.TypeOf(float32(0)),
+	"float64": reflect.TypeOf(float64(0)),
+	"string":  reflect.TypeOf(""),
+}
+
+// decodeData returns the data of the record, decoded as its type
+func (sr *SignalRecord) decodeData(root Ki) (interface{}, error) {
+	if sr.DataPath != "" {
+		dk := signalRecordNode(root, sr.DataPath)
+		if dk == nil {
+			return nil, errors.New("data node not found")
+		}
+		return dk, nil
+	}
+	if sr.DataType == "" {
+		return nil, nil
+	}
+	tnm := strings.TrimPrefix(sr.DataType, "*")
+	typ := signalRecordBuiltins[tnm]
+	if typ == nil {
+		typ = kit.Types.Type(tnm)
+	}
+	if typ == nil {
+		typ = kit.Enums.Enum(tnm)
+	}
+	if typ == nil || len(sr.Data) == 0 {
+		return nil, fmt.Errorf("data type not decodable")
+	}
+	dv := reflect.New(typ)
+	if err := json.Unmarshal(sr.Data, dv.Interface()); err != nil {
+		return nil, err
+	}
+	if tnm != sr.DataType {
+		return dv.Interface(), nil
+	}
+	return dv.Elem().Interface(), nil
+}
+
+// signalDataType returns the type name of data for the record
+func signalDataType(data interface{}) string {
+	typ := reflect.TypeOf(data)
+	ptr := ""
+	if typ.Kind() == reflect.Ptr {
+		ptr = "*"
+		typ = typ.Elem()
+	}
+	if typ.Name() == "" || typ.PkgPath() == "" {
+		return ptr + typ.String()
+	}
+	return ptr + kit.FullTypeName(typ)
+}
+
+// signalRecordNode returns the node at given record path under root, nil if
+// not found
+func signalRecordNode(root Ki, path string) Ki {
+	if path == "/" {
+		return root
+	}
+	if strings.HasPrefix(path, "!") {
+		return root.Root().FindPathUnique(path[1:])
+	}
+	return root.FindPathUnique("/" + root.UniqueName() + path)
+}
+
+// signalFieldName returns the name of the field of k that is given signal,
+// including fields of embedded structs, "" if not found
+func signalFieldName(k Ki, sig *Signal) string {
+	v := reflect.Indirect(reflect.ValueOf(k))
+	if v.Kind() != reflect.Struct || !v.CanAddr() {
+		return ""
+	}
+	return signalFieldNameValue(v, uintptr(unsafe.Pointer(sig)))
+}
+
+func signalFieldNameValue(v reflect.Value, addr uintptr) string {
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		fv := v.Field(i)
+		if f.Type == KiT_Signal {
+			if fv.UnsafeAddr() == addr {
+				return f.Name
+			}
+		} else if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if nm := signalFieldNameValue(fv, addr); nm != "" {
+				return nm
+			}
+		}
+	}
+	return ""
+}
+
+// signalByFieldName returns the Signal field of k with given name, nil if
+// not found
+func signalByFieldName(k Ki, name string) *Signal {
+	v := reflect.Indirect(reflect.ValueOf(k))
+	if name == "" || v.Kind() != reflect.Struct || !v.CanAddr() {
+		return nil
+	}
+	fv := v.FieldByName(name)
+	if !fv.IsValid() || fv.Type() != KiT_Signal {
+		return nil
+	}
+	return (*Signal)(unsafe.Pointer(fv.UnsafeAddr()))
+}
+
+// SignalRecorder records the signals emitted by nodes within the subtree
+// under Root, while started -- get the recorded signals with Log
+type SignalRecorder struct {
+	Root  Ki         `desc:"root of the subtree of senders to record -- receivers can be anywhere"`
+	Mu    sync.Mutex `json:"-" xml:"-" desc:"mutex protecting the log"`
+	log   SignalLog
+	seq   int
+	depth int
+}
+
+// signalRecorders are the started recorders, with their number for a quick
+// check in the signal sending path
+var (
+	signalRecorders   []*SignalRecorder
+	signalRecordersMu sync.RWMutex
+	nSignalRecorders  int32
+)
+
+// NewSignalRecorder returns a new recorder for signals emitted from given
+// subtree -- call Start to start recording
+func NewSignalRecorder(root Ki) *SignalRecorder {
+	return &SignalRecorder{Root: root}
+}
+
+// Start starts recording signals, adding to any already recorded
+func (sr *SignalRecorder) Start() {
+	signalRecordersMu.Lock()
+	defer signalRecordersMu.Unlock()
+	for _, r := range signalRecorders {
+		if r == sr {
+			return
+		}
+	}
+	signalRecorders = append(signalRecorders, sr)
+	atomic.StoreInt32(&nSignalRecorders, int32(len(signalRecorders)))
+}
+
+// Stop stops recording signals
+func (sr *SignalRecorder) Stop() {
+	signalRecordersMu.Lock()
+	defer signalRecordersMu.Unlock()
+	for i, r := range signalRecorders {
+		if r == sr {
+			signalRecorders = append(signalRecorders[:i], signalRecorders[i+1:]...)
+			break
+		}
+	}
+	atomic.StoreInt32(&nSignalRecorders, int32(len(signalRecorders)))
+}
+
+// Log returns a copy of the signals recorded so far
+func (sr *SignalRecorder) Log() SignalLog {
+	sr.Mu.Lock()
+	defer sr.Mu.Unlock()
+	return append(SignalLog(nil), sr.log...)
+}
+
+// Reset clears the recorded signals
+func (sr *SignalRecorder) Reset() {
+	sr.Mu.Lock()
+	sr.log = nil
+	sr.seq = 0
+	sr.Mu.Unlock()
+}
+
+// path returns the path of k for the log
+func (sr *SignalRecorder) path(k Ki) string {
+	if k == sr.Root {
+		return "/"
+	}
+	if !k.HasParent(sr.Root) {
+		return "!" + k.PathUnique()
+	}
+	path := ""
+	for ; k != sr.Root; k = k.Parent() {
+		if k.IsField() {
+			path = "." + k.UniqueName() + path
+		} else {
+			path = "/" + k.UniqueName() + path
+		}
+	}
+	return path
+}
+
+// signalRecEmit is the recording of one emission by one recorder
+type signalRecEmit struct {
+	rec   *SignalRecorder
+	base  SignalRecord
+	ndeli int
+}
+
+// startSignalRecords starts the recording of an emission by all the
+// recorders whose subtree includes the sender -- returns nil if none
+func startSignalRecords(s *Signal, sender Ki, sig int64, data interface{}) []*signalRecEmit {
+	signalRecordersMu.RLock()
+	defer signalRecordersMu.RUnlock()
+	var recs []*signalRecEmit
+	for _, sr := range signalRecorders {
+		if sr.Root == nil || (sender != sr.Root && !sender.HasParent(sr.Root)) {
+			continue
+		}
+		re := &signalRecEmit{rec: sr}
+		re.base.Sender = sr.path(sender)
+		re.base.SigField = signalFieldName(sender, s)
+		re.base.Sig = sig
+		re.base.SigName = s.senderSigName(sender, sig)
+		if data != nil {
+			re.base.DataType = signalDataType(data)
+			if dk, ok := data.(Ki); ok {
+				re.base.DataPath = sr.path(dk)
+			} else if b, err := json.Marshal(data); err == nil {
+				re.base.Data = b
+			}
+		}
+		sr.Mu.Lock()
+		re.base.Seq = sr.seq
+		re.base.Depth = sr.depth
+		sr.seq++
+		sr.depth++
+		sr.Mu.Unlock()
+		recs = append(recs, re)
+	}
+	return recs
+}
+
+// recordSignal records the delivery of the signal to given receiver
+func recordSignal(recs []*signalRecEmit, recv Ki) {
+	for _, re := range recs {
+		re.ndeli++
+		r := re.base
+		r.Recv = re.rec.path(recv)
+		r.Time = time.Now()
+		re.rec.Mu.Lock()
+		re.rec.log = append(re.rec.log, r)
+		re.rec.Mu.Unlock()
+	}
+}
+
+// endSignalRecords ends the recording of an emission, recording it with no
+// receiver if it was not delivered to any
+func endSignalRecords(recs []*signalRecEmit) {
+	for _, re := range recs {
+		re.rec.Mu.Lock()
+		re.rec.depth--
+		if re.ndeli == 0 {
+			r := re.base
+			r.Time = time.Now()
+			re.rec.log = append(re.rec.log, r)
+		}
+		re.rec.Mu.Unlock()
+	}
+}
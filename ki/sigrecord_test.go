@@ -0,0 +1,97 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// sigRecordTestTree makes a tree where child1 relays sig1 signals from the
+// root on its own sig2, to child2
+func sigRecordTestTree() (*TestNode, *[]string) {
+	parent := &TestNode{}
+	parent.InitName(parent, "par1")
+	typ := parent.Type()
+	child1 := parent.AddNewChild(typ, "child1").(*TestNode)
+	child2 := parent.AddNewChild(typ, "child2").(*TestNode)
+	parent.sig1.SetSigType(KiT_NodeSignals)
+	res := &[]string{}
+	parent.sig1.Connect(child1, func(recv, send Ki, sig int64, data interface{}) {
+		*res = append(*res, recv.Name()+" got "+data.(string))
+		recv.(*TestNode).sig2.Emit(recv, sig+1, send)
+	})
+	child1.sig2.Connect(child2, func(recv, send Ki, sig int64, data interface{}) {
+		*res = append(*res, recv.Name()+" got relay from "+data.(Ki).Name())
+	})
+	return parent, res
+}
+
+func TestSignalRecorder(t *testing.T) {
+	parent, res := sigRecordTestTree()
+	rec := NewSignalRecorder(parent)
+	rec.Start()
+	parent.sig1.Emit(parent, int64(NodeSignalNil), "a")
+	parent.sig2.Emit(parent, 5, nil) // no receivers
+	parent.sig1.Emit(parent, int64(NodeSignalNil), "b")
+	rec.Stop()
+	parent.sig1.Emit(parent, int64(NodeSignalNil), "c")
+
+	sl := rec.Log()
+	if len(sl) != 5 || len(*res) != 6 {
+		t.Fatalf("recorded %v signals, should be 5:\n%v", len(sl), sl)
+	}
+	r0, r1, r2 := sl[0], sl[1], sl[2]
+	if r0.Sender != "/" || r0.SigField != "sig1" || r0.Recv != "/child1" || r0.Depth != 0 || r0.DataType != "string" || string(r0.Data) != `"a"` {
+		t.Errorf("first record wrong: %v", r0.String())
+	}
+	if r1.Sender != "/child1" || r1.SigField != "sig2" || r1.Recv != "/child2" || r1.Depth != 1 || r1.Sig != 1 || r1.DataPath != "/" {
+		t.Errorf("relayed record wrong: %v", r1.String())
+	}
+	if r2.Seq != 2 || r2.Recv != "" || r2.SigName != "5" {
+		t.Errorf("record of signal without receivers wrong: %v", r2.String())
+	}
+	if fl := sl.FilterPath("/child2"); len(fl) != 2 || fl[0].Recv != "/child2" {
+		t.Errorf("FilterPath got: %v", fl)
+	}
+	if fl := sl.FilterSig("NodeSignalNil"); len(fl) != 2 {
+		t.Errorf("FilterSig got: %v", fl)
+	}
+
+	// save, load and replay against a fresh tree, recording it again
+	fnm := filepath.Join(t.TempDir(), "siglog.json")
+	if err := sl.SaveJSONToFile(fnm); err != nil {
+		t.Fatal(err)
+	}
+	var ll SignalLog
+	if err := ll.LoadJSONFromFile(fnm); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(fnm)
+	nparent, nres := sigRecordTestTree()
+	nrec := NewSignalRecorder(nparent)
+	nrec.Start()
+	if err := ll.Replay(nparent); err != nil {
+		t.Errorf("replay error: %v", err)
+	}
+	nrec.Stop()
+	if err := sl.Compare(nrec.Log()); err != nil {
+		t.Error(err)
+	}
+	if len(*nres) != 4 || (*nres)[3] != "child2 got relay from par1" {
+		t.Errorf("replay results: %v", *nres)
+	}
+
+	// a changed tree gives a different log
+	nparent.sig1.Disconnect(nil, nil)
+	nrec.Reset()
+	nrec.Start()
+	ll.Replay(nparent)
+	nrec.Stop()
+	if err := sl.Compare(nrec.Log()); err == nil {
+		t.Errorf("compare of different logs should fail")
+	}
+}
@@ -10,10 +10,12 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/json-iterator/go"
 	"github.com/rcoreilly/goki/ki/bitflag"
@@ -253,6 +255,10 @@ func (k *Slice) UniqueNameToIndexMap() map[string]int {
 // values
 func (k *Slice) Config(n Ki, config kit.TypeAndNameList, uniqNm bool) (mods, updt bool) {
 	mods, updt = false, false
+	var mu *sync.RWMutex // tree mutex protecting modifications of the slice
+	if n != nil {
+		mu = n.TreeMutex()
+	}
 	// first make a map for looking up the indexes of the names
 	nm := make(map[string]int)
 	for i, tn := range config {
@@ -278,9 +284,14 @@ func (k *Slice) Config(n Ki, config kit.TypeAndNameList, uniqNm bool) (mods, upd
 	// next add and move items as needed -- in order so guaranteed
 	for i, tn := range config {
 		var kidx int
-		if uniqNm {
+		switch {
+		case n != nil && uniqNm: // uses the NameIndex if there is one
+			kidx = n.ChildIndexByUniqueName(tn.Name, i)
+		case n != nil:
+			kidx = n.ChildIndexByName(tn.Name, i)
+		case uniqNm:
 			kidx = k.IndexByUniqueName(tn.Name, i)
-		} else {
+		default:
 			kidx = k.IndexByName(tn.Name, i)
 		}
 		if kidx < 0 {
@@ -292,10 +303,18 @@ func (k *Slice) Config(n Ki, config kit.TypeAndNameList, uniqNm bool) (mods, upd
 			}
 			nkid := NewOfType(tn.Type)
 			nkid.Init(nkid)
+			if mu != nil {
+				mu.Lock()
+			}
 			k.Insert(nkid, i)
+			if mu != nil {
+				mu.Unlock()
+			}
 			if n != nil {
+				recordEdit(&UndoRec{Op: UndoInsert, Node: n, Kid: nkid, Idx: i})
 				nkid.SetParent(n)
 				bitflag.Set(n.Flags(), int(ChildAdded))
+				nameIndexAdded(n, nkid, i)
 			}
 			if uniqNm {
 				nkid.SetNameRaw(tn.Name)
@@ -303,6 +322,9 @@ func (k *Slice) Config(n Ki, config kit.TypeAndNameList, uniqNm bool) (mods, upd
 			} else {
 				nkid.SetName(tn.Name)
 			}
+			if n != nil {
+				callOnAdded(nkid, n)
+			}
 		} else {
 			if kidx != i {
 				if !mods {
@@ -311,7 +333,16 @@ func (k *Slice) Config(n Ki, config kit.TypeAndNameList, uniqNm bool) (mods, upd
 						updt = n.UpdateStart()
 					}
 				}
+				if mu != nil {
+					mu.Lock()
+				}
 				k.Move(kidx, i)
+				if mu != nil {
+					mu.Unlock()
+				}
+				if n != nil {
+					recordEdit(&UndoRec{Op: UndoMove, Node: n, Idx: kidx, ToIdx: i})
+				}
 			}
 		}
 	}
@@ -329,8 +360,24 @@ func (k *Slice) configDeleteKid(kid Ki, i int, n Ki, mods, updt *bool) {
 	bitflag.Set(kid.Flags(), int(NodeDeleted))
 	kid.NodeSignal().Emit(kid, int64(NodeSignalDeleting), nil)
 	kid.SetParent(nil)
-	DelMgr.Add(kid)
+	if n == nil || !recordEdit(&UndoRec{Op: UndoDelete, Node: n, Kid: kid, Idx: i, Destroy: true}) {
+		DelMgr.Add(kid)
+	}
+	var mu *sync.RWMutex
+	if n != nil {
+		mu = n.TreeMutex()
+	}
+	if mu != nil {
+		mu.Lock()
+	}
 	k.DeleteAtIndex(i)
+	if mu != nil {
+		mu.Unlock()
+	}
+	if n != nil {
+		nameIndexRemoved(n, kid)
+		callOnRemoved(kid, n)
+	}
 	kid.UpdateReset() // it won't get the UpdateEnd from us anymore -- init fresh in any case
 }
 
@@ -349,7 +396,7 @@ func (k Slice) MarshalJSON() ([]byte, error) {
 	b = append(b, []byte(nstr)...)
 	for i, kid := range k {
 		// fmt.Printf("json out of %v\n", kid.PathUnique())
-		knm := kit.FullTypeName(reflect.TypeOf(kid).Elem())
+		knm := kit.Migrations.SavedTypeName(reflect.TypeOf(kid).Elem())
 		tstr := fmt.Sprintf("\"type\":\"%v\", \"name\": \"%v\"", knm, kid.UniqueName()) // todo: escape names!
 		b = append(b, []byte(tstr)...)
 		if i < nk-1 {
@@ -388,7 +435,8 @@ func (k Slice) MarshalJSON() ([]byte, error) {
 
 // UnmarshalJSON parses the length and type information for each object in the
 // slice, creates the new slice with those elements, and then loads based on
-// the remaining bytes which represent each element
+// the remaining bytes which represent each element -- elements saved with an older
+// schema version or type name are upgraded using kit.Migrations
 func (k *Slice) UnmarshalJSON(b []byte) error {
 	// fmt.Printf("json in: %v\n", string(b))
 	if bytes.Equal(b, []byte("null")) {
@@ -420,6 +468,8 @@ func (k *Slice) UnmarshalJSON(b []byte) error {
 	// fmt.Printf("n parsed: %d from %v\n", n, string(bn))
 
 	tnl := make(kit.TypeAndNameList, n)
+	saved := make([]string, n) // saved type names, for migrations
+	migrate := false
 
 	for i := 0; i < n; i++ {
 		fld := flds[2*i+1]
@@ -430,12 +480,16 @@ func (k *Slice) UnmarshalJSON(b []byte) error {
 		ni := bytes.Index(fld, []byte("\"name\":"))
 		nm := string(bytes.Trim(bytes.TrimSpace(fld[ni+7:]), "\""))
 		// fmt.Printf("making type: %v", tn)
-		typ := kit.Types.Type(tn)
+		typ, _ := kit.Migrations.Type(tn)
 		if typ == nil {
 			return fmt.Errorf("ki.Slice UnmarshalJSON: kit.Types type name not found: %v", tn)
 		}
 		tnl[i].Type = typ
 		tnl[i].Name = nm
+		saved[i] = tn
+		if kit.Migrations.NeedsMigrate(typ, tn) {
+			migrate = true
+		}
 	}
 
 	k.Config(nil, tnl, true) // true = uniq names
@@ -452,6 +506,10 @@ func (k *Slice) UnmarshalJSON(b []byte) error {
 
 	// fmt.Printf("loading:\n%v", string(cb))
 
+	if migrate {
+		return sliceUnmarshalMigrate(cb, nwk, tnl, saved)
+	}
+
 	if UseJsonIter {
 		err = jsoniter.Unmarshal(cb, &nwk)
 	} else {
@@ -463,6 +521,98 @@ func (k *Slice) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// sliceUnmarshalMigrate loads each of the kids from the JSON list in b,
+// applying kit.Migrations to those saved with an older version or type name
+func sliceUnmarshalMigrate(b []byte, kids []Ki, tnl kit.TypeAndNameList, saved []string) error {
+	var raws []json.RawMessage
+	if err := json.Unmarshal(b, &raws); err != nil {
+		return err
+	}
+	for i, kid := range kids {
+		if i >= len(raws) {
+			break
+		}
+		kb, rec, err := MigrateJSON(tnl[i].Type, saved[i], tnl[i].Name, raws[i])
+		if err != nil {
+			log.Printf("ki.Slice UnmarshalJSON: migration error: %v\n", err)
+		}
+		if rec != nil {
+			addMigrated(kid, *rec)
+		}
+		if UseJsonIter {
+			err = jsoniter.Unmarshal(kb, kid)
+		} else {
+			err = json.Unmarshal(kb, kid)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrateJSON applies kit.Migrations to the JSON encoding b of an object of
+// given type and name, saved under given type name (including version) --
+// returns the migrated encoding and a record of what was done -- returns b
+// unchanged and a nil record if no migration is needed
+func MigrateJSON(typ reflect.Type, saved, name string, b []byte) ([]byte, *kit.MigrationRecord, error) {
+	if !kit.Migrations.NeedsMigrate(typ, saved) {
+		return b, nil, nil
+	}
+	m := make(map[string]interface{})
+	if err := json.Unmarshal(b, &m); err != nil {
+		return b, nil, err
+	}
+	rec, merr := kit.Migrations.Migrate(typ, saved, name, m)
+	mb, err := json.Marshal(m)
+	if err != nil {
+		return b, nil, err
+	}
+	return mb, &rec, merr
+}
+
+// migrated holds the records of migrations applied during a load, by the
+// object they were loaded into (a Ki child, or a *Props), until they are
+// collected for the loaded tree by UnmarshalPost
+var migrated = struct {
+	recs map[interface{}][]kit.MigrationRecord
+	mu   sync.Mutex
+}{recs: make(map[interface{}][]kit.MigrationRecord)}
+
+// addMigrated adds records of migrations applied in loading given object
+func addMigrated(owner interface{}, recs ...kit.MigrationRecord) {
+	if len(recs) == 0 {
+		return
+	}
+	migrated.mu.Lock()
+	migrated.recs[owner] = append(migrated.recs[owner], recs...)
+	migrated.mu.Unlock()
+}
+
+// takeMigrated returns and removes the records of migrations applied in
+// loading given object
+func takeMigrated(owner interface{}) []kit.MigrationRecord {
+	migrated.mu.Lock()
+	recs := migrated.recs[owner]
+	delete(migrated.recs, owner)
+	migrated.mu.Unlock()
+	return recs
+}
+
+// takeMigratedTree returns and removes the records of migrations applied in
+// loading the tree under k (including k), in depth-first order
+func takeMigratedTree(k Ki) []kit.MigrationRecord {
+	var recs []kit.MigrationRecord
+	k.FuncDownMeFirst(0, nil, func(k Ki, level int, d interface{}) bool {
+		recs = append(recs, takeMigrated(k)...)
+		if kn, ok := k.EmbeddedStruct(KiT_Node).(*Node); ok {
+			recs = append(recs, takeMigrated(&kn.Props)...)
+		}
+		return true
+	})
+	return recs
+}
+
 // todo: save N as an attr instead of a full element
 
 // MarshalXML saves the length and type information for each object in a
@@ -475,7 +625,7 @@ func (k Slice) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 	nt := xml.StartElement{Name: xml.Name{"", "N"}}
 	tokens = append(tokens, nt, xml.CharData(fmt.Sprintf("%d", nk)), xml.EndElement{nt.Name})
 	for _, kid := range k {
-		knm := kit.FullTypeName(reflect.TypeOf(kid).Elem())
+		knm := kit.Migrations.SavedTypeName(reflect.TypeOf(kid).Elem())
 		t := xml.StartElement{Name: xml.Name{"", "Type"}}
 		tokens = append(tokens, t, xml.CharData(knm), xml.EndElement{t.Name})
 	}
@@ -508,6 +658,48 @@ func (k Slice) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 	return nil
 }
 
+// xmlMigrateReader reads the tokens of one element of an object saved under
+// an older type name or schema version, starting with its start element,
+// renaming its field elements following the kit.Migrations field renames
+type xmlMigrateReader struct {
+	d     *xml.Decoder
+	start *xml.StartElement
+	typ   reflect.Type
+	saved string
+	depth int
+}
+
+func (mr *xmlMigrateReader) Token() (xml.Token, error) {
+	if mr.start != nil {
+		st := *mr.start
+		mr.start = nil
+		mr.depth = 1
+		return st, nil
+	}
+	if mr.depth == 0 {
+		return nil, io.EOF
+	}
+	t, err := mr.d.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tv := t.(type) {
+	case xml.StartElement:
+		mr.depth++
+		if mr.depth == 2 {
+			tv.Name.Local = kit.Migrations.FieldName(mr.typ, mr.saved, tv.Name.Local)
+		}
+		return tv, nil
+	case xml.EndElement:
+		if mr.depth == 2 {
+			tv.Name.Local = kit.Migrations.FieldName(mr.typ, mr.saved, tv.Name.Local)
+		}
+		mr.depth--
+		return tv, nil
+	}
+	return xml.CopyToken(t), nil
+}
+
 // read a start element token
 func DecodeXMLStartEl(d *xml.Decoder) (start xml.StartElement, err error) {
 	for {
@@ -605,9 +797,12 @@ func DecodeXMLCharEl(d *xml.Decoder) (name, val string, err error) {
 	return
 }
 
-// UnmarshalJSON parses the length and type information for each object in the
+// UnmarshalXML parses the length and type information for each object in the
 // slice, creates the new slice with those elements, and then loads based on
-// the remaining bytes which represent each element
+// the remaining elements which represent each element -- elements saved with
+// an older schema version or type name are upgraded using kit.Migrations
+// type and field renames -- migration Fun value transforms operate on JSON
+// maps, and are not applied to XML data
 func (k *Slice) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	// for _, attr := range start.Attr {
 	// 	// todo: need to set the props from name / value -- don't have parent though!
@@ -626,14 +821,15 @@ func (k *Slice) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 			return DecodeXMLEndEl(d, start)
 		}
 		// fmt.Printf("n parsed: %d from %v\n", n, string(val))
-		nwk := make([]Ki, 0, n) // allocate new slice
+		nwk := make([]Ki, 0, n)       // allocate new slice
+		saved := make([]string, 0, n) // saved type names, for migrations
 
 		for i := 0; i < n; i++ {
 			name, val, err = DecodeXMLCharEl(d)
 			if name == "Type" {
 				tn := strings.TrimSpace(val)
 				// fmt.Printf("making type: %v\n", tn)
-				typ := kit.Types.Type(tn)
+				typ, _ := kit.Migrations.Type(tn)
 				if typ == nil {
 					return fmt.Errorf("ki.Slice UnmarshalXML: kit.Types type name not found: %v", tn)
 				}
@@ -645,6 +841,7 @@ func (k *Slice) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 				}
 				kid.Init(kid)
 				nwk = append(nwk, kid)
+				saved = append(saved, tn)
 			}
 		}
 
@@ -654,7 +851,12 @@ func (k *Slice) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 				return err
 			}
 			// todo: could double-check st
-			err = d.DecodeElement(nwk[i], &st)
+			if typ := nwk[i].Type(); kit.Migrations.NeedsMigrate(typ, saved[i]) {
+				mr := &xmlMigrateReader{d: d, start: &st, typ: typ, saved: saved[i]}
+				err = xml.NewTokenDecoder(mr).Decode(nwk[i])
+			} else {
+				err = d.DecodeElement(nwk[i], &st)
+			}
 			if err != nil {
 				log.Printf("%v", err)
 				return err
@@ -0,0 +1,419 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/rcoreilly/goki/ki/kit"
+)
+
+// Snapshots are immutable copies of the state of a tree, for scrubbing
+// through the history of a model: a Snapshotter attached to a tree takes a
+// Snapshot each time Take is called, and the nodes of the snapshot that are
+// unchanged since the previous one -- same field values, Props, name, and
+// (recursively) children -- are shared with it, so a long history of
+// snapshots of a large tree with small changes takes little memory.  Two
+// snapshots can be compared with DiffSnapshots, which skips shared subtrees,
+// and a snapshot can be restored into the live tree with Restore, which
+// applies the differences using Patch, so the tree (and any views of it) is
+// minimally updated.
+//
+// Field values and Props are stored in their JSON encoding, as in Diff, so
+// the same fields are saved as in JSON.  Changes are detected by comparing
+// these encodings, so taking a snapshot still visits the whole tree, but
+// allocates new memory only for what has changed.
+
+// Snapshot is an immutable snapshot of a tree, taken by a Snapshotter
+type Snapshot struct {
+	Root *SnapNode `desc:"snapshot of the root node"`
+	Time time.Time `desc:"time when the snapshot was taken"`
+}
+
+// SnapNode is the snapshot of one node, which is shared by all the snapshots
+// in which the node and its subtree are unchanged -- it must not be modified
+type SnapNode struct {
+	Type       reflect.Type `desc:"type of the node"`
+	Name       string       `desc:"name of the node"`
+	UniqueName string       `desc:"unique name of the node"`
+	UUID       string       `desc:"persistent unique id of the node (Node.Uid), if it has one -- restored so that Ref references to the node resolve again"`
+	Fields     []SnapValue  `desc:"JSON encoding of the field values, for fields that are saved in JSON (excluding Ki fields), in order"`
+	Props      []SnapValue  `desc:"JSON encoding of each property (as a single-key Props), sorted by key"`
+	KiFields   []*SnapNode  `desc:"snapshots of the Ki fields, with the field name as their Name"`
+	Kids       []*SnapNode  `desc:"snapshots of the children"`
+}
+
+// SnapValue is the JSON encoding of one field value or property
+type SnapValue struct {
+	Name  string          `desc:"field name or property key"`
+	Value json.RawMessage `desc:"JSON encoding"`
+}
+
+// Snapshotter takes snapshots of the tree under Root, sharing the unchanged
+// parts of each new snapshot with the previous one
+type Snapshotter struct {
+	Root Ki         `desc:"root of the tree"`
+	Mu   sync.Mutex `json:"-" xml:"-" desc:"mutex for taking snapshots"`
+	last map[Ki]*SnapNode
+}
+
+// NewSnapshotter returns a new snapshotter for the tree under given root
+func NewSnapshotter(root Ki) *Snapshotter {
+	return &Snapshotter{Root: root}
+}
+
+// TakeSnapshot returns a snapshot of the tree under given root, not sharing
+// anything with previous snapshots -- use a Snapshotter for that
+func TakeSnapshot(root Ki) *Snapshot {
+	return NewSnapshotter(root).Take()
+}
+
+// Take takes a new snapshot of the tree
+func (ss *Snapshotter) Take() *Snapshot {
+	ss.Mu.Lock()
+	defer ss.Mu.Unlock()
+	cur := make(map[Ki]*SnapNode, len(ss.last))
+	sn := ss.snapNode(ss.Root, ss.Root.Name(), ss.Root.UniqueName(), cur, nil)
+	ss.last = cur
+	return &Snapshot{Root: sn, Time: time.Now()}
+}
+
+// TakeChanges takes a new snapshot of the tree after the changes in given
+// batch from the ChangeNotifier for the tree, encoding only the nodes that
+// changed, and sharing the rest of the tree with the last snapshot, without
+// visiting it -- the batch must have all the changes since the last
+// snapshot, else Take must be used
+func (ss *Snapshotter) TakeChanges(cb ChangeBatch) *Snapshot {
+	ss.Mu.Lock()
+	defer ss.Mu.Unlock()
+	if ss.last == nil {
+		ss.last = make(map[Ki]*SnapNode)
+	}
+	changed := make(map[Ki]bool)
+	for _, cr := range cb {
+		if cr.Op == ChangeChildRemoved {
+			cr.Kid.FuncDownMeFirst(0, nil, func(k Ki, level int, d interface{}) bool {
+				delete(ss.last, k)
+				return true
+			})
+		}
+		for k := cr.Node; k != nil && !changed[k]; k = k.Parent() {
+			changed[k] = true
+			if k == ss.Root {
+				break
+			}
+		}
+	}
+	sn := ss.snapNode(ss.Root, ss.Root.Name(), ss.Root.UniqueName(), ss.last, changed)
+	return &Snapshot{Root: sn, Time: time.Now()}
+}
+
+// Restore restores given snapshot into the tree, by taking a new snapshot
+// and patching the tree with the differences between them -- see Patch
+func (ss *Snapshotter) Restore(snap *Snapshot) error {
+	return Patch(ss.Root, DiffSnapshots(ss.Take(), snap))
+}
+
+// snapNode returns the snapshot of given node, reusing the last one if it
+// has not changed -- name and uniqNm are as they should be recorded -- if
+// changed is non-nil, the last snapshot of nodes not in it is reused without
+// checking them
+func (ss *Snapshotter) snapNode(k Ki, name, uniqNm string, cur map[Ki]*SnapNode, changed map[Ki]bool) *SnapNode {
+	if changed != nil && !changed[k] {
+		if prev, ok := ss.last[k]; ok && prev.Name == name && prev.UniqueName == uniqNm {
+			cur[k] = prev
+			return prev
+		}
+	}
+	sn := &SnapNode{Type: k.Type(), Name: name, UniqueName: uniqNm, UUID: k.UUID()}
+	sn.Fields, sn.KiFields = ss.snapFields(k, cur, changed)
+	sn.Props = snapProps(k.Properties())
+	for _, kid := range k.Children() {
+		sn.Kids = append(sn.Kids, ss.snapNode(kid, kid.Name(), kid.UniqueName(), cur, changed))
+	}
+	if prev, ok := ss.last[k]; ok && prev.sameAs(sn) {
+		sn = prev
+	}
+	cur[k] = sn
+	return sn
+}
+
+// snapFields returns the JSON-encoded field values, and Ki field snapshots,
+// of given node, using the same fields as Diff
+func (ss *Snapshotter) snapFields(k Ki, cur map[Ki]*SnapNode, changed map[Ki]bool) ([]SnapValue, []*SnapNode) {
+	var flds []SnapValue
+	var kis []*SnapNode
+	kit.FlatFieldsTypeFun(k.Type(), func(typ reflect.Type, field reflect.StructField) bool {
+		if typ == KiT_Node || field.PkgPath != "" || field.Tag.Get("json") == "-" {
+			return true
+		}
+		fv := kit.FlatFieldValueByName(k, field.Name)
+		if kit.EmbeddedTypeImplements(field.Type, KiType()) {
+			if field.Type.Kind() == reflect.Struct {
+				fk := kit.PtrValue(fv).Interface().(Ki)
+				kis = append(kis, ss.snapNode(fk, field.Name, field.Name, cur, changed))
+			}
+			return true
+		}
+		b, err := json.Marshal(fv.Interface())
+		if err != nil {
+			return true
+		}
+		flds = append(flds, SnapValue{Name: field.Name, Value: b})
+		return true
+	})
+	return flds, kis
+}
+
+// snapProps returns the JSON encoding of each of the props, sorted by key
+func snapProps(p Props) []SnapValue {
+	if len(p) == 0 {
+		return nil
+	}
+	vals := make([]SnapValue, 0, len(p))
+	for _, key := range diffSortedKeys(p) {
+		b, err := Props{key: p[key]}.MarshalJSON()
+		if err != nil {
+			continue
+		}
+		vals = append(vals, SnapValue{Name: key, Value: b})
+	}
+	return vals
+}
+
+// sameAs returns true if the snapshots are the same -- children and Ki
+// fields are compared by pointer, as they are shared if unchanged
+func (sn *SnapNode) sameAs(osn *SnapNode) bool {
+	if sn.Type != osn.Type || sn.Name != osn.Name || sn.UniqueName != osn.UniqueName || sn.UUID != osn.UUID ||
+		len(sn.Kids) != len(osn.Kids) || len(sn.KiFields) != len(osn.KiFields) ||
+		!snapValuesEqual(sn.Fields, osn.Fields) || !snapValuesEqual(sn.Props, osn.Props) {
+		return false
+	}
+	for i := range sn.Kids {
+		if sn.Kids[i] != osn.Kids[i] {
+			return false
+		}
+	}
+	for i := range sn.KiFields {
+		if sn.KiFields[i] != osn.KiFields[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func snapValuesEqual(a, b []SnapValue) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || !bytes.Equal(a[i].Value, b[i].Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// NewTree creates a new tree from the snapshot
+func (sn *SnapNode) NewTree() Ki {
+	k := NewOfType(sn.Type)
+	k.InitName(k, sn.Name)
+	k.SetUniqueName(sn.UniqueName)
+	sn.setNode(k)
+	k.UnmarshalPost()
+	return k
+}
+
+// setNode sets the UUID, fields, props, and children of given (new) node
+// from the snapshot
+func (sn *SnapNode) setNode(k Ki) {
+	if sn.UUID != "" {
+		k.SetUUID(sn.UUID)
+	}
+	for _, fv := range sn.Fields {
+		v := kit.FlatFieldValueByName(k, fv.Name)
+		if !v.IsValid() || !v.CanAddr() {
+			continue
+		}
+		if err := json.Unmarshal(fv.Value, v.Addr().Interface()); err != nil {
+			log.Printf("ki.SnapNode: could not set field %v: %v\n", fv.Name, err)
+		}
+	}
+	for _, pv := range sn.Props {
+		var p Props
+		if err := p.UnmarshalJSON(pv.Value); err == nil {
+			k.SetProp(pv.Name, p[pv.Name])
+		}
+	}
+	for _, kf := range sn.KiFields {
+		if fk := k.KiFieldByName(kf.Name); fk != nil {
+			kf.setNode(fk)
+		}
+	}
+	for _, ks := range sn.Kids {
+		kid := NewOfType(ks.Type)
+		kid.Init(kid)
+		kid.SetNameRaw(ks.Name)
+		kid.SetUniqueName(ks.UniqueName)
+		k.AddChild(kid)
+		ks.setNode(kid)
+	}
+}
+
+// DiffSnapshots compares two snapshots and returns the edit script that
+// transforms the tree of snapshot a into that of b, as in Diff -- shared
+// subtrees are skipped, so this is fast for snapshots from the same
+// Snapshotter with few changes between them
+func DiffSnapshots(a, b *Snapshot) Diffs {
+	if a.Root.Type != b.Root.Type {
+		log.Printf("ki.DiffSnapshots: root nodes are of different types: %v vs %v\n", a.Root.Type.String(), b.Root.Type.String())
+		return nil
+	}
+	var diffs Diffs
+	diffs.diffSnapNode(a.Root, b.Root, "")
+	return diffs
+}
+
+// diffSnapNode adds the edits for snapshot a vs. b at given path, recursively
+func (diffs *Diffs) diffSnapNode(a, b *SnapNode, path string) {
+	if a == b {
+		return
+	}
+	bf := make(map[string]json.RawMessage, len(b.Fields))
+	for _, fv := range b.Fields {
+		bf[fv.Name] = fv.Value
+	}
+	for _, fv := range a.Fields {
+		if bv, ok := bf[fv.Name]; ok && !bytes.Equal(fv.Value, bv) {
+			*diffs = append(*diffs, &DiffEdit{Op: DiffSetField, Path: path, Name: fv.Name, Value: bv})
+		}
+	}
+	diffs.diffSnapProps(a.Props, b.Props, path)
+	for _, akf := range a.KiFields {
+		for _, bkf := range b.KiFields {
+			if bkf.Name == akf.Name {
+				diffs.diffSnapNode(akf, bkf, path+"."+bkf.Name)
+				break
+			}
+		}
+	}
+	diffs.diffSnapKids(a.Kids, b.Kids, path)
+}
+
+// diffSnapProps adds the edits for props a vs. b, which are sorted by key
+func (diffs *Diffs) diffSnapProps(a, b []SnapValue, path string) {
+	am := make(map[string]json.RawMessage, len(a))
+	for _, pv := range a {
+		am[pv.Name] = pv.Value
+	}
+	bm := make(map[string]bool, len(b))
+	for _, pv := range b {
+		bm[pv.Name] = true
+		if av, ok := am[pv.Name]; ok && bytes.Equal(av, pv.Value) {
+			continue
+		}
+		var p Props
+		if err := p.UnmarshalJSON(pv.Value); err != nil {
+			log.Printf("ki.DiffSnapshots: could not decode prop %v: %v\n", pv.Name, err)
+			continue
+		}
+		*diffs = append(*diffs, &DiffEdit{Op: DiffSetProp, Path: path, Name: pv.Name, Props: p})
+	}
+	for _, pv := range a {
+		if !bm[pv.Name] {
+			*diffs = append(*diffs, &DiffEdit{Op: DiffDeleteProp, Path: path, Name: pv.Name})
+		}
+	}
+}
+
+// diffSnapKids adds the edits for children a vs. b, as in diffKids
+func (diffs *Diffs) diffSnapKids(akids, bkids []*SnapNode, path string) {
+	bmap := make(map[string]*SnapNode, len(bkids))
+	for _, bk := range bkids {
+		bmap[bk.UniqueName] = bk
+	}
+	// first delete -- working back from the end as in Slice.Config
+	cur := make([]*SnapNode, 0, len(akids))
+	for i := len(akids) - 1; i >= 0; i-- {
+		ak := akids[i]
+		if bk, ok := bmap[ak.UniqueName]; !ok || bk.Type != ak.Type {
+			*diffs = append(*diffs, &DiffEdit{Op: DiffDelete, Path: path, Name: ak.UniqueName, Idx: i})
+		} else {
+			cur = append(cur, ak)
+		}
+	}
+	for i, j := 0, len(cur)-1; i < j; i, j = i+1, j-1 {
+		cur[i], cur[j] = cur[j], cur[i]
+	}
+	// then insert and move, in order
+	var matched [][2]*SnapNode
+	for i, bk := range bkids {
+		ci := -1
+		for j, ck := range cur {
+			if ck.UniqueName == bk.UniqueName {
+				ci = j
+				break
+			}
+		}
+		if ci < 0 {
+			kb, err := bk.NewTree().SaveJSON(false)
+			if err != nil {
+				log.Println(err)
+			}
+			*diffs = append(*diffs, &DiffEdit{Op: DiffInsert, Path: path, Name: bk.UniqueName, Type: kit.FullTypeName(bk.Type), Idx: i, Value: kb})
+			cur = append(cur, nil)
+			copy(cur[i+1:], cur[i:])
+			cur[i] = bk
+			continue
+		}
+		matched = append(matched, [2]*SnapNode{cur[ci], bk})
+		if ci != i {
+			*diffs = append(*diffs, &DiffEdit{Op: DiffMove, Path: path, Name: bk.UniqueName, Idx: i})
+			ck := cur[ci]
+			cur = append(cur[:ci], cur[ci+1:]...)
+			cur = append(cur, nil)
+			copy(cur[i+1:], cur[i:])
+			cur[i] = ck
+		}
+	}
+	for _, m := range matched {
+		diffs.diffSnapNode(m[0], m[1], path+"/"+m[1].UniqueName)
+	}
+}
+
+// String returns a summary of the snapshot
+func (s *Snapshot) String() string {
+	return fmt.Sprintf("ki.Snapshot of %v at %v", s.Root.Name, s.Time.Format(time.RFC3339Nano))
+}
+
+// NumSnapNodes returns the number of distinct SnapNodes in given snapshots,
+// counting shared nodes once -- e.g., to measure the memory used by a
+// history of snapshots
+func NumSnapNodes(snaps ...*Snapshot) int {
+	seen := make(map[*SnapNode]bool)
+	var count func(sn *SnapNode)
+	count = func(sn *SnapNode) {
+		if seen[sn] {
+			return
+		}
+		seen[sn] = true
+		for _, kf := range sn.KiFields {
+			count(kf)
+		}
+		for _, kid := range sn.Kids {
+			count(kid)
+		}
+	}
+	for _, s := range snaps {
+		count(s.Root)
+	}
+	return len(seen)
+}
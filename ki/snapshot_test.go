@@ -0,0 +1,146 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	tree := diffTestTree()
+	ss := NewSnapshotter(tree)
+	s1 := ss.Take()
+	n1 := NumSnapNodes(s1)
+
+	s2 := ss.Take()
+	if s2.Root != s1.Root {
+		t.Errorf("snapshot of unchanged tree should be shared")
+	}
+
+	tree.Child(1).Child(0).SetProp("intprop", 17.0)
+	s3 := ss.Take()
+	if s3.Root == s1.Root || s3.Root.Kids[1] == s1.Root.Kids[1] {
+		t.Errorf("changed nodes should not be shared")
+	}
+	if s3.Root.Kids[0] != s1.Root.Kids[0] || s3.Root.Kids[1].Kids[1] != s1.Root.Kids[1].Kids[1] {
+		t.Errorf("unchanged subtrees should be shared")
+	}
+	// only the changed node and its parents are new
+	if n := NumSnapNodes(s1, s3); n != n1+3 {
+		t.Errorf("snapshots should have %v distinct nodes, have: %v", n1+3, n)
+	}
+
+	typ := KiT_NodeEmbed
+	tree.DeleteChildAtIndex(0, true)
+	tree.AddNewChild(typ, "child4").AddNewChild(typ, "subchild4")
+	tree.MoveChild(0, 1)
+	tree.Mbr1 = "bloop"
+	tree.Field2.Mbr2 = 42
+	tree.SetProp("floatprop", 2.0)
+	tree.DeleteProp("stringprop")
+	s4 := ss.Take()
+	if len(DiffSnapshots(s4, s4)) != 0 {
+		t.Errorf("diff of same snapshot should be empty")
+	}
+	if diffs := DiffSnapshots(s1, s4); len(diffs) == 0 {
+		t.Errorf("diff of different snapshots should not be empty")
+	}
+
+	// restore s1, checking against an independent tree
+	if err := ss.Restore(s1); err != nil {
+		t.Error(err)
+	}
+	if diffs := Diff(tree, diffTestTree()); len(diffs) != 0 {
+		t.Errorf("restored tree differs from original: %v", diffs)
+	}
+	if s := ss.Take(); s.Root == s1.Root {
+		t.Errorf("restored tree snapshot should not be the same nodes as before")
+	} else if diffs := DiffSnapshots(s1, s); len(diffs) != 0 {
+		t.Errorf("restored tree snapshot differs: %v", diffs)
+	}
+
+	// restore s4 and back again
+	if err := ss.Restore(s4); err != nil {
+		t.Error(err)
+	}
+	if tree.Mbr1 != "bloop" || tree.Child(2).Name() != "child4" || tree.Child(2).Child(0).Name() != "subchild4" {
+		t.Errorf("restore of later snapshot failed")
+	}
+
+	// new tree from a snapshot
+	nt := s3.Root.NewTree()
+	if diffs := DiffSnapshots(s3, TakeSnapshot(nt)); len(diffs) != 0 {
+		t.Errorf("tree made from snapshot differs: %v", diffs)
+	}
+	if nt.Child(1).Child(0).Prop("intprop", false, false) != 17.0 {
+		t.Errorf("tree made from snapshot is missing prop")
+	}
+}
+
+func TestSnapshotRef(t *testing.T) {
+	root := &RefTestNode{}
+	root.InitName(root, "root")
+	typ := root.Type()
+	a := root.AddNewChild(typ, "a").(*RefTestNode)
+	b := root.AddNewChild(typ, "b").(*RefTestNode)
+	b.AddNewChild(typ, "bk")
+	a.Target.Set(b)
+	uuid := b.UUID()
+	ss := NewSnapshotter(root)
+	s1 := ss.Take()
+
+	root.DeleteChild(b, true)
+	if a.Target.Resolve(a) {
+		t.Errorf("ref to deleted node should not resolve")
+	}
+	if err := ss.Restore(s1); err != nil {
+		t.Error(err)
+	}
+	if nb := root.Child(1); nb.UUID() != uuid {
+		t.Errorf("restored node UUID should be %v, was: %v", uuid, nb.UUID())
+	}
+	if !a.Target.Resolve(a) || a.Target.Ptr != root.Child(1) {
+		t.Errorf("ref to restored node not resolved: %v", a.Target.Ptr)
+	}
+	if nt := s1.Root.NewTree(); nt.Child(1).UUID() != uuid {
+		t.Errorf("tree made from snapshot is missing UUID")
+	}
+}
+
+func TestSnapshotChanges(t *testing.T) {
+	tree := diffTestTree()
+	cn := AttachChangeNotifier(tree)
+	defer cn.Detach()
+	ss := NewSnapshotter(tree)
+	s1 := ss.Take()
+	var snaps []*Snapshot
+	cn.ChangeSig.Connect(tree.This, func(recv, send Ki, sig int64, data interface{}) {
+		snaps = append(snaps, ss.TakeChanges(data.(ChangeBatch)))
+	})
+
+	typ := KiT_NodeEmbed
+	updt := tree.UpdateStart()
+	tree.Child(1).Child(0).SetProp("intprop", 17)
+	tree.Child(1).AddNewChild(typ, "subchild3").SetField("Mbr1", "new")
+	tree.Child(2).SetName("child3b")
+	tree.DeleteChildAtIndex(0, true)
+	tree.UpdateEnd(updt)
+	if len(snaps) != 1 {
+		t.Fatalf("should be 1 snapshot, was: %v", len(snaps))
+	}
+	s2 := snaps[0]
+	if diffs := DiffSnapshots(s2, TakeSnapshot(tree)); len(diffs) != 0 {
+		t.Errorf("snapshot of changes differs from tree: %v", diffs)
+	}
+	if s2.Root.Kids[0].Kids[1] != s1.Root.Kids[1].Kids[1] {
+		t.Errorf("unchanged subtrees should be shared")
+	}
+	if s2.Root.Kids[0].Kids[0] == s1.Root.Kids[1].Kids[0] {
+		t.Errorf("changed nodes should not be shared")
+	}
+	if diffs := DiffSnapshots(ss.Take(), s2); len(diffs) != 0 {
+		t.Errorf("snapshot of changes differs from full snapshot: %v", diffs)
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package treealgo
+
+import (
+	"github.com/rcoreilly/goki/ki"
+	"github.com/rcoreilly/goki/ki/kit"
+)
+
+// IterOrders are the orders in which an Iter visits the nodes of a tree
+type IterOrders int32
+
+const (
+	// PreOrder visits each node before its children -- the same order as
+	// FuncDownMeFirst
+	PreOrder IterOrders = iota
+
+	// PostOrder visits each node after its children -- the same order as
+	// FuncDownDepthFirst
+	PostOrder
+
+	// LevelOrder visits all the nodes at each level before those at the
+	// next level down (breadth first)
+	LevelOrder
+
+	IterOrdersN
+)
+
+//go:generate stringer -type=IterOrders
+
+var KiT_IterOrders = kit.Enums.AddEnum(IterOrdersN, false, nil)
+
+// Iter is a pull-style iterator over the nodes of the subtree under a given
+// top node (including the top node itself), in a given order -- use as:
+//
+//	for it := treealgo.NewPreOrderIter(root); it.Next(); {
+//		k := it.Node()
+//		...
+//	}
+//
+// The children of a node are read once, as it is visited (pre and level
+// order) or when the iteration reaches it on the way down (post order), so
+// the tree should not be modified during the iteration, except for the node
+// returned by Node and its children in pre and level order
+type Iter struct {
+	Order IterOrders `desc:"order of visiting the nodes"`
+	stack []iterItem
+	head  int
+	cur   iterItem
+	skip  bool
+	done  bool
+}
+
+// iterItem is a node with its level and index -- and for post order, its
+// children and the index of the next child to visit
+type iterItem struct {
+	node  ki.Ki
+	level int
+	index int
+	kids  ki.Slice
+	next  int
+}
+
+// NewIter returns a new iterator over the subtree under given top node, in
+// given order
+func NewIter(top ki.Ki, order IterOrders) *Iter {
+	it := &Iter{Order: order}
+	itm := iterItem{node: top}
+	if order == PostOrder {
+		itm.kids = top.Children()
+	}
+	it.stack = append(it.stack, itm)
+	return it
+}
+
+// NewPreOrderIter returns a new iterator over the subtree under given top
+// node, visiting each node before its children
+func NewPreOrderIter(top ki.Ki) *Iter {
+	return NewIter(top, PreOrder)
+}
+
+// NewPostOrderIter returns a new iterator over the subtree under given top
+// node, visiting each node after its children
+func NewPostOrderIter(top ki.Ki) *Iter {
+	return NewIter(top, PostOrder)
+}
+
+// NewLevelOrderIter returns a new iterator over the subtree under given top
+// node, visiting the nodes level by level
+func NewLevelOrderIter(top ki.Ki) *Iter {
+	return NewIter(top, LevelOrder)
+}
+
+// Next advances to the next node, returning false when there are no more
+func (it *Iter) Next() bool {
+	if it.done {
+		return false
+	}
+	switch it.Order {
+	case PreOrder:
+		it.nextPre()
+	case PostOrder:
+		it.nextPost()
+	case LevelOrder:
+		it.nextLevel()
+	}
+	return !it.done
+}
+
+// Node returns the current node -- nil before the first Next or after the
+// last one
+func (it *Iter) Node() ki.Ki {
+	return it.cur.node
+}
+
+// Level returns the level of the current node below the top node -- 0 for
+// the top node
+func (it *Iter) Level() int {
+	return it.cur.level
+}
+
+// Index returns the index of the current node within the children of its
+// parent -- 0 for the top node
+func (it *Iter) Index() int {
+	return it.cur.index
+}
+
+// SkipChildren skips the children of the current node, in pre and level
+// order -- in post order, they have already been visited
+func (it *Iter) SkipChildren() {
+	it.skip = true
+}
+
+// pushKids adds the children of the current node to the stack, unless
+// skipped -- in reverse order for pre order, so the first is popped first
+func (it *Iter) pushKids() {
+	if it.cur.node == nil || it.skip {
+		it.skip = false
+		return
+	}
+	kids := it.cur.node.Children()
+	lev := it.cur.level + 1
+	if it.Order == PreOrder {
+		for i := len(kids) - 1; i >= 0; i-- {
+			it.stack = append(it.stack, iterItem{node: kids[i], level: lev, index: i})
+		}
+		return
+	}
+	for i, kid := range kids {
+		it.stack = append(it.stack, iterItem{node: kid, level: lev, index: i})
+	}
+}
+
+func (it *Iter) finish() {
+	it.done = true
+	it.cur = iterItem{}
+	it.stack = nil
+}
+
+func (it *Iter) nextPre() {
+	it.pushKids()
+	n := len(it.stack)
+	if n == 0 {
+		it.finish()
+		return
+	}
+	it.cur = it.stack[n-1]
+	it.stack = it.stack[:n-1]
+}
+
+func (it *Iter) nextLevel() {
+	it.pushKids()
+	if it.head >= len(it.stack) {
+		it.finish()
+		return
+	}
+	it.cur = it.stack[it.head]
+	it.stack[it.head] = iterItem{}
+	it.head++
+	if it.head > 1024 && it.head*2 > len(it.stack) { // reclaim the visited part of the queue
+		it.stack = append(it.stack[:0], it.stack[it.head:]...)
+		it.head = 0
+	}
+}
+
+func (it *Iter) nextPost() {
+	for {
+		n := len(it.stack)
+		if n == 0 {
+			it.finish()
+			return
+		}
+		top := &it.stack[n-1]
+		if top.next < len(top.kids) {
+			kn := top.kids[top.next]
+			kid := iterItem{node: kn, level: top.level + 1, index: top.next, kids: kn.Children()}
+			top.next++ // before append, which can move the stack
+			it.stack = append(it.stack, kid)
+			continue
+		}
+		it.cur = *top
+		it.cur.kids = nil
+		it.stack[n-1] = iterItem{}
+		it.stack = it.stack[:n-1]
+		return
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// package treealgo provides generic algorithms on ki.Ki trees, beyond the
+// FuncDown* traversals: measures of trees (Depth, Height, Size, NumLeaves),
+// LowestCommonAncestor, pull-style pre-order, post-order, and level-order
+// iterators (Iter), sibling and in-order navigation, flattening a tree into
+// a list with indexes, and sorting children -- all of these operate on the
+// Children of nodes, not Ki fields, and work on any ki.Ki, without
+// recursion, so they are fine on very deep trees
+package treealgo
+
+// github.com/rcoreilly/goki/ki/treealgo
+
+import (
+	"sort"
+
+	"github.com/rcoreilly/goki/ki"
+)
+
+// Depth returns the number of parents above given node -- 0 for a root
+func Depth(k ki.Ki) int {
+	d := 0
+	for p := k.Parent(); p != nil; p = p.Parent() {
+		d++
+	}
+	return d
+}
+
+// Height returns the number of levels below given node, along the longest
+// path down to a leaf -- 0 for a leaf
+func Height(k ki.Ki) int {
+	h := 0
+	for it := NewPreOrderIter(k); it.Next(); {
+		if it.Level() > h {
+			h = it.Level()
+		}
+	}
+	return h
+}
+
+// Size returns the number of nodes in the subtree under given node,
+// including the node itself
+func Size(k ki.Ki) int {
+	n := 0
+	for it := NewPreOrderIter(k); it.Next(); {
+		n++
+	}
+	return n
+}
+
+// NumLeaves returns the number of leaves (nodes without children) in the
+// subtree under given node -- 1 if the node itself is a leaf
+func NumLeaves(k ki.Ki) int {
+	n := 0
+	for it := NewPreOrderIter(k); it.Next(); {
+		if !it.Node().HasChildren() {
+			n++
+		}
+	}
+	return n
+}
+
+// LowestCommonAncestor returns the lowest node that has both a and b below
+// it, or is one of them -- nil if they are in different trees
+func LowestCommonAncestor(a, b ki.Ki) ki.Ki {
+	da, db := Depth(a), Depth(b)
+	for ; da > db; da-- {
+		a = a.Parent()
+	}
+	for ; db > da; db-- {
+		b = b.Parent()
+	}
+	for a != b {
+		a, b = a.Parent(), b.Parent()
+		if a == nil || b == nil {
+			return nil
+		}
+	}
+	return a
+}
+
+//////////////////////////////////////////////////////////////////////////
+//  Navigation
+
+// NextSibling returns the next child of the parent of given node, nil if it
+// is the last one, or has no parent
+func NextSibling(k ki.Ki) ki.Ki {
+	par := k.Parent()
+	if par == nil {
+		return nil
+	}
+	idx := k.Index()
+	if idx < 0 || idx+1 >= len(par.Children()) {
+		return nil
+	}
+	return par.Child(idx + 1)
+}
+
+// PrevSibling returns the previous child of the parent of given node, nil if
+// it is the first one, or has no parent
+func PrevSibling(k ki.Ki) ki.Ki {
+	par := k.Parent()
+	if par == nil {
+		return nil
+	}
+	idx := k.Index()
+	if idx <= 0 {
+		return nil
+	}
+	return par.Child(idx - 1)
+}
+
+// NextInOrder returns the node after given node in a pre-order traversal of
+// the whole tree: its first child, or else the next sibling of it or its
+// closest parent that has one -- nil if it is the last node
+func NextInOrder(k ki.Ki) ki.Ki {
+	if k.HasChildren() {
+		return k.Child(0)
+	}
+	for cur := k; cur != nil; cur = cur.Parent() {
+		if ns := NextSibling(cur); ns != nil {
+			return ns
+		}
+	}
+	return nil
+}
+
+// PrevInOrder returns the node before given node in a pre-order traversal of
+// the whole tree: the last node under its previous sibling, or else its
+// parent -- nil if it is the root
+func PrevInOrder(k ki.Ki) ki.Ki {
+	ps := PrevSibling(k)
+	if ps == nil {
+		return k.Parent()
+	}
+	for ps.HasChildren() {
+		ps = ps.Child(len(ps.Children()) - 1)
+	}
+	return ps
+}
+
+//////////////////////////////////////////////////////////////////////////
+//  Flatten
+
+// FlatNode is one node in a flattened tree -- see Flatten
+type FlatNode struct {
+	Node   ki.Ki `desc:"the node"`
+	Parent int   `desc:"index of the parent in the flattened list -- -1 for the top node"`
+	Level  int   `desc:"level of the node below the top node -- 0 for the top node"`
+	Index  int   `desc:"index of the node within the children of its parent -- 0 for the top node"`
+}
+
+// Flatten returns the nodes of the subtree under given node as a list in
+// pre-order, with the index of the parent of each node in the list, so the
+// tree structure can be processed as flat arrays
+func Flatten(k ki.Ki) []FlatNode {
+	var fl []FlatNode
+	var pars []int // index in fl of the current node at each level
+	for it := NewPreOrderIter(k); it.Next(); {
+		lev := it.Level()
+		pars = append(pars[:lev], len(fl))
+		fn := FlatNode{Node: it.Node(), Parent: -1, Level: lev, Index: it.Index()}
+		if lev > 0 {
+			fn.Parent = pars[lev-1]
+		}
+		fl = append(fl, fn)
+	}
+	return fl
+}
+
+//////////////////////////////////////////////////////////////////////////
+//  Sort
+
+// SortChildren sorts the children of given node using given less function,
+// keeping the order of equal children -- the children are moved using
+// MoveChild, within one update, so the move edits are recorded (e.g., for
+// undo) and only one update signal is sent
+func SortChildren(k ki.Ki, less func(a, b ki.Ki) bool) {
+	kids := append(ki.Slice(nil), k.Children()...)
+	sort.SliceStable(kids, func(i, j int) bool {
+		return less(kids[i], kids[j])
+	})
+	updt := k.UpdateStart()
+	for i, kid := range kids {
+		if idx := k.ChildIndex(kid, i); idx != i {
+			k.MoveChild(idx, i)
+		}
+	}
+	k.UpdateEnd(updt)
+}
+
+// SortChildrenByKey sorts the children of given node by the string key
+// returned by given function -- see SortChildren
+func SortChildrenByKey(k ki.Ki, key func(kid ki.Ki) string) {
+	keys := make(map[ki.Ki]string, len(k.Children()))
+	for _, kid := range k.Children() {
+		keys[kid] = key(kid)
+	}
+	SortChildren(k, func(a, b ki.Ki) bool {
+		return keys[a] < keys[b]
+	})
+}
+
+// SortChildrenByName sorts the children of given node by their names -- see
+// SortChildren
+func SortChildrenByName(k ki.Ki) {
+	SortChildrenByKey(k, func(kid ki.Ki) string { return kid.Name() })
+}
@@ -0,0 +1,265 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package treealgo
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/rcoreilly/goki/ki"
+)
+
+// genTree generates a random tree of n nodes, each added under a random
+// earlier node -- also returns the nodes in order of creation
+func genTree(n int, seed int64) (ki.Ki, []ki.Ki) {
+	rnd := rand.New(rand.NewSource(seed))
+	root := &ki.Node{}
+	root.InitName(root, "root")
+	nodes := []ki.Ki{root}
+	for i := 1; i < n; i++ {
+		par := nodes[rnd.Intn(len(nodes))]
+		nodes = append(nodes, par.AddNewChild(nil, fmt.Sprintf("n%d", i)))
+	}
+	return root, nodes
+}
+
+// genChain generates a chain of n nodes, each the only child of the previous
+func genChain(n int) (ki.Ki, ki.Ki) {
+	root := &ki.Node{}
+	root.InitName(root, "root")
+	var k ki.Ki = root
+	for i := 1; i < n; i++ {
+		k = k.AddNewChild(nil, fmt.Sprintf("n%d", i))
+	}
+	return root, k
+}
+
+func iterNodes(it *Iter) []ki.Ki {
+	var res []ki.Ki
+	for it.Next() {
+		res = append(res, it.Node())
+	}
+	return res
+}
+
+func sameNodes(t *testing.T, what string, a, b []ki.Ki) {
+	t.Helper()
+	if len(a) != len(b) {
+		t.Errorf("%v: %v nodes, should be %v", what, len(a), len(b))
+		return
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			t.Errorf("%v: node %v is %v, should be %v", what, i, a[i].Name(), b[i].Name())
+			return
+		}
+	}
+}
+
+func TestIters(t *testing.T) {
+	root, nodes := genTree(50000, 1)
+
+	var pre, post, level []ki.Ki
+	root.FuncDownMeFirst(0, nil, func(k ki.Ki, lev int, d interface{}) bool {
+		pre = append(pre, k)
+		return true
+	})
+	root.FuncDownDepthFirst(0, nil, func(k ki.Ki, lev int, d interface{}) bool { return true },
+		func(k ki.Ki, lev int, d interface{}) bool {
+			post = append(post, k)
+			return true
+		})
+	level = append(level, root)
+	root.FuncDownBreadthFirst(0, nil, func(k ki.Ki, lev int, d interface{}) bool {
+		level = append(level, k)
+		return true
+	})
+	sameNodes(t, "pre order", iterNodes(NewPreOrderIter(root)), pre)
+	sameNodes(t, "post order", iterNodes(NewPostOrderIter(root)), post)
+	if lv := iterNodes(NewLevelOrderIter(root)); len(lv) != len(nodes) {
+		t.Errorf("level order: %v nodes, should be %v", len(lv), len(nodes))
+	} else {
+		for i := 1; i < len(lv); i++ {
+			if Depth(lv[i]) < Depth(lv[i-1]) {
+				t.Errorf("level order: node %v is above previous node", i)
+				break
+			}
+		}
+	}
+
+	// levels and indexes
+	for it := NewPreOrderIter(root); it.Next(); {
+		if it.Level() != Depth(it.Node()) || it.Index() != it.Node().Index() && it.Level() > 0 {
+			t.Errorf("iter level / index wrong for %v", it.Node().Name())
+			break
+		}
+	}
+
+	// skip children
+	sub := nodes[1]
+	n := 0
+	for it := NewPreOrderIter(root); it.Next(); {
+		if it.Node() == sub {
+			it.SkipChildren()
+		}
+		n++
+	}
+	if n != len(nodes)-Size(sub)+1 {
+		t.Errorf("skip children visited %v nodes, should be %v", n, len(nodes)-Size(sub)+1)
+	}
+
+	// in-order navigation goes through the pre order both ways
+	var fwd []ki.Ki
+	for k := ki.Ki(root); k != nil; k = NextInOrder(k) {
+		fwd = append(fwd, k)
+	}
+	sameNodes(t, "NextInOrder", fwd, pre)
+	var bwd []ki.Ki
+	for k := pre[len(pre)-1]; k != nil; k = PrevInOrder(k) {
+		bwd = append(bwd, k)
+	}
+	for i, j := 0, len(bwd)-1; i < j; i, j = i+1, j-1 {
+		bwd[i], bwd[j] = bwd[j], bwd[i]
+	}
+	sameNodes(t, "PrevInOrder", bwd, pre)
+}
+
+func TestMeasures(t *testing.T) {
+	root, nodes := genTree(20000, 2)
+	if s := Size(root); s != len(nodes) {
+		t.Errorf("Size: %v, should be %v", s, len(nodes))
+	}
+	h, nl := 0, 0
+	for _, k := range nodes {
+		if d := Depth(k); d > h {
+			h = d
+		}
+		if !k.HasChildren() {
+			nl++
+		}
+	}
+	if hr := Height(root); hr != h {
+		t.Errorf("Height: %v, should be %v", hr, h)
+	}
+	if l := NumLeaves(root); l != nl {
+		t.Errorf("NumLeaves: %v, should be %v", l, nl)
+	}
+
+	// deep trees work without recursion
+	croot, leaf := genChain(10000)
+	if h := Height(croot); h != 9999 {
+		t.Errorf("chain Height: %v", h)
+	}
+	if d := Depth(leaf); d != 9999 {
+		t.Errorf("chain Depth: %v", d)
+	}
+	if n := len(iterNodes(NewPostOrderIter(croot))); n != 10000 {
+		t.Errorf("chain post order: %v nodes", n)
+	}
+}
+
+func TestLowestCommonAncestor(t *testing.T) {
+	root, nodes := genTree(20000, 3)
+	rnd := rand.New(rand.NewSource(3))
+	for i := 0; i < 1000; i++ {
+		a, b := nodes[rnd.Intn(len(nodes))], nodes[rnd.Intn(len(nodes))]
+		ancs := make(map[ki.Ki]bool)
+		for k := a; k != nil; k = k.Parent() {
+			ancs[k] = true
+		}
+		var lca ki.Ki
+		for k := b; k != nil; k = k.Parent() {
+			if ancs[k] {
+				lca = k
+				break
+			}
+		}
+		if res := LowestCommonAncestor(a, b); res != lca {
+			t.Fatalf("LowestCommonAncestor of %v and %v: %v, should be %v", a.Name(), b.Name(), res.Name(), lca.Name())
+		}
+	}
+	if LowestCommonAncestor(root, root) != root {
+		t.Errorf("LowestCommonAncestor of root with itself should be root")
+	}
+	other, _ := genTree(10, 4)
+	if LowestCommonAncestor(nodes[5], other.Child(0)) != nil {
+		t.Errorf("LowestCommonAncestor of different trees should be nil")
+	}
+}
+
+func TestSiblings(t *testing.T) {
+	root, _ := genTree(1000, 5)
+	for it := NewPreOrderIter(root); it.Next(); {
+		k := it.Node()
+		idx := it.Index()
+		ns, ps := NextSibling(k), PrevSibling(k)
+		if it.Level() == 0 {
+			if ns != nil || ps != nil {
+				t.Errorf("root should have no siblings")
+			}
+			continue
+		}
+		par := k.Parent()
+		if (idx+1 < len(par.Children()) && ns != par.Child(idx+1)) || (idx+1 == len(par.Children()) && ns != nil) {
+			t.Errorf("NextSibling of %v wrong", k.Name())
+		}
+		if (idx > 0 && ps != par.Child(idx-1)) || (idx == 0 && ps != nil) {
+			t.Errorf("PrevSibling of %v wrong", k.Name())
+		}
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	root, nodes := genTree(20000, 6)
+	fl := Flatten(root)
+	if len(fl) != len(nodes) {
+		t.Fatalf("Flatten: %v nodes, should be %v", len(fl), len(nodes))
+	}
+	if fl[0].Node != root || fl[0].Parent != -1 || fl[0].Level != 0 {
+		t.Errorf("Flatten: first node should be root: %v", fl[0])
+	}
+	for i, fn := range fl[1:] {
+		par := fl[fn.Parent]
+		if fn.Parent > i || par.Node != fn.Node.Parent() || fn.Level != par.Level+1 || par.Node.Child(fn.Index) != fn.Node {
+			t.Errorf("Flatten: node %v has wrong parent or index: %+v", i+1, fn)
+			break
+		}
+	}
+}
+
+func TestSortChildren(t *testing.T) {
+	root, nodes := genTree(5000, 7)
+	for _, k := range nodes {
+		if k.HasChildren() {
+			SortChildrenByName(k)
+		}
+	}
+	for _, k := range nodes {
+		kids := k.Children()
+		for i := 1; i < len(kids); i++ {
+			if kids[i-1].Name() > kids[i].Name() {
+				t.Fatalf("children of %v not sorted: %v", k.Name(), kids)
+			}
+		}
+	}
+	if Size(root) != len(nodes) {
+		t.Errorf("sort changed the number of nodes")
+	}
+
+	// stable, by key
+	par := &ki.Node{}
+	par.InitName(par, "par")
+	for i := 0; i < 10; i++ {
+		par.AddNewChild(nil, fmt.Sprintf("k%d_%d", i%3, i))
+	}
+	SortChildrenByKey(par, func(kid ki.Ki) string { return kid.Name()[:2] })
+	exp := []string{"k0_0", "k0_3", "k0_6", "k0_9", "k1_1", "k1_4", "k1_7", "k2_2", "k2_5", "k2_8"}
+	for i, kid := range par.Children() {
+		if kid.Name() != exp[i] {
+			t.Errorf("stable sort by key: child %v is %v, should be %v", i, kid.Name(), exp[i])
+		}
+	}
+}
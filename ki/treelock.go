@@ -0,0 +1,79 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"sync"
+)
+
+// Concurrent access to a Ki tree is supported through an optional tree-level
+// sync.RWMutex, shared by all the nodes in the tree (Node.TreeMu), which is
+// set with EnableTreeLock.  Once enabled:
+//
+// * The structural and name / property mutation methods (AddChild,
+// InsertChild, DeleteChild, MoveChild, ConfigChildren, SetName, SetProp,
+// DeleteProp, SetField etc) write-lock the mutex while they modify the Kids,
+// Par, Nm, Props, or field data of the node.
+//
+// * The accessors of that data (Parent, Children, Child, Name, UniqueName,
+// Prop, FieldByName, Path etc) and the traversal methods (FuncDown*, FuncUp*,
+// GoFuncDown, FuncFields) read-lock it -- Children returns a copy of the
+// Kids slice, and traversals iterate over such a copy.
+//
+// * The node flags are read and written atomically, and Signal has its own
+// mutex, so signals can be emitted and connected from any goroutine.
+//
+// The lock is only ever held for the duration of one such access or
+// modification, and never while calling functions passed to traversals,
+// signal receivers, or other Ki methods -- so these can freely call any Ki
+// methods, including modifying the tree, without deadlock.  However, it also
+// means that a sequence of operations is not atomic: use WithLock / WithRLock
+// for a consistent block of access directly to the node data (e.g., Kids,
+// Props, or fields of derived types, which are not otherwise protected) --
+// as sync.RWMutex is not reentrant, the function passed to these must NOT
+// call any of the Ki methods that lock the mutex.
+//
+// Direct modification of node data outside of the Ki methods and WithLock is
+// not protected -- in particular, the GUI code accesses the Kids of widgets
+// directly, so a tree viewed in the GUI should be locked separately from the
+// GUI trees themselves.
+
+// EnableTreeLock creates a tree mutex for the tree rooted at k and sets it on
+// all nodes in the tree, enabling safe concurrent access as described above
+// -- returns the existing mutex if k already has one
+func EnableTreeLock(k Ki) *sync.RWMutex {
+	if mu := k.TreeMutex(); mu != nil {
+		return mu
+	}
+	mu := &sync.RWMutex{}
+	k.SetTreeMutex(mu)
+	return mu
+}
+
+// DisableTreeLock removes the tree mutex from all nodes in the tree rooted at
+// k -- there must not be any concurrent access at this point
+func DisableTreeLock(k Ki) {
+	k.SetTreeMutex(nil)
+}
+
+// WithLock calls fun with the tree mutex of k write-locked, if there is one
+// (else just calls fun) -- fun must not call Ki methods that lock the mutex
+func WithLock(k Ki, fun func()) {
+	if mu := k.TreeMutex(); mu != nil {
+		mu.Lock()
+		defer mu.Unlock()
+	}
+	fun()
+}
+
+// WithRLock calls fun with the tree mutex of k read-locked, if there is one
+// (else just calls fun) -- fun must not call Ki methods that lock the mutex
+func WithRLock(k Ki, fun func()) {
+	if mu := k.TreeMutex(); mu != nil {
+		mu.RLock()
+		defer mu.RUnlock()
+	}
+	fun()
+}
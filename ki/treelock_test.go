@@ -0,0 +1,131 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// run with go test -race to check for data races
+func TestTreeLock(t *testing.T) {
+	parent := undoTestTree()
+	mu := EnableTreeLock(parent)
+	if EnableTreeLock(parent) != mu || parent.Child(1).Child(0).TreeMutex() != mu {
+		t.Fatalf("tree mutex not shared by all nodes")
+	}
+	typ := parent.Type()
+	strace := SignalTrace
+	SignalTrace = false
+	defer func() { SignalTrace = strace }()
+
+	nsig := 0
+	var sigmu sync.Mutex
+	parent.NodeSignal().Connect(parent.This, func(recv, send Ki, sig int64, data interface{}) {
+		sigmu.Lock()
+		nsig++
+		sigmu.Unlock()
+	})
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				parent.FuncDownMeFirst(0, nil, func(k Ki, level int, d interface{}) bool {
+					_ = k.Name()
+					_ = k.PathUnique()
+					_ = k.Prop("intprop", true, false)
+					_ = k.Children()
+					return true
+				})
+				parent.NodeSignal().Emit(parent.This, int64(NodeSignalNil), nil)
+				WithRLock(parent, func() {
+					_ = len(parent.Kids)
+				})
+			}
+		}()
+	}
+
+	for i := 0; i < 200; i++ {
+		nm := fmt.Sprintf("new%v", i)
+		kid := parent.AddNewChild(typ, nm)
+		kid.AddNewChild(typ, nm+"sub")
+		kid.SetProp("intprop", i)
+		if kid.TreeMutex() != mu || kid.Child(0).TreeMutex() != mu {
+			t.Errorf("new child did not get tree mutex")
+		}
+		parent.Child(1).SetName(fmt.Sprintf("child2_%v", i))
+		if i%3 == 0 {
+			parent.MoveChild(len(parent.Children())-1, 0)
+		}
+		if len(parent.Children()) > 5 {
+			parent.DeleteChildAtIndex(0, true)
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	if nsig == 0 {
+		t.Errorf("no signals received")
+	}
+	nkids := 0
+	WithLock(parent, func() {
+		nkids = len(parent.Kids)
+	})
+	if nkids != 5 {
+		t.Errorf("should be 5 children, was: %v", nkids)
+	}
+	DisableTreeLock(parent)
+	if parent.TreeMutex() != nil || parent.Child(0).Child(0).TreeMutex() != nil {
+		t.Errorf("tree mutex not removed")
+	}
+}
+
+// run with go test -race to check for data races
+func TestTreeLockWriters(t *testing.T) {
+	parent := undoTestTree()
+	EnableTreeLock(parent)
+	typ := parent.Type()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				switch i % 4 {
+				case 0:
+					parent.AddNewChild(typ, fmt.Sprintf("w%v_%v", w, i))
+				case 1:
+					parent.InsertNewChild(typ, 0, fmt.Sprintf("w%v_%v", w, i))
+				case 2:
+					if nk := len(parent.Children()); nk > 1 {
+						parent.MoveChild(nk-1, 0)
+					}
+				case 3:
+					if len(parent.Children()) > 3 {
+						parent.DeleteChildAtIndex(1, false)
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	for i, kid := range parent.Children() {
+		if kid.Parent() != parent.This || kid.IsDeleted() {
+			t.Errorf("child %v at %v was deleted but is still in Kids", kid.Name(), i)
+		}
+	}
+}
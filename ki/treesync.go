@@ -0,0 +1,577 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+
+	"github.com/rcoreilly/goki/ki/kit"
+)
+
+// Tree synchronization mirrors a tree between processes, e.g., from a
+// headless compute server to one or more GUI viewers: a SyncServer publishes
+// the tree under its Root, and a SyncClient keeps a mirror copy of it, over
+// any io.ReadWriter connection (net.Conn, net.Pipe, os pipes, etc).  The
+// protocol is a stream of JSON-encoded SyncMsg messages, one per line:
+//
+// * the client opens with SyncHello, giving the Version of its mirror (0 for
+//   none -- e.g., the first time it connects), and the Epoch of the server
+//   it got that version from.
+//
+// * the server replies with the SyncDiffs it has missed, if the server still
+//   has them in its history (e.g., when reconnecting after a dropped
+//   connection), or otherwise a SyncTree message with the whole tree.  Each
+//   server has a random Epoch, sent in all its messages, as versions are only
+//   comparable within one server -- a client reconnecting to a different (or
+//   restarted) server always gets the whole tree.
+//
+// * then, each time the tree changes, the server sends SyncDiffs with the
+//   Diffs (see Diff) from the Base version to the new Version -- changes are
+//   published once for each ChangeBatch from the ChangeNotifier attached to
+//   the Root (see AttachChangeNotifier), at the end of each update
+//   (UpdateEnd), and for each NodeSignalUpdated signal of the Root itself
+//   that the batch does not cover (e.g., after LoadJSON), and can be
+//   published explicitly with Publish, e.g., after directly setting fields.
+//
+// * the client applies diffs only to the Base version of the same Epoch, and
+//   sends SyncResync to get the whole tree if it has missed any, which the
+//   server also sends if the client is not reading the messages as fast as
+//   they are published.
+//
+// The diffs are computed from Snapshots of the tree (see Snapshotter) --
+// for a ChangeBatch, only the nodes in its ChangeRecs are snapshot again
+// (see TakeChanges), so the cost is proportional to the changes, not the
+// tree -- and the whole tree is sent from the latest snapshot, so the server
+// does not access the live tree outside of the updates.
+
+// SyncMsgTypes are the types of messages in the tree synchronization
+// protocol
+type SyncMsgTypes int32
+
+const (
+	// SyncHello is sent by the client when it connects, with the Version and
+	// Epoch of its mirror tree -- 0 if it has none
+	SyncHello SyncMsgTypes = iota
+
+	// SyncTree is sent by the server with the whole Tree at Version
+	SyncTree
+
+	// SyncDiffs is sent by the server with the Diffs that transform the tree
+	// at the Base version into Version
+	SyncDiffs
+
+	// SyncResync is sent by the client to request the whole tree, when it
+	// has missed some diffs
+	SyncResync
+
+	SyncMsgTypesN
+)
+
+//go:generate stringer -type=SyncMsgTypes
+
+var KiT_SyncMsgTypes = kit.Enums.AddEnum(SyncMsgTypesN, false, nil)
+
+func (ev SyncMsgTypes) MarshalJSON() ([]byte, error)  { return kit.EnumMarshalJSON(ev) }
+func (ev *SyncMsgTypes) UnmarshalJSON(b []byte) error { return kit.EnumUnmarshalJSON(ev, b) }
+
+// SyncMsg is one message in the tree synchronization protocol
+type SyncMsg struct {
+	Type    SyncMsgTypes    `desc:"type of message"`
+	Version uint64          `desc:"version of the tree -- for SyncHello, the version of the client mirror"`
+	Base    uint64          `json:",omitempty" desc:"for SyncDiffs, the version that the diffs apply to"`
+	Epoch   string          `json:",omitempty" desc:"random ID of the server that the versions belong to -- for SyncHello, the server the client mirror was from"`
+	Tree    json.RawMessage `json:",omitempty" desc:"for SyncTree, the JSON encoding of the tree (see SaveJSON)"`
+	Diffs   Diffs           `json:",omitempty" desc:"for SyncDiffs, the diffs from Base to Version"`
+}
+
+// String returns a summary of the message
+func (sm *SyncMsg) String() string {
+	switch sm.Type {
+	case SyncDiffs:
+		return fmt.Sprintf("%v %v -> %v: %v diffs", sm.Type, sm.Base, sm.Version, len(sm.Diffs))
+	case SyncTree:
+		return fmt.Sprintf("%v %v: %v bytes", sm.Type, sm.Version, len(sm.Tree))
+	}
+	return fmt.Sprintf("%v %v", sm.Type, sm.Version)
+}
+
+// SyncServerHistN is the default number of SyncDiffs messages kept by a
+// SyncServer for clients that reconnect
+var SyncServerHistN = 64
+
+// SyncServerQueueN is the default number of messages that can be queued for
+// sending to each client -- when a client falls further behind than this, it
+// is sent the whole tree instead
+var SyncServerQueueN = 64
+
+//////////////////////////////////////////////////////////////////////////
+//  SyncServer
+
+// SyncServer publishes the tree under Root to SyncClient's -- see the
+// protocol description above
+type SyncServer struct {
+	Root    Ki         `desc:"root of the published tree"`
+	HistN   int        `desc:"number of SyncDiffs messages kept for clients that reconnect -- defaults to SyncServerHistN"`
+	QueueN  int        `desc:"number of messages that can be queued for each client -- defaults to SyncServerQueueN"`
+	Mu      sync.Mutex `json:"-" xml:"-" desc:"mutex protecting the server state"`
+	snaps   *Snapshotter
+	last    *Snapshot
+	epoch   string
+	version uint64
+	hist    []*SyncMsg
+	conns   map[*syncConn]struct{}
+	serving map[*syncServe]struct{}
+	lists   map[net.Listener]struct{}
+	closed  bool
+	cn      *ChangeNotifier
+	ownCN   bool
+	sigCons []SignalConn
+}
+
+// syncServe is one connection being served, with a channel that is closed
+// when Serve returns
+type syncServe struct {
+	rw   io.ReadWriter
+	done chan struct{}
+}
+
+// syncConn is one client connection to a server, with its queue of messages
+// to send
+type syncConn struct {
+	enc    *json.Encoder
+	out    chan *SyncMsg
+	mu     sync.Mutex
+	resync bool
+}
+
+// NewSyncServer returns a new server publishing the tree under given root,
+// at version 1 of a new random epoch -- changes are published at the end of
+// each update -- call Close when done
+func NewSyncServer(root Ki) *SyncServer {
+	sv := &SyncServer{Root: root, HistN: SyncServerHistN, QueueN: SyncServerQueueN}
+	sv.snaps = NewSnapshotter(root)
+	sv.last = sv.snaps.Take()
+	sv.epoch = NewUUID()
+	sv.version = 1
+	sv.conns = make(map[*syncConn]struct{})
+	sv.serving = make(map[*syncServe]struct{})
+	sv.lists = make(map[net.Listener]struct{})
+	sv.cn = ChangeNotifierFor(root)
+	if sv.cn == nil {
+		sv.cn = AttachChangeNotifier(root)
+		sv.ownCN = true
+	}
+	changes := func(recv, send Ki, sig int64, data interface{}) {
+		if cb, ok := data.(ChangeBatch); ok && sig == int64(NodeSignalChanged) {
+			sv.PublishChanges(cb)
+		}
+	}
+	if sc, err := sv.cn.ChangeSig.Connect(root, changes); err == nil {
+		sv.sigCons = append(sv.sigCons, sc)
+	}
+	updated := func(recv, send Ki, sig int64, data interface{}) {
+		if sig == int64(NodeSignalUpdated) && !sv.cn.Covers(send) {
+			sv.Publish()
+		}
+	}
+	if sc, err := root.NodeSignal().Connect(root, updated); err == nil {
+		sv.sigCons = append(sv.sigCons, sc)
+	}
+	return sv
+}
+
+// Version returns the current version of the tree
+func (sv *SyncServer) Version() uint64 {
+	sv.Mu.Lock()
+	defer sv.Mu.Unlock()
+	return sv.version
+}
+
+// Epoch returns the random ID of this server, which its versions belong to
+func (sv *SyncServer) Epoch() string {
+	return sv.epoch
+}
+
+// Publish sends any changes in the tree since the last version to all the
+// clients, as a new version -- returns the current version -- this is
+// called automatically at the end of each update
+func (sv *SyncServer) Publish() uint64 {
+	sv.Mu.Lock()
+	defer sv.Mu.Unlock()
+	return sv.publish(sv.snaps.Take())
+}
+
+// PublishChanges sends the changes in given batch from the ChangeNotifier
+// for the tree to all the clients, as a new version, only snapshotting the
+// nodes that changed (see TakeChanges) -- returns the current version --
+// this is called automatically for each batch
+func (sv *SyncServer) PublishChanges(cb ChangeBatch) uint64 {
+	sv.Mu.Lock()
+	defer sv.Mu.Unlock()
+	return sv.publish(sv.snaps.TakeChanges(cb))
+}
+
+// publish sends the diffs from the last snapshot to given one to all the
+// clients, as a new version, if there are any -- lock must be held
+func (sv *SyncServer) publish(snap *Snapshot) uint64 {
+	diffs := DiffSnapshots(sv.last, snap)
+	sv.last = snap
+	if len(diffs) == 0 {
+		return sv.version
+	}
+	msg := &SyncMsg{Type: SyncDiffs, Base: sv.version, Version: sv.version + 1, Epoch: sv.epoch, Diffs: diffs}
+	sv.version++
+	sv.hist = append(sv.hist, msg)
+	if hn := sv.HistN; len(sv.hist) > hn {
+		sv.hist = append(sv.hist[:0], sv.hist[len(sv.hist)-hn:]...)
+	}
+	for sc := range sv.conns {
+		sc.send(msg)
+	}
+	return sv.version
+}
+
+// Serve runs the protocol for one client on given connection, returning when
+// the connection is closed (nil if closed by the client) or on an error --
+// typically called in its own goroutine for each connection
+func (sv *SyncServer) Serve(rw io.ReadWriter) error {
+	ss := &syncServe{rw: rw, done: make(chan struct{})}
+	sv.Mu.Lock()
+	if sv.closed {
+		sv.Mu.Unlock()
+		return errSyncClosed
+	}
+	sv.serving[ss] = struct{}{}
+	sv.Mu.Unlock()
+	defer func() {
+		sv.Mu.Lock()
+		delete(sv.serving, ss)
+		sv.Mu.Unlock()
+		close(ss.done)
+	}()
+
+	dec := json.NewDecoder(rw)
+	var hello SyncMsg
+	if err := dec.Decode(&hello); err != nil {
+		return syncReadErr("SyncServer", err)
+	}
+	if hello.Type != SyncHello {
+		err := fmt.Errorf("ki.SyncServer: expected %v message from client, got: %v", SyncHello, hello.Type)
+		log.Println(err)
+		return err
+	}
+	sc := &syncConn{enc: json.NewEncoder(rw), out: make(chan *SyncMsg, sv.QueueN)}
+	sv.Mu.Lock()
+	if hello.Epoch != sv.epoch { // versions from another server
+		sc.setResync()
+	} else if missed := sv.histSince(hello.Version); missed != nil {
+		for _, msg := range missed {
+			sc.send(msg)
+		}
+	} else if hello.Version != sv.version {
+		sc.setResync()
+	}
+	sv.conns[sc] = struct{}{}
+	sv.Mu.Unlock()
+
+	werr := make(chan error, 1)
+	go func() { werr <- sv.writeConn(sc) }()
+	var err error
+	for {
+		var msg SyncMsg
+		if err = dec.Decode(&msg); err != nil {
+			err = syncReadErr("SyncServer", err)
+			break
+		}
+		if msg.Type == SyncResync {
+			sc.setResync()
+		}
+	}
+	sv.Mu.Lock()
+	delete(sv.conns, sc)
+	sv.Mu.Unlock()
+	close(sc.out)
+	if werr := <-werr; err == nil {
+		err = werr
+	}
+	return err
+}
+
+// ServeListener accepts connections on given listener (e.g., a local unix
+// socket), serving each in its own goroutine, until the listener is closed,
+// which Close also does
+func (sv *SyncServer) ServeListener(l net.Listener) error {
+	sv.Mu.Lock()
+	if sv.closed {
+		sv.Mu.Unlock()
+		return errSyncClosed
+	}
+	sv.lists[l] = struct{}{}
+	sv.Mu.Unlock()
+	defer func() {
+		sv.Mu.Lock()
+		delete(sv.lists, l)
+		sv.Mu.Unlock()
+	}()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			sv.Serve(conn)
+			conn.Close()
+		}()
+	}
+}
+
+// Close stops publishing changes to the tree, closes the listeners passed
+// to ServeListener, and closes the connections being served that are
+// io.Closer's (e.g., net.Conn), waiting for their Serve calls to return --
+// Serve cannot be stopped on other connections, but nothing further is
+// published on them
+func (sv *SyncServer) Close() {
+	for _, sc := range sv.sigCons {
+		sc.Disconnect()
+	}
+	sv.sigCons = nil
+	if sv.ownCN {
+		sv.cn.Detach()
+	}
+	sv.Mu.Lock()
+	sv.closed = true
+	for l := range sv.lists {
+		l.Close()
+	}
+	var waits []chan struct{}
+	for ss := range sv.serving {
+		if c, ok := ss.rw.(io.Closer); ok {
+			c.Close()
+			waits = append(waits, ss.done)
+		}
+	}
+	sv.Mu.Unlock()
+	for _, done := range waits {
+		<-done
+	}
+}
+
+// errSyncClosed is returned by Serve and ServeListener after Close
+var errSyncClosed = errors.New("ki.SyncServer: server is closed")
+
+// histSince returns the messages in the history after given version, nil if
+// not available -- lock must be held
+func (sv *SyncServer) histSince(version uint64) []*SyncMsg {
+	if version == 0 || version >= sv.version {
+		return nil
+	}
+	for i, msg := range sv.hist {
+		if msg.Base == version {
+			return sv.hist[i:]
+		}
+	}
+	return nil
+}
+
+// treeMsg returns a SyncTree message with the latest snapshot of the tree
+func (sv *SyncServer) treeMsg() (*SyncMsg, error) {
+	sv.Mu.Lock()
+	snap, version := sv.last, sv.version
+	sv.Mu.Unlock()
+	b, err := snap.Root.NewTree().SaveJSON(false)
+	if err != nil {
+		return nil, err
+	}
+	return &SyncMsg{Type: SyncTree, Version: version, Epoch: sv.epoch, Tree: b}, nil
+}
+
+// writeConn sends the queued messages to the client until the queue is
+// closed, sending the whole tree instead when a resync is needed
+func (sv *SyncServer) writeConn(sc *syncConn) error {
+	if sc.takeResync() {
+		if err := sv.writeTree(sc); err != nil {
+			return err
+		}
+	}
+	for msg := range sc.out {
+		if sc.takeResync() {
+			if err := sv.writeTree(sc); err != nil {
+				return err
+			}
+			continue
+		}
+		if msg == nil {
+			continue
+		}
+		if err := sc.enc.Encode(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTree sends the whole tree to the client
+func (sv *SyncServer) writeTree(sc *syncConn) error {
+	msg, err := sv.treeMsg()
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	return sc.enc.Encode(msg)
+}
+
+// send queues the message, flagging a resync if the queue is full
+func (sc *syncConn) send(msg *SyncMsg) {
+	select {
+	case sc.out <- msg:
+	default:
+		sc.setResync()
+	}
+}
+
+// setResync flags that the whole tree must be sent, and wakes up the writer
+func (sc *syncConn) setResync() {
+	sc.mu.Lock()
+	sc.resync = true
+	sc.mu.Unlock()
+	select {
+	case sc.out <- nil:
+	default: // queue full, so the writer will see the flag anyway
+	}
+}
+
+// takeResync returns and clears the resync flag
+func (sc *syncConn) takeResync() bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	rs := sc.resync
+	sc.resync = false
+	return rs
+}
+
+//////////////////////////////////////////////////////////////////////////
+//  SyncClient
+
+// SyncClient keeps the tree under Root as a mirror of the tree published by
+// a SyncServer -- see the protocol description above.  The Root must be of
+// the same type as the server root, and the mirror is updated on the
+// goroutine that calls Run, within UpdateStart / UpdateEnd, so views of the
+// tree are updated in the usual way.
+type SyncClient struct {
+	Root    Ki         `desc:"root of the mirror tree"`
+	Mu      sync.Mutex `json:"-" xml:"-" desc:"mutex protecting the version"`
+	epoch   string
+	version uint64
+}
+
+// NewSyncClient returns a new client keeping given root as the mirror tree
+func NewSyncClient(root Ki) *SyncClient {
+	return &SyncClient{Root: root}
+}
+
+// Version returns the version of the mirror tree -- 0 if none yet
+func (cl *SyncClient) Version() uint64 {
+	cl.Mu.Lock()
+	defer cl.Mu.Unlock()
+	return cl.version
+}
+
+// Epoch returns the epoch of the server that the mirror tree is from -- ""
+// if none yet
+func (cl *SyncClient) Epoch() string {
+	cl.Mu.Lock()
+	defer cl.Mu.Unlock()
+	return cl.epoch
+}
+
+// Run runs the protocol on given connection to a server, updating the mirror
+// tree, until the connection is closed (returning nil if closed by the
+// server) or on an error -- Run can be called again on a new connection,
+// e.g., after a dropped connection, and only the changes since the current
+// version are sent if possible, by the same server
+func (cl *SyncClient) Run(rw io.ReadWriter) error {
+	enc := json.NewEncoder(rw)
+	cl.Mu.Lock()
+	hello := &SyncMsg{Type: SyncHello, Version: cl.version, Epoch: cl.epoch}
+	cl.Mu.Unlock()
+	if err := enc.Encode(hello); err != nil {
+		return err
+	}
+	dec := json.NewDecoder(rw)
+	resyncing := false
+	for {
+		var msg SyncMsg
+		if err := dec.Decode(&msg); err != nil {
+			return syncReadErr("SyncClient", err)
+		}
+		ok, err := cl.apply(&msg)
+		if err != nil {
+			log.Println(err)
+		}
+		switch {
+		case ok:
+			resyncing = false
+		case !resyncing:
+			resyncing = true
+			if err := enc.Encode(&SyncMsg{Type: SyncResync, Version: cl.Version()}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// apply applies the message to the mirror tree -- returns false if the
+// mirror is out of sync, in which case the whole tree is needed
+func (cl *SyncClient) apply(msg *SyncMsg) (bool, error) {
+	cl.Mu.Lock()
+	defer cl.Mu.Unlock()
+	switch msg.Type {
+	case SyncTree:
+		if err := cl.Root.LoadJSON(msg.Tree); err != nil {
+			cl.version = 0
+			return false, err
+		}
+		cl.epoch = msg.Epoch
+		cl.version = msg.Version
+	case SyncDiffs:
+		if msg.Epoch != cl.epoch {
+			return false, nil // from another server
+		}
+		if msg.Version <= cl.version {
+			return true, nil // old, e.g., sent before a resync
+		}
+		if msg.Base != cl.version {
+			return false, nil
+		}
+		updt := cl.Root.UpdateStart()
+		err := Patch(cl.Root, msg.Diffs)
+		cl.Root.UpdateEnd(updt)
+		if err != nil {
+			cl.version = 0
+			return false, err
+		}
+		cl.version = msg.Version
+	default:
+		return true, fmt.Errorf("ki.SyncClient: unexpected %v message from server", msg.Type)
+	}
+	return true, nil
+}
+
+// syncReadErr returns nil for errors signaling that the connection was
+// closed, and otherwise the error, logged
+func syncReadErr(who string, err error) error {
+	if err == io.EOF || errors.Is(err, io.ErrClosedPipe) || errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	err = fmt.Errorf("ki.%v: %v", who, err)
+	log.Println(err)
+	return err
+}
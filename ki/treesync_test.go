@@ -0,0 +1,238 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// syncWait waits for the client to reach given version
+func syncWait(t *testing.T, cl *SyncClient, version uint64) {
+	t.Helper()
+	for i := 0; i < 500; i++ {
+		if cl.Version() == version {
+			return
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	t.Fatalf("client did not reach version %v, at: %v", version, cl.Version())
+}
+
+// syncConnect connects the client to the server over a new net.Pipe --
+// returns the client end, to close the connection
+func syncConnect(sv *SyncServer, cl *SyncClient) (net.Conn, chan error) {
+	sc, cc := net.Pipe()
+	go sv.Serve(sc)
+	done := make(chan error, 1)
+	go func() { done <- cl.Run(cc) }()
+	return cc, done
+}
+
+func TestTreeSync(t *testing.T) {
+	tree := diffTestTree()
+	sv := NewSyncServer(tree)
+	defer sv.Close()
+
+	mirror := &NodeField2{}
+	mirror.InitName(mirror, "mirror")
+	cl := NewSyncClient(mirror)
+	cc, done := syncConnect(sv, cl)
+	syncWait(t, cl, 1)
+	if diffs := Diff(mirror, tree); len(diffs) != 0 {
+		t.Errorf("mirror differs after initial tree: %v", diffs)
+	}
+
+	// incremental updates, at UpdateEnd
+	updt := tree.UpdateStart()
+	tree.Child(1).AddNewChild(KiT_NodeEmbed, "subchild3")
+	tree.Child(0).SetProp("intprop", 17.0)
+	tree.SetField("Mbr1", "bloop")
+	tree.UpdateEnd(updt)
+	tree.DeleteChildAtIndex(2, true)
+	syncWait(t, cl, 3)
+	if diffs := Diff(mirror, tree); len(diffs) != 0 {
+		t.Errorf("mirror differs after updates: %v", diffs)
+	}
+
+	// direct changes are sent by Publish
+	tree.Field2.Mbr2 = 42
+	if v := sv.Publish(); v != 4 {
+		t.Errorf("publish version should be 4, was: %v", v)
+	}
+	syncWait(t, cl, 4)
+	if mirror.Field2.Mbr2 != 42 {
+		t.Errorf("mirror did not get published field")
+	}
+
+	// dropped connection: changes made while disconnected are sent as diffs
+	// on reconnect
+	cc.Close()
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+	tree.Child(0).SetName("child1b")
+	tree.Child(0).AddNewChild(KiT_NodeEmbed, "subchild5")
+	cc, done = syncConnect(sv, cl)
+	syncWait(t, cl, 6)
+	if diffs := Diff(mirror, tree); len(diffs) != 0 {
+		t.Errorf("mirror differs after reconnect: %v", diffs)
+	}
+	cc.Close()
+	<-done
+
+	// reconnect beyond the history: resync with the whole tree
+	sv.HistN = 1
+	for i := 0; i < 3; i++ {
+		tree.SetProp("count", float64(i))
+	}
+	cc, done = syncConnect(sv, cl)
+	syncWait(t, cl, 9)
+	if diffs := Diff(mirror, tree); len(diffs) != 0 {
+		t.Errorf("mirror differs after resync: %v", diffs)
+	}
+
+	// out of sync client requests the whole tree
+	cl.Mu.Lock()
+	cl.version = 2
+	cl.Mu.Unlock()
+	tree.SetProp("count", 10.0)
+	syncWait(t, cl, 10)
+	if diffs := Diff(mirror, tree); len(diffs) != 0 {
+		t.Errorf("mirror differs after client resync: %v", diffs)
+	}
+	cc.Close()
+	<-done
+}
+
+func TestTreeSyncNewServer(t *testing.T) {
+	tree := diffTestTree()
+	sv := NewSyncServer(tree)
+	mirror := &NodeField2{}
+	mirror.InitName(mirror, "mirror")
+	cl := NewSyncClient(mirror)
+	cc, done := syncConnect(sv, cl)
+	syncWait(t, cl, 1)
+	cc.Close()
+	<-done
+	sv.Close()
+
+	// a new server, with a different tree, that has reached a later version
+	// -- its history from version 1 does not apply to the mirror
+	tree2 := diffTestTree()
+	tree2.SetField("Mbr1", "server2")
+	sv2 := NewSyncServer(tree2)
+	defer sv2.Close()
+	tree2.Child(0).SetName("child1b")
+	tree2.SetProp("count", 1.0)
+	if sv2.Version() != 3 || sv2.Epoch() == sv.Epoch() {
+		t.Fatalf("new server should be at version 3 of a new epoch, was: %v %v", sv2.Version(), sv2.Epoch())
+	}
+	cc, done = syncConnect(sv2, cl)
+	syncWait(t, cl, 3)
+	if cl.Epoch() != sv2.Epoch() {
+		t.Errorf("client should have new server epoch: %v, has: %v", sv2.Epoch(), cl.Epoch())
+	}
+	if diffs := Diff(mirror, tree2); len(diffs) != 0 {
+		t.Errorf("mirror differs after connecting to new server: %v", diffs)
+	}
+	cc.Close()
+	<-done
+
+	// a restarted server at the same version as the mirror
+	tree3 := diffTestTree()
+	sv3 := NewSyncServer(tree3)
+	defer sv3.Close()
+	cl.Mu.Lock()
+	cl.version = 1
+	cl.Mu.Unlock()
+	cc, done = syncConnect(sv3, cl)
+	for i := 0; i < 500 && cl.Epoch() != sv3.Epoch(); i++ {
+		time.Sleep(2 * time.Millisecond)
+	}
+	if cl.Epoch() != sv3.Epoch() || cl.Version() != 1 {
+		t.Errorf("client should get the whole tree from restarted server")
+	}
+	if diffs := Diff(mirror, tree3); len(diffs) != 0 {
+		t.Errorf("mirror differs after connecting to restarted server: %v", diffs)
+	}
+	cc.Close()
+	<-done
+}
+
+func TestTreeSyncClose(t *testing.T) {
+	tree := diffTestTree()
+	sv := NewSyncServer(tree)
+	mirror := &NodeField2{}
+	mirror.InitName(mirror, "mirror")
+	cl := NewSyncClient(mirror)
+	_, done := syncConnect(sv, cl)
+	syncWait(t, cl, 1)
+
+	// loading is not recorded by the ChangeNotifier, so is published from
+	// the NodeSignalUpdated signal
+	b, err := diffTestTree().SaveJSON(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree.Child(0).SetName("renamed")
+	syncWait(t, cl, 2)
+	tree.LoadJSON(b)
+	syncWait(t, cl, 3)
+	if diffs := Diff(mirror, tree); len(diffs) != 0 {
+		t.Errorf("mirror differs after load: %v", diffs)
+	}
+
+	sv.Close()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("client connection not closed by server Close")
+	}
+	if err := sv.Serve(&net.TCPConn{}); err == nil {
+		t.Errorf("Serve should fail after Close")
+	}
+}
+
+func TestTreeSyncListener(t *testing.T) {
+	tree := diffTestTree()
+	sv := NewSyncServer(tree)
+	defer sv.Close()
+	l, err := net.Listen("unix", filepath.Join(t.TempDir(), "ki.sock"))
+	if err != nil {
+		t.Skip(err)
+	}
+	defer l.Close()
+	go sv.ServeListener(l)
+
+	var cls []*SyncClient
+	for i := 0; i < 2; i++ {
+		conn, err := net.Dial("unix", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		mirror := &NodeField2{}
+		mirror.InitName(mirror, "mirror")
+		cl := NewSyncClient(mirror)
+		go cl.Run(conn)
+		cls = append(cls, cl)
+	}
+	for _, cl := range cls {
+		syncWait(t, cl, 1)
+	}
+	tree.Child(1).Child(0).SetProp("floatprop", 1.5)
+	for _, cl := range cls {
+		syncWait(t, cl, 2)
+		if diffs := Diff(cl.Root, tree); len(diffs) != 0 {
+			t.Errorf("mirror differs: %v", diffs)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"errors"
+	"log"
+	"reflect"
+)
+
+// TypedSignal is a type-safe interface to a Signal, for signals whose codes
+// are of enum type S, and whose data is always of type D (e.g., a payload
+// struct) -- receivers get the signal code and data as those types, instead
+// of int64 and interface{}, so connecting a receiver with the wrong
+// signature, or emitting the wrong kind of data, is a compile-time error.
+// Get one for a Signal with Typed -- e.g., as an accessor for a Signal field:
+//
+//	func (g *MyWidget) MySignal() ki.TypedSignal[MySignals, MyPayload] {
+//		return ki.Typed[MySignals, MyPayload](&g.MySig)
+//	}
+//
+// The enum type S should be registered with kit.Enums, and it is set as the
+// SigType of the Signal, so that traces show the signal names.  Regular
+// RecvFunc receivers can still connect to the Signal directly, and get the
+// data as a D in the interface{}.
+type TypedSignal[S ~int64, D any] struct {
+	Sig *Signal
+}
+
+// TypedRecvFunc is a receiver function for a TypedSignal, getting the signal
+// code and data as their actual types
+type TypedRecvFunc[S ~int64, D any] func(recv, send Ki, sig S, data D)
+
+// Typed returns a TypedSignal for given Signal, and sets the SigType of the
+// Signal to S if not already set
+func Typed[S ~int64, D any](sig *Signal) TypedSignal[S, D] {
+	sig.Mu.Lock()
+	if sig.SigType == nil {
+		sig.SigType = reflect.TypeOf(S(0))
+	}
+	sig.Mu.Unlock()
+	return TypedSignal[S, D]{Sig: sig}
+}
+
+// Connect attaches a new receiver to the signal -- checks to make sure
+// connection does not already exist, in which case the existing connection
+// is returned -- returns a handle to the connection, error if not ok
+func (ts TypedSignal[S, D]) Connect(recv Ki, fun TypedRecvFunc[S, D]) (SignalConn, error) {
+	return ts.ConnectPrio(recv, fun, 0)
+}
+
+// ConnectPrio attaches a new receiver to the signal with given priority --
+// see Signal.ConnectPrio
+func (ts TypedSignal[S, D]) ConnectPrio(recv Ki, fun TypedRecvFunc[S, D], prio int) (SignalConn, error) {
+	if recv == nil {
+		err := errors.New("ki TypedSignal Connect: no recv node provided\n")
+		log.Println(err)
+		return SignalConn{}, err
+	}
+	if fun == nil {
+		err := errors.New("ki TypedSignal Connect: no recv func provided\n")
+		log.Println(err)
+		return SignalConn{}, err
+	}
+	ts.Sig.Mu.Lock()
+	defer ts.Sig.Mu.Unlock()
+	if i := ts.findConnectionIndex(recv, fun); i >= 0 {
+		return SignalConn{ts.Sig, ts.Sig.Cons[i].ID}, nil
+	}
+	rf := func(recv, send Ki, sig int64, data interface{}) {
+		d, _ := data.(D) // zero value if data is nil
+		fun(recv, send, S(sig), d)
+	}
+	return ts.Sig.addConnection(Connection{Recv: recv, Func: rf, Orig: fun, Prio: prio}), nil
+}
+
+// findConnectionIndex returns the index of the connection for given recv
+// and fun, -1 if not found -- lock must be held
+func (ts TypedSignal[S, D]) findConnectionIndex(recv Ki, fun TypedRecvFunc[S, D]) int {
+	rfref := reflect.ValueOf(fun).Pointer()
+	for i, con := range ts.Sig.Cons {
+		if con.Recv != recv || con.Orig == nil {
+			continue
+		}
+		if ofun, ok := con.Orig.(TypedRecvFunc[S, D]); ok && reflect.ValueOf(ofun).Pointer() == rfref {
+			return i
+		}
+	}
+	return -1
+}
+
+// Disconnect removes the connection for given receiver and function, if it
+// exists -- returns true if found
+func (ts TypedSignal[S, D]) Disconnect(recv Ki, fun TypedRecvFunc[S, D]) bool {
+	ts.Sig.Mu.Lock()
+	defer ts.Sig.Mu.Unlock()
+	i := ts.findConnectionIndex(recv, fun)
+	if i < 0 {
+		return false
+	}
+	copy(ts.Sig.Cons[i:], ts.Sig.Cons[i+1:])
+	ts.Sig.Cons[len(ts.Sig.Cons)-1] = Connection{}
+	ts.Sig.Cons = ts.Sig.Cons[:len(ts.Sig.Cons)-1]
+	return true
+}
+
+// Emit sends the signal across all the connections to the receivers --
+// sequential
+func (ts TypedSignal[S, D]) Emit(sender Ki, sig S, data D) {
+	ts.Sig.Emit(sender, int64(sig), data)
+}
+
+// EmitGo concurrent version -- sends the signal across all the connections
+// to the receivers
+func (ts TypedSignal[S, D]) EmitGo(sender Ki, sig S, data D) {
+	ts.Sig.EmitGo(sender, int64(sig), data)
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"testing"
+
+	"github.com/rcoreilly/goki/ki/kit"
+)
+
+type testSignals int64
+
+const (
+	testSigPressed testSignals = iota
+	testSigReleased
+	testSignalsN
+)
+
+func (ts testSignals) String() string {
+	switch ts {
+	case testSigPressed:
+		return "testSigPressed"
+	case testSigReleased:
+		return "testSigReleased"
+	}
+	return "testSignalsN"
+}
+
+var KiT_testSignals = kit.Enums.AddEnum(testSignalsN, false, nil)
+
+type testPayload struct {
+	Pos   int
+	Label string
+}
+
+func TestTypedSignal(t *testing.T) {
+	parent := TestNode{}
+	parent.InitName(&parent, "par1")
+	child1 := parent.AddNewChild(nil, "child1")
+	child2 := parent.AddNewChild(nil, "child2")
+
+	tsig := Typed[testSignals, testPayload](&parent.sig1)
+	if parent.sig1.SigType != KiT_testSignals {
+		t.Errorf("Typed did not set SigType: %v", parent.sig1.SigType)
+	}
+
+	var got []testPayload
+	var sigs []testSignals
+	recv := func(recv, send Ki, sig testSignals, data testPayload) {
+		sigs = append(sigs, sig)
+		got = append(got, data)
+	}
+	tsig.Connect(child1, recv)
+	tsig.Connect(child1, recv)
+	if len(parent.sig1.Cons) != 1 {
+		t.Errorf("duplicate typed connection made")
+	}
+	var untyped interface{}
+	parent.sig1.Connect(child2, func(recv, send Ki, sig int64, data interface{}) {
+		untyped = data
+	})
+
+	tsig.Emit(parent.This, testSigReleased, testPayload{Pos: 2, Label: "b"})
+	if len(got) != 1 || sigs[0] != testSigReleased || got[0].Label != "b" {
+		t.Errorf("typed receiver got: %v %v", sigs, got)
+	}
+	if pl, ok := untyped.(testPayload); !ok || pl.Pos != 2 {
+		t.Errorf("untyped receiver got: %v", untyped)
+	}
+
+	// untyped emit with nil data gives zero payload
+	parent.sig1.Emit(parent.This, int64(testSigPressed), nil)
+	if len(got) != 2 || sigs[1] != testSigPressed || got[1] != (testPayload{}) {
+		t.Errorf("typed receiver of untyped emit got: %v %v", sigs, got)
+	}
+
+	if !tsig.Disconnect(child1, recv) || len(parent.sig1.Cons) != 1 {
+		t.Errorf("typed Disconnect failed")
+	}
+
+	// traces show signal names of the signal type
+	strace, stracestr := SignalTrace, SignalTraceString
+	trc := ""
+	SignalTrace = true
+	SignalTraceString = &trc
+	tsig.Emit(parent.This, testSigPressed, testPayload{})
+	parent.NodeSignal().Emit(parent.This, int64(NodeSignalUpdated), nil)
+	parent.sig2.Emit(parent.This, 3, nil)
+	SignalTrace, SignalTraceString = strace, stracestr
+	trg := `ki.Signal Emit from: par1 sig: testSigPressed data: {0 }
+ki.Signal Emit from: par1 sig: NodeSignalUpdated data: <nil>
+ki.Signal Emit from: par1 sig: 3 data: <nil>
+`
+	if trc != trg {
+		t.Errorf("signal trace:\n%v\nnot as expected:\n%v\n", trc, trg)
+	}
+}
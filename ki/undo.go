@@ -0,0 +1,477 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/rcoreilly/goki/ki/bitflag"
+	"github.com/rcoreilly/goki/ki/kit"
+)
+
+// UndoStack records reversible operations on a Ki tree, so that they can be
+// undone and redone.  Attach an UndoStack to a node (typically the root) with
+// AttachUndoStack, and all structural edits (AddChild, InsertChild,
+// DeleteChild, MoveChild, ConfigChildren, SetName) and field / property
+// changes (SetField, SetProp, DeleteProp) made to that node or anywhere below
+// it are recorded.  Records are grouped by the UpdateStart / UpdateEnd
+// brackets: everything that happens between the outermost UpdateStart and its
+// matching UpdateEnd becomes one undoable step, and edits made outside of any
+// update (e.g., a plain SetProp) are each their own step.
+//
+// Deleted nodes (even when deleted with destroy = true) are held by the
+// stack instead of being destroyed, so they can be restored -- they are
+// destroyed when the record holding them is dropped from the stack (via
+// MaxDepth, a new edit clearing the redo records, Reset, or Detach).
+//
+// Direct modification of fields, Props, or Kids that bypasses the Ki methods
+// is not recorded, and neither is loading (LoadJSON etc) or CopyFrom.
+type UndoStack struct {
+	Root     Ki          `desc:"node that this stack is attached to -- edits on it and all nodes below it are recorded"`
+	MaxDepth int         `desc:"maximum number of undo steps retained -- oldest are dropped first -- 0 = UndoMaxDepth"`
+	Undos    []UndoGroup `desc:"steps that can be undone -- last is most recent"`
+	Redos    []UndoGroup `desc:"steps that have been undone and can be redone -- last is most recent"`
+	Mu       sync.Mutex  `desc:"mutex protecting updates to the stack"`
+	cur      UndoGroup   // group being accumulated within current update
+	curOwner Ki          // node whose UpdateStart opened the current group
+	applying bool        // true while undoing or redoing -- nothing is recorded
+}
+
+// UndoMaxDepth is the default maximum number of steps retained in an UndoStack
+var UndoMaxDepth = 100
+
+// UndoOps are the types of reversible operations recorded in an UndoStack
+type UndoOps int32
+
+const (
+	// UndoInsert records that Kid was inserted into Node children at Idx
+	UndoInsert UndoOps = iota
+
+	// UndoDelete records that Kid was deleted from Node children at Idx
+	UndoDelete
+
+	// UndoMove records that a child of Node was moved from Idx to ToIdx
+	UndoMove
+
+	// UndoSetName records that Node name changed from Old to New (Key holds
+	// the old UniqueName)
+	UndoSetName
+
+	// UndoSetField records that field Key on Node changed from Old to New
+	UndoSetField
+
+	// UndoSetProp records that property Key on Node changed from Old to New
+	// -- HadOld / HasNew indicate if the property was set before / after
+	UndoSetProp
+
+	UndoOpsN
+)
+
+//go:generate stringer -type=UndoOps
+
+var KiT_UndoOps = kit.Enums.AddEnum(UndoOpsN, false, nil)
+
+// UndoRec is one reversible operation recorded in an UndoStack
+type UndoRec struct {
+	Op      UndoOps     `desc:"type of operation"`
+	Node    Ki          `desc:"node that was modified -- for child operations, this is the parent"`
+	Kid     Ki          `desc:"child that was inserted or deleted"`
+	Idx     int         `desc:"index of child inserted or deleted, or from index for move"`
+	ToIdx   int         `desc:"to index for move"`
+	Key     string      `desc:"field name or property key -- old unique name for SetName"`
+	Old     interface{} `desc:"value prior to operation"`
+	New     interface{} `desc:"value after operation"`
+	HadOld  bool        `desc:"for SetProp: property was set prior to operation"`
+	HasNew  bool        `desc:"for SetProp: property is set after operation"`
+	Destroy bool        `desc:"for Delete: destroy was requested -- kid is destroyed when this record is dropped"`
+}
+
+// UndoGroup is the list of records comprising one undoable step
+type UndoGroup []*UndoRec
+
+// undoStacks holds all the UndoStacks, indexed by the node they are attached to
+var undoStacks = struct {
+	sync.RWMutex
+	m map[Ki]*UndoStack
+}{}
+
+// AttachUndoStack attaches an UndoStack to given node, which will then
+// record all edits on that node and any nodes below it -- returns the
+// existing stack if one is already attached -- maxDepth is the maximum number
+// of steps retained, 0 for the default UndoMaxDepth
+func AttachUndoStack(k Ki, maxDepth int) *UndoStack {
+	undoStacks.Lock()
+	defer undoStacks.Unlock()
+	if undoStacks.m == nil {
+		undoStacks.m = make(map[Ki]*UndoStack)
+	}
+	if us, ok := undoStacks.m[k]; ok {
+		return us
+	}
+	us := &UndoStack{Root: k, MaxDepth: maxDepth}
+	undoStacks.m[k] = us
+	return us
+}
+
+// UndoStackFor returns the UndoStack that records edits on given node --
+// the stack attached to the node itself or its closest parent -- nil if
+// none
+func UndoStackFor(k Ki) *UndoStack {
+	undoStacks.RLock()
+	defer undoStacks.RUnlock()
+	if len(undoStacks.m) == 0 {
+		return nil
+	}
+	for cur := k; cur != nil; cur = cur.Parent() {
+		if us, ok := undoStacks.m[cur]; ok {
+			return us
+		}
+		if cur.IsRoot() {
+			break
+		}
+	}
+	return nil
+}
+
+// Detach removes this stack from its node so nothing further is recorded,
+// and drops all recorded steps
+func (us *UndoStack) Detach() {
+	undoStacks.Lock()
+	if undoStacks.m[us.Root] == us {
+		delete(undoStacks.m, us.Root)
+	}
+	undoStacks.Unlock()
+	us.Reset()
+}
+
+// Reset drops all recorded steps
+func (us *UndoStack) Reset() {
+	us.Mu.Lock()
+	drops := make([]UndoGroup, 0, len(us.Undos)+len(us.Redos))
+	drops = append(drops, us.Undos...)
+	drops = append(drops, us.Redos...)
+	us.Undos = nil
+	us.Redos = nil
+	us.cur = nil
+	us.curOwner = nil
+	us.Mu.Unlock()
+	undoDropGroups(drops...)
+}
+
+// CanUndo returns true if there is a step that can be undone
+func (us *UndoStack) CanUndo() bool {
+	us.Mu.Lock()
+	defer us.Mu.Unlock()
+	return len(us.Undos) > 0
+}
+
+// CanRedo returns true if there is a step that can be redone
+func (us *UndoStack) CanRedo() bool {
+	us.Mu.Lock()
+	defer us.Mu.Unlock()
+	return len(us.Redos) > 0
+}
+
+// Undo reverses the most recent step -- returns false if there was nothing
+// to undo
+func (us *UndoStack) Undo() bool {
+	us.Mu.Lock()
+	n := len(us.Undos)
+	if n == 0 || us.applying {
+		us.Mu.Unlock()
+		return false
+	}
+	grp := us.Undos[n-1]
+	us.Undos = us.Undos[:n-1]
+	us.applying = true
+	us.Mu.Unlock()
+
+	for i := len(grp) - 1; i >= 0; i-- {
+		grp[i].undo()
+	}
+
+	us.Mu.Lock()
+	us.applying = false
+	us.Redos = append(us.Redos, grp)
+	us.Mu.Unlock()
+	return true
+}
+
+// Redo re-applies the most recently undone step -- returns false if there
+// was nothing to redo
+func (us *UndoStack) Redo() bool {
+	us.Mu.Lock()
+	n := len(us.Redos)
+	if n == 0 || us.applying {
+		us.Mu.Unlock()
+		return false
+	}
+	grp := us.Redos[n-1]
+	us.Redos = us.Redos[:n-1]
+	us.applying = true
+	us.Mu.Unlock()
+
+	for _, rec := range grp {
+		rec.redo()
+	}
+
+	us.Mu.Lock()
+	us.applying = false
+	us.Undos = append(us.Undos, grp)
+	us.Mu.Unlock()
+	return true
+}
+
+// record adds records to the current group, or as their own step if there
+// is no update in progress -- returns false if not recorded
+func (us *UndoStack) record(recs ...*UndoRec) bool {
+	us.Mu.Lock()
+	if us.applying {
+		us.Mu.Unlock()
+		return false
+	}
+	if us.curOwner != nil {
+		us.cur = append(us.cur, recs...)
+		us.Mu.Unlock()
+		return true
+	}
+	drops := us.pushUndo(UndoGroup(recs))
+	us.Mu.Unlock()
+	undoDropGroups(drops...)
+	return true
+}
+
+// pushUndo pushes a new step onto Undos, clearing Redos and enforcing
+// MaxDepth -- returns the groups that were dropped -- must be called under
+// mutex
+func (us *UndoStack) pushUndo(grp UndoGroup) []UndoGroup {
+	drops := us.Redos
+	us.Redos = nil
+	us.Undos = append(us.Undos, grp)
+	mx := us.MaxDepth
+	if mx <= 0 {
+		mx = UndoMaxDepth
+	}
+	if over := len(us.Undos) - mx; over > 0 {
+		drops = append(drops, us.Undos[:over]...)
+		us.Undos = append(us.Undos[:0], us.Undos[over:]...)
+	}
+	return drops
+}
+
+// updateStart is called when an UpdateStart on given node returned true --
+// starts a new group if one is not already in progress
+func (us *UndoStack) updateStart(k Ki) {
+	us.Mu.Lock()
+	if !us.applying && us.curOwner == nil {
+		us.curOwner = k
+		us.cur = nil
+	}
+	us.Mu.Unlock()
+}
+
+// updateEnd is called when an UpdateEnd on given node is passed true --
+// finishes the current group if the node is the one that started it
+func (us *UndoStack) updateEnd(k Ki) {
+	us.Mu.Lock()
+	if us.curOwner != k {
+		us.Mu.Unlock()
+		return
+	}
+	grp := us.cur
+	us.cur = nil
+	us.curOwner = nil
+	var drops []UndoGroup
+	if len(grp) > 0 {
+		drops = us.pushUndo(grp)
+	}
+	us.Mu.Unlock()
+	undoDropGroups(drops...)
+}
+
+// undoRecord records given operations, which must all be on the same node,
+// in the UndoStack for that node, if there is one -- returns true if
+// recorded
+func undoRecord(recs ...*UndoRec) bool {
+	if len(recs) == 0 || recs[0].Node.IsDestroyed() {
+		return false
+	}
+	us := UndoStackFor(recs[0].Node)
+	if us == nil {
+		return false
+	}
+	return us.record(recs...)
+}
+
+// undoUpdateStart notifies the UndoStack for given node, if any, of an
+// UpdateStart that returned true
+func undoUpdateStart(k Ki) {
+	if us := UndoStackFor(k); us != nil {
+		us.updateStart(k)
+	}
+}
+
+// undoUpdateEnd notifies any UndoStack with a group started by given node
+// that its update has ended -- the node may no longer be under the stack
+// (e.g., if it deleted itself) so all stacks are checked
+func undoUpdateEnd(k Ki) {
+	undoStacks.RLock()
+	if len(undoStacks.m) == 0 {
+		undoStacks.RUnlock()
+		return
+	}
+	var owns []*UndoStack
+	for _, us := range undoStacks.m {
+		us.Mu.Lock()
+		if us.curOwner == k {
+			owns = append(owns, us)
+		}
+		us.Mu.Unlock()
+	}
+	undoStacks.RUnlock()
+	for _, us := range owns {
+		us.updateEnd(k)
+	}
+}
+
+// undoDetach detaches the UndoStack attached to given node, if any
+func undoDetach(k Ki) {
+	undoStacks.RLock()
+	us, ok := undoStacks.m[k]
+	undoStacks.RUnlock()
+	if ok {
+		us.Detach()
+	}
+}
+
+// undoDropGroups destroys any deleted nodes held by given groups, which
+// will no longer be restored
+func undoDropGroups(grps ...UndoGroup) {
+	for _, grp := range grps {
+		for _, rec := range grp {
+			if rec.Op == UndoDelete && rec.Destroy && rec.Kid.Parent() == nil && !rec.Kid.IsDestroyed() {
+				DelMgr.Add(rec.Kid)
+			}
+		}
+	}
+	if len(grps) > 0 {
+		DelMgr.DestroyDeleted()
+	}
+}
+
+// undo reverses the operation
+func (rec *UndoRec) undo() {
+	if rec.Node.IsDestroyed() {
+		return
+	}
+	switch rec.Op {
+	case UndoInsert:
+		rec.removeKid()
+	case UndoDelete:
+		rec.Node.InsertChild(rec.Kid, rec.Idx)
+	case UndoMove:
+		rec.Node.MoveChild(rec.ToIdx, rec.Idx)
+	case UndoSetName:
+		rec.setName(rec.Old.(string), rec.Key)
+	case UndoSetField:
+		rec.Node.SetField(rec.Key, undoCopy(rec.Old))
+	case UndoSetProp:
+		rec.setProp(rec.HadOld, rec.Old)
+	}
+}
+
+// redo re-applies the operation
+func (rec *UndoRec) redo() {
+	if rec.Node.IsDestroyed() {
+		return
+	}
+	switch rec.Op {
+	case UndoInsert:
+		rec.Node.InsertChild(rec.Kid, rec.Idx)
+	case UndoDelete:
+		rec.removeKid()
+	case UndoMove:
+		rec.Node.MoveChild(rec.Idx, rec.ToIdx)
+	case UndoSetName:
+		rec.setName(rec.New.(string), "")
+	case UndoSetField:
+		rec.Node.SetField(rec.Key, undoCopy(rec.New))
+	case UndoSetProp:
+		rec.setProp(rec.HasNew, rec.New)
+	}
+}
+
+// undoCopy returns a copy of a field value for an UndoRec -- maps and slices
+// (including those nested within them) are copied, so that the record does
+// not share data with the field, which could then be edited in place
+func undoCopy(val interface{}) interface{} {
+	if val == nil {
+		return nil
+	}
+	return undoCopyValue(reflect.ValueOf(val)).Interface()
+}
+
+func undoCopyValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.MakeMap(v.Type())
+		for _, key := range v.MapKeys() {
+			cp.SetMapIndex(key, undoCopyValue(v.MapIndex(key)))
+		}
+		return cp
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			cp.Index(i).Set(undoCopyValue(v.Index(i)))
+		}
+		return cp
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.New(v.Type()).Elem()
+		cp.Set(undoCopyValue(v.Elem()))
+		return cp
+	}
+	return v
+}
+
+// removeKid removes Kid from Node children, without destroying it -- the
+// kid may have moved since the record was made, so it is searched for
+// starting at Idx
+func (rec *UndoRec) removeKid() {
+	idx := rec.Node.ChildIndex(rec.Kid, rec.Idx)
+	if idx < 0 {
+		return
+	}
+	rec.Node.DeleteChildAtIndex(idx, false)
+}
+
+// setName sets the name, and the unique name if non-empty
+func (rec *UndoRec) setName(name, uniqNm string) {
+	rec.Node.SetName(name)
+	if uniqNm != "" {
+		rec.Node.SetUniqueName(uniqNm)
+	}
+	rec.Node.UpdateSig()
+}
+
+// setProp sets the property to given value if set is true, else deletes it
+func (rec *UndoRec) setProp(set bool, val interface{}) {
+	if set {
+		rec.Node.SetPropUpdate(rec.Key, val)
+	} else {
+		rec.Node.DeleteProp(rec.Key)
+		bitflag.Set(rec.Node.Flags(), int(PropUpdated))
+		rec.Node.UpdateSig()
+	}
+}
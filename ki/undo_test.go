@@ -0,0 +1,226 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"testing"
+
+	"github.com/rcoreilly/goki/ki/kit"
+)
+
+func undoTestTree() *NodeEmbed {
+	parent := NodeEmbed{}
+	parent.InitName(&parent, "par1")
+	typ := parent.Type()
+	parent.AddNewChild(typ, "child1")
+	child2 := parent.AddNewChild(typ, "child2")
+	parent.AddNewChild(typ, "child3")
+	child2.AddNewChild(typ, "subchild1")
+	return &parent
+}
+
+// undoTestPaths returns the unique paths of all nodes in the tree, in order
+func undoTestPaths(k Ki) string {
+	paths := ""
+	k.FuncDownMeFirst(0, nil, func(k Ki, level int, d interface{}) bool {
+		paths += k.PathUnique() + " "
+		return true
+	})
+	return paths
+}
+
+func TestUndoStructure(t *testing.T) {
+	parent := undoTestTree()
+	us := AttachUndoStack(parent, 0)
+	if UndoStackFor(parent.Child(1).Child(0)) != us {
+		t.Errorf("UndoStackFor did not find stack from subchild")
+	}
+	orig := undoTestPaths(parent)
+	typ := parent.Type()
+
+	parent.AddNewChild(typ, "child4")
+	parent.MoveChild(3, 0)
+	parent.DeleteChildAtIndex(2, true)
+	parent.Child(1).SetName("newname")
+	if len(us.Undos) != 4 {
+		t.Errorf("undo steps should be 4, was: %v", len(us.Undos))
+	}
+	after := undoTestPaths(parent)
+
+	for us.Undo() {
+	}
+	if res := undoTestPaths(parent); res != orig {
+		t.Errorf("undo all: structure should be: %v, was: %v", orig, res)
+	}
+	if parent.Child(1).Child(0).Parent() != parent.Child(1) {
+		t.Errorf("undo all: restored subchild has wrong parent")
+	}
+
+	for us.Redo() {
+	}
+	if res := undoTestPaths(parent); res != after {
+		t.Errorf("redo all: structure should be: %v, was: %v", after, res)
+	}
+	if us.CanRedo() {
+		t.Errorf("CanRedo should be false after redo all")
+	}
+}
+
+func TestUndoGroup(t *testing.T) {
+	parent := undoTestTree()
+	us := AttachUndoStack(parent, 0)
+	orig := undoTestPaths(parent)
+	typ := parent.Type()
+
+	updt := parent.UpdateStart()
+	parent.AddNewChild(typ, "child4")
+	parent.DeleteChildren(true)
+	parent.AddNewChild(typ, "child5")
+	parent.UpdateEnd(updt)
+	if len(us.Undos) != 1 {
+		t.Errorf("undo steps should be 1, was: %v", len(us.Undos))
+	}
+	us.Undo()
+	if res := undoTestPaths(parent); res != orig {
+		t.Errorf("undo group: structure should be: %v, was: %v", orig, res)
+	}
+
+	// new edit clears redos, and destroys the kids held for redo
+	kid := parent.Child(0)
+	parent.SetProp("newprop", 1)
+	if us.CanRedo() {
+		t.Errorf("CanRedo should be false after new edit")
+	}
+	if kid.IsDestroyed() {
+		t.Errorf("restored kid should not be destroyed")
+	}
+	us.Detach()
+	if UndoStackFor(parent) != nil {
+		t.Errorf("UndoStackFor should be nil after Detach")
+	}
+}
+
+func TestUndoConfig(t *testing.T) {
+	parent := undoTestTree()
+	us := AttachUndoStack(parent, 0)
+	orig := undoTestPaths(parent)
+	typ := parent.Type()
+
+	config := kit.TypeAndNameList{}
+	config.Add(typ, "child3")
+	config.Add(typ, "child5")
+	config.Add(typ, "child1")
+	mods, updt := parent.ConfigChildren(config, false)
+	if mods {
+		parent.UpdateEnd(updt)
+	}
+	after := undoTestPaths(parent)
+	us.Undo()
+	if res := undoTestPaths(parent); res != orig {
+		t.Errorf("undo config: structure should be: %v, was: %v", orig, res)
+	}
+	us.Redo()
+	if res := undoTestPaths(parent); res != after {
+		t.Errorf("redo config: structure should be: %v, was: %v", after, res)
+	}
+}
+
+func TestUndoFieldProp(t *testing.T) {
+	parent := undoTestTree()
+	us := AttachUndoStack(parent, 0)
+	child := parent.Child(0).(*NodeEmbed)
+
+	child.SetField("Mbr1", "bloop")
+	child.SetField("Mbr2", 42)
+	child.SetProp("intprop", 42)
+	parent.SetProp("stringprop", "new")
+	parent.SetProp("stringprop", "newer")
+	parent.DeleteProp("stringprop")
+	if len(us.Undos) != 6 {
+		t.Errorf("undo steps should be 6, was: %v", len(us.Undos))
+	}
+
+	us.Undo()
+	if parent.Prop("stringprop", false, false) != "newer" {
+		t.Errorf("undo DeleteProp: stringprop should be newer, was: %v", parent.Prop("stringprop", false, false))
+	}
+	for us.Undo() {
+	}
+	if child.Mbr1 != "" || child.Mbr2 != 0 {
+		t.Errorf("undo SetField: fields should be empty, were: %v, %v", child.Mbr1, child.Mbr2)
+	}
+	if _, ok := child.Props["intprop"]; ok {
+		t.Errorf("undo SetProp: intprop should not be set")
+	}
+	if _, ok := parent.Props["stringprop"]; ok {
+		t.Errorf("undo SetProp: stringprop should not be set")
+	}
+
+	for us.Redo() {
+	}
+	if child.Mbr1 != "bloop" || child.Mbr2 != 42 {
+		t.Errorf("redo SetField: fields should be bloop, 42, were: %v, %v", child.Mbr1, child.Mbr2)
+	}
+	if child.Prop("intprop", false, false) != 42 {
+		t.Errorf("redo SetProp: intprop should be 42, was: %v", child.Prop("intprop", false, false))
+	}
+	if _, ok := parent.Props["stringprop"]; ok {
+		t.Errorf("redo DeleteProp: stringprop should not be set")
+	}
+}
+
+func TestUndoFieldInPlace(t *testing.T) {
+	nb := NodeBinary{}
+	nb.InitName(&nb, "nb")
+	nb.Map = map[string]int{"a": 1}
+	nb.Vals = []float32{1, 2}
+	us := AttachUndoStack(&nb, 0)
+
+	nb.SetField("Map", map[string]int{"a": 2})
+	nb.SetField("Vals", []float32{3, 4})
+	nb.Map["a"] = 5
+	nb.Vals[0] = 5
+
+	for us.Undo() {
+	}
+	if nb.Map["a"] != 1 || len(nb.Map) != 1 {
+		t.Errorf("undo SetField: Map should be a: 1, was: %v", nb.Map)
+	}
+	if len(nb.Vals) != 2 || nb.Vals[0] != 1 || nb.Vals[1] != 2 {
+		t.Errorf("undo SetField: Vals should be [1 2], was: %v", nb.Vals)
+	}
+	nb.Map["a"] = 6
+	nb.Vals[0] = 6
+
+	for us.Redo() {
+	}
+	if nb.Map["a"] != 2 {
+		t.Errorf("redo SetField: Map should be a: 2, was: %v", nb.Map)
+	}
+	if nb.Vals[0] != 3 || nb.Vals[1] != 4 {
+		t.Errorf("redo SetField: Vals should be [3 4], was: %v", nb.Vals)
+	}
+	us.Undo()
+	us.Undo()
+	if nb.Map["a"] != 1 || nb.Vals[0] != 1 {
+		t.Errorf("second undo SetField: should be a: 1, [1 2], was: %v, %v", nb.Map, nb.Vals)
+	}
+}
+
+func TestUndoMaxDepth(t *testing.T) {
+	parent := undoTestTree()
+	us := AttachUndoStack(parent, 3)
+	for i := 0; i < 10; i++ {
+		parent.SetProp("intprop", i)
+	}
+	if len(us.Undos) != 3 {
+		t.Errorf("undo steps should be limited to 3, was: %v", len(us.Undos))
+	}
+	for us.Undo() {
+	}
+	if parent.Prop("intprop", false, false) != 6 {
+		t.Errorf("undo to max depth: intprop should be 6, was: %v", parent.Prop("intprop", false, false))
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+
+	"github.com/rcoreilly/goki/ki/kit"
+)
+
+// Node lifecycle hooks and validation: Ki types can implement any of the
+// optional interfaces below, to be notified when they are added to or
+// removed from a parent, or loaded, and to declare their own invariants,
+// checked by ValidateTree.  Types can also declare the types and numbers of
+// children they allow, as ChildConstraints -- set on a node with
+// SetChildConstraints (not saved with the node), or for all nodes of a type,
+// as a "ChildConstraints" property of the type (in kit.Types) -- which are
+// enforced by AddChild, InsertChild, AddNewChild, InsertNewChild, and
+// ConfigChildren.  Minimum numbers of children are only enforced by
+// ConfigChildren, as other children can be added later -- ValidateTree
+// reports any nodes below the minimum.
+
+// Validator is an optional interface for Ki types that can check their own
+// invariants -- see ValidateTree
+type Validator interface {
+	// Validate returns an error describing what is wrong with the node, nil
+	// if it is valid
+	Validate() error
+}
+
+// OnAddedHook is an optional interface for Ki types that are notified when
+// they are added to a parent, including when moved from another parent
+type OnAddedHook interface {
+	// OnAdded is called after the node has been added to the parent
+	OnAdded(parent Ki)
+}
+
+// OnRemovedHook is an optional interface for Ki types that are notified when
+// they are removed from a parent, including when moved to another parent
+type OnRemovedHook interface {
+	// OnRemoved is called after the node has been removed from the parent
+	OnRemoved(parent Ki)
+}
+
+// OnLoadedHook is an optional interface for Ki types that are notified when
+// they have been loaded, e.g., from JSON
+type OnLoadedHook interface {
+	// OnLoaded is called in UnmarshalPost, after the entire tree has been
+	// loaded and its pointers restored, for each node in the tree in
+	// depth-first order
+	OnLoaded()
+}
+
+// OnMigratedHook is an optional interface for Ki types that are notified of
+// the kit.Migrations applied in loading them, e.g., from JSON
+type OnMigratedHook interface {
+	// OnMigrated is called in UnmarshalPost on the node that was loaded, after
+	// the OnLoaded hooks, with the records of the migrations applied to
+	// objects in its tree (children and struct properties) in depth-first
+	// order -- only called if any migrations were applied
+	OnMigrated(recs []kit.MigrationRecord)
+}
+
+// callOnAdded calls the OnAdded hook of kid if it has one
+func callOnAdded(kid, parent Ki) {
+	if h, ok := kid.(OnAddedHook); ok {
+		h.OnAdded(parent)
+	}
+}
+
+// callOnRemoved calls the OnRemoved hook of kid if it has one
+func callOnRemoved(kid, parent Ki) {
+	if h, ok := kid.(OnRemovedHook); ok {
+		h.OnRemoved(parent)
+	}
+}
+
+// ChildConstraint allows children of a given type, optionally with a
+// minimum and maximum number of them
+type ChildConstraint struct {
+	Type reflect.Type `desc:"allowed type of children -- children of types that embed this type, or implement it if it is an interface, are also allowed"`
+	Min  int          `desc:"minimum number of children of this type -- 0 = none required"`
+	Max  int          `desc:"maximum number of children of this type -- 0 = unlimited"`
+}
+
+// Matches returns true if children of given type match this constraint
+func (cc *ChildConstraint) Matches(typ reflect.Type) bool {
+	if cc.Type.Kind() == reflect.Interface {
+		return reflect.PtrTo(typ).Implements(cc.Type)
+	}
+	return typ == cc.Type || kit.TypeEmbeds(typ, cc.Type)
+}
+
+// ChildConstraints are the constraints on the children of a node -- only
+// children matching one of the constraints are allowed, and each child
+// counts toward the first constraint that it matches -- no constraints means
+// that any children are allowed
+type ChildConstraints []ChildConstraint
+
+var KiT_ChildConstraints = kit.Types.AddType(&ChildConstraints{}, nil)
+
+// Index returns the index of the first constraint matching given type, -1
+// if none
+func (cc ChildConstraints) Index(typ reflect.Type) int {
+	for i := range cc {
+		if cc[i].Matches(typ) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Types returns the list of types of the constraints
+func (cc ChildConstraints) Types() []reflect.Type {
+	typs := make([]reflect.Type, len(cc))
+	for i := range cc {
+		typs[i] = cc[i].Type
+	}
+	return typs
+}
+
+// Counts returns the number of given types of children (with duplicates)
+// counting toward each constraint -- types not matching any are ignored
+func (cc ChildConstraints) Counts(typs []reflect.Type) []int {
+	cnts := make([]int, len(cc))
+	for _, typ := range typs {
+		if ci := cc.Index(typ); ci >= 0 {
+			cnts[ci]++
+		}
+	}
+	return cnts
+}
+
+// Check returns an error if the given types of children do not satisfy the
+// constraints -- min is whether to check the minimum numbers
+func (cc ChildConstraints) Check(typs []reflect.Type, min bool) error {
+	if len(cc) == 0 {
+		return nil
+	}
+	for _, typ := range typs {
+		if cc.Index(typ) < 0 {
+			return fmt.Errorf("child type %v is not allowed -- allowed types: %v", typ.Name(), cc.Types())
+		}
+	}
+	cnts := cc.Counts(typs)
+	for i, c := range cc {
+		if c.Max > 0 && cnts[i] > c.Max {
+			return fmt.Errorf("%v children of type %v, above maximum of %v", cnts[i], c.Type.Name(), c.Max)
+		}
+		if min && cnts[i] < c.Min {
+			return fmt.Errorf("%v children of type %v, below minimum of %v", cnts[i], c.Type.Name(), c.Min)
+		}
+	}
+	return nil
+}
+
+// childTypes returns the types of the children in the slice
+func childTypes(kids Slice) []reflect.Type {
+	typs := make([]reflect.Type, len(kids))
+	for i, kid := range kids {
+		typs[i] = kid.Type()
+	}
+	return typs
+}
+
+func (n *Node) SetChildConstraints(cons ChildConstraints) error {
+	for _, c := range cons {
+		if c.Type.Kind() != reflect.Interface && !reflect.PtrTo(c.Type).Implements(KiType()) {
+			err := fmt.Errorf("Ki Node %v SetChildConstraints: type does not implement the Ki interface -- must -- type passed is: %v", n.PathUnique(), c.Type.Name())
+			log.Print(err)
+			return err
+		}
+	}
+	if len(cons) == 0 {
+		cons = nil
+	}
+	n.childCons = cons
+	return nil
+}
+
+func (n *Node) ChildConstraints() ChildConstraints {
+	if n.childCons != nil {
+		return n.childCons
+	}
+	cons, _ := n.Prop("ChildConstraints", false, true).(ChildConstraints) // no inherit but yes from type
+	return cons
+}
+
+func (n *Node) CanAddChild(typ reflect.Type) error {
+	cons := n.ChildConstraints()
+	if len(cons) == 0 {
+		return nil
+	}
+	ci := cons.Index(typ)
+	if ci < 0 {
+		return fmt.Errorf("Ki Node %v cannot add child: type %v is not allowed -- allowed types: %v", n.PathUnique(), typ.Name(), cons.Types())
+	}
+	if mx := cons[ci].Max; mx > 0 && cons.Counts(childTypes(n.Children()))[ci] >= mx {
+		return fmt.Errorf("Ki Node %v cannot add child: already has maximum of %v children of type %v", n.PathUnique(), mx, cons[ci].Type.Name())
+	}
+	return nil
+}
+
+// canAddKid checks if kid can be added as a child, logging the error if not
+// -- a kid that is already our child can always be moved
+func (n *Node) canAddKid(kid Ki) error {
+	if kid.Parent() == n.This {
+		return nil
+	}
+	err := n.CanAddChild(reflect.TypeOf(kid).Elem()) // kid may not be initialized yet
+	if err != nil {
+		log.Print(err)
+	}
+	return err
+}
+
+// AllowedChildTypes returns those of given types that can be added as
+// children of given node, given its ChildConstraints and current children
+// -- e.g., for offering a choice of types to add in a GUI
+func AllowedChildTypes(par Ki, typs []reflect.Type) []reflect.Type {
+	if len(par.ChildConstraints()) == 0 {
+		return typs
+	}
+	var atyps []reflect.Type
+	for _, typ := range typs {
+		if par.CanAddChild(typ) == nil {
+			atyps = append(atyps, typ)
+		}
+	}
+	return atyps
+}
+
+// ValidateTree checks the entire tree from given node down, calling
+// Validate on each node that is a Validator, and checking the children of
+// each node against its ChildConstraints, including minimum numbers -- all
+// the violations are returned as NodeErrors, which report the path of each
+// node -- returns nil if valid
+func ValidateTree(k Ki) error {
+	var errs NodeErrors
+	k.FuncDownMeFirst(0, nil, func(kn Ki, level int, d interface{}) bool {
+		if v, ok := kn.(Validator); ok {
+			if err := v.Validate(); err != nil {
+				errs = append(errs, &NodeError{Node: kn, Err: err})
+			}
+		}
+		if err := kn.ChildConstraints().Check(childTypes(kn.Children()), true); err != nil {
+			errs = append(errs, &NodeError{Node: kn, Err: err})
+		}
+		return true
+	})
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rcoreilly/goki/ki/kit"
+)
+
+// HookTestNode records its lifecycle hooks, and is invalid if named "bad"
+type HookTestNode struct {
+	Node
+	Hooks []string `json:"-"`
+}
+
+var KiT_HookTestNode = kit.Types.AddType(&HookTestNode{}, nil)
+
+func (n *HookTestNode) New() Ki { return &HookTestNode{} }
+
+func (n *HookTestNode) OnAdded(parent Ki)   { n.Hooks = append(n.Hooks, "added to "+parent.Name()) }
+func (n *HookTestNode) OnRemoved(parent Ki) { n.Hooks = append(n.Hooks, "removed from "+parent.Name()) }
+func (n *HookTestNode) OnLoaded()           { n.Hooks = append(n.Hooks, "loaded") }
+
+func (n *HookTestNode) Validate() error {
+	if n.Name() == "bad" {
+		return errors.New("bad name")
+	}
+	return nil
+}
+
+func TestNodeHooks(t *testing.T) {
+	root := &HookTestNode{}
+	root.InitName(root, "root")
+	typ := root.Type()
+	a := root.AddNewChild(typ, "a").(*HookTestNode)
+	b := root.AddNewChild(typ, "b").(*HookTestNode)
+	kid := a.AddNewChild(typ, "kid").(*HookTestNode)
+	b.AddChild(kid) // move
+	b.DeleteChild(kid, false)
+	trg := []string{"added to a", "removed from a", "added to b", "removed from b"}
+	if !reflect.DeepEqual(kid.Hooks, trg) {
+		t.Errorf("hooks: %v != %v", kid.Hooks, trg)
+	}
+
+	a.ConfigChildren(kit.TypeAndNameList{{Type: typ, Name: "c1"}}, false)
+	c1 := a.Child(0).(*HookTestNode)
+	a.ConfigChildren(kit.TypeAndNameList{}, false)
+	if !reflect.DeepEqual(c1.Hooks, []string{"added to a", "removed from a"}) {
+		t.Errorf("config hooks: %v", c1.Hooks)
+	}
+
+	jb, _ := root.SaveJSON(false)
+	nroot := &HookTestNode{}
+	nroot.InitName(nroot, "root")
+	nroot.LoadJSON(jb)
+	nb := nroot.Child(1).(*HookTestNode)
+	if !reflect.DeepEqual(nroot.Hooks, []string{"loaded"}) || !reflect.DeepEqual(nb.Hooks, []string{"loaded"}) {
+		t.Errorf("loaded hooks: %v, %v", nroot.Hooks, nb.Hooks)
+	}
+}
+
+func TestChildConstraints(t *testing.T) {
+	root := &Node{}
+	root.InitName(root, "root")
+	if err := root.SetChildConstraints(ChildConstraints{{Type: reflect.TypeOf(0)}}); err == nil {
+		t.Errorf("non-Ki constraint type should fail")
+	}
+	root.SetChildConstraints(ChildConstraints{{Type: KiT_HookTestNode, Min: 1, Max: 2}})
+	if root.AddNewChild(KiT_Node, "n") != nil || len(root.Kids) != 0 {
+		t.Errorf("disallowed child type was added")
+	}
+	root.AddNewChild(KiT_HookTestNode, "h1")
+	if err := root.AddChild(&HookTestNode{}); err != nil {
+		t.Errorf("allowed child not added: %v", err)
+	}
+	if root.InsertNewChild(KiT_HookTestNode, 0, "h3") != nil || len(root.Kids) != 2 {
+		t.Errorf("child above maximum was added")
+	}
+	if typs := AllowedChildTypes(root, []reflect.Type{KiT_Node, KiT_HookTestNode}); len(typs) != 0 {
+		t.Errorf("no types should be allowed at maximum: %v", typs)
+	}
+	root.DeleteChildAtIndex(1, true)
+	if typs := AllowedChildTypes(root, []reflect.Type{KiT_Node, KiT_HookTestNode}); len(typs) != 1 || typs[0] != KiT_HookTestNode {
+		t.Errorf("allowed types: %v", typs)
+	}
+
+	// config is checked as a whole, including minimum
+	if mods, _ := root.ConfigChildren(kit.TypeAndNameList{}, false); mods || len(root.Kids) != 1 {
+		t.Errorf("config below minimum was applied")
+	}
+	if mods, _ := root.ConfigChildren(kit.TypeAndNameList{{Type: KiT_HookTestNode, Name: "x"}, {Type: KiT_HookTestNode, Name: "y"}}, false); !mods || len(root.Kids) != 2 {
+		t.Errorf("allowed config not applied")
+	}
+
+	// validation reports all violations, with paths
+	root.Child(0).SetName("bad")
+	root.SetChildConstraints(ChildConstraints{{Type: KiT_HookTestNode, Min: 3}})
+	err := ValidateTree(root)
+	nerrs, ok := err.(NodeErrors)
+	if !ok || len(nerrs) != 2 || !strings.Contains(nerrs[0].Error(), "below minimum") || nerrs[1].Error() != "/root/bad: bad name" {
+		t.Errorf("ValidateTree errors: %v", nerrs)
+	}
+	root.SetChildConstraints(nil)
+	root.Child(0).SetName("good")
+	if err := ValidateTree(root); err != nil {
+		t.Errorf("valid tree got error: %v", err)
+	}
+}
+
+func TestChildConstraintsSave(t *testing.T) {
+	root := &Node{}
+	root.InitName(root, "root")
+	root.SetChildConstraints(ChildConstraints{{Type: KiT_HookTestNode, Max: 2}})
+	root.AddNewChild(KiT_HookTestNode, "h1")
+	root.AddNewChild(KiT_HookTestNode, "h2")
+	jb, err := root.SaveJSON(false)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if strings.Contains(string(jb), "ChildConstraints") {
+		t.Errorf("node constraints were saved: %v", string(jb))
+	}
+	if err := root.LoadJSON(jb); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(root.Kids) != 2 || len(root.ChildConstraints()) != 1 {
+		t.Errorf("constraints lost on load: %v", root.ChildConstraints())
+	}
+	if root.AddNewChild(KiT_HookTestNode, "h3") != nil || len(root.Kids) != 2 {
+		t.Errorf("child above maximum was added after load")
+	}
+}
@@ -0,0 +1,396 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// YAML and TOML are supported by converting to / from the JSON encoding, so
+// all the same type information is preserved in exactly the same way: the
+// Slice type / name header for children, and the Props __type: and __enum:
+// conventions.  The only difference is the Slice header, which in JSON has
+// repeated "type" and "name" keys -- this is not valid in YAML or TOML, so
+// it is represented as a mapping with the number of children n and a list of
+// the type and name for each child, e.g., in YAML:
+//
+//	Kids:
+//	- n: 2
+//	  kids:
+//	  - type: ki.Node
+//	    name: child1
+//	  - type: ki.Node
+//	    name: child2
+//	- Nm: child1
+//	  ...
+//
+// In TOML, null values are omitted (TOML has no null), and map keys are
+// sorted, but otherwise the same structure is used.
+
+// textMap is an ordered map of the JSON encoding, which can contain
+// duplicate keys, as in the Slice header
+type textMap []textItem
+
+// textItem is one key, value item in a textMap
+type textItem struct {
+	Key   string
+	Value interface{}
+}
+
+// textFmJSON parses the JSON encoding into a generic ordered representation
+// of textMap, []interface{}, string, int64, float64, bool and nil values,
+// converting Slice headers into the n, kids form
+func textFmJSON(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return textFmJSONDec(dec)
+}
+
+func textFmJSONDec(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tv := tok.(type) {
+	case json.Delim:
+		if tv == '{' {
+			var tm textMap
+			for dec.More() {
+				kt, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				val, err := textFmJSONDec(dec)
+				if err != nil {
+					return nil, err
+				}
+				tm = append(tm, textItem{fmt.Sprint(kt), val})
+			}
+			_, err = dec.Token()
+			return tm, err
+		}
+		ar := []interface{}{}
+		for dec.More() {
+			val, err := textFmJSONDec(dec)
+			if err != nil {
+				return nil, err
+			}
+			ar = append(ar, val)
+		}
+		_, err = dec.Token()
+		if len(ar) > 0 {
+			if hdr, ok := textSliceHeader(ar[0]); ok {
+				ar[0] = hdr
+			}
+		}
+		return ar, err
+	case json.Number:
+		if iv, err := tv.Int64(); err == nil {
+			return iv, nil
+		}
+		return tv.Float64()
+	}
+	return tok, nil
+}
+
+// textSliceHeader converts a Slice header with repeated type, name keys
+// into the n, kids form, if v is such a header
+func textSliceHeader(v interface{}) (textMap, bool) {
+	tm, ok := v.(textMap)
+	if !ok || len(tm) == 0 || tm[0].Key != "n" || len(tm)%2 != 1 {
+		return nil, false
+	}
+	n, ok := tm[0].Value.(int64)
+	if !ok || int(n)*2+1 != len(tm) {
+		return nil, false
+	}
+	kids := make([]interface{}, n)
+	for i := range kids {
+		ti := tm[2*i+1]
+		ni := tm[2*i+2]
+		if ti.Key != "type" || ni.Key != "name" {
+			return nil, false
+		}
+		kids[i] = textMap{{"type", ti.Value}, {"name", ni.Value}}
+	}
+	return textMap{{"n", n}, {"kids", kids}}, true
+}
+
+// textGet returns the value for given key in map m, which can be a textMap
+// or a map[string]interface{} (e.g., from TOML)
+func textGet(m interface{}, key string) (interface{}, bool) {
+	switch mv := m.(type) {
+	case textMap:
+		for _, it := range mv {
+			if it.Key == key {
+				return it.Value, true
+			}
+		}
+	case map[string]interface{}:
+		val, ok := mv[key]
+		return val, ok
+	}
+	return nil, false
+}
+
+// textWriteJSON writes the JSON encoding of a generic representation,
+// converting Slice headers in the n, kids form back into the JSON form
+func textWriteJSON(buf *bytes.Buffer, v interface{}) error {
+	switch tv := v.(type) {
+	case textMap:
+		buf.WriteByte('{')
+		for i, it := range tv {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			kb, _ := json.Marshal(it.Key)
+			buf.Write(kb)
+			buf.WriteByte(':')
+			if err := textWriteJSON(buf, it.Value); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, el := range tv {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if i == 0 {
+				if ok, err := textWriteSliceHeader(buf, el); ok || err != nil {
+					if err != nil {
+						return err
+					}
+					continue
+				}
+			}
+			if err := textWriteJSON(buf, el); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	default:
+		b, err := json.Marshal(tv)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+	}
+	return nil
+}
+
+// textWriteSliceHeader writes a Slice header in the n, kids form in the
+// JSON form, returning false if v is not such a header
+func textWriteSliceHeader(buf *bytes.Buffer, v interface{}) (bool, error) {
+	if tm, ok := v.(textMap); !ok || len(tm) != 2 {
+		return false, nil
+	}
+	nv, ok := textGet(v, "n")
+	if !ok {
+		return false, nil
+	}
+	kv, ok := textGet(v, "kids")
+	if !ok {
+		return false, nil
+	}
+	kids, ok := kv.([]interface{})
+	if !ok {
+		return false, nil
+	}
+	buf.WriteString("{\"n\":")
+	if err := textWriteJSON(buf, nv); err != nil {
+		return true, err
+	}
+	for _, kid := range kids {
+		tn, _ := textGet(kid, "type")
+		nm, _ := textGet(kid, "name")
+		tb, _ := json.Marshal(fmt.Sprint(tn))
+		nb, _ := json.Marshal(fmt.Sprint(nm))
+		buf.WriteString(",\"type\":")
+		buf.Write(tb)
+		buf.WriteString(",\"name\":")
+		buf.Write(nb)
+	}
+	buf.WriteByte('}')
+	return true, nil
+}
+
+//////////////////////////////////////////////////////////////////////////
+//  YAML
+
+// textToYAML converts the generic representation for yaml.Marshal
+func textToYAML(v interface{}) interface{} {
+	switch tv := v.(type) {
+	case textMap:
+		ms := make(yaml.MapSlice, len(tv))
+		for i, it := range tv {
+			ms[i] = yaml.MapItem{Key: it.Key, Value: textToYAML(it.Value)}
+		}
+		return ms
+	case []interface{}:
+		ar := make([]interface{}, len(tv))
+		for i, el := range tv {
+			ar[i] = textToYAML(el)
+		}
+		return ar
+	}
+	return v
+}
+
+// textFmYAML converts the result of yaml.Unmarshal into the generic
+// representation
+func textFmYAML(v interface{}) interface{} {
+	switch tv := v.(type) {
+	case yaml.MapSlice:
+		tm := make(textMap, len(tv))
+		for i, it := range tv {
+			tm[i] = textItem{fmt.Sprint(it.Key), textFmYAML(it.Value)}
+		}
+		return tm
+	case map[interface{}]interface{}:
+		tm := make(textMap, 0, len(tv))
+		for key, val := range tv {
+			tm = append(tm, textItem{fmt.Sprint(key), textFmYAML(val)})
+		}
+		sort.Slice(tm, func(i, j int) bool { return tm[i].Key < tm[j].Key })
+		return tm
+	case []interface{}:
+		ar := make([]interface{}, len(tv))
+		for i, el := range tv {
+			ar[i] = textFmYAML(el)
+		}
+		return ar
+	}
+	return v
+}
+
+// YAMLFmJSON converts the JSON encoding of a tree (or Props) to YAML, as
+// described above
+func YAMLFmJSON(b []byte) ([]byte, error) {
+	tv, err := textFmJSON(b)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(textToYAML(tv))
+}
+
+// JSONFmYAML converts the YAML encoding of a tree (or Props) to JSON, as
+// described above
+func JSONFmYAML(b []byte) ([]byte, error) {
+	var ms yaml.MapSlice
+	if err := yaml.Unmarshal(b, &ms); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	err := textWriteJSON(&buf, textFmYAML(ms))
+	return buf.Bytes(), err
+}
+
+//////////////////////////////////////////////////////////////////////////
+//  TOML
+
+// textToTOML converts the generic representation for the toml encoder,
+// dropping null values
+func textToTOML(v interface{}) interface{} {
+	switch tv := v.(type) {
+	case textMap:
+		m := make(map[string]interface{}, len(tv))
+		for _, it := range tv {
+			if it.Value == nil {
+				continue
+			}
+			m[it.Key] = textToTOML(it.Value)
+		}
+		return m
+	case []interface{}:
+		ar := make([]interface{}, 0, len(tv))
+		for _, el := range tv {
+			if el == nil {
+				continue
+			}
+			ar = append(ar, textToTOML(el))
+		}
+		if len(ar) > 0 {
+			if _, ok := ar[0].(map[string]interface{}); ok { // array of tables
+				tar := make([]map[string]interface{}, 0, len(ar))
+				for _, el := range ar {
+					if m, ok := el.(map[string]interface{}); ok {
+						tar = append(tar, m)
+					}
+				}
+				if len(tar) == len(ar) {
+					return tar
+				}
+			}
+		}
+		return ar
+	}
+	return v
+}
+
+// textFmTOML converts the result of toml.Decode into the generic
+// representation -- keys are sorted, except for the Slice header
+func textFmTOML(v interface{}) interface{} {
+	switch tv := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(tv))
+		for key := range tv {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		if _, ok := tv["kids"]; ok && len(keys) == 2 && keys[1] == "n" {
+			keys[0], keys[1] = "n", "kids"
+		}
+		tm := make(textMap, len(keys))
+		for i, key := range keys {
+			tm[i] = textItem{key, textFmTOML(tv[key])}
+		}
+		return tm
+	case []map[string]interface{}:
+		ar := make([]interface{}, len(tv))
+		for i, el := range tv {
+			ar[i] = textFmTOML(el)
+		}
+		return ar
+	case []interface{}:
+		ar := make([]interface{}, len(tv))
+		for i, el := range tv {
+			ar[i] = textFmTOML(el)
+		}
+		return ar
+	}
+	return v
+}
+
+// TOMLFmJSON converts the JSON encoding of a tree (or Props) to TOML, as
+// described above
+func TOMLFmJSON(b []byte) ([]byte, error) {
+	tv, err := textFmJSON(b)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	err = toml.NewEncoder(&buf).Encode(textToTOML(tv))
+	return buf.Bytes(), err
+}
+
+// JSONFmTOML converts the TOML encoding of a tree (or Props) to JSON, as
+// described above
+func JSONFmTOML(b []byte) ([]byte, error) {
+	var m map[string]interface{}
+	if _, err := toml.Decode(string(b), &m); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	err := textWriteJSON(&buf, textFmTOML(m))
+	return buf.Bytes(), err
+}
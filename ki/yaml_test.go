@@ -0,0 +1,119 @@
+// Copyright (c) 2018, Randall C. O'Reilly. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ki
+
+import (
+	"bytes"
+	"testing"
+)
+
+// textTestTree has one prop per node, so the JSON rep is deterministic
+func textTestTree() *NodeEmbed {
+	parent := NodeEmbed{}
+	parent.InitName(&parent, "par1")
+	parent.Mbr1 = "bloop"
+	parent.Mbr2 = 32
+	child1 := parent.AddNewChild(nil, "child1")
+	child2 := parent.AddNewChild(nil, "child2").(*NodeEmbed)
+	child3 := parent.AddNewChild(nil, "child3")
+	schild2 := child2.AddNewChild(nil, "subchild1")
+	child4 := parent.AddNewChild(KiT_NodeWithField, "child4").(*NodeWithField)
+	child4.Field1.Mbr1 = "field"
+
+	parent.SetProp("floatprop", 3.1415)
+	child1.SetProp("stringprop", "str: with \"quotes\"")
+	child2.SetProp("enumprop", DiffSetProp)
+	child3.SetProp("structprop", NodeEmbed{Mbr1: "sp", Mbr2: 42})
+	schild2.SetProp("propsprop", Props{"intprop": 17})
+
+	parent.Ptr.Ptr = child2
+	child2.Ptr.Ptr = schild2
+	return &parent
+}
+
+func TestNodeYAMLSave(t *testing.T) {
+	parent := textTestTree()
+	jb, _ := parent.SaveJSON(true)
+
+	b, err := parent.SaveYAML()
+	if err != nil {
+		t.Error(err)
+	}
+	// fmt.Printf("yaml output:\n%v\n", string(b))
+	tstload := NodeEmbed{}
+	tstload.InitName(&tstload, "")
+	err = tstload.LoadYAML(b)
+	if err != nil {
+		t.Error(err)
+	}
+	tstb, _ := tstload.SaveJSON(true)
+	if !bytes.Equal(tstb, jb) {
+		t.Errorf("original and YAML loaded json rep are not equivalent:\n%v\nvs:\n%v", string(jb), string(tstb))
+	}
+	if tstload.Ptr.Ptr != tstload.Child(1) || tstload.Child(1).(*NodeEmbed).Ptr.Ptr != tstload.Child(1).Child(0) {
+		t.Errorf("LoadYAML: Ptr not restored")
+	}
+	if pv := tstload.Child(1).Prop("enumprop", false, false); pv != DiffSetProp {
+		t.Errorf("LoadYAML: enumprop should be DiffSetProp, was %#v", pv)
+	}
+	if pv, ok := tstload.Child(2).Prop("structprop", false, false).(*NodeEmbed); !ok || pv.Mbr1 != "sp" || pv.Mbr2 != 42 {
+		t.Errorf("LoadYAML: structprop should be restored, was %#v", pv)
+	}
+
+	// and chained through XML
+	xb, err := tstload.SaveXML(true)
+	if err != nil {
+		t.Error(err)
+	}
+	xload := NodeEmbed{}
+	xload.InitName(&xload, "")
+	if err = xload.LoadXML(xb); err != nil {
+		t.Error(err)
+	}
+	xb2, _ := xload.SaveXML(true)
+	if !bytes.Equal(xb, xb2) {
+		t.Errorf("YAML loaded XML rep is not equivalent:\n%v\nvs:\n%v", string(xb), string(xb2))
+	}
+}
+
+func TestNodeTOMLSave(t *testing.T) {
+	parent := textTestTree()
+	jb, _ := parent.SaveJSON(true)
+
+	b, err := parent.SaveTOML()
+	if err != nil {
+		t.Error(err)
+	}
+	// fmt.Printf("toml output:\n%v\n", string(b))
+	tstload := NodeEmbed{}
+	tstload.InitName(&tstload, "")
+	err = tstload.LoadTOML(b)
+	if err != nil {
+		t.Error(err)
+	}
+	tstb, _ := tstload.SaveJSON(true)
+	if !bytes.Equal(tstb, jb) {
+		t.Errorf("original and TOML loaded json rep are not equivalent:\n%v\nvs:\n%v", string(jb), string(tstb))
+	}
+	if tstload.Ptr.Ptr != tstload.Child(1) {
+		t.Errorf("LoadTOML: Ptr not restored")
+	}
+}
+
+func TestNodeTextNoThis(t *testing.T) {
+	var n NodeEmbed
+	if _, err := n.SaveYAML(); err == nil {
+		t.Errorf("SaveYAML without This should give error")
+	}
+	if err := n.LoadYAML([]byte("Nm: a\n")); err == nil {
+		t.Errorf("LoadYAML without This should give error")
+	}
+	if _, err := n.SaveTOML(); err == nil {
+		t.Errorf("SaveTOML without This should give error")
+	}
+	if err := n.LoadTOML([]byte("Nm = \"a\"\n")); err == nil {
+		t.Errorf("LoadTOML without This should give error")
+	}
+}